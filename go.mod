@@ -1,6 +1,6 @@
 module github.com/maistra/maistra-test-tool
 
-go 1.15
+go 1.18
 
 require (
 	github.com/joho/godotenv v1.4.0
@@ -8,4 +8,10 @@ require (
 	github.com/pmezard/go-difflib v1.0.0
 	github.com/sirupsen/logrus v1.8.1
 	golang.org/x/net v0.7.0
+	sigs.k8s.io/yaml v1.3.0
+)
+
+require (
+	golang.org/x/sys v0.5.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -15,12 +15,11 @@
 package examples
 
 import (
-	"time"
-
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-// samples directory is github.com/maistra/maistra-test-tool/testdata/examples/x86
+// samples directory is github.com/maistra/maistra-test-tool/testdata/examples/<arch>, see util.SampleFile
 
 // Bookinfo includes app deployment namespace
 type Bookinfo struct {
@@ -29,31 +28,32 @@ type Bookinfo struct {
 
 func (b *Bookinfo) Install(mtls bool) {
 	util.Log.Info("Deploying Bookinfo")
-	util.KubeApply(b.Namespace, bookinfoYaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(b.Namespace, "app=details")
-	util.CheckPodRunning(b.Namespace, "app=ratings")
-	util.CheckPodRunning(b.Namespace, "app=reviews,version=v1")
-	util.CheckPodRunning(b.Namespace, "app=reviews,version=v2")
-	util.CheckPodRunning(b.Namespace, "app=reviews,version=v3")
-	util.CheckPodRunning(b.Namespace, "app=productpage")
+	util.KubeApply(b.Namespace, util.SampleFile(bookinfoYaml))
+	waitForPods(b.Namespace, "app=details", "app=ratings", "app=reviews", "app=productpage")
 
 	util.Log.Info("Creating Gateway")
-	util.KubeApply(b.Namespace, bookinfoGateway)
+	util.KubeApply(b.Namespace, util.SampleFile(bookinfoGateway))
 
 	util.Log.Info("Creating destination rules all")
+	sync := wait.BeforeConfigChange(wait.Proxies{Namespace: b.Namespace, Selector: "app=productpage"})
 	if mtls {
-		util.KubeApply(b.Namespace, bookinfoRuleAllTLSYaml)
+		util.KubeApply(b.Namespace, util.SampleFile(bookinfoRuleAllTLSYaml))
 	} else {
-		util.KubeApply(b.Namespace, bookinfoRuleAllYaml)
+		util.KubeApply(b.Namespace, util.SampleFile(bookinfoRuleAllYaml))
+	}
+	var rules []wait.Resource
+	for _, name := range []string{"productpage", "reviews", "ratings", "details"} {
+		rules = append(rules, wait.Resource{Kind: "DestinationRule", Namespace: b.Namespace, Name: name})
+	}
+	if err := wait.UpTo(podsTimeout, sync.Propagated(rules...)); err != nil {
+		util.Log.Error(err)
 	}
-	time.Sleep(time.Duration(10) * time.Second)
 }
 
 func (b *Bookinfo) Uninstall() {
 	util.Log.Info("Cleanup Bookinfo")
-	util.KubeDelete(b.Namespace, bookinfoRuleAllYaml)
-	util.KubeDelete(b.Namespace, bookinfoGateway)
-	util.KubeDelete(b.Namespace, bookinfoYaml)
-	time.Sleep(time.Duration(10) * time.Second)
+	deleteManifest(b.Namespace, util.SampleFile(bookinfoRuleAllYaml))
+	deleteManifest(b.Namespace, util.SampleFile(bookinfoGateway))
+	deleteManifest(b.Namespace, util.SampleFile(bookinfoYaml))
+	waitForPodsDeleted(b.Namespace, "app in (details,ratings,reviews,productpage)")
 }
@@ -15,8 +15,6 @@
 package examples
 
 import (
-	"time"
-
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
@@ -26,29 +24,24 @@ type Echo struct {
 
 func (e *Echo) Install() {
 	util.Log.Info("Deploy Echo")
-	util.KubeApply(e.Namespace, echoYaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(e.Namespace, "app=tcp-echo,version=v1")
-	util.CheckPodRunning(e.Namespace, "app=tcp-echo,version=v2")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(e.Namespace, util.SampleFile(echoYaml))
+	waitForPods(e.Namespace, "app=tcp-echo,version=v1", "app=tcp-echo,version=v2")
 }
 
 func (e *Echo) InstallWithProxy() {
 	util.Log.Info("Deploy Echo")
-	util.KubeApply(e.Namespace, echoWithProxy)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(e.Namespace, "app=tcp-echo,version=v1")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(e.Namespace, util.SampleFile(echoWithProxy))
+	waitForPods(e.Namespace, "app=tcp-echo,version=v1")
 }
 
 func (e *Echo) Uninstall() {
 	util.Log.Info("Cleanup Echo")
-	util.KubeDelete(e.Namespace, echoYaml)
-	time.Sleep(time.Duration(10) * time.Second)
+	deleteManifest(e.Namespace, util.SampleFile(echoYaml))
+	waitForPodsDeleted(e.Namespace, "app=tcp-echo")
 }
 
 func (e *Echo) UninstallWithProxy() {
 	util.Log.Info("Cleanup Echo")
-	util.KubeDelete(e.Namespace, echoWithProxy)
-	time.Sleep(time.Duration(10) * time.Second)
+	deleteManifest(e.Namespace, util.SampleFile(echoWithProxy))
+	waitForPodsDeleted(e.Namespace, "app=tcp-echo")
 }
@@ -15,8 +15,6 @@
 package examples
 
 import (
-	"time"
-
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
@@ -26,14 +24,12 @@ type Fortio struct {
 
 func (f *Fortio) Install() {
 	util.Log.Info("Deploy Fortio")
-	util.KubeApply(f.Namespace, fortioYaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(f.Namespace, "app=fortio")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(f.Namespace, util.SampleFile(fortioYaml))
+	waitForPods(f.Namespace, "app=fortio")
 }
 
 func (f *Fortio) Uninstall() {
 	util.Log.Info("Cleanup Fortio")
-	util.KubeDelete(f.Namespace, fortioYaml)
-	time.Sleep(time.Duration(10) * time.Second)
+	deleteManifest(f.Namespace, util.SampleFile(fortioYaml))
+	waitForPodsDeleted(f.Namespace, "app=fortio")
 }
@@ -28,49 +28,43 @@ type Httpbin struct {
 
 func (h *Httpbin) Install() error {
 	util.Log.Infof("Deploying Httpbin on namespace %s", h.Namespace)
-	util.KubeApply(h.Namespace, httpbinYaml)
+	util.KubeApply(h.Namespace, util.SampleFile(httpbinYaml))
 	_, err := util.CheckDeploymentIsReady(h.Namespace, "httpbin", time.Second*180)
 	return err
 }
 
 func (h *Httpbin) InstallLegacy() {
 	util.Log.Info("Deploy Httpbin")
-	util.KubeApply(h.Namespace, httpbinLegacyYaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(h.Namespace, "app=httpbin")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(h.Namespace, util.SampleFile(httpbinLegacyYaml))
+	waitForPods(h.Namespace, "app=httpbin")
 }
 
 func (h *Httpbin) InstallV1() {
 	util.Log.Info("Deploy Httpbin-v1")
-	util.KubeApply(h.Namespace, httpbinv1Yaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(h.Namespace, "app=httpbin,version=v1")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(h.Namespace, util.SampleFile(httpbinv1Yaml))
+	waitForPods(h.Namespace, "app=httpbin,version=v1")
 }
 
 func (h *Httpbin) InstallV2() {
 	util.Log.Info("Deploy Httpbin-v2")
-	util.KubeApply(h.Namespace, httpbinv2Yaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(h.Namespace, "app=httpbin,version=v2")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(h.Namespace, util.SampleFile(httpbinv2Yaml))
+	waitForPods(h.Namespace, "app=httpbin,version=v2")
 }
 
 func (h *Httpbin) Uninstall() {
 	util.Log.Infof("Removing Httpbin on namespace %s", h.Namespace)
-	util.KubeDelete(h.Namespace, httpbinYaml)
-	util.Shell(`oc -n %s wait --for=delete -l app=httpbin pods --timeout=30s`, h.Namespace)
+	deleteManifest(h.Namespace, util.SampleFile(httpbinYaml))
+	waitForPodsDeleted(h.Namespace, "app=httpbin")
 }
 
 func (h *Httpbin) UninstallV1() {
 	util.Log.Info("Cleanup Httpbin-v1")
-	util.KubeDelete(h.Namespace, httpbinv1Yaml)
-	time.Sleep(time.Duration(10) * time.Second)
+	deleteManifest(h.Namespace, util.SampleFile(httpbinv1Yaml))
+	waitForPodsDeleted(h.Namespace, "app=httpbin,version=v1")
 }
 
 func (h *Httpbin) UninstallV2() {
 	util.Log.Info("Cleanup Httpbin-v2")
-	util.KubeDelete(h.Namespace, httpbinv2Yaml)
-	time.Sleep(time.Duration(10) * time.Second)
+	deleteManifest(h.Namespace, util.SampleFile(httpbinv2Yaml))
+	waitForPodsDeleted(h.Namespace, "app=httpbin,version=v2")
 }
@@ -14,7 +14,46 @@
 
 package examples
 
+import (
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
+)
+
 type ExampleInterface interface {
 	Install() error
 	Uninstall()
 }
+
+// podsTimeout is the time the pods of an example get to become ready or to terminate.
+const podsTimeout = 3 * time.Minute
+
+// waitForPods waits until the pods matching the selector are ready. Failures are logged, as the
+// tests check the behavior of the example anyway.
+func waitForPods(ns string, selectors ...string) {
+	for _, selector := range selectors {
+		if err := wait.UpTo(podsTimeout, wait.PodsReady(ns, selector)); err != nil {
+			util.Log.Error(err)
+		}
+	}
+}
+
+// waitForPodsDeleted waits until the pods matching the selector have terminated.
+func waitForPodsDeleted(ns, selector string) {
+	if err := wait.UpTo(podsTimeout, wait.AllDeleted(ns, "pods", selector)); err != nil {
+		util.Log.Error(err)
+	}
+}
+
+// deleteManifest deletes the objects of a manifest file and waits until they are gone. Failures are
+// logged, as leftovers of an example are deleted with its namespace at the latest.
+func deleteManifest(ns, filename string) {
+	m, err := util.LoadManifest(filename)
+	if err == nil {
+		err = m.Delete(ns, podsTimeout)
+	}
+	if err != nil {
+		util.Log.Errorf("Failed to delete %s: %v", filename, err)
+	}
+}
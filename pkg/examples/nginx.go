@@ -15,8 +15,6 @@
 package examples
 
 import (
-	"time"
-
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
@@ -27,41 +25,40 @@ type Nginx struct {
 func (n *Nginx) Install(config string) {
 	util.Log.Info("Create Secret")
 	util.CreateTLSSecret("nginx-server-certs", n.Namespace, nginxServerCertKey, nginxServerCert)
-	util.Shell(`kubectl create -n %s secret generic nginx-ca-certs --from-file=%s`, n.Namespace, nginxServerCACert)
+	msg, _ := util.Shell(`kubectl create -n %s secret generic nginx-ca-certs --from-file=%s`, n.Namespace, nginxServerCACert)
+	util.TrackCreated(n.Namespace, msg)
 
 	util.Log.Info("Create ConfigMap")
-	util.Shell(`kubectl create configmap nginx-configmap --from-file=nginx.conf=%s -n %s`, config, n.Namespace)
-	time.Sleep(time.Duration(5) * time.Second)
+	msg, _ = util.Shell(`kubectl create configmap nginx-configmap --from-file=nginx.conf=%s -n %s`, config, n.Namespace)
+	util.TrackCreated(n.Namespace, msg)
 
 	util.Log.Info("Deploy Nginx")
-	util.KubeApply(n.Namespace, nginxYaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(n.Namespace, "run=my-nginx")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(n.Namespace, util.SampleFile(nginxYaml))
+	waitForPods(n.Namespace, "run=my-nginx")
 }
 
-// Install_mTLS deploys a nginx server with mtls config in mesh-external namespace
+// Install_mTLS deploys a nginx server with mtls config. The server certificate is issued for
+// my-nginx.mesh-external.svc.cluster.local, so the namespace must be mesh-external.
 func (n *Nginx) Install_mTLS(config string) {
 	util.Log.Info("Create Secret")
-	util.CreateTLSSecret("nginx-server-certs", "mesh-external", meshExtServerCertKey, meshExtServerCert)
-	util.Shell(`kubectl create -n %s secret generic nginx-ca-certs --from-file=%s`, "mesh-external", nginxServerCACert)
+	util.CreateTLSSecret("nginx-server-certs", n.Namespace, meshExtServerCertKey, meshExtServerCert)
+	msg, _ := util.Shell(`kubectl create -n %s secret generic nginx-ca-certs --from-file=%s`, n.Namespace, nginxServerCACert)
+	util.TrackCreated(n.Namespace, msg)
 
 	util.Log.Info("Create ConfigMap")
-	util.Shell(`kubectl create configmap nginx-configmap --from-file=nginx.conf=%s -n %s`, config, "mesh-external")
-	time.Sleep(time.Duration(5) * time.Second)
+	msg, _ = util.Shell(`kubectl create configmap nginx-configmap --from-file=nginx.conf=%s -n %s`, config, n.Namespace)
+	util.TrackCreated(n.Namespace, msg)
 
 	util.Log.Info("Deploy Nginx")
-	util.KubeApply("mesh-external", nginxYaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning("mesh-external", "run=my-nginx")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(n.Namespace, util.SampleFile(nginxYaml))
+	waitForPods(n.Namespace, "run=my-nginx")
 }
 
 func (n *Nginx) Uninstall() {
 	util.Log.Info("Cleanup Nginx")
-	util.KubeDelete(n.Namespace, nginxYaml)
+	deleteManifest(n.Namespace, util.SampleFile(nginxYaml))
 	util.Shell(`kubectl delete configmap nginx-configmap -n %s`, n.Namespace)
 	util.Shell(`kubectl delete secret nginx-server-certs -n %s`, n.Namespace)
 	util.Shell(`kubectl delete secret nginx-ca-certs -n %s`, n.Namespace)
-	time.Sleep(time.Duration(10) * time.Second)
+	waitForPodsDeleted(n.Namespace, "run=my-nginx")
 }
@@ -16,9 +16,9 @@ package examples
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
 type Redis struct {
@@ -32,7 +32,7 @@ func (r *Redis) Install() error {
 		return fmt.Errorf("error creating redis namespace: %v", err)
 	}
 
-	if err := util.KubeApply(r.Namespace, redisYaml); err != nil {
+	if _, err := util.KubeApply(r.Namespace, util.SampleFile(redisYaml)); err != nil {
 		return fmt.Errorf("error deploying redis: %v", err)
 	}
 
@@ -49,7 +49,9 @@ func (r *Redis) Install() error {
 
 func (r *Redis) Uninstall() {
 	util.Log.Info("Cleanup Redis")
-	util.KubeDelete(r.Namespace, redisYaml)
+	deleteManifest(r.Namespace, util.SampleFile(redisYaml))
 	util.DeleteNamespace(r.Namespace)
-	time.Sleep(time.Duration(10) * time.Second)
+	if err := wait.UpTo(podsTimeout, wait.Deleted("", "namespace", r.Namespace)); err != nil {
+		util.Log.Error(err)
+	}
 }
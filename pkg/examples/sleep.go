@@ -27,11 +27,20 @@ kind: ConfigMap
 metadata:
   name: sleep-configmap
 data:
-  https-proxy: "{{ .HTTPProxy }}"
-  http-proxy: "{{ .HTTPSProxy }}"
-  no-proxy: "{{ .NoProxy }}"
+  https-proxy: {{ quote .HTTPProxy }}
+  http-proxy: {{ quote .HTTPSProxy }}
+  no-proxy: {{ quote .NoProxy }}
 `
 
+// renderSleepConfigmap returns the configmap with the proxy settings of the cluster.
+func renderSleepConfigmap() (string, error) {
+	proxy, err := util.GetProxy()
+	if err != nil {
+		return "", err
+	}
+	return util.RunTemplate(sleepConfigmap, proxy)
+}
+
 var _ ExampleInterface = &Sleep{}
 
 type Sleep struct {
@@ -40,32 +49,39 @@ type Sleep struct {
 
 func (s *Sleep) Install() error {
 	util.Log.Infof("Deploying Sleep in namespace %s", s.Namespace)
-	proxy, _ := util.GetProxy()
-	configmap := util.RunTemplate(sleepConfigmap, proxy)
+	configmap, err := renderSleepConfigmap()
+	if err != nil {
+		return err
+	}
 	util.Log.Infof("Creating configmap %s", configmap)
 	util.KubeApplyContents(s.Namespace, configmap)
-	util.KubeApply(s.Namespace, sleepYaml)
-	_, err := util.CheckDeploymentIsReady(s.Namespace, "sleep", time.Second*180)
+	util.KubeApply(s.Namespace, util.SampleFile(sleepYaml))
+	_, err = util.CheckDeploymentIsReady(s.Namespace, "sleep", time.Second*180)
 	return err
 }
 
 func (s *Sleep) InstallLegacy() {
 	util.Log.Info("Deploy Sleep")
-	proxy, _ := util.GetProxy()
-	configmap := util.RunTemplate(sleepConfigmap, proxy)
+	configmap, err := renderSleepConfigmap()
+	if err != nil {
+		util.Log.Errorf("Failed to render the sleep configmap: %v", err)
+		return
+	}
 	util.Log.Infof("Creating configmap %s", configmap)
 	util.KubeApplyContents(s.Namespace, configmap)
-	util.KubeApply(s.Namespace, sleepLegacyYaml)
-	time.Sleep(time.Duration(5) * time.Second)
-	util.CheckPodRunning(s.Namespace, "app=sleep")
-	time.Sleep(time.Duration(10) * time.Second)
+	util.KubeApply(s.Namespace, util.SampleFile(sleepLegacyYaml))
+	waitForPods(s.Namespace, "app=sleep")
 }
 
 func (s *Sleep) Uninstall() {
 	util.Log.Infof("Removing Sleep on namespace %s", s.Namespace)
-	proxy, _ := util.GetProxy()
-	configmap := util.RunTemplate(sleepConfigmap, proxy)
-	util.KubeDeleteContents(s.Namespace, configmap)
-	util.KubeDelete(s.Namespace, sleepYaml)
-	util.Shell(`oc -n %s wait --for=delete -l app=sleep pods --timeout=30s`, s.Namespace)
+	if configmap, err := renderSleepConfigmap(); err != nil {
+		util.Log.Errorf("Failed to render the sleep configmap: %v", err)
+	} else if m, err := util.ParseManifest(configmap); err != nil {
+		util.Log.Errorf("Failed to parse the sleep configmap: %v", err)
+	} else if err := m.Delete(s.Namespace, podsTimeout); err != nil {
+		util.Log.Errorf("Failed to delete the sleep configmap: %v", err)
+	}
+	deleteManifest(s.Namespace, util.SampleFile(sleepYaml))
+	waitForPodsDeleted(s.Namespace, "app=sleep")
 }
@@ -16,49 +16,40 @@ package examples
 
 import (
 	"fmt"
-	"os"
 )
 
-func getenv(key, fallback string) string {
-	value := os.Getenv(key)
-	if len(value) == 0 {
-		return fallback
-	}
-	return value
-}
-
 var (
-	branch  = getenv("SAMPLEARCH", "x86")
-	basedir = "../testdata/examples"
 	certdir = "../sampleCerts"
-)
-
-var (
-	bookinfoYaml           = fmt.Sprintf("%s/%s/bookinfo/bookinfo.yaml", basedir, branch)
-	bookinfoGateway        = fmt.Sprintf("%s/%s/bookinfo/bookinfo-gateway.yaml", basedir, branch)
-	bookinfoRuleAllYaml    = fmt.Sprintf("%s/%s/bookinfo/destination-rule-all.yaml", basedir, branch)
-	bookinfoRuleAllTLSYaml = fmt.Sprintf("%s/%s/bookinfo/destination-rule-all-mtls.yaml", basedir, branch)
-
-	echoYaml      = fmt.Sprintf("%s/%s/tcp-echo/tcp-echo-services.yaml", basedir, branch)
-	echoWithProxy = fmt.Sprintf("%s/%s/tcp-echo/tcp-echo.yaml", basedir, branch)
-
-	fortioYaml = fmt.Sprintf("%s/%s/httpbin/sample-client/fortio-deploy.yaml", basedir, branch)
-
-	httpbinYaml       = fmt.Sprintf("%s/%s/httpbin/httpbin.yaml", basedir, branch)
-	httpbinLegacyYaml = fmt.Sprintf("%s/%s/httpbin/httpbin_legacy.yaml", basedir, branch)
-	httpbinv1Yaml     = fmt.Sprintf("%s/%s/httpbin/httpbinv1.yaml", basedir, branch)
-	httpbinv2Yaml     = fmt.Sprintf("%s/%s/httpbin/httpbinv2.yaml", basedir, branch)
 
 	nginxServerCertKey   = fmt.Sprintf("%s/nginx.example.com/nginx.example.com.key", certdir)
 	nginxServerCert      = fmt.Sprintf("%s/nginx.example.com/nginx.example.com.crt", certdir)
 	nginxServerCACert    = fmt.Sprintf("%s/nginx.example.com/example.com.crt", certdir)
 	meshExtServerCertKey = fmt.Sprintf("%s/nginx.example.com/my-nginx.mesh-external.svc.cluster.local.key", certdir)
 	meshExtServerCert    = fmt.Sprintf("%s/nginx.example.com/my-nginx.mesh-external.svc.cluster.local.crt", certdir)
-	nginxConf            = fmt.Sprintf("%s/%s/nginx/nginx.conf", basedir, branch)
-	nginxYaml            = fmt.Sprintf("%s/%s/nginx/nginx.yaml", basedir, branch)
+)
+
+// The sample files are resolved for the configured architecture with util.SampleFile when they are used.
+const (
+	bookinfoYaml           = "bookinfo/bookinfo.yaml"
+	bookinfoGateway        = "bookinfo/bookinfo-gateway.yaml"
+	bookinfoRuleAllYaml    = "bookinfo/destination-rule-all.yaml"
+	bookinfoRuleAllTLSYaml = "bookinfo/destination-rule-all-mtls.yaml"
+
+	echoYaml      = "tcp-echo/tcp-echo-services.yaml"
+	echoWithProxy = "tcp-echo/tcp-echo.yaml"
+
+	fortioYaml = "httpbin/sample-client/fortio-deploy.yaml"
+
+	httpbinYaml       = "httpbin/httpbin.yaml"
+	httpbinLegacyYaml = "httpbin/httpbin_legacy.yaml"
+	httpbinv1Yaml     = "httpbin/httpbinv1.yaml"
+	httpbinv2Yaml     = "httpbin/httpbinv2.yaml"
+
+	nginxConf = "nginx/nginx.conf"
+	nginxYaml = "nginx/nginx.yaml"
 
-	redisYaml = fmt.Sprintf("%s/%s/redis/redis.yaml", basedir, branch)
+	redisYaml = "redis/redis.yaml"
 
-	sleepYaml       = fmt.Sprintf("%s/%s/sleep/sleep.yaml", basedir, branch)
-	sleepLegacyYaml = fmt.Sprintf("%s/%s/sleep/sleep_legacy.yaml", basedir, branch)
+	sleepYaml       = "sleep/sleep.yaml"
+	sleepLegacyYaml = "sleep/sleep_legacy.yaml"
 )
@@ -15,29 +15,86 @@
 package federation
 
 import (
-	"os"
+	"context"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:        "T31",
+		Title:     "Federation in a single cluster",
+		Tags:      []string{suite.ARM, suite.Power, suite.Z},
+		Resources: []suite.Resource{suite.Webhooks},
+		Test:      TestSingleClusterFed,
+	})
+}
+
 func cleanupSingleClusterFed() {
 	util.Log.Info("Cleanup ...")
-	util.Shell(`pushd ../testdata/examples/federation \
-			&& export MESH1_KUBECONFIG=~/.kube/config \
-			&& export MESH2_KUBECONFIG=~/.kube/config \
-			&& ./cleanup.sh`)
-	time.Sleep(time.Duration(20) * time.Second)
+	util.Shell(federationScript("cleanup.sh"))
+	if err := waitForFederationDeleted(); err != nil {
+		util.Log.Error(err)
+	}
+}
+
+// waitForFederationDeleted waits until the namespaces of the two meshes, deleted by cleanup.sh, are gone.
+func waitForFederationDeleted() error {
+	var conds []wait.Condition
+	for _, ns := range []string{"mesh1-system", "mesh2-system", "mesh1-bookinfo", "mesh2-bookinfo"} {
+		conds = append(conds, wait.Deleted("", "namespace", ns))
+	}
+	return wait.UpTo(util.SMCPTimeout, wait.All(conds...))
+}
+
+// federationConnected is met when the meshes installed by the scripts are connected to each other and
+// mesh2 imported the services exported by mesh1.
+func federationConnected() wait.Condition {
+	return wait.All(
+		objectContains("servicemeshpeer", "mesh1-system", "mesh2", `"connected":true`),
+		objectContains("servicemeshpeer", "mesh2-system", "mesh1", `"connected":true`),
+		objectContains("importedservicesets", "mesh2-system", "mesh1",
+			"mongodb.bookinfo.svc.mesh2-exports.local", "ratings.bookinfo.svc.mesh2-exports.local"))
 }
 
-func getenv(key, fallback string) string {
-	value := os.Getenv(key)
-	if len(value) == 0 {
-		return fallback
+// objectContains is met when the compact JSON of the object contains all values.
+func objectContains(kind, ns, name string, values ...string) wait.Condition {
+	return wait.Condition{
+		Description: fmt.Sprintf("%s %s/%s with %s", kind, ns, name, strings.Join(values, ", ")),
+		Check: func(ctx context.Context) error {
+			var obj util.Object
+			if err := util.GetKubeClient().Get(ctx, kind, ns, name, &obj); err != nil {
+				return err
+			}
+			data, err := json.Marshal(&obj)
+			if err != nil {
+				return err
+			}
+			for _, v := range values {
+				if !strings.Contains(string(data), v) {
+					return fmt.Errorf("%s %s/%s has no %s yet", kind, ns, name, v)
+				}
+			}
+			return nil
+		},
 	}
-	return value
+}
+
+// federationScript returns the shell command running a script of the federation example with the
+// kubeconfig files of the two meshes, e.g. federationScript("install.sh").
+func federationScript(script string) string {
+	c := util.GetConfig()
+	return fmt.Sprintf(`pushd ../testdata/examples/federation \
+			&& export MESH1_KUBECONFIG=%s \
+			&& export MESH2_KUBECONFIG=%s \
+			&& ./%s`, c.Mesh1Kubeconfig, c.Mesh2Kubeconfig, script)
 }
 
 func TestSingleClusterFed(t *testing.T) {
@@ -47,14 +104,14 @@ func TestSingleClusterFed(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Test federation install in a single cluster")
 		util.Log.Info("Reference: https://github.com/maistra/istio/blob/maistra-2.3/samples/federation/base/install.sh")
-		util.Log.Info("Running install.sh waiting 1 min...")
-		util.Shell(`pushd ../testdata/examples/federation \
-			&& export MESH1_KUBECONFIG=~/.kube/config \
-			&& export MESH2_KUBECONFIG=~/.kube/config \
-			&& ./install.sh`)
+		util.Log.Info("Running install.sh...")
+		util.Shell(federationScript("install.sh"))
 
-		util.Log.Info("Waiting 60s...")
-		time.Sleep(time.Duration(60) * time.Second)
+		util.Log.Info("Waiting for the meshes to connect...")
+		if err := wait.UpTo(5*time.Minute, federationConnected()); err != nil {
+			t.Error(err)
+			util.Log.Error(err)
+		}
 
 		util.Log.Info("Verify mesh1 connection status")
 		msg, err := util.Shell(`oc -n mesh1-system get servicemeshpeer mesh2 -o json`)
@@ -19,17 +19,28 @@ import (
 	"testing"
 	"time"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:        "T32",
+		Title:     "Federation in a single cluster with different certificates",
+		Tags:      []string{suite.Power, suite.Z},
+		Resources: []suite.Resource{suite.Webhooks},
+		Test:      TestSingleClusterFedDiffCert,
+	})
+}
+
 func cleanupSingleClusterFedDiffCert() {
 	util.Log.Info("Cleanup ...")
 	util.Shell(`kubectl -n mesh1-system delete secret cacerts`)
-	util.Shell(`pushd ../testdata/examples/federation \
-			&& export MESH1_KUBECONFIG=~/.kube/config \
-			&& export MESH2_KUBECONFIG=~/.kube/config \
-			&& ./cleanup.sh`)
-	time.Sleep(time.Duration(20) * time.Second)
+	util.Shell(federationScript("cleanup.sh"))
+	if err := waitForFederationDeleted(); err != nil {
+		util.Log.Error(err)
+	}
 }
 
 func TestSingleClusterFedDiffCert(t *testing.T) {
@@ -39,14 +50,14 @@ func TestSingleClusterFedDiffCert(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Test federation install in a single cluster")
 		util.Log.Info("Reference: https://github.com/maistra/istio/blob/maistra-2.1/pkg/servicemesh/federation/example/config-poc/install.sh")
-		util.Log.Info("Running install_diff_cert.sh waiting 1 min...")
-		util.Shell(`pushd ../testdata/examples/federation \
-			&& export MESH1_KUBECONFIG=~/.kube/config \
-			&& export MESH2_KUBECONFIG=~/.kube/config \
-			&& ./install_diff_cert.sh`)
+		util.Log.Info("Running install_diff_cert.sh...")
+		util.Shell(federationScript("install_diff_cert.sh"))
 
-		util.Log.Info("Waiting 2 minutes...")
-		time.Sleep(time.Duration(120) * time.Second)
+		util.Log.Info("Waiting for the meshes to connect...")
+		if err := wait.UpTo(5*time.Minute, federationConnected()); err != nil {
+			t.Error(err)
+			util.Log.Error(err)
+		}
 
 		util.Log.Info("Verify mesh1 connection status")
 		msg, err := util.Shell(`oc -n mesh1-system get servicemeshpeer mesh2 -o json`)
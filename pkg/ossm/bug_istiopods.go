@@ -15,30 +15,49 @@
 package ossm
 
 import (
+	"fmt"
 	"math/rand"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T35",
+		Title:      "Istiod probes after restarts with many members",
+		Tags:       []string{suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestIstioPodProbesFails,
+	})
+}
+
 func cleanupIstioPodsTest() {
 	util.Log.Info("Cleanup ...")
-	bookinfo := examples.Bookinfo{"bookinfo"}
-	bookinfo.Uninstall()
 	util.Shell(`../scripts/smmr/clean_members_50.sh`)
-	time.Sleep(time.Duration(20) * time.Second)
+	conds := []wait.Condition{wait.SMCPReady(util.GetConfig().MeshNamespace, util.GetConfig().SMCPName)}
+	for i := 1; i <= 50; i++ {
+		conds = append(conds, wait.Deleted("", "namespace", fmt.Sprintf("test%d", i)))
+	}
+	if err := wait.UpTo(util.SMCPTimeout, wait.All(conds...)); err != nil {
+		util.Log.Error(err)
+	}
 }
 
 // TestIstioPodProbesFails tests that Istio pod get stuck with probes failure after restart. Jira ticket: https://issues.redhat.com/browse/OSSM-2434
 func TestIstioPodProbesFails(t *testing.T) {
+	ns := util.Namespace(t, "bookinfo")
 	defer cleanupIstioPodsTest()
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Deploy bookinfo in bookinfo ns")
-	bookinfo := examples.Bookinfo{"bookinfo"}
+	bookinfo := examples.Bookinfo{Namespace: ns}
 	bookinfo.Install(false)
 
 	t.Run("smcp_test_istio_pod_probes_failure", func(t *testing.T) {
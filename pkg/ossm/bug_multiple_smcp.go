@@ -1,35 +1,59 @@
 package ossm
 
 import (
+	"context"
+	"fmt"
 	"strings"
 	"testing"
-	"time"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:        "T36",
+		Title:     "Multiple SMCPs in one namespace",
+		Tags:      []string{suite.Power, suite.Z, suite.Disruptive},
+		Resources: []suite.Resource{suite.SMCP, suite.Webhooks},
+		Test:      TestSMCPMutiple,
+	})
+}
+
 func cleanupMultipleSMCP() {
+	meshNamespace := util.GetConfig().MeshNamespace
 	util.Log.Info("Delete the Multiple CP", meshNamespace)
 	util.KubeDeleteContents(meshNamespace, smmr)
-	util.KubeDeleteContents(meshNamespace, util.RunTemplate(smcpV23_template, smcp))
-	time.Sleep(time.Duration(40) * time.Second)
-	util.KubeDeleteContents(meshNamespace, util.RunTemplate(smcpV23_template_meta, smcp))
-	time.Sleep(time.Duration(40) * time.Second)
+	util.KubeDeleteTemplate(meshNamespace, smcpV23_template, util.GetConfig().ControlPlane())
+	if err := waitForSMCPDeleted(util.GetConfig().SMCPName); err != nil {
+		util.Log.Error(err)
+	}
+	util.KubeDeleteTemplate(meshNamespace, smcpV23_template_meta, util.GetConfig().ControlPlane())
+	if err := waitForSMCPDeleted("meta"); err != nil {
+		util.Log.Error(err)
+	}
 }
 
 // TestSMCPMutiple tests If multiple SMCPs exist in a namespace, the controller reconciles them all. Jira ticket: https://issues.redhat.com/browse/OSSM-2434
 func TestSMCPMutiple(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
 	defer cleanupMultipleSMCP()
 	defer util.RecoverPanic(t)
 	util.Log.Info("Delete Validation Webhook ")
 	util.Shell(`oc delete validatingwebhookconfiguration/openshift-operators.servicemesh-resources.maistra.io`)
 
 	util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-	util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV23_template, smcp))
+	util.KubeApplyTemplate(meshNamespace, smcpV23_template, util.GetConfig().ControlPlane())
 	util.KubeApplyContents(meshNamespace, smmr)
-	time.Sleep(time.Duration(20) * time.Second)
-	util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV23_template_meta, smcp))
-	time.Sleep(time.Duration(20) * time.Second)
+	if err := waitForSMCP(smcpName); err != nil {
+		t.Error(err)
+	}
+	util.KubeApplyTemplate(meshNamespace, smcpV23_template_meta, util.GetConfig().ControlPlane())
+	if err := wait.UpTo(util.SMCPTimeout, smcpRejected(meshNamespace, "meta", "ErrMultipleSMCPs")); err != nil {
+		t.Error(err)
+	}
 
 	util.Log.Info("Verify SMCP status and pods")
 	msg, _ := util.Shell(`oc get -n %s smcp/%s -o wide`, meshNamespace, smcpName)
@@ -48,3 +72,23 @@ func TestSMCPMutiple(t *testing.T) {
 	util.Shell(`oc wait --for=condition=Ready pods --all -n %s`, meshNamespace)
 
 }
+
+// smcpRejected is met when the operator reports a status condition of the SMCP with the reason it was not
+// reconciled for, e.g. ErrMultipleSMCPs.
+func smcpRejected(ns, name, reason string) wait.Condition {
+	return wait.Condition{
+		Description: fmt.Sprintf("SMCP %s/%s rejected with %s", ns, name, reason),
+		Check: func(ctx context.Context) error {
+			var smcp util.ServiceMeshControlPlane
+			if err := util.GetKubeClient().Get(ctx, "smcp", ns, name, &smcp); err != nil {
+				return err
+			}
+			for _, c := range smcp.Status.Conditions {
+				if c.Reason == reason {
+					return nil
+				}
+			}
+			return fmt.Errorf("no condition with reason %s reported yet", reason)
+		},
+	}
+}
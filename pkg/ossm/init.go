@@ -15,9 +15,9 @@
 package ossm
 
 import (
-	"time"
-
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
 // Install nightly build operators from quay.io. This is used in Jenkins daily build pipeline.
@@ -25,23 +25,41 @@ func installNightlyOperators() {
 	util.KubeApply("openshift-operators", jaegerSubYaml)
 	util.KubeApply("openshift-operators", kialiSubYaml)
 	util.KubeApply("openshift-operators", ossmSubYaml)
-	time.Sleep(time.Duration(60) * time.Second)
-	util.CheckPodRunning("openshift-operators", "name=istio-operator")
-	time.Sleep(time.Duration(30) * time.Second)
+	if err := wait.UpTo(util.SMCPTimeout, wait.PodsReady("openshift-operators", "name=istio-operator")); err != nil {
+		util.Log.Error(err)
+	}
+}
+
+// waitForSMCP waits until the SMCP in the mesh namespace and all pods of the control plane are ready.
+func waitForSMCP(name string) error {
+	meshNamespace := util.GetConfig().MeshNamespace
+	return wait.UpTo(util.SMCPTimeout, wait.All(wait.SMCPReady(meshNamespace, name), wait.PodsReady(meshNamespace, "")))
+}
+
+// waitForSMCPDeleted waits until the SMCP in the mesh namespace and its istiod pods are deleted.
+func waitForSMCPDeleted(name string) error {
+	meshNamespace := util.GetConfig().MeshNamespace
+	return wait.UpTo(util.SMCPTimeout, wait.All(wait.Deleted(meshNamespace, "smcp", name), wait.AllDeleted(meshNamespace, "pods", "app=istiod")))
 }
 
-// Initialize a default SMCP and SMMR
 func init() {
+	suite.RegisterSetup(setupDefaultSMCP)
+}
 
-	if util.Getenv("NIGHTLY", "false") == "true" {
+// Initialize a default SMCP and SMMR
+func setupDefaultSMCP() {
+	meshNamespace := util.GetConfig().MeshNamespace
+	if util.GetConfig().Nightly {
 		installNightlyOperators()
 	}
 
 	util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-	util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV23_template, smcp))
+	util.KubeApplyTemplate(meshNamespace, smcpV23_template, util.GetConfig().ControlPlane())
 	util.KubeApplyContents(meshNamespace, smmr)
-	time.Sleep(time.Duration(30) * time.Second)
-	if ipv6 == "true" {
+	if err := waitForSMCP(util.GetConfig().SMCPName); err != nil {
+		util.Log.Error(err)
+	}
+	if util.GetConfig().IPv6 {
 		util.Log.Info("Running the test with IPv6 configuration")
 	}
 
@@ -18,6 +18,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
@@ -26,21 +27,27 @@ const (
 	initContainerGoldString = "init worked"
 )
 
-func cleanupInitContainer() {
-	util.KubeDeleteContents(initContainerNS, testInitContainerYAML)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T33",
+		Title:      "Init container",
+		Tags:       []string{suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Test:       TestInitContainer,
+	})
 }
 
 func TestInitContainer(t *testing.T) {
-	defer cleanupInitContainer()
+	ns := util.Namespace(t, initContainerNS)
 
-	if err := util.KubeApplyContents(initContainerNS, testInitContainerYAML); err != nil {
+	if _, err := util.KubeApplyContents(ns, testInitContainerYAML); err != nil {
 		t.Fatalf("error creating the pod: %v", err)
 	}
-	if err := util.CheckPodRunning(initContainerNS, "app=sleep-init"); err != nil {
+	if err := util.CheckPodRunning(ns, "app=sleep-init"); err != nil {
 		t.Fatalf("sleep-init pod is not running: %v", err)
 	}
 
-	logs := util.GetPodLogsForLabel(initContainerNS, "app=sleep-init", "init", false, false)
+	logs := util.GetPodLogsForLabel(ns, "app=sleep-init", "init", false, false)
 	if !strings.Contains(logs, initContainerGoldString) {
 		t.Fatalf("expected init container log to contain the string %q, but got %q", initContainerGoldString, logs)
 	}
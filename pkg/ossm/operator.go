@@ -18,11 +18,22 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
 var workername string
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:        "T40",
+		Title:     "OSSM operator on infra nodes",
+		Tags:      []string{suite.Power, suite.Z, suite.Disruptive},
+		Resources: []suite.Resource{suite.SMCP, suite.Nodes},
+		Test:      TestOperator,
+	})
+}
+
 func cleanupOperatorTest() {
 	util.Log.Info("Cleanup ...")
 	response, err := util.Shell(`oc adm taint nodes -l node-role.kubernetes.io/infra node-role.kubernetes.io/infra=reserved:NoSchedule- node-role.kubernetes.io/infra=reserved:NoExecute-`)
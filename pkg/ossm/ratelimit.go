@@ -20,7 +20,9 @@ import (
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
 const (
@@ -115,32 +117,56 @@ spec:
 `
 )
 
-func cleanupRateLimiting(redisDeploy examples.Redis, bookinfoDeploy examples.Bookinfo) {
-	util.Shell(`kubectl -n %s patch smcp/%s --type=json -p='[{"op": "remove", "path": "/spec/techPreview/rateLimiting"}]'`, meshNamespace, smcpName)
-	util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName)
-	util.KubeDeleteContents(meshNamespace, util.RunTemplate(rateLimitFilterYaml_template, smcp))
-	time.Sleep(time.Second * 5)
-	util.KubeDeleteContents(meshNamespace, rateLimitSMCPPatch)
-	util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName)
-	redisDeploy.Uninstall()
-	bookinfoDeploy.Uninstall()
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T28",
+		Title:      "Rate limiting",
+		Tags:       []string{suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"bookinfo", "redis"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestRateLimiting,
+	})
+}
+
+func cleanupRateLimiting() {
+	meshNamespace := util.GetConfig().MeshNamespace
+	sync := wait.BeforeConfigChange(wait.Proxies{Namespace: meshNamespace, Selector: "istio=ingressgateway"})
+	util.KubeDeleteTemplate(meshNamespace, rateLimitFilterYaml_template, util.GetConfig().ControlPlane())
+	if err := wait.UpTo(2*time.Minute, sync.Propagated(rateLimitFilters(meshNamespace)...)); err != nil {
+		util.Log.Error(err)
+	}
+}
+
+// rateLimitFilters returns the envoy filters of the ingress gateway calling the rate limit service.
+func rateLimitFilters(meshNamespace string) []wait.Resource {
+	return []wait.Resource{
+		{Kind: "EnvoyFilter", Namespace: meshNamespace, Name: "filter-ratelimit"},
+		{Kind: "EnvoyFilter", Namespace: meshNamespace, Name: "filter-ratelimit-svc"},
+	}
 }
 
 func TestRateLimiting(t *testing.T) {
-	redisDeploy := examples.Redis{Namespace: "redis"}
-	bookinfo := examples.Bookinfo{Namespace: "bookinfo"}
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
+	redisDeploy := examples.Redis{Namespace: util.Namespace(t, "redis")}
+	bookinfo := examples.Bookinfo{Namespace: util.Namespace(t, "bookinfo")}
 	bookinfo.Install(false)
 
-	defer cleanupRateLimiting(redisDeploy, bookinfo)
+	util.PreserveSMCP(t, meshNamespace, smcpName)
+	defer cleanupRateLimiting()
 
 	if err := redisDeploy.Install(); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := util.Shell(`kubectl -n %s patch smcp/%s --type=merge --patch="%s"`, meshNamespace, smcpName, rateLimitSMCPPatch); err != nil {
+	patch, err := util.RunTemplate(rateLimitSMCPPatchTemplate, redisDeploy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := util.Kubectl("patch", "smcp", smcpName).Namespace(meshNamespace).Patch(util.MergePatch, patch).Run(); err != nil {
 		t.Fatal(err)
 	}
 
-	if _, err := util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName); err != nil {
+	if err := wait.UpTo(util.SMCPTimeout, wait.SMCPReady(meshNamespace, smcpName)); err != nil {
 		t.Fatal(err)
 	}
 
@@ -148,14 +174,16 @@ func TestRateLimiting(t *testing.T) {
 		t.Fatalf("rls deployment not ready: %v", err)
 	}
 
-	if err := util.KubeApplyContents(meshNamespace, util.RunTemplate(rateLimitFilterYaml_template, smcp)); err != nil {
+	sync := wait.BeforeConfigChange(wait.Proxies{Namespace: meshNamespace, Selector: "istio=ingressgateway"})
+	if _, err := util.KubeApplyTemplate(meshNamespace, rateLimitFilterYaml_template, util.GetConfig().ControlPlane()); err != nil {
 		t.Fatalf("error applying envoy filter: %v", err)
 	}
 	util.Shell(`kubectl -n %s get envoyfilter -o yaml > rrr.yaml`, meshNamespace)
 	//util.Log.Info(msg)
 
-	// Give some time to envoy filters apply
-	time.Sleep(time.Second * 5)
+	if err := wait.UpTo(2*time.Minute, sync.Propagated(rateLimitFilters(meshNamespace)...)); err != nil {
+		t.Fatal(err)
+	}
 
 	host, err := util.Shell("oc -n %s get route istio-ingressgateway -o jsonpath='{.spec.host}'", meshNamespace)
 	if err != nil {
@@ -163,21 +191,16 @@ func TestRateLimiting(t *testing.T) {
 	}
 	host = strings.Trim(host, "'")
 
-	time.Sleep(time.Duration(20) * time.Second)
-
 	// Should work first time
 	checkProductPageResponseCode(t, host, "200")
 
 	// Should fail first time
 	checkProductPageResponseCode(t, host, "429")
 
-	// Should work again after 1 minute
-	time.Sleep(time.Second * 65)
-	checkProductPageResponseCode(t, host, "200")
-
-	// Should fail
-	// time.Sleep(time.Second * 20)	
-	// checkProductPageResponseCode(t, host, "429")
+	// Should work again once the limit resets, at the latest after 1 minute
+	if err := wait.UpTo(2*time.Minute, wait.HTTPStatus("http://"+host+"/productpage", "", 200)); err != nil {
+		t.Fatal(err)
+	}
 }
 
 func checkProductPageResponseCode(t *testing.T, host string, expectedCode string) {
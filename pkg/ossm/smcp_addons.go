@@ -15,26 +15,42 @@
 package ossm
 
 import (
+	"context"
 	"testing"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:        "T34",
+		Title:     "SMCP addons",
+		Tags:      []string{suite.Power, suite.Z, suite.Disruptive},
+		Resources: []suite.Resource{suite.SMCP},
+		Test:      TestSMCPAddons,
+	})
+}
+
 func TestSMCPAddons(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
+	util.PreserveSMCP(t, meshNamespace, smcpName)
 
 	t.Run("smcp_test_addons_3scale", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Enable 3scale in a CR. Expected validation error.")
-		_, err := util.Shell(`kubectl patch -n %s smcp/%s --type merge -p '{"spec":{"addons":{"3scale":{"enabled":true}}}}'`, meshNamespace, smcpName)
-		if err != nil {
-			util.Log.Info("Expected validation error")
-		} else {
+		err := util.GetKubeClient().Patch(context.Background(), "smcp", meshNamespace, smcpName, util.MergePatch, `{"spec":{"addons":{"3scale":{"enabled":true}}}}`)
+		switch {
+		case util.IsValidationRejected(err):
+			util.Log.Infof("Expected validation error: %v", err)
+		case err != nil:
+			t.Errorf("Failed to patch smcp/%s for another reason than a validation error: %v", smcpName, err)
+		default:
 			util.Log.Error("Failed check. enabling 3scale should be deprecated.")
 		}
 
 		util.Log.Info("Verify SMCP status")
 		util.Shell(`oc get -n %s smcp/%s -o wide`, meshNamespace, smcpName)
-		util.Shell(`kubectl patch -n %s smcp/%s --type merge -p '{"spec":{"addons":{"3scale":{"enabled":false}}}}'`, meshNamespace, smcpName)
-		util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName)
 	})
 }
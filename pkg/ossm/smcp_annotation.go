@@ -19,7 +19,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
 const (
@@ -35,28 +37,30 @@ spec:
 `
 )
 
-func cleanupSMCPAnnotations() {
-	util.Log.Info("Cleanup ...")
-	util.KubeDeleteContents("bookinfo", testAnnotationProxyEnv)
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T29",
+		Title:      "SMCP proxy annotations",
+		Tags:       []string{suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestSMCPAnnotations,
+	})
 }
 
 func TestSMCPAnnotations(t *testing.T) {
-	defer cleanupSMCPAnnotations()
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
+	ns := util.Namespace(t, "bookinfo")
+	util.PreserveSMCP(t, meshNamespace, smcpName)
 
 	t.Run("smcp_test_annotation_proxyEnv", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Test annotation sidecar.maistra.io/proxyEnv")
-		if util.Getenv("SAMPLEARCH", "x86") == "p" {
-			util.KubeApplyContents("bookinfo", testAnnotationProxyEnvP)
-		} else if util.Getenv("SAMPLEARCH", "x86") == "z" {
-			util.KubeApplyContents("bookinfo", testAnnotationProxyEnvZ)
-		} else {
-			util.KubeApplyContents("bookinfo", testAnnotationProxyEnv)
-		}
-		util.CheckPodRunning("bookinfo", "app=env")
-		msg, err := util.ShellMuteOutput(`kubectl get po -n bookinfo -o yaml | grep maistra_test_env`)
+		util.KubeApplyTemplate(ns, testAnnotationProxyEnv, nil)
+		util.CheckPodRunning(ns, "app=env")
+		msg, err := util.ShellMuteOutput(`kubectl get po -n %s -o yaml | grep maistra_test_env`, ns)
 		util.Inspect(err, "Failed to get variables", "", t)
 
 		if strings.Contains(msg, "env_value") {
@@ -64,52 +68,48 @@ func TestSMCPAnnotations(t *testing.T) {
 		} else {
 			t.Errorf("Failed to get env variable: %v", msg)
 		}
-		util.KubeDeleteContents("bookinfo", testAnnotationProxyEnv)
-		time.Sleep(time.Duration(30) * time.Second)
+		util.KubeDeleteTemplate(ns, testAnnotationProxyEnv, nil)
+		if err := wait.UpTo(time.Minute, wait.AllDeleted(ns, "pods", "app=env")); err != nil {
+			t.Error(err)
+		}
 	})
 
 	t.Run("smcp_test_annotation_quote_injection", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Test SMCP annotation quote value injection")
-		if _, err := util.Shell(`kubectl -n %s patch smcp/%s --type=merge --patch="%s"`, meshNamespace, smcpName, InjectedAnnotationsSMCPPath); err != nil {
+		if _, err := util.Kubectl("patch", "smcp", smcpName).Namespace(meshNamespace).Patch(util.MergePatch, InjectedAnnotationsSMCPPath).Run(); err != nil {
 			t.Fatal(err)
 		}
 
-		if _, err := util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName); err != nil {
+		if err := waitForSMCP(smcpName); err != nil {
 			t.Fatal(err)
 		}
 
 		util.Log.Info("Check a pod annotations")
-		if util.Getenv("SAMPLEARCH", "x86") == "p" {
-			util.KubeApplyContents("bookinfo", testAnnotationProxyEnvP)
-		} else if util.Getenv("SAMPLEARCH", "x86") == "z" {
-			util.KubeApplyContents("bookinfo", testAnnotationProxyEnvZ)
-		} else {
-			util.KubeApplyContents("bookinfo", testAnnotationProxyEnv)
-		}
-		util.CheckPodRunning("bookinfo", "app=env")
-		podName, err := util.GetPodName("bookinfo", "app=env")
+		util.KubeApplyTemplate(ns, testAnnotationProxyEnv, nil)
+		util.CheckPodRunning(ns, "app=env")
+		podName, err := util.GetPodName(ns, "app=env")
 		if err != nil {
 			t.Fatalf("Failed to get pod name: %v", err)
 		}
-		annotations, err := util.GetPodAnnotations("bookinfo", podName, 30)
+		annotations, err := util.GetPodAnnotations(ns, podName, 30)
 		if err != nil {
 			t.Fatalf("Failed to get annotations: %v", err)
 		}
-		util.Log.Infof("Checking annotation: %", annotations["test1.annotation-from-smcp"])
+		util.Log.Infof("Checking annotation: %s", annotations["test1.annotation-from-smcp"])
 		if _, ok := annotations["test1.annotation-from-smcp"]; !ok {
 			t.Errorf("Failed to get annotations: test1.annotation-from-smcp: test1")
 		} else if annotations["test1.annotation-from-smcp"] != "test1" {
 			t.Errorf("Failed to get annotations: test1.annotation-from-smcp: test1")
 		}
-		util.Log.Infof("Checking annotation: %", annotations["test2.annotation-from-smcp"])
+		util.Log.Infof("Checking annotation: %s", annotations["test2.annotation-from-smcp"])
 		if _, ok := annotations["test2.annotation-from-smcp"]; !ok {
 			t.Errorf("Failed to get annotations: test2.annotation-from-smcp: '[test2]'")
 		} else if annotations["test2.annotation-from-smcp"] != "[test2]" {
 			t.Errorf("Failed to get annotations: test2.annotation-from-smcp: '[test2]'")
 		}
-		util.Log.Infof("Checking annotation: %", annotations["test3.annotation-from-smcp"])
+		util.Log.Infof("Checking annotation: %s", annotations["test3.annotation-from-smcp"])
 		if _, ok := annotations["test3.annotation-from-smcp"]; !ok {
 			t.Errorf("Failed to get annotations: test3.annotation-from-smcp: '{test3}'")
 		} else if annotations["test3.annotation-from-smcp"] != "{test3}" {
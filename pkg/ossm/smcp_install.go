@@ -17,53 +17,71 @@ package ossm
 import (
 	"strings"
 	"testing"
-	"time"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:        "A1",
+		Title:     "SMCP install, uninstall and upgrade",
+		Tags:      []string{suite.Smoke, suite.ARM, suite.Interop, suite.Power, suite.Z, suite.Disruptive},
+		Resources: []suite.Resource{suite.SMCP},
+		Test:      TestSMCPInstall,
+	})
+}
+
 func installDefaultSMCP23() {
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
 	util.Log.Info("Create SMCP v2.3 in ", meshNamespace)
 	util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-	util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV23_template, smcp))
+	util.KubeApplyTemplate(meshNamespace, smcpV23_template, util.GetConfig().ControlPlane())
 	util.KubeApplyContents(meshNamespace, smmr)
 
 	// patch SMCP identity if it's on a ROSA cluster
-	if util.Getenv("ROSA", "false") == "true" {
+	if util.GetConfig().ROSA {
 		util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 	}
 	util.Log.Info("Waiting for mesh installation to complete")
-	util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 300s`, meshNamespace, smcpName)
-	time.Sleep(time.Duration(20) * time.Second)
+	if err := waitForSMCP(smcpName); err != nil {
+		util.Log.Error(err)
+	}
 }
 
 func installDefaultSMCP22() {
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
 	util.Log.Info("Create SMCP v2.2 in ", meshNamespace)
 	util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-	util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV22_template, smcp))
+	util.KubeApplyTemplate(meshNamespace, smcpV22_template, util.GetConfig().ControlPlane())
 	util.KubeApplyContents(meshNamespace, smmr)
 
 	// patch SMCP identity if it's on a ROSA cluster
-	if util.Getenv("ROSA", "false") == "true" {
+	if util.GetConfig().ROSA {
 		util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 	}
 	util.Log.Info("Waiting for mesh installation to complete")
-	util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 300s`, meshNamespace, smcpName)
-	time.Sleep(time.Duration(20) * time.Second)
+	if err := waitForSMCP(smcpName); err != nil {
+		util.Log.Error(err)
+	}
 }
 
 func TestSMCPInstall(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
 	defer installDefaultSMCP23()
 
 	t.Run("smcp_test_install_2.3", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Create SMCP v2.3 in ", meshNamespace)
 		util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV23_template, smcp))
+		util.KubeApplyTemplate(meshNamespace, smcpV23_template, util.GetConfig().ControlPlane())
 		util.KubeApplyContents(meshNamespace, smmr)
 
 		// patch SMCP identity if it's on a ROSA cluster
-		if util.Getenv("ROSA", "false") == "true" {
+		if util.GetConfig().ROSA {
 			util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 		}
 		util.Log.Info("Waiting for mesh installation to complete")
@@ -82,19 +100,21 @@ func TestSMCPInstall(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Delete SMCP v2.3 in ", meshNamespace)
 		util.KubeDeleteContents(meshNamespace, smmr)
-		util.KubeDeleteContents(meshNamespace, util.RunTemplate(smcpV23_template, smcp))
-		time.Sleep(time.Duration(40) * time.Second)
+		util.KubeDeleteTemplate(meshNamespace, smcpV23_template, util.GetConfig().ControlPlane())
+		if err := waitForSMCPDeleted(smcpName); err != nil {
+			t.Error(err)
+		}
 	})
 
 	t.Run("smcp_test_install_2.2", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Create SMCP v2.2 in ", meshNamespace)
 		util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV22_template, smcp))
+		util.KubeApplyTemplate(meshNamespace, smcpV22_template, util.GetConfig().ControlPlane())
 		util.KubeApplyContents(meshNamespace, smmr)
 
 		// patch SMCP identity if it's on a ROSA cluster
-		if util.Getenv("ROSA", "false") == "true" {
+		if util.GetConfig().ROSA {
 			util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 		}
 		util.Log.Info("Waiting for mesh installation to complete")
@@ -113,19 +133,21 @@ func TestSMCPInstall(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Delete SMCP v2.2 in ", meshNamespace)
 		util.KubeDeleteContents(meshNamespace, smmr)
-		util.KubeDeleteContents(meshNamespace, util.RunTemplate(smcpV22_template, smcp))
-		time.Sleep(time.Duration(40) * time.Second)
+		util.KubeDeleteTemplate(meshNamespace, smcpV22_template, util.GetConfig().ControlPlane())
+		if err := waitForSMCPDeleted(smcpName); err != nil {
+			t.Error(err)
+		}
 	})
 
 	t.Run("smcp_test_install_2.1", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Create SMCP v2.1 in namespace ", meshNamespace)
 		util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV21_template, smcp))
+		util.KubeApplyTemplate(meshNamespace, smcpV21_template, util.GetConfig().ControlPlane())
 		util.KubeApplyContents(meshNamespace, smmr)
 
 		// patch SMCP identity if it's on a ROSA cluster
-		if util.Getenv("ROSA", "false") == "true" {
+		if util.GetConfig().ROSA {
 			util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 		}
 		util.Log.Info("Waiting for mesh installation to complete")
@@ -144,8 +166,10 @@ func TestSMCPInstall(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Delete SMCP v2.1 in ", meshNamespace)
 		util.KubeDeleteContents(meshNamespace, smmr)
-		util.KubeDeleteContents(meshNamespace, util.RunTemplate(smcpV21_template, smcp))
-		time.Sleep(time.Duration(60) * time.Second)
+		util.KubeDeleteTemplate(meshNamespace, smcpV21_template, util.GetConfig().ControlPlane())
+		if err := waitForSMCPDeleted(smcpName); err != nil {
+			t.Error(err)
+		}
 	})
 
 	t.Run("smcp_test_upgrade_2.1_to_2.2", func(t *testing.T) {
@@ -153,11 +177,11 @@ func TestSMCPInstall(t *testing.T) {
 
 		util.Log.Info("Create SMCP v2.1 in namespace ", meshNamespace)
 		util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV21_template, smcp))
+		util.KubeApplyTemplate(meshNamespace, smcpV21_template, util.GetConfig().ControlPlane())
 		util.KubeApplyContents(meshNamespace, smmr)
 
 		// patch SMCP identity if it's on a ROSA cluster
-		if util.Getenv("ROSA", "false") == "true" {
+		if util.GetConfig().ROSA {
 			util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 		}
 		util.Log.Info("Waiting for mesh installation to complete")
@@ -168,15 +192,16 @@ func TestSMCPInstall(t *testing.T) {
 		util.Shell(`oc get -n %s pods`, meshNamespace)
 
 		util.Log.Info("Upgrade SMCP to v2.2")
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV22_template, smcp))
+		util.KubeApplyTemplate(meshNamespace, smcpV22_template, util.GetConfig().ControlPlane())
 
 		// patch SMCP identity if it's on a ROSA cluster
-		if util.Getenv("ROSA", "false") == "true" {
+		if util.GetConfig().ROSA {
 			util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 		}
-		util.Log.Info("Waiting for mesh installation to complete")
-		time.Sleep(time.Duration(10) * time.Second)
-		util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 360s`, meshNamespace, smcpName)
+		util.Log.Info("Waiting for mesh upgrade to complete")
+		if err := waitForSMCP(smcpName); err != nil {
+			t.Error(err)
+		}
 
 		util.Log.Info("Verify SMCP status and pods")
 		msg, _ := util.Shell(`oc get -n %s smcp/%s -o wide`, meshNamespace, smcpName)
@@ -192,11 +217,11 @@ func TestSMCPInstall(t *testing.T) {
 
 		util.Log.Info("Create SMCP v2.2 in namespace ", meshNamespace)
 		util.ShellMuteOutputError(`oc new-project %s`, meshNamespace)
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV22_template, smcp))
+		util.KubeApplyTemplate(meshNamespace, smcpV22_template, util.GetConfig().ControlPlane())
 		util.KubeApplyContents(meshNamespace, smmr)
 
 		// patch SMCP identity if it's on a ROSA cluster
-		if util.Getenv("ROSA", "false") == "true" {
+		if util.GetConfig().ROSA {
 			util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 		}
 		util.Log.Info("Waiting for mesh installation to complete")
@@ -207,15 +232,16 @@ func TestSMCPInstall(t *testing.T) {
 		util.Shell(`oc get -n %s pods`, meshNamespace)
 
 		util.Log.Info("Upgrade SMCP to v2.2")
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(smcpV23_template, smcp))
+		util.KubeApplyTemplate(meshNamespace, smcpV23_template, util.GetConfig().ControlPlane())
 
 		// patch SMCP identity if it's on a ROSA cluster
-		if util.Getenv("ROSA", "false") == "true" {
+		if util.GetConfig().ROSA {
 			util.Shell(`oc patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"identity":{"type":"ThirdParty"}}}}'`, meshNamespace, smcpName)
 		}
-		util.Log.Info("Waiting for mesh installation to complete")
-		time.Sleep(time.Duration(10) * time.Second)
-		util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 360s`, meshNamespace, smcpName)
+		util.Log.Info("Waiting for mesh upgrade to complete")
+		if err := waitForSMCP(smcpName); err != nil {
+			t.Error(err)
+		}
 
 		util.Log.Info("Verify SMCP status and pods")
 		msg, _ := util.Shell(`oc get -n %s smcp/%s -o wide`, meshNamespace, smcpName)
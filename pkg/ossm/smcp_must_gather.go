@@ -17,32 +17,36 @@ package ossm
 import (
 	"path/filepath"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
 var mustGatherImage = "registry.redhat.io/openshift-service-mesh/istio-must-gather-rhel8"
-var mustGatherTag string = util.Getenv("MUSTGATHERTAG", "2.3")
 
-func cleanupMustGatherTest() {
-	util.Log.Info("Cleanup ...")
-	bookinfo := examples.Bookinfo{"bookinfo"}
-	bookinfo.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T30",
+		Title:      "Must-gather log collection",
+		Tags:       []string{suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway, suite.Webhooks},
+		Test:       TestMustGather,
+	})
 }
 
 func TestMustGather(t *testing.T) {
-	defer cleanupMustGatherTest()
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Deploy bookinfo in bookinfo ns")
-	bookinfo := examples.Bookinfo{"bookinfo"}
+	bookinfo := examples.Bookinfo{Namespace: ns}
 	bookinfo.Install(false)
 
 	t.Run("smcp_test_must_gather", func(t *testing.T) {
 		defer util.RecoverPanic(t)
+		mustGatherTag := util.GetConfig().MustGatherTag
 		util.Log.Info("Test must-gather log collection")
 		util.Log.Info("Must-gather image: ", mustGatherImage, ":", mustGatherTag)
 		util.Shell(`mkdir -p debug; oc adm must-gather --dest-dir=./debug --image=%s:%s`, mustGatherImage, mustGatherTag)
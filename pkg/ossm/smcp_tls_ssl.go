@@ -19,28 +19,33 @@ import (
 	"testing"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
-func cleanupTestTLSVersionSMCP() {
-	util.Log.Info("Cleanup ...")
-	util.Shell(`kubectl patch -n %s smcp/%s --type=json -p='[{"op": "remove", "path": "/spec/security/controlPlane/tls"}]'`, meshNamespace, smcpName)
-	util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 180s`, meshNamespace, smcpName)
-}
-
-func cleanupTestSSL() {
-	util.Log.Info("Cleanup ...")
-	bookinfo := examples.Bookinfo{"bookinfo"}
-	util.KubeDeleteContents("bookinfo", testSSLDeployment)
-	bookinfo.Uninstall()
+func init() {
+	suite.Register(suite.TestCase{
+		ID:        "T26",
+		Title:     "SMCP control plane TLS versions",
+		Tags:      []string{suite.ARM, suite.Interop, suite.Power, suite.Z, suite.Disruptive},
+		Resources: []suite.Resource{suite.SMCP},
+		Test:      TestTLSVersionSMCP,
+	})
 
-	util.Shell(`kubectl patch -n %s smcp/%s --type=json -p='[{"op": "remove", "path": "/spec/security/controlPlane/tls"}]'`, meshNamespace, smcpName)
-	util.Shell(`kubectl patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"dataPlane":{"mtls":false},"controlPlane":{"mtls":false}}}}'`, meshNamespace, smcpName)
-	util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 180s`, meshNamespace, smcpName)
+	suite.Register(suite.TestCase{
+		ID:         "T27",
+		Title:      "SMCP TLS configuration verified with testssl.sh",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestSSL,
+	})
 }
 
 func TestTLSVersionSMCP(t *testing.T) {
-	defer cleanupTestTLSVersionSMCP()
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
+	util.PreserveSMCP(t, meshNamespace, smcpName)
 
 	t.Run("Operator_test_smcp_global_tls_minVersion_TLSv1_0", func(t *testing.T) {
 		defer util.RecoverPanic(t)
@@ -77,7 +82,10 @@ func TestTLSVersionSMCP(t *testing.T) {
 }
 
 func TestSSL(t *testing.T) {
-	defer cleanupTestSSL()
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
+	ns := util.Namespace(t, "bookinfo")
+	util.PreserveSMCP(t, meshNamespace, smcpName)
 
 	t.Run("Operator_test_smcp_testssl", func(t *testing.T) {
 		defer util.RecoverPanic(t)
@@ -89,36 +97,33 @@ func TestSSL(t *testing.T) {
 
 		util.Log.Info("Update SMCP spec.security.controlPlane.tls")
 
-		util.Shell(`kubectl patch -n %s smcp/%s --type merge -p '{%s:{%s,%s,%s,%s}}}}}'`,
-			meshNamespace, smcpName,
-			`"spec":{"security":{"controlPlane":{"tls"`,
-			`"minProtocolVersion":"TLSv1_2"`,
-			`"maxProtocolVersion":"TLSv1_2"`,
-			`"cipherSuites":["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"]`,
-			`"ecdhCurves":["CurveP256", "CurveP384"]`)
+		_, err := util.Kubectl("patch", "smcp", smcpName).Namespace(meshNamespace).Patch(util.MergePatch, `
+{"spec":{"security":{"controlPlane":{"tls":{
+  "minProtocolVersion":"TLSv1_2",
+  "maxProtocolVersion":"TLSv1_2",
+  "cipherSuites":["TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"],
+  "ecdhCurves":["CurveP256", "CurveP384"]
+}}}}}`).Run()
+		if err != nil {
+			t.Fatalf("Failed to configure the control plane TLS: %v", err)
+		}
 
 		util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 180s`, meshNamespace, smcpName)
 
 		util.Log.Info("Deploy bookinfo")
-		bookinfo := examples.Bookinfo{"bookinfo"}
+		bookinfo := examples.Bookinfo{Namespace: ns}
 		bookinfo.Install(true)
 
 		util.Log.Info("Deploy testssl pod")
-		if util.Getenv("SAMPLEARCH", "x86") == "p" {
-			util.KubeApplyContents("bookinfo", testSSLDeploymentP)
-		} else if util.Getenv("SAMPLEARCH", "x86") == "z" {
-			util.KubeApplyContents("bookinfo", testSSLDeploymentZ)
-		} else {
-			util.KubeApplyContents("bookinfo", testSSLDeployment)
-		}
-		util.CheckPodRunning("bookinfo", "app=testssl")
+		util.KubeApplyTemplate(ns, testSSLDeployment, nil)
+		util.CheckPodRunning(ns, "app=testssl")
 
 		util.Log.Info("Check testssl.sh results. Ignore info	Command error")
-		pod, err := util.GetPodName("bookinfo", "app=testssl")
+		pod, err := util.GetPodName(ns, "app=testssl")
 		util.Inspect(err, "failed to get testssl pod", "", t)
 
 		command := "./testssl/testssl.sh -6 productpage:9080"
-		msg, err := util.PodExec("bookinfo", pod, "testssl", command, false)
+		msg, err := util.PodExec(ns, pod, "testssl", command, false)
 		if !strings.Contains(msg, "TLSv1.2") {
 			t.Errorf("Results not include: TLSv1.2")
 			util.Log.Errorf("Results not include: TLSv1.2")
@@ -23,6 +23,7 @@ import (
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
 func cleanUpTestExtensionInstall() {
@@ -34,18 +35,20 @@ func cleanUpTestExtensionInstall() {
 	}
 
 	util.Log.Info("# Cleanup ...")
-	httpbin := examples.Httpbin{"bookinfo"}
-	sleep := examples.Sleep{"bookinfo"}
+	httpbin := examples.Httpbin{Namespace: "bookinfo"}
+	sleep := examples.Sleep{Namespace: "bookinfo"}
 	httpbin.Uninstall()
 	sleep.Uninstall()
 	util.KubeDeleteContents("bookinfo", httpbinServiceMeshExtension)
-	time.Sleep(time.Duration(20) * time.Second)
+	if err := wait.UpTo(time.Minute, wait.Deleted("bookinfo", "servicemeshextension", "header-append")); err != nil {
+		util.Log.Error(err)
+	}
 }
 
 func TestExtensionInstall(t *testing.T) {
 	defer cleanUpTestExtensionInstall()
-	httpbin := examples.Httpbin{"bookinfo"}
-	sleep := examples.Sleep{"bookinfo"}
+	httpbin := examples.Httpbin{Namespace: "bookinfo"}
+	sleep := examples.Sleep{Namespace: "bookinfo"}
 	util.Log.Info("Deploy httpbin pod")
 	httpbin.Install()
 	util.Log.Info("Deploy sleep pod")
@@ -55,7 +58,7 @@ func TestExtensionInstall(t *testing.T) {
 
 	t.Run("Operator_test_sme_install", func(t *testing.T) {
 		defer util.RecoverPanic(t)
-		util.CheckPodRunning(meshNamespace, "app=wasm-cacher")
+		util.CheckPodRunning(util.GetConfig().MeshNamespace, "app=wasm-cacher")
 
 		util.Log.Info("Creating ServiceMeshExtension")
 		util.KubeApplyContents("bookinfo", httpbinServiceMeshExtension)
@@ -64,27 +67,41 @@ func TestExtensionInstall(t *testing.T) {
 			t.Fatalf("error checking for SME header-append: %v", err)
 		}
 
-		time.Sleep(time.Duration(30) * time.Second)
-		command := "curl -s -I httpbin:8000/headers"
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
-		if err != nil {
-			t.Fatalf("error running command %q in pod %q: %v", command, sleepPod, err)
-		}
-		if !strings.Contains(msg, "maistra: rocks") {
-			t.Fatalf("custom header not present: Expected value 'maistra: rocks'")
+		if err := wait.UpTo(2*time.Minute, headerAppended("bookinfo", sleepPod)); err != nil {
+			t.Fatal(err)
 		}
 	})
 }
 
-func checkSMEReady(n, name string) error {
-	retry := util.Retrier{
-		BaseDelay: 30 * time.Second,
-		MaxDelay:  30 * time.Second,
-		Retries:   6,
+// headerAppended is met when the responses of httpbin to the sleep pod have the header added by the extension.
+func headerAppended(ns, sleepPod string) wait.Condition {
+	return wait.Condition{
+		Description: "httpbin responses with the header maistra: rocks",
+		Check: func(ctx context.Context) error {
+			res, err := util.GetKubeClient().Exec(ctx, ns, sleepPod, "sleep", "curl", "-s", "-I", "httpbin:8000/headers")
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(res.Stdout, "maistra: rocks") {
+				return fmt.Errorf("custom header not present: Expected value 'maistra: rocks'")
+			}
+			return nil
+		},
 	}
+}
+
+// smeReadyRetrier polls the status of a ServiceMeshExtension in checkSMEReady.
+var smeReadyRetrier = util.Retrier{
+	BaseDelay: 30 * time.Second,
+	MaxDelay:  30 * time.Second,
+	Retries:   6,
+}
+
+func checkSMEReady(n, name string) error {
+	retry := smeReadyRetrier
 
 	retryFn := func(_ context.Context, i int) error {
-		ready, err := isSMEReady(n, "header-append")
+		ready, err := isSMEReady(n, name)
 		if err != nil {
 			return err
 		}
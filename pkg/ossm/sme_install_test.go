@@ -0,0 +1,86 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ossm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+func TestCheckSMEReady(t *testing.T) {
+	const query = `^kubectl -n bookinfo get sme header-append -o jsonpath='\{\.status\.deployment\.ready\}'$`
+	notFound := util.CommandResult{Stderr: `Error from server (NotFound): servicemeshextensions.maistra.io "header-append" not found`, ExitCode: 1}
+
+	tests := []struct {
+		name    string
+		results []util.CommandResult
+		calls   int
+		wantErr string
+	}{
+		{
+			name:    "ready",
+			results: []util.CommandResult{{Stdout: "true"}},
+			calls:   1,
+		},
+		{
+			name:    "ready after polling",
+			results: []util.CommandResult{{Stdout: ""}, {Stdout: "false"}, {Stdout: "true"}},
+			calls:   3,
+		},
+		{
+			name:    "created while polling",
+			results: []util.CommandResult{notFound, {Stdout: "true"}},
+			calls:   2,
+		},
+		{
+			name:    "never ready",
+			results: []util.CommandResult{{Stdout: "false"}},
+			calls:   3,
+			wantErr: "sme is not ready",
+		},
+		{
+			name:    "not found",
+			results: []util.CommandResult{notFound},
+			calls:   3,
+			wantErr: "failed to get SME status for bookinfo/header-append",
+		},
+	}
+
+	previous := smeReadyRetrier
+	defer func() { smeReadyRetrier = previous }()
+	smeReadyRetrier = util.Retrier{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Retries: 3}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := util.NewFakeExecutor().OnSequence(query, tt.results...)
+			defer util.SetExecutor(util.SetExecutor(fake))
+
+			err := checkSMEReady("bookinfo", "header-append")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if calls := len(fake.Commands()); calls != tt.calls {
+				t.Errorf("expected %d queries, got %d: %v", tt.calls, calls, fake.Commands())
+			}
+		})
+	}
+}
@@ -17,28 +17,34 @@ package ossm
 import (
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
-func cleanupBookinfo() {
-	util.Log.Info("Cleanup")
-	app := examples.Bookinfo{"bookinfo"}
-	app.Uninstall()
-	time.Sleep(time.Duration(30) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "A2",
+		Title:      "Bookinfo smoke test",
+		Tags:       []string{suite.Smoke, suite.ARM, suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway},
+		Test:       TestBookinfo,
+	})
 }
 
 func TestBookinfo(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Test Bookinfo Installation")
-	app := examples.Bookinfo{"bookinfo"}
+	app := examples.Bookinfo{Namespace: ns}
 	app.Install(false)
 
 	util.Log.Info("Check pods running 2/2 ready")
-	msg, _ := util.Shell(`oc get pods -n bookinfo`)
+	msg, _ := util.Shell(`oc get pods -n %s`, ns)
 	if strings.Contains(msg, "2/2") {
 		util.Log.Info("Success. proxy container is running.")
 	} else {
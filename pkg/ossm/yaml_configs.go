@@ -223,60 +223,18 @@ spec:
     spec:
       containers:
       - name: testssl
-        image: quay.io/maistra/testssl:latest
+        image: {{ sampleImage "testssl" }}
         imagePullPolicy: Always
 `
 
-	testSSLDeploymentZ = `
-apiVersion: apps/v1
-kind: Deployment
-metadata:
-  name: testssl
-spec:
-  replicas: 1
-  selector:
-    matchLabels:
-      app: testssl
-  template:
-    metadata:
-      labels:
-        app: testssl
-    spec:
-      containers:
-      - name: testssl
-        image: quay.io/maistra/testssl:0.0-ibm-z
-        imagePullPolicy: Always
-`
-
-	testSSLDeploymentP = `
-apiVersion: apps/v1
-kind: Deployment
-metadata:
-  name: testssl
-spec:
-  replicas: 1
-  selector:
-    matchLabels:
-      app: testssl
-  template:
-    metadata:
-      labels:
-        app: testssl
-    spec:
-      containers:
-      - name: testssl
-        image: quay.io/maistra/testssl:0.0-ibm-p
-        imagePullPolicy: Always
-`
-
-	rateLimitSMCPPatch = `
+	rateLimitSMCPPatchTemplate = `
 spec:
   techPreview:
     rateLimiting:
       rls:
         enabled: true
         storageBackend: redis
-        storageAddress: redis.redis:6379
+        storageAddress: redis.{{ .Namespace }}:6379
       rawRules:
         domain: productpage-ratelimit
         descriptors:
@@ -311,53 +269,7 @@ spec:
     spec:
       containers:
       - name: testenv
-        image: quay.io/maistra/testssl:latest
-        imagePullPolicy: Always
-`
-
-	testAnnotationProxyEnvZ = `
-apiVersion: apps/v1
-kind: Deployment
-metadata:
-  name: testenv
-spec:
-  replicas: 1
-  selector:
-    matchLabels:
-      app: env
-  template:
-    metadata:
-      annotations:
-        sidecar.maistra.io/proxyEnv: '{ "maistra_test_env": "env_value", "maistra_test_env_2": "env_value_2" }'
-      labels:
-        app: env
-    spec:
-      containers:
-      - name: testenv
-        image: quay.io/maistra/testssl:0.0-ibm-z
-        imagePullPolicy: Always
-`
-
-	testAnnotationProxyEnvP = `
-apiVersion: apps/v1
-kind: Deployment
-metadata:
-  name: testenv
-spec:
-  replicas: 1
-  selector:
-    matchLabels:
-      app: env
-  template:
-    metadata:
-      annotations:
-        sidecar.maistra.io/proxyEnv: '{ "maistra_test_env": "env_value", "maistra_test_env_2": "env_value_2" }'
-      labels:
-        app: env
-    spec:
-      containers:
-      - name: testenv
-        image: quay.io/maistra/testssl:0.0-ibm-p
+        image: {{ sampleImage "testssl" }}
         imagePullPolicy: Always
 `
 
@@ -14,24 +14,8 @@
 
 package ossm
 
-import (
-	"github.com/maistra/maistra-test-tool/pkg/util"
-)
-
-type SMCP struct {
-	Name      string `default:"basic"`
-	Namespace string `default:"istio-system"`
-}
-
 const (
 	jaegerSubYaml = "../templates/olm-templates/nightly/jaeger_subscription.yaml"
 	kialiSubYaml  = "../templates/olm-templates/nightly/kiali_subscription.yaml"
 	ossmSubYaml   = "../templates/olm-templates/nightly/ossm_subscription.yaml"
 )
-
-var (
-	smcpName      string = util.Getenv("SMCPNAME", "basic")
-	meshNamespace string = util.Getenv("MESHNAMESPACE", "istio-system")
-	smcp          SMCP   = SMCP{smcpName, meshNamespace}
-	ipv6          string = util.Getenv("IPV6", "false")
-)
@@ -0,0 +1,116 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// ArtifactCollector saves diagnostic artifacts of the cluster when a test or subtest fails.
+// Artifacts are collected from util.RecoverPanic, which the tests defer, so they reflect the state
+// of the cluster before the test cleaned up after itself.
+type ArtifactCollector struct {
+	// Dir is the root directory. Every failed test gets its own subdirectory, see ArtifactDir.
+	Dir string
+	// MeshNamespace is the control plane namespace.
+	MeshNamespace string
+
+	mu        sync.Mutex
+	collected map[string]bool
+}
+
+// NewArtifactCollector returns a collector saving artifacts under dir.
+func NewArtifactCollector(dir, meshNamespace string) *ArtifactCollector {
+	return &ArtifactCollector{
+		Dir:           dir,
+		MeshNamespace: meshNamespace,
+		collected:     map[string]bool{},
+	}
+}
+
+// Enable collects artifacts for every failing test from now on.
+func (c *ArtifactCollector) Enable() {
+	util.OnTestFailure(c.Collect)
+}
+
+// ArtifactDir returns the directory holding the artifacts of a test or subtest.
+func ArtifactDir(root, testName string) string {
+	return filepath.Join(root, strings.ReplaceAll(testName, "/", "__"))
+}
+
+// Collect saves the artifacts of a failed test. A test is collected once and a test is skipped
+// if the artifacts of one of its subtests have already been collected.
+func (c *ArtifactCollector) Collect(t *testing.T) {
+	name := t.Name()
+	if !c.markCollected(name) {
+		return
+	}
+
+	namespaces := []string{}
+	if tc, ok := Lookup(topLevelName(name)); ok {
+		for _, ns := range tc.Namespaces {
+			namespaces = append(namespaces, util.Namespace(t, ns))
+		}
+	}
+
+	dir := ArtifactDir(c.Dir, name)
+	util.TestLog(t).Infof("Collecting diagnostic artifacts of failed test %s into %s", name, dir)
+	if err := util.DumpResources(dir, c.MeshNamespace, namespaces); err != nil {
+		util.TestLog(t).Warnf("Failed to collect some artifacts of test %s: %v", name, err)
+	}
+}
+
+func (c *ArtifactCollector) markCollected(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.collected[name] {
+		return false
+	}
+	for collected := range c.collected {
+		if strings.HasPrefix(collected, name+"/") {
+			return false
+		}
+	}
+	c.collected[name] = true
+	return true
+}
+
+// AddArtifacts references the artifact directories of the failed tests from the report.
+func AddArtifacts(report *Report, root string) {
+	report.Walk(func(res *Result) {
+		if res.Status != StatusFail {
+			return
+		}
+		dir := ArtifactDir(root, res.Name)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			res.Artifacts = dir
+		}
+	})
+}
+
+// topLevelName returns the name of the top level test of a (sub)test name.
+func topLevelName(name string) string {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
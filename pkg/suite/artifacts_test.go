@@ -0,0 +1,77 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarkCollected(t *testing.T) {
+	c := NewArtifactCollector(t.TempDir(), "istio-system")
+
+	steps := []struct {
+		name string
+		want bool
+	}{
+		{"T1/subtest/nested", true},
+		// RecoverPanic of the enclosing tests reports the same failure again
+		{"T1/subtest", false},
+		{"T1", false},
+		{"T1/subtest/nested", false},
+		{"T1/other", true},
+		// a prefix of the name that is not a parent
+		{"T10", true},
+		{"T2", true},
+		{"T2/late", true},
+	}
+	for _, s := range steps {
+		if got := c.markCollected(s.name); got != s.want {
+			t.Errorf("%s: got %v, want %v", s.name, got, s.want)
+		}
+	}
+}
+
+func TestAddArtifacts(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"T1/subtest", "T2"} {
+		if err := os.MkdirAll(ArtifactDir(root, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := &Report{Tests: []*Result{
+		{Name: "T1", Status: StatusFail, Subtests: []*Result{
+			{Name: "T1/subtest", Status: StatusFail},
+		}},
+		// artifacts of a test that passed on a retry are not referenced
+		{Name: "T2", Status: StatusFlaky},
+		{Name: "T3", Status: StatusFail},
+	}}
+	AddArtifacts(r, root)
+
+	want := map[string]string{
+		"T1":         "",
+		"T1/subtest": filepath.Join(root, "T1__subtest"),
+		"T2":         "",
+		"T3":         "",
+	}
+	r.Walk(func(res *Result) {
+		if res.Artifacts != want[res.Name] {
+			t.Errorf("%s: got %q, want %q", res.Name, res.Artifacts, want[res.Name])
+		}
+	})
+}
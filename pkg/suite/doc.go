@@ -0,0 +1,32 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package suite registers the test cases and runs them.
+//
+// Every test case registers itself with Register in an init function next to its test: a stable ID, a
+// title, tags, the namespaces it uses and the shared Resources it modifies. Importing a package of test
+// cases does not touch the cluster; setup registered with RegisterSetup runs right before the first test
+// case, and the gateway host and ports are resolved once and handed to the tests as util.TestEnv(t).
+//
+// Select picks test cases by a tag expression and a Filter narrows them down by name patterns. InternalTests wraps
+// them for the testing package: it allocates their namespaces, binds their logs and sessions and deletes
+// the objects they created when they complete. A Scheduler runs test cases declaring the same Resource one
+// after another and a test case declaring SMCP alone. Test cases sharing a namespace that is never isolated,
+// e.g. mesh-external, run one after another as well, see TestCase.AllResources.
+//
+// RunChild runs the selected test cases in a child process of the test binary, so that their output is
+// captured into a Report, written as JSON and JUnit XML. Failed test cases are rerun with AddRetry and a
+// test case passing on a retry is reported as flaky. The diagnostic artifacts of failed tests are
+// collected by util.RecoverPanic.
+package suite
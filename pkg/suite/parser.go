@@ -0,0 +1,131 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+var (
+	// e.g. "=== RUN   T3/TrafficManagement_shift_50_percent_v3_traffic"
+	eventLine = regexp.MustCompile(`^=== (RUN|NAME|CONT|PAUSE)\s+(\S+)$`)
+	// e.g. "    --- FAIL: T3/TrafficManagement_shift_50_percent_v3_traffic (12.34s)"
+	resultLine = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \(([0-9.]+)s\)$`)
+	// e.g. "cleanup.go:78: Leaked resource bookinfo-x7k2q/deployment.apps/productpage-v1: still exists"
+	leakedLine = regexp.MustCompile(`^\S+:\d+: ` + util.LeakedResourcePrefix + `(.*)$`)
+)
+
+// OutputParser builds a Report from the output of `go test -v`. Lines written by t.Log and friends
+// are recorded as messages of the test they belong to and any other line (e.g. util.Log output)
+// is recorded as output of the test that was running when it was printed.
+type OutputParser struct {
+	report  *Report
+	results map[string]*Result
+	current *Result
+	last    *[]string
+}
+
+// NewOutputParser returns a parser adding the tests it finds to the given report.
+func NewOutputParser(report *Report) *OutputParser {
+	return &OutputParser{
+		report:  report,
+		results: map[string]*Result{},
+	}
+}
+
+// ParseLine processes a single line of output, without the trailing newline.
+func (p *OutputParser) ParseLine(line string) {
+	// in -test.v=test2json mode the testing package marks its own lines with a ^V byte
+	line = strings.TrimPrefix(strings.TrimRight(line, "\r"), "\x16")
+
+	if m := eventLine.FindStringSubmatch(line); m != nil {
+		if m[1] != "PAUSE" {
+			p.current = p.result(m[2])
+		}
+		p.last = nil
+		return
+	}
+
+	if m := resultLine.FindStringSubmatch(line); m != nil {
+		res := p.result(m[2])
+		res.Status = strings.ToLower(m[1])
+		if secs, err := strconv.ParseFloat(m[3], 64); err == nil {
+			res.Duration = time.Duration(secs * float64(time.Second))
+		}
+		p.last = nil
+		return
+	}
+
+	switch {
+	case line == "PASS" || line == "FAIL" || strings.HasPrefix(line, "ok  \t") || strings.HasPrefix(line, "FAIL\t"):
+		// summary printed by the testing package once all tests completed
+		p.current = nil
+		p.last = nil
+	case p.current != nil && strings.HasPrefix(line, "        ") && p.last != nil && len(*p.last) > 0:
+		// continuation of a multi-line message
+		(*p.last)[len(*p.last)-1] += "\n" + strings.TrimPrefix(line, "        ")
+	case p.current != nil && strings.HasPrefix(line, "    "):
+		msg := strings.TrimPrefix(line, "    ")
+		p.current.Messages = append(p.current.Messages, msg)
+		p.last = &p.current.Messages
+		if m := leakedLine.FindStringSubmatch(msg); m != nil {
+			p.current.Leaked = append(p.current.Leaked, m[1])
+		}
+	case p.current != nil:
+		p.current.Output = append(p.current.Output, line)
+		p.last = nil
+	default:
+		p.report.Output = append(p.report.Output, line)
+		p.last = nil
+	}
+}
+
+// Finish marks tests that never printed a result as failed, e.g. because the test binary panicked or timed out.
+func (p *OutputParser) Finish() {
+	p.report.Walk(func(res *Result) {
+		if res.Status == "" {
+			res.Status = StatusFail
+			res.Messages = append(res.Messages, "test did not finish")
+		}
+	})
+}
+
+// result returns the result for a test name, creating it and its parents if needed.
+func (p *OutputParser) result(name string) *Result {
+	if res, ok := p.results[name]; ok {
+		return res
+	}
+
+	res := &Result{Name: name}
+	p.results[name] = res
+
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		parent := p.result(name[:i])
+		parent.Subtests = append(parent.Subtests, res)
+		return res
+	}
+
+	if tc, ok := Lookup(name); ok {
+		res.Title = tc.Title
+		res.Package = packageOf(tc.FuncName())
+	}
+	p.report.Tests = append(p.report.Tests, res)
+	return res
+}
@@ -0,0 +1,218 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parse runs the parser over the output of `go test -v` and finishes it like RunChild.
+func parse(output string) *Report {
+	report := &Report{}
+	p := NewOutputParser(report)
+	for _, line := range strings.Split(strings.TrimPrefix(output, "\n"), "\n") {
+		p.ParseLine(line)
+	}
+	p.Finish()
+	return report
+}
+
+// byName returns the results of the report and its subtests by name.
+func byName(r *Report) map[string]*Result {
+	results := map[string]*Result{}
+	r.Walk(func(res *Result) { results[res.Name] = res })
+	return results
+}
+
+func TestOutputParser(t *testing.T) {
+	type want struct {
+		status   string
+		duration time.Duration
+		messages []string
+		output   []string
+		leaked   []string
+		subtests []string
+	}
+	cases := []struct {
+		name   string
+		output string
+		tests  []string
+		want   map[string]want
+		global []string
+	}{
+		{
+			name: "sequential tests",
+			output: `
+starting the run
+=== RUN   T1
+time="10:00:00" level=info msg="Deploying Bookinfo"
+    request_routing.go:42: request failed
+        with status 503
+--- FAIL: T1 (1.50s)
+=== RUN   T2
+--- PASS: T2 (0.25s)
+FAIL
+exit status 1`,
+			tests: []string{"T1", "T2"},
+			want: map[string]want{
+				"T1": {status: StatusFail, duration: 1500 * time.Millisecond,
+					messages: []string{"request_routing.go:42: request failed\nwith status 503"},
+					output:   []string{`time="10:00:00" level=info msg="Deploying Bookinfo"`}},
+				"T2": {status: StatusPass, duration: 250 * time.Millisecond},
+			},
+			global: []string{"starting the run", "exit status 1"},
+		},
+		{
+			name: "interleaved parallel tests",
+			output: `
+=== RUN   T1
+=== PAUSE T1
+=== RUN   T2
+=== PAUSE T2
+=== CONT  T1
+log of T1
+=== CONT  T2
+log of T2
+    fault_injection.go:10: message of T2
+=== NAME  T1
+    request_routing.go:20: message of T1
+--- PASS: T2 (2.00s)
+--- FAIL: T1 (3.00s)
+FAIL`,
+			tests: []string{"T1", "T2"},
+			want: map[string]want{
+				"T1": {status: StatusFail, duration: 3 * time.Second,
+					messages: []string{"request_routing.go:20: message of T1"}, output: []string{"log of T1"}},
+				"T2": {status: StatusPass, duration: 2 * time.Second,
+					messages: []string{"fault_injection.go:10: message of T2"}, output: []string{"log of T2"}},
+			},
+		},
+		{
+			name: "subtests",
+			output: `
+=== RUN   T3
+=== RUN   T3/shift_50_percent
+    traffic_shifting.go:60: checking
+=== RUN   T3/shift_100_percent
+    traffic_shifting.go:70: not supported
+--- PASS: T3 (4.00s)
+    --- PASS: T3/shift_50_percent (1.00s)
+    --- SKIP: T3/shift_100_percent (0.00s)
+PASS`,
+			tests: []string{"T3"},
+			want: map[string]want{
+				"T3": {status: StatusPass, duration: 4 * time.Second,
+					subtests: []string{"T3/shift_50_percent", "T3/shift_100_percent"}},
+				"T3/shift_50_percent":  {status: StatusPass, duration: time.Second, messages: []string{"traffic_shifting.go:60: checking"}},
+				"T3/shift_100_percent": {status: StatusSkip, messages: []string{"traffic_shifting.go:70: not supported"}},
+			},
+		},
+		{
+			name: "leaked resources",
+			output: `
+=== RUN   T4
+    cleanup.go:78: Leaked resource bookinfo-x7k2q/deployment.apps/productpage-v1: still exists
+--- FAIL: T4 (1.00s)`,
+			tests: []string{"T4"},
+			want: map[string]want{
+				"T4": {status: StatusFail, duration: time.Second,
+					messages: []string{"cleanup.go:78: Leaked resource bookinfo-x7k2q/deployment.apps/productpage-v1: still exists"},
+					leaked:   []string{"bookinfo-x7k2q/deployment.apps/productpage-v1: still exists"}},
+			},
+		},
+		{
+			name: "panic",
+			output: `
+=== RUN   T5
+=== RUN   T5/step
+panic: boom [recovered]
+	panic: boom
+
+goroutine 7 [running]:
+FAIL	github.com/maistra/maistra-test-tool/tests	0.010s`,
+			tests: []string{"T5"},
+			want: map[string]want{
+				"T5": {status: StatusFail, messages: []string{"test did not finish"}, subtests: []string{"T5/step"}},
+				"T5/step": {status: StatusFail, messages: []string{"test did not finish"},
+					output: []string{"panic: boom [recovered]", "\tpanic: boom", "", "goroutine 7 [running]:"}},
+			},
+		},
+		{
+			name: "truncated stream",
+			output: `
+=== RUN   T6
+--- PASS: T6 (1.00s)
+=== RUN   T7
+=== RUN   T7/step
+    egress.go:5: waiting`,
+			tests: []string{"T6", "T7"},
+			want: map[string]want{
+				"T6": {status: StatusPass, duration: time.Second},
+				"T7": {status: StatusFail, messages: []string{"test did not finish"}, subtests: []string{"T7/step"}},
+				"T7/step": {status: StatusFail,
+					messages: []string{"egress.go:5: waiting", "test did not finish"}},
+			},
+		},
+		{
+			name:   "test2json markers",
+			output: "\x16=== RUN   T8\r\n    x.go:1: hello\r\n\x16--- PASS: T8 (0.50s)\r",
+			tests:  []string{"T8"},
+			want: map[string]want{
+				"T8": {status: StatusPass, duration: 500 * time.Millisecond, messages: []string{"x.go:1: hello"}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			report := parse(c.output)
+
+			var tests []string
+			for _, res := range report.Tests {
+				tests = append(tests, res.Name)
+			}
+			if !equalStrings(tests, c.tests) {
+				t.Errorf("tests: got %v, want %v", tests, c.tests)
+			}
+			if c.global != nil && !reflect.DeepEqual(report.Output, c.global) {
+				t.Errorf("report output: got %q, want %q", report.Output, c.global)
+			}
+
+			results := byName(report)
+			if len(results) != len(c.want) {
+				t.Errorf("got %d results, want %d", len(results), len(c.want))
+			}
+			for name, w := range c.want {
+				res, ok := results[name]
+				if !ok {
+					t.Errorf("%s: missing", name)
+					continue
+				}
+				var subtests []string
+				for _, sub := range res.Subtests {
+					subtests = append(subtests, sub.Name)
+				}
+				got := want{status: res.Status, duration: res.Duration, messages: res.Messages,
+					output: res.Output, leaked: res.Leaked, subtests: subtests}
+				if !reflect.DeepEqual(got, w) {
+					t.Errorf("%s:\ngot  %+v\nwant %+v", name, got, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// Tags used to group test cases. A test case can belong to several groups.
+const (
+	Smoke      = "smoke"
+	ARM        = "arm"
+	Power      = "p"
+	Z          = "z"
+	Interop    = "interop"
+	Disruptive = "disruptive"
+
+	// Full is not a tag. It selects every registered test case.
+	Full = "full"
+)
+
+var knownTags = map[string]bool{
+	Smoke:      true,
+	ARM:        true,
+	Power:      true,
+	Z:          true,
+	Interop:    true,
+	Disruptive: true,
+	Full:       true,
+}
+
+// TestCase describes a test registered in the suite.
+type TestCase struct {
+	// ID is the stable short name of the test case, e.g. "T3". It is the name used with `go test -run`.
+	ID string
+	// Title is a human readable description of the test case.
+	Title string
+	// Tags lists the groups the test case belongs to.
+	Tags []string
+	// Namespaces lists the namespaces the test case deploys its workloads in.
+	Namespaces []string
+	// Resources lists the shared resources the test case modifies. Test cases declaring the same
+	// resource never run at the same time, see Scheduler.
+	Resources []Resource
+	// Test is the test function.
+	Test func(t *testing.T)
+}
+
+// AllResources returns the declared resources of the test case and the resources of the fixed namespaces
+// it uses, e.g. mesh-external, which is deleted when any test case using it completes.
+func (tc TestCase) AllResources() []Resource {
+	resources := append([]Resource{}, tc.Resources...)
+	for _, ns := range tc.Namespaces {
+		if util.IsFixedNamespace(ns) {
+			resources = append(resources, NamespaceResource(ns))
+		}
+	}
+	return resources
+}
+
+// HasTag returns true if the test case belongs to the given group.
+func (tc TestCase) HasTag(tag string) bool {
+	if tag == Full {
+		return true
+	}
+	for _, t := range tc.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]TestCase{}
+)
+
+// Register adds a test case to the suite. It is meant to be called from an init() function
+// next to the test function. Register panics if the ID is empty or already registered.
+func Register(tc TestCase) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if tc.ID == "" || tc.Test == nil {
+		panic(fmt.Sprintf("suite: test case %q must have an ID and a test function", tc.Title))
+	}
+	if existing, ok := registry[tc.ID]; ok {
+		panic(fmt.Sprintf("suite: test case ID %s registered twice: %q and %q", tc.ID, existing.Title, tc.Title))
+	}
+	for _, tag := range tc.Tags {
+		if !knownTags[tag] || tag == Full {
+			panic(fmt.Sprintf("suite: test case %s has unknown tag %q", tc.ID, tag))
+		}
+	}
+	registry[tc.ID] = tc
+}
+
+var setups []func()
+
+// RegisterSetup adds a function preparing the cluster for the test cases, e.g. installing the control plane.
+// Like Register, it is meant to be called from an init() function. The setup functions run in the order
+// of registration when RunSetup is called, never when a package is merely imported.
+func RegisterSetup(fn func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	setups = append(setups, fn)
+}
+
+// RunSetup runs the registered setup functions.
+func RunSetup() {
+	mu.Lock()
+	fns := append([]func(){}, setups...)
+	mu.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+// TestCases returns all registered test cases ordered by ID (A1, A2, T1, T2, ..., T10, ...).
+func TestCases() []TestCase {
+	mu.Lock()
+	defer mu.Unlock()
+
+	tcs := make([]TestCase, 0, len(registry))
+	for _, tc := range registry {
+		tcs = append(tcs, tc)
+	}
+	sort.Slice(tcs, func(i, j int) bool {
+		return lessID(tcs[i].ID, tcs[j].ID)
+	})
+	return tcs
+}
+
+// Lookup returns the registered test case with the given ID.
+func Lookup(id string) (TestCase, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	tc, ok := registry[id]
+	return tc, ok
+}
+
+// lessID compares IDs by their letter prefix first and their numeric suffix second.
+func lessID(a, b string) bool {
+	pa, na := splitID(a)
+	pb, nb := splitID(b)
+	if pa != pb {
+		return pa < pb
+	}
+	if na != nb {
+		return na < nb
+	}
+	return a < b
+}
+
+func splitID(id string) (string, int) {
+	i := strings.IndexAny(id, "0123456789")
+	if i < 0 {
+		return id, 0
+	}
+	n, err := strconv.Atoi(id[i:])
+	if err != nil {
+		return id, 0
+	}
+	return id[:i], n
+}
+
+// Select returns the registered test cases matching a tag expression.
+//
+// The expression is a comma separated list of terms and a test case must match all of them.
+// A term is a tag, a negated tag ("!disruptive") or several alternatives separated by "|" ("arm|p").
+// The special term "full" matches every test case. Examples:
+//
+//	full                 every test case
+//	smoke                test cases tagged smoke
+//	smoke,!disruptive    smoke test cases that do not disrupt the shared control plane
+//	interop|arm          test cases tagged interop or arm
+func Select(expr string) ([]TestCase, error) {
+	match, err := ParseTagExpression(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []TestCase
+	for _, tc := range TestCases() {
+		if match(tc) {
+			selected = append(selected, tc)
+		}
+	}
+	return selected, nil
+}
+
+// ParseTagExpression parses a tag expression (see Select) into a predicate.
+func ParseTagExpression(expr string) (func(TestCase) bool, error) {
+	var terms []func(TestCase) bool
+	for _, term := range strings.Split(expr, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		negate := strings.HasPrefix(term, "!")
+		if negate {
+			term = strings.TrimSpace(strings.TrimPrefix(term, "!"))
+		}
+
+		var alternatives []string
+		for _, tag := range strings.Split(term, "|") {
+			tag = strings.TrimSpace(tag)
+			if !knownTags[tag] {
+				return nil, fmt.Errorf("unknown test group %q in expression %q", tag, expr)
+			}
+			alternatives = append(alternatives, tag)
+		}
+
+		terms = append(terms, func(tc TestCase) bool {
+			for _, tag := range alternatives {
+				if tc.HasTag(tag) {
+					return !negate
+				}
+			}
+			return negate
+		})
+	}
+
+	return func(tc TestCase) bool {
+		for _, term := range terms {
+			if !term(tc) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+var (
+	allocator *util.NamespaceAllocator
+	scheduler *Scheduler
+)
+
+// SetNamespaceAllocator makes the tests returned by InternalTests run in namespaces allocated by a.
+// Without an allocator the tests use the namespaces named after the base names in TestCase.Namespaces.
+func SetNamespaceAllocator(a *util.NamespaceAllocator) {
+	allocator = a
+}
+
+// SetScheduler makes the tests returned by InternalTests run in parallel as far as s allows.
+// Without a scheduler the tests run one after another.
+func SetScheduler(s *Scheduler) {
+	scheduler = s
+}
+
+// InternalTests converts test cases to the form accepted by testing.Main. The test functions are wrapped
+// with util.RecoverPanic so that failure handlers run even for tests that do not defer it themselves.
+// If a scheduler is set, the test functions wait for the resources they declared. If a namespace allocator
+// is set, the namespaces of a test case are allocated before its test function runs. The objects the test
+// creates in its namespaces are deleted when it completes, see util.TrackResources. Recorded and replayed
+// sessions include the namespace allocation and the cleanup, see RecordSessions. Log entries are attributed
+// to the test case with its ID and namespaces, see util.BindTestLog.
+func InternalTests(tcs []TestCase) []testing.InternalTest {
+	tests := make([]testing.InternalTest, 0, len(tcs))
+	for _, tc := range tcs {
+		tc := tc
+		tests = append(tests, testing.InternalTest{
+			Name: tc.ID,
+			F: func(t *testing.T) {
+				if scheduler != nil {
+					t.Parallel()
+					// cleanup functions run in reverse order, so the resources are released last
+					t.Cleanup(scheduler.Acquire(tc.AllResources()))
+				}
+				util.BindTestLog(t, tc.ID)
+				defer util.RecoverPanic(t)
+				if session != nil {
+					session(t, tc.ID)
+				}
+				if allocator != nil {
+					if err := allocator.Allocate(t, tc.Namespaces...); err != nil {
+						t.Fatalf("Failed to allocate namespaces %v: %v", tc.Namespaces, err)
+					}
+				}
+				namespaces := make([]string, 0, len(tc.Namespaces))
+				for _, ns := range tc.Namespaces {
+					namespaces = append(namespaces, util.Namespace(t, ns))
+				}
+				if len(namespaces) > 0 {
+					util.SetTestLogField(t, "namespace", strings.Join(namespaces, ","))
+				}
+				util.TrackResources(t, namespaces...)
+				tc.Test(t)
+			},
+		})
+	}
+	return tests
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestParseTagExpression(t *testing.T) {
+	tcs := []TestCase{
+		{ID: "T1", Tags: []string{Smoke}},
+		{ID: "T2", Tags: []string{Smoke, Disruptive}},
+		{ID: "T3", Tags: []string{ARM}},
+		{ID: "T4", Tags: []string{Power, Disruptive}},
+		{ID: "T5"},
+	}
+	cases := []struct {
+		expr string
+		want []string
+	}{
+		{"full", []string{"T1", "T2", "T3", "T4", "T5"}},
+		{"", []string{"T1", "T2", "T3", "T4", "T5"}},
+		{"smoke", []string{"T1", "T2"}},
+		{"!disruptive", []string{"T1", "T3", "T5"}},
+		{"smoke,!disruptive", []string{"T1"}},
+		{" smoke , ! disruptive ", []string{"T1"}},
+		{"arm|p", []string{"T3", "T4"}},
+		// "|" binds tighter than ",": (smoke or arm) and not disruptive
+		{"smoke|arm,!disruptive", []string{"T1", "T3"}},
+		// "!" negates the whole term: neither smoke nor arm
+		{"!smoke|arm", []string{"T4", "T5"}},
+		{"full,!smoke", []string{"T3", "T4", "T5"}},
+	}
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			match, err := ParseTagExpression(c.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tc := range tcs {
+				if match(tc) {
+					got = append(got, tc.ID)
+				}
+			}
+			if !equalStrings(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseTagExpressionErrors(t *testing.T) {
+	for _, expr := range []string{"unknown", "smoke,foo", "!", "smoke|", "|arm", "Smoke"} {
+		if _, err := ParseTagExpression(expr); err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
+
+func TestLessID(t *testing.T) {
+	ids := []string{"T10", "A2", "T2", "T1", "A10", "A1", "B", "T02"}
+	sort.Slice(ids, func(i, j int) bool { return lessID(ids[i], ids[j]) })
+	want := []string{"A1", "A2", "A10", "B", "T1", "T02", "T2", "T10"}
+	if !equalStrings(ids, want) {
+		t.Errorf("got %v, want %v", ids, want)
+	}
+}
+
+func TestAllResources(t *testing.T) {
+	tcs := []struct {
+		name string
+		tc   TestCase
+		want []Resource
+	}{
+		{"no resources", TestCase{Namespaces: []string{"bookinfo"}}, []Resource{}},
+		{"declared", TestCase{Namespaces: []string{"bookinfo"}, Resources: []Resource{EgressGateway}}, []Resource{EgressGateway}},
+		{"fixed namespace", TestCase{Namespaces: []string{"bookinfo", "mesh-external"}, Resources: []Resource{EgressGateway}},
+			[]Resource{EgressGateway, NamespaceResource("mesh-external")}},
+	}
+	for _, tt := range tcs {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.tc.AllResources()
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,346 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// Test statuses as reported by `go test -v`.
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+	// StatusFlaky is the status of a test case that failed and passed when it was retried.
+	StatusFlaky = "flaky"
+)
+
+// Result is the outcome of a test or subtest.
+type Result struct {
+	// Name is the full name as printed by the testing package, e.g. "T3/TrafficManagement_shift_50_percent_v3_traffic".
+	Name string `json:"name"`
+	// Title is the title of the registered test case. It is empty for subtests.
+	Title string `json:"title,omitempty"`
+	// Package is the package of the test function relative to pkg, e.g. "tasks/traffic".
+	Package  string        `json:"package,omitempty"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration"`
+	// Messages are the lines written by t.Log, t.Error, t.Fatal and t.Skip.
+	Messages []string `json:"messages,omitempty"`
+	// Output are the log lines printed while the test was running.
+	Output []string `json:"output,omitempty"`
+	// Artifacts is the directory holding the diagnostic artifacts collected when the test failed.
+	Artifacts string `json:"artifacts,omitempty"`
+	// Leaked lists the objects created by the test that could not be deleted when it completed.
+	Leaked   []string  `json:"leaked,omitempty"`
+	Subtests []*Result `json:"subtests,omitempty"`
+	// Attempts are the failed earlier runs of a retried test case, oldest first. The result itself is the last run.
+	Attempts []*Result `json:"attempts,omitempty"`
+}
+
+// Property is a name/value pair describing the environment of a run.
+type Property struct {
+	Name  string `json:"name" xml:"name,attr"`
+	Value string `json:"value" xml:"value,attr"`
+}
+
+// Report is the outcome of a suite run.
+type Report struct {
+	Name       string        `json:"name"`
+	Started    time.Time     `json:"started"`
+	Duration   time.Duration `json:"duration"`
+	Properties []Property    `json:"properties,omitempty"`
+	// Output are the lines printed outside of any test.
+	Output []string  `json:"output,omitempty"`
+	Tests  []*Result `json:"tests"`
+}
+
+// AddProperty appends a property to the report.
+func (r *Report) AddProperty(name, value string) {
+	r.Properties = append(r.Properties, Property{Name: name, Value: value})
+}
+
+// Walk calls fn for every test and subtest of the report, parents before their subtests.
+// Earlier attempts of retried tests are not visited.
+func (r *Report) Walk(fn func(res *Result)) {
+	walkResults(r.Tests, fn)
+}
+
+func walkResults(results []*Result, fn func(res *Result)) {
+	for _, res := range results {
+		fn(res)
+		walkResults(res.Subtests, fn)
+	}
+}
+
+// Counts returns the number of tests and subtests in total, failed and skipped.
+func (r *Report) Counts() (total, failed, skipped int) {
+	r.Walk(func(res *Result) {
+		total++
+		switch res.Status {
+		case StatusFail:
+			failed++
+		case StatusSkip:
+			skipped++
+		}
+	})
+	return
+}
+
+// Failed returns the names of the test cases that failed, without their subtests.
+func (r *Report) Failed() []string {
+	var failed []string
+	for _, res := range r.Tests {
+		if res.Status == StatusFail {
+			failed = append(failed, res.Name)
+		}
+	}
+	return failed
+}
+
+// Flaky returns the results of the test cases that passed on a retry.
+func (r *Report) Flaky() []*Result {
+	var flaky []*Result
+	for _, res := range r.Tests {
+		if res.Status == StatusFlaky {
+			flaky = append(flaky, res)
+		}
+	}
+	return flaky
+}
+
+// AddRetry replaces the results of the test cases rerun in retry. The replaced results are kept as
+// attempts of the new ones and a test case passing on the retry is marked as flaky.
+func (r *Report) AddRetry(retry *Report) {
+	for _, res := range retry.Tests {
+		for i, prev := range r.Tests {
+			if prev.Name != res.Name {
+				continue
+			}
+			attempts := append(prev.Attempts, prev)
+			prev.Attempts = nil
+			res.Attempts = attempts
+			if res.Status == StatusPass {
+				res.Status = StatusFlaky
+			}
+			r.Tests[i] = res
+		}
+	}
+	r.Output = append(r.Output, retry.Output...)
+	r.Duration = time.Since(r.Started)
+}
+
+// ReadTestLogs replaces the output of the test cases with the entries of their log files in dir, see
+// util.EnableTestLogs. Test cases running in parallel print to the same stdout, where the lines of one
+// test case cannot be told from those of another, while a log file holds the entries of its test case
+// only. The output of subtests is dropped, since the log file of their test case includes it.
+func (r *Report) ReadTestLogs(dir string) error {
+	for _, res := range r.Tests {
+		data, err := ioutil.ReadFile(util.TestLogFile(dir, res.Name))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		res.Output = nil
+		if len(data) > 0 {
+			res.Output = strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		}
+		walkResults(res.Subtests, func(sub *Result) { sub.Output = nil })
+	}
+	return nil
+}
+
+// Leaked returns the objects that could not be deleted after the tests, including earlier attempts,
+// prefixed with the test name.
+func (r *Report) Leaked() []string {
+	var leaked []string
+	add := func(res *Result) {
+		for _, l := range res.Leaked {
+			leaked = append(leaked, res.Name+": "+l)
+		}
+	}
+	for _, res := range r.Tests {
+		walkResults(res.Attempts, add)
+		walkResults([]*Result{res}, add)
+	}
+	return leaked
+}
+
+// WriteJSON writes the report as indented JSON to the given file.
+func (r *Report) WriteJSON(filename string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []Property      `xml:"properties>property,omitempty"`
+	TestCases  []junitTestCase `xml:"testcase"`
+	SystemOut  string          `xml:"system-out,omitempty"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	// the failed attempts of a flaky test and of a test failing every retry, in the format of Maven Surefire
+	FlakyFailures []junitRerun `xml:"flakyFailure,omitempty"`
+	RerunFailures []junitRerun `xml:"rerunFailure,omitempty"`
+	SystemOut     string       `xml:"system-out,omitempty"`
+}
+
+type junitRerun struct {
+	Message    string `xml:"message,attr"`
+	StackTrace string `xml:"stackTrace,omitempty"`
+	SystemOut  string `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML to the given file. Subtests are reported as separate
+// test cases named after their full path, e.g. "T3/TrafficManagement_shift_50_percent_v3_traffic".
+func (r *Report) WriteJUnit(filename string) error {
+	total, failed, skipped := r.Counts()
+	suite := junitTestSuite{
+		Name:       r.Name,
+		Tests:      total,
+		Failures:   failed,
+		Skipped:    skipped,
+		Time:       seconds(r.Duration),
+		Timestamp:  r.Started.Format(time.RFC3339),
+		Properties: r.Properties,
+		SystemOut:  strings.Join(r.Output, "\n"),
+	}
+
+	// subtests are reported with the package of their test case, results without one with an empty classname
+	var add func(res *Result, parentPackage string)
+	add = func(res *Result, parentPackage string) {
+		pkg := res.Package
+		if pkg == "" {
+			pkg = parentPackage
+		}
+		out := append(append([]string{}, res.Output...), res.Messages...)
+		if res.Artifacts != "" {
+			// attachment syntax understood by the Jenkins JUnit attachments plugin
+			out = append(out, fmt.Sprintf("[[ATTACHMENT|%s]]", res.Artifacts))
+		}
+		tc := junitTestCase{
+			ClassName: pkg,
+			Name:      res.Name,
+			Time:      seconds(res.Duration),
+			SystemOut: strings.Join(out, "\n"),
+		}
+		switch res.Status {
+		case StatusFail:
+			tc.Failure = &junitMessage{Message: firstLine(res.Messages, "Failed"), Text: strings.Join(res.Messages, "\n")}
+			tc.RerunFailures = junitReruns(res.Attempts)
+		case StatusSkip:
+			tc.Skipped = &junitMessage{Message: firstLine(res.Messages, "Skipped")}
+		case StatusFlaky:
+			tc.FlakyFailures = junitReruns(res.Attempts)
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+		for _, sub := range res.Subtests {
+			add(sub, pkg)
+		}
+	}
+	for _, res := range r.Tests {
+		add(res, "")
+	}
+
+	suites := junitTestSuites{
+		Tests:    total,
+		Failures: failed,
+		Skipped:  skipped,
+		Time:     seconds(r.Duration),
+		Suites:   []junitTestSuite{suite},
+	}
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append([]byte(xml.Header), data...), 0644)
+}
+
+// junitReruns converts the attempts of a test to rerun elements holding the messages and the output of
+// the attempt and its subtests.
+func junitReruns(attempts []*Result) []junitRerun {
+	var reruns []junitRerun
+	for _, attempt := range attempts {
+		var messages, out []string
+		walkResults([]*Result{attempt}, func(res *Result) {
+			messages = append(messages, res.Messages...)
+			out = append(out, res.Output...)
+		})
+		reruns = append(reruns, junitRerun{
+			Message:    firstLine(messages, "Failed"),
+			StackTrace: strings.Join(messages, "\n"),
+			SystemOut:  strings.Join(out, "\n"),
+		})
+	}
+	return reruns
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(lines []string, fallback string) string {
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			return l
+		}
+	}
+	return fallback
+}
+
+// packageOf returns the package part of a function name as returned by TestCase.FuncName.
+func packageOf(funcName string) string {
+	dir, file := path.Split(funcName)
+	if i := strings.Index(file, "."); i >= 0 {
+		file = file[:i]
+	}
+	return dir + file
+}
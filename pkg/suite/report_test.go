@@ -0,0 +1,221 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// readJUnit writes the report as JUnit XML and parses it back.
+func readJUnit(t *testing.T, r *Report) junitTestSuites {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "junit.xml")
+	if err := r.WriteJUnit(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("invalid JUnit XML: %v\n%s", err, data)
+	}
+	return suites
+}
+
+func TestWriteJUnitClassName(t *testing.T) {
+	r := &Report{Name: "maistra", Started: time.Now(), Tests: []*Result{
+		{Name: "T1", Package: "tasks/traffic", Status: StatusPass, Subtests: []*Result{
+			{Name: "T1/a", Status: StatusPass, Subtests: []*Result{
+				{Name: "T1/a/b", Status: StatusPass},
+			}},
+		}},
+		// e.g. a test that is not registered
+		{Name: "TestUnknown", Status: StatusPass, Subtests: []*Result{
+			{Name: "TestUnknown/a", Status: StatusPass},
+		}},
+		{Name: "T2", Package: "ossm", Status: StatusPass},
+	}}
+
+	want := map[string]string{
+		"T1":            "tasks/traffic",
+		"T1/a":          "tasks/traffic",
+		"T1/a/b":        "tasks/traffic",
+		"TestUnknown":   "",
+		"TestUnknown/a": "",
+		"T2":            "ossm",
+	}
+	suites := readJUnit(t, r)
+	cases := suites.Suites[0].TestCases
+	if len(cases) != len(want) {
+		t.Fatalf("got %d test cases, want %d", len(cases), len(want))
+	}
+	for _, tc := range cases {
+		if tc.ClassName != want[tc.Name] {
+			t.Errorf("%s: got classname %q, want %q", tc.Name, tc.ClassName, want[tc.Name])
+		}
+	}
+}
+
+func failed(name string, messages ...string) *Result {
+	return &Result{Name: name, Status: StatusFail, Messages: messages}
+}
+
+func TestAddRetry(t *testing.T) {
+	r := &Report{Name: "maistra", Started: time.Now(), Tests: []*Result{
+		failed("T1", "timed out"),
+		failed("T2", "503"),
+		{Name: "T3", Status: StatusPass},
+	}}
+	r.Tests[0].Leaked = []string{"bookinfo/deployment.apps/ratings-v1: still exists"}
+
+	r.AddRetry(&Report{Tests: []*Result{failed("T1", "connection refused"), failed("T2", "503 again")}, Output: []string{"retry 1"}})
+	r.AddRetry(&Report{Tests: []*Result{{Name: "T1", Status: StatusPass}, failed("T2", "503 once more")}, Output: []string{"retry 2"}})
+
+	want := []struct {
+		status   string
+		attempts []string
+	}{
+		{StatusFlaky, []string{"timed out", "connection refused"}},
+		{StatusFail, []string{"503", "503 again"}},
+		{StatusPass, nil},
+	}
+	for i, w := range want {
+		res := r.Tests[i]
+		if res.Status != w.status {
+			t.Errorf("%s: got status %q, want %q", res.Name, res.Status, w.status)
+		}
+		var attempts []string
+		for _, a := range res.Attempts {
+			attempts = append(attempts, a.Messages[0])
+			if len(a.Attempts) != 0 {
+				t.Errorf("%s: the attempts are nested", res.Name)
+			}
+		}
+		if !reflect.DeepEqual(attempts, w.attempts) {
+			t.Errorf("%s: got attempts %q, want %q", res.Name, attempts, w.attempts)
+		}
+	}
+
+	if flaky := r.Flaky(); len(flaky) != 1 || flaky[0].Name != "T1" {
+		t.Errorf("got flaky %v, want T1", flaky)
+	}
+	if got, want := r.Failed(), []string{"T2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got failed %q, want %q", got, want)
+	}
+	// the objects leaked by an attempt are still reported
+	if got, want := r.Leaked(), []string{"T1: bookinfo/deployment.apps/ratings-v1: still exists"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got leaked %q, want %q", got, want)
+	}
+	if got, want := r.Output, []string{"retry 1", "retry 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+}
+
+func TestReadTestLogs(t *testing.T) {
+	// T1 and T2 run in parallel, the log lines on stdout are attributed to whichever test printed last
+	r := parse(`
+=== RUN   T1
+=== PAUSE T1
+=== RUN   T2
+=== PAUSE T2
+=== CONT  T1
+=== CONT  T2
+=== RUN   T2/step
+time="10:00:01" level=info msg="Deploying bookinfo" test=T1
+time="10:00:02" level=info msg="Deploying httpbin" test=T2
+--- PASS: T2 (2.00s)
+    --- PASS: T2/step (1.00s)
+time="10:00:03" level=info msg="Checking productpage" test=T1
+--- PASS: T1 (3.00s)
+=== RUN   T3
+--- SKIP: T3 (0.00s)
+PASS
+`)
+	dir := t.TempDir()
+	logs := map[string]string{
+		"T1": "time=\"10:00:01\" level=info msg=\"Deploying bookinfo\" test=T1\ntime=\"10:00:03\" level=info msg=\"Checking productpage\" test=T1\n",
+		"T2": "time=\"10:00:02\" level=info msg=\"Deploying httpbin\" test=T2\n",
+	}
+	for id, content := range logs {
+		if err := ioutil.WriteFile(filepath.Join(dir, id+".log"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.ReadTestLogs(dir); err != nil {
+		t.Fatal(err)
+	}
+	results := byName(r)
+	want := map[string][]string{
+		"T1":      {`time="10:00:01" level=info msg="Deploying bookinfo" test=T1`, `time="10:00:03" level=info msg="Checking productpage" test=T1`},
+		"T2":      {`time="10:00:02" level=info msg="Deploying httpbin" test=T2`},
+		"T2/step": nil,
+		// a test case without a log file has no output
+		"T3": nil,
+	}
+	for name, output := range want {
+		if got := results[name].Output; !reflect.DeepEqual(got, output) {
+			t.Errorf("%s: got output %q, want %q", name, got, output)
+		}
+	}
+}
+
+func TestWriteJUnitReruns(t *testing.T) {
+	r := &Report{Name: "maistra", Started: time.Now(), Tests: []*Result{
+		failed("T1", "timed out"),
+		failed("T2", "", "503", "at line 42"),
+		{Name: "T3", Status: StatusPass},
+	}}
+	r.Tests[1].Subtests = []*Result{{Name: "T2/a", Status: StatusFail, Messages: []string{"subtest failed"}, Output: []string{"curl productpage"}}}
+	r.AddRetry(&Report{Tests: []*Result{{Name: "T1", Status: StatusPass}, failed("T2", "503 again")}})
+
+	suites := readJUnit(t, r)
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Skipped != 0 {
+		t.Errorf("got %d tests, %d failures and %d skipped, want 3, 1 and 0", suites.Tests, suites.Failures, suites.Skipped)
+	}
+	cases := map[string]junitTestCase{}
+	for _, tc := range suites.Suites[0].TestCases {
+		cases[tc.Name] = tc
+	}
+
+	flaky := cases["T1"]
+	if flaky.Failure != nil || len(flaky.RerunFailures) != 0 {
+		t.Errorf("T1: a flaky test is not a failure: %+v", flaky)
+	}
+	if len(flaky.FlakyFailures) != 1 || flaky.FlakyFailures[0].Message != "timed out" {
+		t.Errorf("T1: got flaky failures %+v", flaky.FlakyFailures)
+	}
+
+	fail := cases["T2"]
+	if fail.Failure == nil || fail.Failure.Message != "503 again" || len(fail.FlakyFailures) != 0 {
+		t.Errorf("T2: got failure %+v", fail.Failure)
+	}
+	// the rerun holds the first message line and the messages and the output of the subtests of the attempt
+	want := []junitRerun{{Message: "503", StackTrace: "\n503\nat line 42\nsubtest failed", SystemOut: "curl productpage"}}
+	if !reflect.DeepEqual(fail.RerunFailures, want) {
+		t.Errorf("T2: got rerun failures %+v, want %+v", fail.RerunFailures, want)
+	}
+
+	if pass := cases["T3"]; pass.Failure != nil || len(pass.FlakyFailures)+len(pass.RerunFailures) != 0 {
+		t.Errorf("T3: got %+v", pass)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// childEnv marks the test binary started by RunChild.
+const childEnv = "MAISTRA_TEST_RUNNER_CHILD"
+
+// IsChild returns true if the current process is the test binary started by RunChild.
+func IsChild() bool {
+	return os.Getenv(childEnv) == "true"
+}
+
+// RunChild runs the current test binary again in verbose mode with the given extra arguments and parses
+// its output into a report. The output is copied to out as it is produced. The returned exit code is the
+// exit code of the child process.
+//
+// The tests are run in a separate process because the testing package prints the results of a test
+// only after its parent returned and exits the process right after the last test.
+func RunChild(name string, out io.Writer, args ...string) (*Report, int, error) {
+	report := &Report{Name: name, Started: time.Now()}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+
+	args = append(append([]string{}, os.Args[1:]...), args...)
+	// keep the -test.v=test2json mode of `go test -json`, it is a superset of the verbose output
+	if f := flag.Lookup("test.v"); f == nil || f.Value.String() != "test2json" {
+		args = append(args, "-test.v=true")
+	}
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), childEnv+"=true")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = w
+	cmd.Stderr = w
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		return nil, 0, fmt.Errorf("failed to start %s: %v", os.Args[0], err)
+	}
+	// the child has its own copy now, closing ours lets the scanner see EOF when the child exits
+	w.Close()
+
+	parser := NewOutputParser(report)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fmt.Fprintln(out, line)
+		parser.ParseLine(line)
+	}
+	scanErr := scanner.Err()
+
+	code := 0
+	if err := cmd.Wait(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return nil, 0, err
+		}
+		code = exitErr.ExitCode()
+	}
+	parser.Finish()
+	report.Duration = time.Since(report.Started)
+
+	if scanErr != nil {
+		return report, code, fmt.Errorf("failed to read test output: %v", scanErr)
+	}
+	return report, code, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"sync"
+)
+
+// Resource is a part of the cluster shared by all test cases. A test case declares the resources it
+// modifies and the scheduler never runs two test cases that declared the same resource at the same time.
+type Resource string
+
+const (
+	// SMCP is the shared control plane, its configuration and mesh-wide policies. Every test case uses
+	// the control plane, so a test case declaring SMCP runs alone.
+	SMCP Resource = "smcp"
+	// IngressGateway is the istio-ingressgateway and the Gateway resources bound to its ports and hosts,
+	// e.g. the bookinfo gateway accepting any host on port 80.
+	IngressGateway Resource = "ingress-gateway"
+	// EgressGateway is the istio-egressgateway and the ServiceEntries of the external hosts used by the egress tasks.
+	EgressGateway Resource = "egress-gateway"
+	// Webhooks are the cluster-scoped admission webhooks of the operator, changed when control planes are added or removed.
+	Webhooks Resource = "webhooks"
+	// Nodes are the labels and taints of the cluster nodes.
+	Nodes Resource = "nodes"
+)
+
+// NamespaceResource returns the resource of a namespace shared by the test cases declaring it, see
+// util.IsFixedNamespace. It is added to the resources of these test cases, see TestCase.AllResources.
+func NamespaceResource(name string) Resource {
+	return Resource("namespace/" + name)
+}
+
+// Scheduler runs test cases concurrently unless they declared the same resource. Waiting test cases are
+// granted their resources in the order they called Acquire, except that a test case may overtake those it
+// does not conflict with, so that a test case is not starved by later ones declaring the same resource.
+// The testing package resumes parallel tests in no particular order, so this is not the order of the IDs.
+type Scheduler struct {
+	// Parallelism is the maximum number of test cases running at the same time.
+	Parallelism int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	running []*request
+	queue   []*request
+}
+
+type request struct {
+	resources map[Resource]bool
+}
+
+// NewScheduler returns a scheduler running at most parallelism test cases at the same time.
+func NewScheduler(parallelism int) *Scheduler {
+	s := &Scheduler{Parallelism: parallelism}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Acquire blocks until the resources can be used exclusively and returns a function releasing them.
+func (s *Scheduler) Acquire(resources []Resource) func() {
+	r := &request{resources: map[Resource]bool{}}
+	for _, res := range resources {
+		r.resources[res] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue = append(s.queue, r)
+	for !s.grantable(r) {
+		s.cond.Wait()
+	}
+	s.remove(&s.queue, r)
+	s.running = append(s.running, r)
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.remove(&s.running, r)
+		s.cond.Broadcast()
+	}
+}
+
+// grantable returns true if r conflicts neither with a running request nor with a request queued before it.
+func (s *Scheduler) grantable(r *request) bool {
+	if len(s.running) >= s.Parallelism {
+		return false
+	}
+	for _, other := range s.running {
+		if r.conflicts(other) {
+			return false
+		}
+	}
+	for _, other := range s.queue {
+		if other == r {
+			break
+		}
+		if r.conflicts(other) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scheduler) remove(list *[]*request, r *request) {
+	for i, other := range *list {
+		if other == r {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *request) conflicts(other *request) bool {
+	if r.resources[SMCP] || other.resources[SMCP] {
+		return true
+	}
+	for res := range r.resources {
+		if other.resources[res] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acquisition is a call of Acquire running in its own goroutine, like a test case waiting for its resources.
+type acquisition struct {
+	granted chan func()
+}
+
+// acquire calls Acquire in a new goroutine and returns once the request is queued or granted.
+func acquire(t *testing.T, s *Scheduler, resources ...Resource) *acquisition {
+	t.Helper()
+	s.mu.Lock()
+	before := len(s.queue) + len(s.running)
+	s.mu.Unlock()
+
+	a := &acquisition{granted: make(chan func(), 1)}
+	go func() { a.granted <- s.Acquire(resources) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.queue) + len(s.running)
+		s.mu.Unlock()
+		if n > before {
+			return a
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("request was neither queued nor granted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// mustRun returns the release function of a granted request.
+func (a *acquisition) mustRun(t *testing.T) func() {
+	t.Helper()
+	select {
+	case release := <-a.granted:
+		return release
+	case <-time.After(5 * time.Second):
+		t.Fatal("request was not granted")
+		return nil
+	}
+}
+
+// mustWait checks that the request is still waiting.
+func (a *acquisition) mustWait(t *testing.T) {
+	t.Helper()
+	select {
+	case release := <-a.granted:
+		a.granted <- release
+		t.Fatal("request was granted while it should be waiting")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSchedulerRunsSMCPAlone(t *testing.T) {
+	s := NewScheduler(4)
+
+	first := acquire(t, s).mustRun(t)
+	smcp := acquire(t, s, SMCP)
+	smcp.mustWait(t)
+	// a later request without resources does not overtake the waiting SMCP request
+	later := acquire(t, s, IngressGateway)
+	later.mustWait(t)
+
+	first()
+	releaseSMCP := smcp.mustRun(t)
+	later.mustWait(t)
+
+	releaseSMCP()
+	later.mustRun(t)()
+}
+
+func TestSchedulerOvertakesWithoutStarving(t *testing.T) {
+	s := NewScheduler(4)
+
+	ingress := acquire(t, s, IngressGateway).mustRun(t)
+	blocked := acquire(t, s, IngressGateway)
+	blocked.mustWait(t)
+
+	// requests not conflicting with the waiting one overtake it
+	egress := acquire(t, s, EgressGateway).mustRun(t)
+	free := acquire(t, s).mustRun(t)
+	// requests conflicting with the waiting one queue behind it instead of starving it
+	next := acquire(t, s, IngressGateway)
+	next.mustWait(t)
+
+	ingress()
+	releaseBlocked := blocked.mustRun(t)
+	next.mustWait(t)
+
+	releaseBlocked()
+	next.mustRun(t)()
+	egress()
+	free()
+}
+
+func TestSchedulerSerializesFixedNamespaces(t *testing.T) {
+	s := NewScheduler(4)
+	first := TestCase{Namespaces: []string{"bookinfo", "mesh-external"}, Resources: []Resource{EgressGateway}}
+	second := TestCase{Namespaces: []string{"bookinfo", "mesh-external"}, Resources: []Resource{IngressGateway}}
+
+	release := acquire(t, s, first.AllResources()...).mustRun(t)
+	waiting := acquire(t, s, second.AllResources()...)
+	waiting.mustWait(t)
+
+	release()
+	waiting.mustRun(t)()
+}
+
+func TestSchedulerParallelism(t *testing.T) {
+	s := NewScheduler(2)
+
+	first := acquire(t, s).mustRun(t)
+	second := acquire(t, s).mustRun(t)
+	third := acquire(t, s)
+	third.mustWait(t)
+
+	second()
+	third.mustRun(t)()
+	first()
+}
+
+func TestSchedulerConcurrently(t *testing.T) {
+	const parallelism = 3
+	s := NewScheduler(parallelism)
+	resources := []Resource{SMCP, IngressGateway, EgressGateway, Webhooks, Nodes}
+
+	var mu sync.Mutex
+	running := 0
+	inUse := map[Resource]bool{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		var declared []Resource
+		for _, res := range resources {
+			if rand.Intn(4) == 0 {
+				declared = append(declared, res)
+			}
+		}
+		wg.Add(1)
+		go func(declared []Resource) {
+			defer wg.Done()
+			release := s.Acquire(declared)
+
+			mu.Lock()
+			if inUse[SMCP] || running >= parallelism {
+				t.Errorf("%v started next to a SMCP test or above the parallelism", declared)
+			}
+			for _, res := range declared {
+				if inUse[res] {
+					t.Errorf("%s used by two tests", res)
+				}
+				inUse[res] = true
+				if res == SMCP && running > 0 {
+					t.Errorf("SMCP test started next to %d other tests", running)
+				}
+			}
+			running++
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			running--
+			for _, res := range declared {
+				delete(inUse, res)
+			}
+			mu.Unlock()
+			release()
+		}(declared)
+	}
+	wg.Wait()
+}
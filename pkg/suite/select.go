@@ -0,0 +1,197 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+const pkgPrefix = "github.com/maistra/maistra-test-tool/pkg/"
+
+// FuncName returns the package qualified name of the test function, e.g. "tasks/traffic/egress.TestEgressGateways".
+func (tc TestCase) FuncName() string {
+	if tc.Test == nil {
+		return ""
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(tc.Test).Pointer()).Name()
+	return strings.TrimPrefix(name, pkgPrefix)
+}
+
+// Names returns all the names a test case can be selected by: the ID, the title and the function name.
+func (tc TestCase) Names() []string {
+	return []string{tc.ID, tc.Title, tc.FuncName()}
+}
+
+// Filter narrows down a selection of test cases by name patterns.
+//
+// A pattern is either a shell glob ("T1?", "*egress*") that has to match a whole name, or a regular
+// expression enclosed in slashes ("/^T(6|28)$/") that has to match a part of a name. Matching is case
+// insensitive and is done against the ID, the title and the function name of a test case.
+type Filter struct {
+	// Include keeps only the test cases matching at least one pattern. An empty list keeps all test cases.
+	Include []string
+	// Exclude drops the test cases matching any pattern.
+	Exclude []string
+}
+
+// ParsePatterns splits a comma separated list of patterns.
+func ParsePatterns(s string) []string {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// Apply returns the test cases accepted by the filter, keeping their order.
+func (f Filter) Apply(tcs []TestCase) ([]TestCase, error) {
+	include, err := compilePatterns(f.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compilePatterns(f.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []TestCase
+	for _, tc := range tcs {
+		if len(include) > 0 && !matchAny(include, tc) {
+			continue
+		}
+		if matchAny(exclude, tc) {
+			continue
+		}
+		selected = append(selected, tc)
+	}
+	return selected, nil
+}
+
+type matcher func(name string) bool
+
+func compilePatterns(patterns []string) ([]matcher, error) {
+	matchers := make([]matcher, 0, len(patterns))
+	for _, p := range patterns {
+		m, err := compilePattern(p)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return matchers, nil
+}
+
+func compilePattern(pattern string) (matcher, error) {
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile("(?i)" + pattern[1:len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid test pattern %q: %v", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+
+	re, err := regexp.Compile("(?i)^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid test pattern %q: %v", pattern, err)
+	}
+	return re.MatchString, nil
+}
+
+// globToRegexp translates the shell glob wildcards '*' and '?' and leaves '[...]' classes as they are.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	inClass := false
+	for _, r := range glob {
+		switch {
+		case inClass:
+			b.WriteRune(r)
+			inClass = r != ']'
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		case r == '[':
+			b.WriteRune(r)
+			inClass = true
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func matchAny(matchers []matcher, tc TestCase) bool {
+	for _, m := range matchers {
+		for _, name := range tc.Names() {
+			if m(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MatchString implements the matchString function of testing.Main for the -run and -skip flags.
+// A pattern matches a name if it is equal to it or if it is a regular expression matching the whole name,
+// so `-run T1` runs T1 only while `-run 'T1|T2'` and `-run 'T1.*'` work as expected.
+func MatchString(pattern, name string) (bool, error) {
+	if pattern == name {
+		return true, nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(name), nil
+}
+
+// RunPattern returns a -run pattern matching exactly the given test names.
+func RunPattern(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	return strings.Join(quoted, "|")
+}
+
+// PrintList writes a table of test cases with their groups to w.
+func PrintList(w io.Writer, tcs []TestCase) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tGROUPS\tNAMESPACES\tRESOURCES\tTITLE\tFUNCTION")
+	for _, tc := range tcs {
+		resources := []string{}
+		for _, res := range tc.AllResources() {
+			resources = append(resources, string(res))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			tc.ID, dashIfEmpty(tc.Tags), dashIfEmpty(tc.Namespaces), dashIfEmpty(resources), tc.Title, tc.FuncName())
+	}
+	return tw.Flush()
+}
+
+func dashIfEmpty(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"testing"
+)
+
+func egressGatewaysTest(*testing.T) {}
+
+func TestFuncName(t *testing.T) {
+	tc := TestCase{Test: egressGatewaysTest}
+	if got, want := tc.FuncName(), "suite.egressGatewaysTest"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := (TestCase{}).FuncName(); got != "" {
+		t.Errorf("got %q for a test case without function", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tcs := []TestCase{
+		{ID: "T1", Title: "Request Routing"},
+		{ID: "T12", Title: "Egress Gateways", Test: egressGatewaysTest},
+		{ID: "T28", Title: "Egress TLS Origination"},
+		{ID: "A1", Title: "Authorization for HTTP traffic"},
+	}
+	cases := []struct {
+		name    string
+		filter  Filter
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty filter", filter: Filter{}, want: []string{"T1", "T12", "T28", "A1"}},
+		{name: "glob matches whole ID", filter: Filter{Include: []string{"T1"}}, want: []string{"T1"}},
+		{name: "glob question mark", filter: Filter{Include: []string{"T??"}}, want: []string{"T12", "T28"}},
+		{name: "glob star on title", filter: Filter{Include: []string{"egress*"}}, want: []string{"T12", "T28"}},
+		{name: "glob class", filter: Filter{Include: []string{"T[12]*"}}, want: []string{"T1", "T12", "T28"}},
+		{name: "glob is case insensitive", filter: Filter{Include: []string{"t1", "REQUEST ROUTING"}}, want: []string{"T1"}},
+		{name: "glob quotes regexp characters", filter: Filter{Include: []string{"T1.*"}}, want: nil},
+		{name: "glob on function name", filter: Filter{Include: []string{"suite.egressGateways*"}}, want: []string{"T12"}},
+		{name: "regexp matches part of a name", filter: Filter{Include: []string{"/tls/"}}, want: []string{"T28"}},
+		{name: "regexp anchors", filter: Filter{Include: []string{"/^t(1|28)$/"}}, want: []string{"T1", "T28"}},
+		{name: "exclude wins over include", filter: Filter{Include: []string{"T*"}, Exclude: []string{"/egress/"}}, want: []string{"T1"}},
+		{name: "exclude only", filter: Filter{Exclude: []string{"A*"}}, want: []string{"T1", "T12", "T28"}},
+		{name: "slashes alone are a glob", filter: Filter{Include: []string{"//"}}, want: nil},
+		{name: "invalid include regexp", filter: Filter{Include: []string{"/(/"}}, wantErr: true},
+		{name: "invalid exclude regexp", filter: Filter{Exclude: []string{"/[a/"}}, wantErr: true},
+		{name: "invalid glob class", filter: Filter{Include: []string{"T[1"}}, wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			selected, err := c.filter.Apply(tcs)
+			if c.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, tc := range selected {
+				got = append(got, tc.ID)
+			}
+			if !equalStrings(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParsePatterns(t *testing.T) {
+	got := ParsePatterns(" T1, ,/^A/ ,egress*,")
+	want := []string{"T1", "/^A/", "egress*"}
+	if !equalStrings(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestMatchString(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+		wantErr       bool
+	}{
+		{pattern: "T1", name: "T1", want: true},
+		{pattern: "T1", name: "T12", want: false},
+		{pattern: "T1|T2", name: "T2", want: true},
+		{pattern: "T1|T2", name: "T12", want: false},
+		{pattern: "T1.*", name: "T12", want: true},
+		{pattern: "t1", name: "T1", want: false},
+		// names with regexp characters match themselves
+		{pattern: "T1(a)", name: "T1(a)", want: true},
+		{pattern: "(", name: "T1", wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := MatchString(c.pattern, c.name)
+		if (err != nil) != c.wantErr {
+			t.Errorf("MatchString(%q, %q): unexpected error %v", c.pattern, c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("MatchString(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestRunPattern(t *testing.T) {
+	pattern := RunPattern([]string{"T1", "T2.1"})
+	for name, want := range map[string]bool{"T1": true, "T2.1": true, "T2x1": false, "T12": false} {
+		if got, _ := MatchString(pattern, name); got != want {
+			t.Errorf("%q matches %q: %v, want %v", pattern, name, got, want)
+		}
+	}
+}
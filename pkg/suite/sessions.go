@@ -0,0 +1,55 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// session starts recording or replaying the commands of a test case, see RecordSessions and ReplaySessions.
+var session func(t *testing.T, id string)
+
+// SessionFile returns the fixture file of a test case in a session directory.
+func SessionFile(dir, id string) string {
+	return filepath.Join(dir, id+".json")
+}
+
+// RecordSessions makes the tests returned by InternalTests save their commands to a fixture file per
+// test case in dir, see util.RecordSession. The test cases must run one after another.
+func RecordSessions(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	session = func(t *testing.T, id string) {
+		util.RecordSession(t, SessionFile(dir, id))
+	}
+	return nil
+}
+
+// ReplaySessions makes the tests returned by InternalTests replay their fixture files in dir instead of
+// running commands, see util.ReplaySession. Test cases without a fixture file are skipped.
+func ReplaySessions(dir string) {
+	session = func(t *testing.T, id string) {
+		filename := SessionFile(dir, id)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			t.Skipf("No recording of %s in %s", id, dir)
+		}
+		util.ReplaySession(t, filename)
+	}
+}
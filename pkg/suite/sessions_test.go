@@ -0,0 +1,83 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package suite
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// productpageTest queries the cluster with a shell command and with the KubeClient and sends a request to the
+// gateway, like the test cases do.
+func productpageTest(t *testing.T) {
+	server, err := util.ShellSilent(`oc whoami --show-server`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(server) != "https://api.ci.example.com:6443" {
+		t.Errorf("unexpected server %q", server)
+	}
+	var pod util.Pod
+	if err := util.GetKubeClient().Get(context.Background(), "pods", "bookinfo", "productpage-v1", &pod); err != nil {
+		t.Fatal(err)
+	}
+	if pod.Status.PodIP != "10.128.2.17" {
+		t.Errorf("unexpected pod IP %q", pod.Status.PodIP)
+	}
+	host := util.TestEnv(t).GatewayHost
+	if host != "istio-ingressgateway-istio-system.apps.ci.example.com" {
+		t.Errorf("unexpected gateway host %q", host)
+	}
+	// the gateway does not exist, the replay must not send the request
+	resp, _, err := util.GetHTTPResponse("http://"+host+"/productpage", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer util.CloseResponseBody(resp)
+	if resp.StatusCode != 200 {
+		t.Errorf("unexpected status %d", resp.StatusCode)
+	}
+}
+
+// runTestCase runs a registered test case like the testing package runs the tests returned by InternalTests
+// and returns its *testing.T once it completed.
+func runTestCase(t *testing.T, tc TestCase) *testing.T {
+	var tt *testing.T
+	t.Run(tc.ID, func(t *testing.T) {
+		tt = t
+		InternalTests([]TestCase{tc})[0].F(t)
+	})
+	return tt
+}
+
+func TestReplaySessions(t *testing.T) {
+	defer func() { session = nil }()
+	// commands outside of the replayed test cases fail, like in the test runner
+	defer util.SetExecutor(util.SetExecutor(util.NewFakeExecutor()))
+
+	ReplaySessions("testdata/replay")
+
+	tc := runTestCase(t, TestCase{ID: "T1", Title: "Replay", Test: productpageTest})
+	if tc.Failed() || tc.Skipped() {
+		t.Errorf("the replay of T1 did not pass")
+	}
+	tc = runTestCase(t, TestCase{ID: "T2", Title: "No recording", Test: productpageTest})
+	if !tc.Skipped() {
+		t.Errorf("T2 without a recording was not skipped")
+	}
+}
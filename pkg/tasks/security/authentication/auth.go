@@ -21,58 +21,58 @@ import (
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T18",
+		Title:      "Authentication policy",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"foo", "bar", "legacy"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestAuthPolicy,
+	})
+}
+
 func cleanupAuthPolicy() {
+	meshNamespace := util.GetConfig().MeshNamespace
 	util.Log.Info("Cleanup")
-	util.KubeDeleteContents(meshNamespace, util.RunTemplate(RequireTokenPathPolicyTemplate, smcp))
-	util.KubeDeleteContents(meshNamespace, util.RunTemplate(RequireTokenPolicyTemplate, smcp))
-	util.KubeDeleteContents(meshNamespace, util.RunTemplate(JWTAuthPolicyTemplate, smcp))
-	util.KubeDeleteContents("foo", HttpbinGateway)
-	util.KubeDeleteContents("foo", OverwritePolicy)
-	util.KubeDeleteContents("bar", PortPolicy)
-	util.KubeDeleteContents("bar", WorkloadPolicyStrict)
-	util.KubeDeleteContents("foo", NamespacePolicyStrict)
-	util.KubeDeleteContents(meshNamespace, util.RunTemplate(PeerAuthPolicyStrictTemplate, smcp))
-
-	sleep := examples.Sleep{"foo"}
-	httpbin := examples.Httpbin{"foo"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	sleep = examples.Sleep{"bar"}
-	httpbin = examples.Httpbin{"bar"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	sleep = examples.Sleep{"legacy"}
-	httpbin = examples.Httpbin{"legacy"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+	util.KubeDeleteTemplate(meshNamespace, RequireTokenPathPolicyTemplate, util.GetConfig().ControlPlane())
+	util.KubeDeleteTemplate(meshNamespace, RequireTokenPolicyTemplate, util.GetConfig().ControlPlane())
+	util.KubeDeleteTemplate(meshNamespace, JWTAuthPolicyTemplate, util.GetConfig().ControlPlane())
+	util.KubeDeleteTemplate(meshNamespace, PeerAuthPolicyStrictTemplate, util.GetConfig().ControlPlane())
 }
 
 func TestAuthPolicy(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	gatewayHTTP := util.TestEnv(t).GatewayHost
+	foo := util.Namespace(t, "foo")
+	bar := util.Namespace(t, "bar")
+	legacy := util.Namespace(t, "legacy")
 	defer cleanupAuthPolicy()
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Test Authentication Policy")
-	httpbin := examples.Httpbin{"foo"}
+	httpbin := examples.Httpbin{Namespace: foo}
 	httpbin.Install()
-	httpbin = examples.Httpbin{"bar"}
+	httpbin = examples.Httpbin{Namespace: bar}
 	httpbin.Install()
-	httpbin = examples.Httpbin{"legacy"}
+	httpbin = examples.Httpbin{Namespace: legacy}
 	httpbin.InstallLegacy()
 
-	sleep := examples.Sleep{"foo"}
+	sleep := examples.Sleep{Namespace: foo}
 	sleep.Install()
-	sleep = examples.Sleep{"bar"}
+	sleep = examples.Sleep{Namespace: bar}
 	sleep.Install()
-	sleep = examples.Sleep{"legacy"}
+	sleep = examples.Sleep{Namespace: legacy}
 	sleep.InstallLegacy()
 
 	util.Log.Info("Verify setup")
-	for _, from := range []string{"foo", "bar", "legacy"} {
-		for _, to := range []string{"foo", "bar"} {
+	for _, from := range []string{foo, bar, legacy} {
+		for _, to := range []string{foo, bar} {
 			sleepPod, err := util.GetPodName(from, "app=sleep")
 			util.Inspect(err, "Failed to get sleep pod name", "", t)
 			cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -s -o /dev/null -w "sleep.%s to httpbin.%s: %%{http_code}"`,
@@ -96,13 +96,13 @@ func TestAuthPolicy(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Auto mutual TLS")
-		out, _ := util.Shell(`kubectl exec $(kubectl get pod -l app=sleep -n foo -o jsonpath={.items..metadata.name}) -c sleep -n foo -- curl http://httpbin.foo:8000/headers -s | grep X-Forwarded-Client-Cert | sed 's/Hash=[a-z0-9]*;/Hash=<redacted>;/'`)
+		out, _ := util.Shell(`kubectl exec $(kubectl get pod -l app=sleep -n %s -o jsonpath={.items..metadata.name}) -c sleep -n %s -- curl http://httpbin.%s:8000/headers -s | grep X-Forwarded-Client-Cert | sed 's/Hash=[a-z0-9]*;/Hash=<redacted>;/'`, foo, foo, foo)
 		if !strings.Contains(out, "X-Forwarded-Client-Cert") {
 			t.Errorf("Auto mTLS failed to get X-Forwarded-Client-Cert")
 			util.Log.Info("Auto mTLS failed to get X-Forwarded-Client-Cert")
 		}
 
-		out, _ = util.ShellMuteOutputError(`kubectl exec $(kubectl get pod -l app=sleep -n foo -o jsonpath={.items..metadata.name}) -c sleep -n foo -- curl http://httpbin.legacy:8000/headers -s | grep X-Forwarded-Client-Cert`)
+		out, _ = util.ShellMuteOutputError(`kubectl exec $(kubectl get pod -l app=sleep -n %s -o jsonpath={.items..metadata.name}) -c sleep -n %s -- curl http://httpbin.%s:8000/headers -s | grep X-Forwarded-Client-Cert`, foo, foo, legacy)
 		if strings.Contains(out, "X-Forwarded-Client-Cert") {
 			t.Errorf("Auto mTLS legacy should not get X-Forwarded-Client-Cert")
 			util.Log.Info("Auto mTLS legacy should not to get X-Forwarded-Client-Cert")
@@ -113,12 +113,12 @@ func TestAuthPolicy(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Globally enabling Istio mutual TLS in STRICT mode")
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(PeerAuthPolicyStrictTemplate, smcp))
-		util.Log.Info("Waiting for rules to propagate. Sleep 30 seconds...")
-		time.Sleep(time.Duration(30) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies(foo, bar)...)
+		util.KubeApplyTemplate(meshNamespace, PeerAuthPolicyStrictTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync, wait.Resource{Kind: "PeerAuthentication", Namespace: meshNamespace, Name: "default"})
 
-		from := "legacy"
-		ns := []string{"foo", "bar"}
+		from := legacy
+		ns := []string{foo, bar}
 		for _, to := range ns {
 			sleepPod, err := util.GetPodName(from, "app=sleep")
 			util.Inspect(err, "Failed to get sleep pod name", "", t)
@@ -132,26 +132,28 @@ func TestAuthPolicy(t *testing.T) {
 				util.Log.Infof("Response 000 as expected: %s", msg)
 			}
 		}
-		util.KubeDeleteContents(meshNamespace, util.RunTemplate(PeerAuthPolicyStrictTemplate, smcp))
-		time.Sleep(time.Duration(30) * time.Second)
+		sync = wait.BeforeConfigChange(httpbinProxies(foo, bar)...)
+		util.KubeDeleteTemplate(meshNamespace, PeerAuthPolicyStrictTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync, wait.Resource{Kind: "PeerAuthentication", Namespace: meshNamespace, Name: "default"})
 	})
 
 	t.Run("Security_authentication_namespace_policy_mtls", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Enable mutual TLS per namespace")
-		util.KubeApplyContents("foo", NamespacePolicyStrict)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies(foo)...)
+		util.KubeApplyTemplate(foo, NamespacePolicyStrictTemplate, AppNamespace{Namespace: foo})
+		waitForConfig(t, sync, wait.Resource{Kind: "PeerAuthentication", Namespace: foo, Name: "default"})
 
-		for _, from := range []string{"foo", "bar", "legacy"} {
-			for _, to := range []string{"foo", "bar"} {
+		for _, from := range []string{foo, bar, legacy} {
+			for _, to := range []string{foo, bar} {
 				sleepPod, err := util.GetPodName(from, "app=sleep")
 				util.Inspect(err, "Failed to get sleep pod name", "", t)
 				cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -s -o /dev/null -w "sleep.%s to httpbin.%s: %%{http_code}"`,
 					to, from, to)
 				msg, err := util.PodExec(from, sleepPod, "sleep", cmd, true)
 
-				if from == "legacy" && to == "foo" {
+				if from == legacy && to == foo {
 					if err != nil {
 						util.Log.Infof("Expected fail from sleep.legacy to httpbin.foo: %v", err)
 					} else {
@@ -168,21 +170,24 @@ func TestAuthPolicy(t *testing.T) {
 				}
 			}
 		}
-		util.KubeDeleteContents("foo", NamespacePolicyStrict)
+		util.KubeDeleteTemplate(foo, NamespacePolicyStrictTemplate, AppNamespace{Namespace: foo})
 	})
 
 	t.Run("Security_authentication_workload_policy_mtls", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Enable mutual TLS per workload")
-		util.KubeApplyContents("bar", WorkloadPolicyStrict)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies(bar)...)
+		util.KubeApplyTemplate(bar, WorkloadPolicyStrictTemplate, AppNamespace{Namespace: bar})
+		waitForConfig(t, sync,
+			wait.Resource{Kind: "PeerAuthentication", Namespace: bar, Name: "httpbin"},
+			wait.Resource{Kind: "DestinationRule", Namespace: bar, Name: "httpbin"})
 
-		sleepPod, err := util.GetPodName("legacy", "app=sleep")
+		sleepPod, err := util.GetPodName(legacy, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
 		cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -s -o /dev/null -w "sleep.%s to httpbin.%s: %%{http_code}"`,
-			"bar", "legacy", "bar")
-		msg, err := util.PodExec("legacy", sleepPod, "sleep", cmd, true)
+			bar, legacy, bar)
+		msg, err := util.PodExec(legacy, sleepPod, "sleep", cmd, true)
 		if err != nil {
 			util.Log.Infof("Expected fail from sleep.legacy to httpbin.bar: %v", err)
 		} else {
@@ -191,36 +196,42 @@ func TestAuthPolicy(t *testing.T) {
 		}
 
 		util.Log.Info("Refine mutual TLS per port")
-		util.KubeApplyContents("bar", PortPolicy)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync = wait.BeforeConfigChange(httpbinProxies(bar)...)
+		util.KubeApplyTemplate(bar, PortPolicyTemplate, AppNamespace{Namespace: bar})
+		waitForConfig(t, sync,
+			wait.Resource{Kind: "PeerAuthentication", Namespace: bar, Name: "httpbin"},
+			wait.Resource{Kind: "DestinationRule", Namespace: bar, Name: "httpbin"})
 
-		sleepPod, err = util.GetPodName("legacy", "app=sleep")
+		sleepPod, err = util.GetPodName(legacy, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
 		cmd = fmt.Sprintf(`curl http://httpbin.%s:8000/ip -s -o /dev/null -w "sleep.%s to httpbin.%s: %%{http_code}"`,
-			"bar", "legacy", "bar")
-		msg, err = util.PodExec("legacy", sleepPod, "sleep", cmd, true)
+			bar, legacy, bar)
+		msg, err = util.PodExec(legacy, sleepPod, "sleep", cmd, true)
 		if strings.Contains(msg, "200") {
 			util.Log.Infof("Expected 200 from sleep.legacy to httpbin.bar: %s", msg)
 		} else {
 			t.Errorf("Expected 200 from sleep.legacy to httpbin.bar; Got unexpected response: %s", msg)
 			util.Log.Errorf("Expected 200 from sleep.legacy to httpbin.bar; Got unexpected response: %s", msg)
 		}
-		util.KubeDeleteContents("bar", PortPolicy)
-		util.KubeDeleteContents("bar", WorkloadPolicyStrict)
+		util.KubeDeleteTemplate(bar, PortPolicyTemplate, AppNamespace{Namespace: bar})
+		util.KubeDeleteTemplate(bar, WorkloadPolicyStrictTemplate, AppNamespace{Namespace: bar})
 	})
 
 	t.Run("Security_authentication_policy_precedence_mtls", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Overwrite foo namespace policy by a workload policy")
-		util.KubeApplyContents("foo", OverwritePolicy)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies(foo)...)
+		util.KubeApplyTemplate(foo, OverwritePolicyTemplate, AppNamespace{Namespace: foo})
+		waitForConfig(t, sync,
+			wait.Resource{Kind: "PeerAuthentication", Namespace: foo, Name: "overwrite-example"},
+			wait.Resource{Kind: "DestinationRule", Namespace: foo, Name: "overwrite-example"})
 
-		sleepPod, err := util.GetPodName("legacy", "app=sleep")
+		sleepPod, err := util.GetPodName(legacy, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
 		cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -s -o /dev/null -w "sleep.%s to httpbin.%s: %%{http_code}"`,
-			"foo", "legacy", "foo")
-		msg, err := util.PodExec("legacy", sleepPod, "sleep", cmd, true)
+			foo, legacy, foo)
+		msg, err := util.PodExec(legacy, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "200") {
 			t.Errorf("Expected: 200; Got unexpected response code: %s", msg)
@@ -228,7 +239,7 @@ func TestAuthPolicy(t *testing.T) {
 		} else {
 			util.Log.Infof("Success. Get expected response: %s", msg)
 		}
-		util.KubeDeleteContents("foo", OverwritePolicy)
+		util.KubeDeleteTemplate(foo, OverwritePolicyTemplate, AppNamespace{Namespace: foo})
 	})
 
 	t.Run("Security_authentication_end-user_JWT", func(t *testing.T) {
@@ -236,8 +247,8 @@ func TestAuthPolicy(t *testing.T) {
 
 		util.Log.Info("End-user authentication")
 		util.Log.Info("Apply httpbin gateway")
-		util.KubeApplyContents("foo", HttpbinGateway)
-		time.Sleep(time.Duration(20) * time.Second)
+		util.KubeApplyTemplate(foo, HttpbinGatewayTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, wait.HTTPStatus(fmt.Sprintf("http://%s/headers", gatewayHTTP), "", 200))
 
 		msg, err := util.Shell(`curl %s/headers -s -o /dev/null -w "%%{http_code}\n"`, gatewayHTTP)
 		util.Inspect(err, "Failed to get httpbin header response", "", t)
@@ -249,8 +260,9 @@ func TestAuthPolicy(t *testing.T) {
 		}
 
 		util.Log.Info("Apply a JWT policy")
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(JWTAuthPolicyTemplate, smcp))
-		time.Sleep(time.Duration(20) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: meshNamespace, Selector: "istio=ingressgateway"})
+		util.KubeApplyTemplate(meshNamespace, JWTAuthPolicyTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync, wait.Resource{Kind: "RequestAuthentication", Namespace: meshNamespace, Name: "jwt-example"})
 
 		util.Log.Info("Request without token returns 200. Request with an invalid token returns 401")
 		msg, err = util.Shell(`curl %s/headers -s -o /dev/null -w "%%{http_code}\n"`, gatewayHTTP)
@@ -293,8 +305,8 @@ func TestAuthPolicy(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Require a valid token")
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(RequireTokenPolicyTemplate, smcp))
-		time.Sleep(time.Duration(20) * time.Second)
+		util.KubeApplyTemplate(meshNamespace, RequireTokenPolicyTemplate, util.GetConfig().ControlPlane())
+		waitForPolicy(t, wait.HTTPStatus(fmt.Sprintf("http://%s/headers", gatewayHTTP), "", 403))
 
 		msg, err := util.Shell(`curl %s/headers -s -o /dev/null -w "%%{http_code}\n"`, gatewayHTTP)
 		util.Inspect(err, "Failed to get httpbin header response", "", t)
@@ -306,8 +318,8 @@ func TestAuthPolicy(t *testing.T) {
 		}
 
 		util.Log.Info("Require valid tokens per-path")
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(RequireTokenPathPolicyTemplate, smcp))
-		time.Sleep(time.Duration(20) * time.Second)
+		util.KubeApplyTemplate(meshNamespace, RequireTokenPathPolicyTemplate, util.GetConfig().ControlPlane())
+		waitForPolicy(t, wait.HTTPStatus(fmt.Sprintf("http://%s/ip", gatewayHTTP), "", 200))
 
 		msg, err = util.Shell(`curl %s/headers -s -o /dev/null -w "%%{http_code}\n"`, gatewayHTTP)
 		util.Inspect(err, "Failed to get httpbin header response", "", t)
@@ -328,3 +340,29 @@ func TestAuthPolicy(t *testing.T) {
 		}
 	})
 }
+
+// httpbinProxies selects the sidecars of httpbin in the namespaces, which the authentication policies of the
+// tests apply to.
+func httpbinProxies(namespaces ...string) []wait.Proxies {
+	proxies := make([]wait.Proxies, 0, len(namespaces))
+	for _, ns := range namespaces {
+		proxies = append(proxies, wait.Proxies{Namespace: ns, Selector: "app=httpbin"})
+	}
+	return proxies
+}
+
+// waitForConfig waits until the proxies tracked by sync received the applied resources, or dropped the deleted
+// ones, for the changes the requests of the sleep pods cannot observe, e.g. because they fail either way.
+// The test fails if they do not arrive.
+func waitForConfig(t *testing.T, sync *wait.ConfigSync, resources ...wait.Resource) {
+	if err := wait.UpTo(2*time.Minute, sync.Propagated(resources...)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForPolicy waits until an applied policy takes effect, as observed by a request it changes the result of.
+func waitForPolicy(t *testing.T, cond wait.Condition) {
+	if err := wait.UpTo(2*time.Minute, cond); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -18,48 +18,51 @@ import (
 	"fmt"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T19",
+		Title:      "Mutual TLS migration",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"foo", "bar", "legacy"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestMigration,
+	})
+}
+
 func cleanupMigration() {
 	util.Log.Info("Cleanup")
-	util.KubeDeleteContents(meshNamespace, util.RunTemplate(MeshPolicyStrictTemplate, smcp))
-	util.KubeDeleteContents("foo", NamespacePolicyStrict)
-	sleep := examples.Sleep{"foo"}
-	httpbin := examples.Httpbin{"foo"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	sleep = examples.Sleep{"bar"}
-	httpbin = examples.Httpbin{"bar"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	sleep = examples.Sleep{"legacy"}
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+	util.KubeDeleteTemplate(util.GetConfig().MeshNamespace, MeshPolicyStrictTemplate, util.GetConfig().ControlPlane())
 }
 
 func TestMigration(t *testing.T) {
+	foo := util.Namespace(t, "foo")
+	bar := util.Namespace(t, "bar")
+	legacy := util.Namespace(t, "legacy")
 	defer cleanupMigration()
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Mutual TLS Migration")
-	httpbin := examples.Httpbin{"foo"}
+	httpbin := examples.Httpbin{Namespace: foo}
 	httpbin.Install()
-	httpbin = examples.Httpbin{"bar"}
+	httpbin = examples.Httpbin{Namespace: bar}
 	httpbin.Install()
-	sleep := examples.Sleep{"foo"}
+	sleep := examples.Sleep{Namespace: foo}
 	sleep.Install()
-	sleep = examples.Sleep{"bar"}
+	sleep = examples.Sleep{Namespace: bar}
 	sleep.Install()
-	sleep = examples.Sleep{"legacy"}
+	sleep = examples.Sleep{Namespace: legacy}
 	sleep.InstallLegacy()
 
 	util.Log.Info("Verify setup")
-	for _, from := range []string{"foo", "bar", "legacy"} {
-		for _, to := range []string{"foo", "bar"} {
+	for _, from := range []string{foo, bar, legacy} {
+		for _, to := range []string{foo, bar} {
 			sleepPod, err := util.GetPodName(from, "app=sleep")
 			util.Inspect(err, "Failed to get sleep pod name", "", t)
 			cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -s -o /dev/null -w "sleep.%s to httpbin.%s: %%{http_code}"`,
@@ -78,18 +81,19 @@ func TestMigration(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Lock down to mutual TLS by namespace")
-		util.KubeApplyContents("foo", NamespacePolicyStrict)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies(foo)...)
+		util.KubeApplyTemplate(foo, NamespacePolicyStrictTemplate, AppNamespace{Namespace: foo})
+		waitForConfig(t, sync, wait.Resource{Kind: "PeerAuthentication", Namespace: foo, Name: "default"})
 
-		for _, from := range []string{"legacy"} {
-			for _, to := range []string{"foo", "bar"} {
+		for _, from := range []string{legacy} {
+			for _, to := range []string{foo, bar} {
 				sleepPod, err := util.GetPodName(from, "app=sleep")
 				util.Inspect(err, "Failed to get sleep pod name", "", t)
 				cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -s -o /dev/null -w "sleep.%s to httpbin.%s: %%{http_code}"`,
 					to, from, to)
 				msg, err := util.PodExec(from, sleepPod, "sleep", cmd, true)
 
-				if from == "legacy" && to == "foo" {
+				if from == legacy && to == foo {
 					if err != nil {
 						util.Log.Infof("Expected fail from sleep.legacy to httpbin.foo: %v", err)
 					} else {
@@ -112,16 +116,17 @@ func TestMigration(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Lock down to mutual TLS for the entire mesh")
-		util.KubeApplyContents(meshNamespace, util.RunTemplate(MeshPolicyStrictTemplate, smcp))
-		time.Sleep(time.Duration(30) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies(foo, bar)...)
+		util.KubeApplyTemplate(util.GetConfig().MeshNamespace, MeshPolicyStrictTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync, wait.Resource{Kind: "PeerAuthentication", Namespace: util.GetConfig().MeshNamespace, Name: "default"})
 
-		for _, from := range []string{"legacy"} {
-			for _, to := range []string{"foo", "bar"} {
+		for _, from := range []string{legacy} {
+			for _, to := range []string{foo, bar} {
 				sleepPod, err := util.GetPodName(from, "app=sleep")
 				util.Inspect(err, "Failed to get sleep pod name", "", t)
 				cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -s -o /dev/null -w "sleep.%s to httpbin.%s: %%{http_code}"`, to, from, to)
 				msg, err := util.PodExec(from, sleepPod, "sleep", cmd, true)
-				if from == "legacy" && to == "foo" {
+				if from == legacy && to == foo {
 					if err != nil {
 						util.Log.Infof("Expected sleep.legacy to httpbin.foo fails: %v", err)
 					} else {
@@ -130,7 +135,7 @@ func TestMigration(t *testing.T) {
 					}
 					continue
 				}
-				if from == "legacy" && to == "bar" {
+				if from == legacy && to == bar {
 					if err != nil {
 						util.Log.Infof("Expected sleep.legacy to httpbin.bar fails: %v", err)
 					} else {
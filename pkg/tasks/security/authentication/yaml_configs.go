@@ -26,23 +26,23 @@ spec:
     mode: STRICT
 `
 
-	NamespacePolicyStrict = `
+	NamespacePolicyStrictTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "PeerAuthentication"
 metadata:
   name: "default"
-  namespace: "foo"
+  namespace: "{{ .Namespace }}"
 spec:
   mtls:
     mode: STRICT
 `
 
-	WorkloadPolicyStrict = `
+	WorkloadPolicyStrictTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "PeerAuthentication"
 metadata:
   name: "httpbin"
-  namespace: "bar"
+  namespace: "{{ .Namespace }}"
 spec:
   selector:
     matchLabels:
@@ -55,18 +55,18 @@ kind: "DestinationRule"
 metadata:
   name: "httpbin"
 spec:
-  host: "httpbin.bar.svc.cluster.local"
+  host: "httpbin.{{ .Namespace }}.svc.cluster.local"
   trafficPolicy:
     tls:
       mode: ISTIO_MUTUAL
 `
 
-	PortPolicy = `
+	PortPolicyTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "PeerAuthentication"
 metadata:
   name: "httpbin"
-  namespace: "bar"
+  namespace: "{{ .Namespace }}"
 spec:
   selector:
     matchLabels:
@@ -82,7 +82,7 @@ kind: "DestinationRule"
 metadata:
   name: "httpbin"
 spec:
-  host: httpbin.bar.svc.cluster.local
+  host: httpbin.{{ .Namespace }}.svc.cluster.local
   trafficPolicy:
     tls:
       mode: ISTIO_MUTUAL
@@ -93,12 +93,12 @@ spec:
         mode: DISABLE
 `
 
-	OverwritePolicy = `
+	OverwritePolicyTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "PeerAuthentication"
 metadata:
   name: "overwrite-example"
-  namespace: "foo"
+  namespace: "{{ .Namespace }}"
 spec:
   selector:
     matchLabels:
@@ -111,18 +111,18 @@ kind: "DestinationRule"
 metadata:
   name: "overwrite-example"
 spec:
-  host: httpbin.foo.svc.cluster.local
+  host: httpbin.{{ .Namespace }}.svc.cluster.local
   trafficPolicy:
     tls:
       mode: DISABLE
 `
 
-	HttpbinGateway = `
+	HttpbinGatewayTemplate = `
 apiVersion: networking.istio.io/v1alpha3
 kind: Gateway
 metadata:
   name: httpbin-gateway
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     istio: ingressgateway # use Istio default gateway implementation
@@ -138,7 +138,7 @@ apiVersion: networking.istio.io/v1alpha3
 kind: VirtualService
 metadata:
   name: httpbin
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   hosts:
   - "*"
@@ -149,7 +149,7 @@ spec:
     - destination:
         port:
           number: 8000
-        host: httpbin.foo.svc.cluster.local
+        host: httpbin.{{ .Namespace }}.svc.cluster.local
 `
 
 	JWTAuthPolicyTemplate = `
@@ -14,18 +14,7 @@
 
 package authentication
 
-import (
-	"github.com/maistra/maistra-test-tool/pkg/util"
-)
-
-type SMCP struct {
-	Name      string `default:"basic"`
-	Namespace string `default:"istio-system"`
+// AppNamespace is the input of the templates of resources deployed in an application namespace.
+type AppNamespace struct {
+	Namespace string
 }
-
-var (
-	smcpName       string = util.Getenv("SMCPNAME", "basic")
-	meshNamespace  string = util.Getenv("MESHNAMESPACE", "istio-system")
-	smcp           SMCP   = SMCP{smcpName, meshNamespace}
-	gatewayHTTP, _        = util.ShellSilent(`kubectl get routes -n %s istio-ingressgateway -o jsonpath='{.spec.host}'`, meshNamespace)
-)
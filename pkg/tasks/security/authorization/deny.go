@@ -18,38 +18,37 @@ import (
 	"fmt"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupAuthorDeny() {
-	util.Log.Info("Cleanup")
-	util.KubeDeleteContents("foo", AllowPathIPPolicy)
-	util.KubeDeleteContents("foo", DenyHeaderNotAdminPolicy)
-	util.KubeDeleteContents("foo", DenyGETPolicy)
-	time.Sleep(time.Duration(40) * time.Second)
-	sleep := examples.Sleep{"foo"}
-	httpbin := examples.Httpbin{"foo"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T23",
+		Title:      "Authorization policies with a deny action",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"foo"},
+		Test:       TestAuthorDeny,
+	})
 }
 
 func TestAuthorDeny(t *testing.T) {
-	defer cleanupAuthorDeny()
+	foo := util.Namespace(t, "foo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Authorization policies with a deny action")
-	httpbin := examples.Httpbin{"foo"}
+	httpbin := examples.Httpbin{Namespace: foo}
 	httpbin.Install()
-	sleep := examples.Sleep{"foo"}
+	sleep := examples.Sleep{Namespace: foo}
 	sleep.Install()
-	sleepPod, err := util.GetPodName("foo", "app=sleep")
+	sleepPod, err := util.GetPodName(foo, "app=sleep")
 	util.Inspect(err, "Failed to get sleep pod name", "", t)
-	cmd := fmt.Sprintf(`curl http://httpbin.foo:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`)
-	msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+	httpbinProxies := wait.Proxies{Namespace: foo, Selector: "app=httpbin"}
+	cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+	msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 	util.Inspect(err, "Failed to get response", "", t)
 	if !strings.Contains(msg, "200") {
 		util.Log.Errorf("Verify setup -- Unexpected response code: %s", msg)
@@ -61,12 +60,13 @@ func TestAuthorDeny(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Explicitly deny a request")
-		util.KubeApplyContents("foo", DenyGETPolicy)
-		time.Sleep(time.Duration(50) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies)
+		util.KubeApplyTemplate(foo, DenyGETPolicyTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, sync.Propagated(wait.Resource{Kind: "AuthorizationPolicy", Namespace: foo, Name: "deny-method-get"}))
 
 		util.Log.Info("Verify GET requests are denied")
-		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/get" -X GET -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/get" -X GET -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "403") {
 			util.Log.Errorf("Verify deny GET requests Unexpected response: %s", msg)
@@ -76,8 +76,8 @@ func TestAuthorDeny(t *testing.T) {
 		}
 
 		util.Log.Info("Verify POST requests are allowed")
-		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/post" -X POST -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err = util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/post" -X POST -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err = util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "200") {
 			util.Log.Errorf("Verify POST requests Unexpected response: %s", msg)
@@ -91,12 +91,13 @@ func TestAuthorDeny(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Apply a deny policy when header x-token value is not admin")
-		util.KubeApplyContents("foo", DenyHeaderNotAdminPolicy)
-		time.Sleep(time.Duration(50) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies)
+		util.KubeApplyTemplate(foo, DenyHeaderNotAdminPolicyTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, sync.Propagated(wait.Resource{Kind: "AuthorizationPolicy", Namespace: foo, Name: "deny-method-get"}))
 
 		util.Log.Info("Verify GET requests with HTTP header x-token: admin are allowed")
-		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/get" -X GET -H "x-token: admin" -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/get" -X GET -H "x-token: admin" -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "200") {
 			util.Log.Errorf("Verify GET requests with HTTP header x-token: admin Unexpected response: %s", msg)
@@ -106,8 +107,8 @@ func TestAuthorDeny(t *testing.T) {
 		}
 
 		util.Log.Info("Verify GET requests with HTTP header x-token: guest are denied")
-		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/get" -X GET -H "x-token: guest" -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err = util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/get" -X GET -H "x-token: guest" -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err = util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "403") {
 			util.Log.Errorf("Verify GET requests with HTTP header x-token: guest Unexpected response: %s", msg)
@@ -121,12 +122,13 @@ func TestAuthorDeny(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Apply a policy that allows requests at the ip path")
-		util.KubeApplyContents("foo", AllowPathIPPolicy)
-		time.Sleep(time.Duration(50) * time.Second)
+		sync := wait.BeforeConfigChange(httpbinProxies)
+		util.KubeApplyTemplate(foo, AllowPathIPPolicyTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, sync.Propagated(wait.Resource{Kind: "AuthorizationPolicy", Namespace: foo, Name: "allow-path-ip"}))
 
 		util.Log.Info("Verify GET requests with the HTTP header x-token: guest at path /ip are denied")
-		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/ip" -X GET -H "x-token: guest" -s -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err = util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/ip" -X GET -H "x-token: guest" -s -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err = util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "403") {
 			util.Log.Errorf("Verify GET requests with HTTP header x-token: guest Unexpected response: %s", msg)
@@ -136,8 +138,8 @@ func TestAuthorDeny(t *testing.T) {
 		}
 
 		util.Log.Info("Verify GET requests with the HTTP header x-token: admin at path /ip are allowed")
-		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/ip" -X GET -H "x-token: admin" -s -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err = util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/ip" -X GET -H "x-token: admin" -s -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err = util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "200") {
 			util.Log.Errorf("Verify GET requests with HTTP header x-token: admin at path /ip Unexpected response: %s", msg)
@@ -147,8 +149,8 @@ func TestAuthorDeny(t *testing.T) {
 		}
 
 		util.Log.Info("Verify GET requests with the HTTP header x-token: admin at path /get are denied")
-		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/get" -X GET -H "x-token: admin" -s -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err = util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/get" -X GET -H "x-token: admin" -s -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err = util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "403") {
 			util.Log.Errorf("Verify GET requests with HTTP header x-token: admin at path /get Unexpected response: %s", msg)
@@ -22,39 +22,51 @@ import (
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
 // referance doc : https://istio.io/latest/docs/tasks/security/authorization/authz-custom/
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T37",
+		Title:      "External authorization",
+		Tags:       []string{suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"foo"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestExtAuth,
+	})
+}
+
+// cleanupExtAuth deletes the control plane to revert the changes of the mesh config map.
+// The control plane is recreated from the snapshot taken by util.PreserveSMCP.
 func cleanupExtAuth() {
 	util.Log.Info("Cleanup Ext Auth")
-	sleep := examples.Sleep{"foo"}
-	httpbin := examples.Httpbin{"foo"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	util.KubeDeleteContents("foo", ExternalAuthzService)
-	util.KubeDeleteContents("foo", ExternalRoute)
-	time.Sleep(time.Duration(20) * time.Second)
-	util.DeleteSMCP(smcpName, meshNamespace)
+	util.DeleteSMCP(util.GetConfig().SMCPName, util.GetConfig().MeshNamespace)
 }
 
 func TestExtAuth(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
+	foo := util.Namespace(t, "foo")
+	util.PreserveSMCP(t, meshNamespace, smcpName)
 	defer cleanupExtAuth()
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Authorization with External Authorization")
-	sleep := examples.Sleep{"foo"}
+	sleep := examples.Sleep{Namespace: foo}
 	sleep.Install()
-	httpbin := examples.Httpbin{"foo"}
+	httpbin := examples.Httpbin{Namespace: foo}
 	httpbin.Install()
 
-	sleepPod, err := util.GetPodName("foo", "app=sleep")
+	sleepPod, err := util.GetPodName(foo, "app=sleep")
 	if err != nil {
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
 	}
-	cmd := fmt.Sprintf(`curl http://httpbin.foo:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`)
-	msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+	cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+	msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 	util.Inspect(err, "Failed to get response", "", t)
 	util.Log.Info("Verify that sleep can access httpbin")
 	if !strings.Contains(msg, "200") {
@@ -66,13 +78,15 @@ func TestExtAuth(t *testing.T) {
 	t.Run("Deploy the External Authorizer", func(t *testing.T) {
 
 		util.Log.Info("Deploy the sample External Authorizer")
-		util.KubeApplyContents("foo", ExternalAuthzService)
-		time.Sleep(time.Duration(50) * time.Second)
+		util.KubeApplyContents(foo, ExternalAuthzService)
+		if err := wait.UpTo(2*time.Minute, wait.DeploymentRolledOut(foo, "ext-authz")); err != nil {
+			t.Fatal(err)
+		}
 
 		util.Log.Info("Verfiy the sample external authorizer is up and running")
-		extAuthPod, err := util.GetPodName("foo", "app=ext-authz")
+		extAuthPod, err := util.GetPodName(foo, "app=ext-authz")
 		util.Inspect(err, "Failed to get ext-authz pod name", "", t)
-		extAuthPodverf, err := util.Shell("oc logs %s -n foo -c ext-authz", extAuthPod)
+		extAuthPodverf, err := util.Shell("oc logs %s -n %s -c ext-authz", extAuthPod, foo)
 
 		if strings.Contains(extAuthPodverf, "Starting HTTP server at [::]:8000") && strings.Contains(extAuthPodverf, "Starting gRPC server at [::]:9000") {
 			util.Log.Infof("Success the sample external authorizer is up and running")
@@ -84,17 +98,31 @@ func TestExtAuth(t *testing.T) {
 	t.Run("Define the external authorizer", func(t *testing.T) {
 
 		util.Log.Info("Edit configmap for two external providers")
-		util.Shell(`kubectl patch -n %s configmap/istio-%s --type merge -p '{"data": {"mesh": "# Add the following content to define the external authorizers.\nextensionProviders:\n- name: \"sample-ext-authz-grpc\"\n  envoyExtAuthzGrpc:\n    service: \"ext-authz.foo.svc.cluster.local\"\n    port: \"9000\"\n- name: \"sample-ext-authz-http\"\n  envoyExtAuthzHttp:\n    service: \"ext-authz.foo.svc.cluster.local\"\n    port: \"8000\"\n    includeRequestHeadersInCheck: [\"x-ext-authz\"]"}}'`, meshNamespace, smcpName)
+		mesh := fmt.Sprintf(`# Add the following content to define the external authorizers.
+extensionProviders:
+- name: "sample-ext-authz-grpc"
+  envoyExtAuthzGrpc:
+    service: "ext-authz.%s.svc.cluster.local"
+    port: "9000"
+- name: "sample-ext-authz-http"
+  envoyExtAuthzHttp:
+    service: "ext-authz.%s.svc.cluster.local"
+    port: "8000"
+    includeRequestHeadersInCheck: ["x-ext-authz"]`, foo, foo)
+		patch, _ := json.Marshal(map[string]interface{}{"data": map[string]string{"mesh": mesh}})
+		if _, err := util.Kubectl("patch", "configmap", "istio-"+smcpName).Namespace(meshNamespace).Patch(util.MergePatch, string(patch)).Run(); err != nil {
+			t.Fatalf("Failed to patch the mesh config: %v", err)
+		}
 	})
 
 	t.Run("Enable with external authorization", func(t *testing.T) {
 
 		util.Log.Info("Enable with external authorization")
-		util.KubeApplyContents("foo", ExternalRoute)
+		util.KubeApplyContents(foo, ExternalRoute)
 
 		util.Log.Info("Verfiy the header with deny server")
-		deny := fmt.Sprintf(`curl "http://httpbin.foo:8000/headers" -H "x-ext-authz: deny" -s`)
-		extAuthDeny, err := util.Shell("kubectl exec %s -c sleep -n foo -- %s", sleepPod, deny)
+		deny := fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -H "x-ext-authz: deny" -s`, foo)
+		extAuthDeny, err := util.Shell("kubectl exec %s -c sleep -n %s -- %s", sleepPod, foo, deny)
 		util.Inspect(err, "Failed to run the command", "", t)
 		if strings.Contains(extAuthDeny, "denied by ext_authz for not found header `x-ext-authz: allow` in the request") {
 			util.Log.Infof("Success, verfication the header with deny server")
@@ -119,19 +147,19 @@ func TestExtAuth(t *testing.T) {
 		}
 
 		var response Response
-		allow := fmt.Sprintf(`curl "http://httpbin.foo:8000/headers" -H "x-ext-authz: allow" -s`)
-		response1, err := util.Shell("kubectl exec %s -c sleep -n foo -- %s", sleepPod, allow)
+		allow := fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -H "x-ext-authz: allow" -s`, foo)
+		response1, err := util.Shell("kubectl exec %s -c sleep -n %s -- %s", sleepPod, foo, allow)
 
 		json.Unmarshal([]byte(response1), &response)
-		if response.Headers.XExtAuthz == "allow" && response.Headers.Host == "httpbin.foo:8000" && response.Headers.Accept == "*/*" {
+		if response.Headers.XExtAuthz == "allow" && response.Headers.Host == "httpbin."+foo+":8000" && response.Headers.Accept == "*/*" {
 			util.Log.Infof("Success, verfication the header with allow server")
 		} else {
 			util.Log.Errorf("Failed, verfication the header with allow server")
 		}
 
 		util.Log.Info("Verfiy request to path /ip is allowed and does not trigger the external authorization")
-		cmds := fmt.Sprintf(`curl http://httpbin.foo:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`)
-		msges, err := util.PodExec("foo", sleepPod, "sleep", cmds, true)
+		cmds := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		msges, err := util.PodExec(foo, sleepPod, "sleep", cmds, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msges, "200") {
 			util.Log.Errorf("Verify setup -- Unexpected response code: %s", msges)
@@ -139,9 +167,9 @@ func TestExtAuth(t *testing.T) {
 			util.Log.Infof("Success. Get expected response: %s", msges)
 		}
 
-		extAuthPod, err := util.GetPodName("foo", "app=ext-authz")
-		extAuthPodverfs, err := util.Shell("oc logs %s -n foo -c ext-authz", extAuthPod)
-		if strings.Contains(extAuthPodverfs, "Starting HTTP server at [::]:8000") && strings.Contains(extAuthPodverfs, "Starting gRPC server at [::]:9000") && response.Headers.XExtAuthz == "allow" && response.Headers.Host == "httpbin.foo:8000" && response.Headers.Accept == "*/*" {
+		extAuthPod, err := util.GetPodName(foo, "app=ext-authz")
+		extAuthPodverfs, err := util.Shell("oc logs %s -n %s -c ext-authz", extAuthPod, foo)
+		if strings.Contains(extAuthPodverfs, "Starting HTTP server at [::]:8000") && strings.Contains(extAuthPodverfs, "Starting gRPC server at [::]:9000") && response.Headers.XExtAuthz == "allow" && response.Headers.Host == "httpbin."+foo+":8000" && response.Headers.Accept == "*/*" {
 			util.Log.Infof("Success, log of the sample ext_authz server to confirm it was called twice (for the two requests)")
 		} else {
 			util.Log.Errorf("Failed to get the 2 request from ext_authz")
@@ -19,37 +19,40 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupAuthorHTTP() {
-	util.Log.Info("Cleanup")
-	util.KubeDeleteContents("bookinfo", DetailsGETPolicy)
-	util.KubeDeleteContents("bookinfo", ReviewsGETPolicy)
-	util.KubeDeleteContents("bookinfo", RatingsGETPolicy)
-	util.KubeDeleteContents("bookinfo", ProductpageGETPolicy)
-	util.KubeDeleteContents("bookinfo", DenyAllPolicy)
-	time.Sleep(time.Duration(20) * time.Second)
-	bookinfo := examples.Bookinfo{"bookinfo"}
-	bookinfo.Uninstall()
-	util.Shell(`kubectl patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"dataPlane":{"mtls":false},"controlPlane":{"mtls":false}}}}'`, meshNamespace, smcpName)
-	util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName)
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T20",
+		Title:      "Authorization for HTTP traffic",
+		Tags:       []string{suite.ARM, suite.Interop, suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestAuthorHTTP,
+	})
 }
 
 func TestAuthorHTTP(t *testing.T) {
-	defer cleanupAuthorHTTP()
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
+	gatewayHTTP := util.TestEnv(t).GatewayHost
+	ns := util.Namespace(t, "bookinfo")
+	util.PreserveSMCP(t, meshNamespace, smcpName)
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Authorization for HTTP traffic")
 	util.Log.Info("Enable Control Plane MTLS")
 	util.Shell(`kubectl patch -n %s smcp/%s --type merge -p '{"spec":{"security":{"dataPlane":{"mtls":true},"controlPlane":{"mtls":true}}}}'`, meshNamespace, smcpName)
-	util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName)
+	if err := wait.UpTo(util.SMCPTimeout, wait.SMCPReady(meshNamespace, smcpName)); err != nil {
+		t.Fatal(err)
+	}
 
-	bookinfo := examples.Bookinfo{"bookinfo"}
+	bookinfo := examples.Bookinfo{Namespace: ns}
 	bookinfo.Install(true)
 	productpageURL := fmt.Sprintf("http://%s/productpage", gatewayHTTP)
 
@@ -57,8 +60,8 @@ func TestAuthorHTTP(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Configure access control for workloads using HTTP traffic")
-		util.KubeApplyContents("bookinfo", DenyAllPolicy)
-		time.Sleep(time.Duration(10) * time.Second)
+		util.KubeApplyTemplate(ns, DenyAllPolicyTemplate, AppNamespace{Namespace: ns})
+		waitForPolicy(t, wait.HTTPStatus(productpageURL, "", 403))
 
 		resp, _, err := util.GetHTTPResponse(productpageURL, nil)
 		util.Inspect(err, "Failed to get HTTP Response", "", t)
@@ -77,10 +80,9 @@ func TestAuthorHTTP(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Allow access with GET method to the productpage workload")
-		util.KubeApplyContents("bookinfo", ProductpageGETPolicy)
-		time.Sleep(time.Duration(10) * time.Second)
+		util.KubeApplyTemplate(ns, ProductpageGETPolicyTemplate, AppNamespace{Namespace: ns})
+		waitForPolicy(t, wait.HTTPStatus(productpageURL, "", 200))
 
-		util.GetHTTPResponse(productpageURL, nil) // dummy request to refresh previous page
 		resp, _, err := util.GetHTTPResponse(productpageURL, nil)
 		util.Inspect(err, "Failed to get HTTP Response", "", t)
 		body, err := ioutil.ReadAll(resp.Body)
@@ -94,12 +96,18 @@ func TestAuthorHTTP(t *testing.T) {
 		util.CloseResponseBody(resp)
 
 		util.Log.Info("Allow other bookinfo services GET method")
-		util.KubeApplyContents("bookinfo", DetailsGETPolicy)
-		util.KubeApplyContents("bookinfo", ReviewsGETPolicy)
-		util.KubeApplyContents("bookinfo", RatingsGETPolicy)
-		time.Sleep(time.Duration(50) * time.Second)
+		sync := wait.BeforeConfigChange(
+			wait.Proxies{Namespace: ns, Selector: "app=details"},
+			wait.Proxies{Namespace: ns, Selector: "app=reviews"},
+			wait.Proxies{Namespace: ns, Selector: "app=ratings"})
+		util.KubeApplyTemplate(ns, DetailsGETPolicyTemplate, AppNamespace{Namespace: ns})
+		util.KubeApplyTemplate(ns, ReviewsGETPolicyTemplate, AppNamespace{Namespace: ns})
+		util.KubeApplyTemplate(ns, RatingsGETPolicyTemplate, AppNamespace{Namespace: ns})
+		waitForPolicy(t, sync.Propagated(
+			wait.Resource{Kind: "AuthorizationPolicy", Namespace: ns, Name: "details-viewer"},
+			wait.Resource{Kind: "AuthorizationPolicy", Namespace: ns, Name: "reviews-viewer"},
+			wait.Resource{Kind: "AuthorizationPolicy", Namespace: ns, Name: "ratings-viewer"}))
 
-		util.GetHTTPResponse(productpageURL, nil) // dummy request to refresh previous page
 		resp, _, err = util.GetHTTPResponse(productpageURL, nil)
 		util.Inspect(err, "Failed to get HTTP Response", "", t)
 
@@ -21,53 +21,53 @@ import (
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupAuthorJWT() {
-	util.Log.Info("Cleanup")
-	util.KubeDeleteContents("foo", JWTGroupClaimRule)
-	util.KubeDeleteContents("foo", JWTRequireRule)
-	util.KubeDeleteContents("foo", JWTExampleRule)
-	time.Sleep(time.Duration(40) * time.Second)
-	sleep := examples.Sleep{"foo"}
-	httpbin := examples.Httpbin{"foo"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T22",
+		Title:      "Authorization with JWT",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"foo"},
+		Test:       TestAuthorJWT,
+	})
 }
 
 func TestAuthorJWT(t *testing.T) {
-	defer cleanupAuthorJWT()
+	foo := util.Namespace(t, "foo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Authorization with JWT Token")
-	httpbin := examples.Httpbin{"foo"}
+	httpbin := examples.Httpbin{Namespace: foo}
 	httpbin.Install()
-	sleep := examples.Sleep{"foo"}
+	sleep := examples.Sleep{Namespace: foo}
 	sleep.Install()
 
-	sleepPod, err := util.GetPodName("foo", "app=sleep")
+	sleepPod, err := util.GetPodName(foo, "app=sleep")
 	util.Inspect(err, "Failed to get sleep pod name", "", t)
-	cmd := fmt.Sprintf(`curl http://httpbin.foo:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`)
-	msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+	cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+	msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 	util.Inspect(err, "Failed to get response", "", t)
 	if !strings.Contains(msg, "200") {
 		util.Log.Errorf("Verify setup -- Unexpected response code: %s", msg)
 	} else {
 		util.Log.Infof("Success. Get expected response: %s", msg)
 	}
+	headersURL := fmt.Sprintf("http://httpbin.%s:8000/headers", foo)
 
 	t.Run("Security_authorization_allow_valid_JWT_list-typed_claims", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Allow requests with valid JWT and list-typed claims")
-		util.KubeApplyContents("foo", JWTExampleRule)
-		time.Sleep(time.Duration(50) * time.Second)
+		util.KubeApplyTemplate(foo, JWTExampleRuleTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, wait.PodHTTPStatus(foo, sleepPod, "sleep", headersURL, 401, `-H "Authorization: Bearer invalidToken"`))
 
 		util.Log.Info("Verify a request with an invalid JWT is denied")
-		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -sS -o /dev/null -H "Authorization: Bearer invalidToken" -w "%%{http_code}\n"`, "foo")
-		msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -sS -o /dev/null -H "Authorization: Bearer invalidToken" -w "%%{http_code}\n"`, foo)
+		msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "401") {
 			util.Log.Errorf("Verify denied request Unexpected response: %s", msg)
@@ -77,8 +77,8 @@ func TestAuthorJWT(t *testing.T) {
 		}
 
 		util.Log.Info("Verify a request without a JWT is allowed")
-		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err = util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err = util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "200") {
 			util.Log.Errorf("Verify request without JWT Unexpected response: %s", msg)
@@ -92,8 +92,8 @@ func TestAuthorJWT(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Apply a policy requires all requests to have a valid JWT")
-		util.KubeApplyContents("foo", JWTRequireRule)
-		time.Sleep(time.Duration(50) * time.Second)
+		util.KubeApplyTemplate(foo, JWTRequireRuleTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, wait.PodHTTPStatus(foo, sleepPod, "sleep", headersURL, 403))
 
 		util.Log.Info("Download JWT token")
 		jwtURL := "https://raw.githubusercontent.com/istio/istio/release-1.9/security/tools/jwt/samples/demo.jwt"
@@ -103,8 +103,8 @@ func TestAuthorJWT(t *testing.T) {
 		util.Inspect(err, "Failed to get JWT token", "", t)
 
 		util.Log.Info("Verify request with a valid JWT")
-		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -sS -o /dev/null -H "Authorization: Bearer %s" -w "%%{http_code}\n"`, "foo", token)
-		msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -sS -o /dev/null -H "Authorization: Bearer %s" -w "%%{http_code}\n"`, foo, token)
+		msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "200") {
 			util.Log.Errorf("Verify request with valid JWT Unexpected response: %s", msg)
@@ -114,8 +114,8 @@ func TestAuthorJWT(t *testing.T) {
 		}
 
 		util.Log.Info("Verify request without a JWT is denied")
-		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		msg, err = util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		msg, err = util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "403") {
 			util.Log.Errorf("Verify request without valid JWT Unexpected response: %s", msg)
@@ -128,10 +128,6 @@ func TestAuthorJWT(t *testing.T) {
 	t.Run("Security_authorization_allow_JWT_claims_group", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
-		util.Log.Info("Apply a require jwt policy with a group claim")
-		util.KubeApplyContents("foo", JWTGroupClaimRule)
-		time.Sleep(time.Duration(50) * time.Second)
-
 		util.Log.Info("Download JWT token and sets the groups claims")
 		jwtURL := "https://raw.githubusercontent.com/istio/istio/release-1.9/security/tools/jwt/samples/demo.jwt"
 		token, err := util.ShellMuteOutput(`curl %s -s`, jwtURL)
@@ -145,9 +141,13 @@ func TestAuthorJWT(t *testing.T) {
 		//tokenGroup, err = util.Shell(`echo %s | cut -d '.' -f2 - | base64 --decode -`, tokenGroup)
 		util.Inspect(err, "Failed to get JWT token", "", t)
 
+		util.Log.Info("Apply a require jwt policy with a group claim")
+		util.KubeApplyTemplate(foo, JWTGroupClaimRuleTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, wait.PodHTTPStatus(foo, sleepPod, "sleep", headersURL, 403, fmt.Sprintf(`-H "Authorization: Bearer %s"`, token)))
+
 		util.Log.Info("Verify request with a JWT includes group1 claim")
-		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -s -o /dev/null -H "Authorization: Bearer %s" -w "%%{http_code}\n"`, "foo", tokenGroup)
-		msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd := fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -s -o /dev/null -H "Authorization: Bearer %s" -w "%%{http_code}\n"`, foo, tokenGroup)
+		msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "200") {
 			util.Log.Errorf("Verify request with JWT group1 claim Unexpected response: %s", msg)
@@ -157,8 +157,8 @@ func TestAuthorJWT(t *testing.T) {
 		}
 
 		util.Log.Info("Verify request without groups claim JWT is denied")
-		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -s -o /dev/null -H "Authorization: Bearer %s" -w "%%{http_code}\n"`, "foo", token)
-		msg, err = util.PodExec("foo", sleepPod, "sleep", cmd, true)
+		cmd = fmt.Sprintf(`curl "http://httpbin.%s:8000/headers" -s -o /dev/null -H "Authorization: Bearer %s" -w "%%{http_code}\n"`, foo, token)
+		msg, err = util.PodExec(foo, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "Failed to get response", "", t)
 		if !strings.Contains(msg, "403") {
 			util.Log.Errorf("Verify request without groups claim JWT Unexpected response: %s", msg)
@@ -168,3 +168,10 @@ func TestAuthorJWT(t *testing.T) {
 		}
 	})
 }
+
+// waitForPolicy waits until an applied policy takes effect, as observed by a request it changes the result of.
+func waitForPolicy(t *testing.T, cond wait.Condition) {
+	if err := wait.UpTo(2*time.Minute, cond); err != nil {
+		t.Fatal(err)
+	}
+}
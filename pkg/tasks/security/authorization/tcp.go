@@ -18,43 +18,41 @@ import (
 	"fmt"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupAuthorTCP() {
-	util.Log.Info("Cleanup")
-	util.KubeDeleteContents("foo", TCPDenyGETPolicy)
-	util.KubeDeleteContents("foo", TCPAllowGETPolicy)
-	util.KubeDeleteContents("foo", TCPAllowPolicy)
-	echo := examples.Echo{"foo"}
-	echo.UninstallWithProxy()
-	sleep := examples.Sleep{"foo"}
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T21",
+		Title:      "Authorization for TCP traffic",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"foo"},
+		Test:       TestAuthorTCP,
+	})
 }
 
 func TestAuthorTCP(t *testing.T) {
-	defer cleanupAuthorTCP()
+	foo := util.Namespace(t, "foo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Authorization for TCP traffic")
-	sleep := examples.Sleep{"foo"}
+	sleep := examples.Sleep{Namespace: foo}
 	sleep.Install()
-	echo := examples.Echo{"foo"}
+	echo := examples.Echo{Namespace: foo}
 	echo.InstallWithProxy()
-	time.Sleep(time.Duration(20) * time.Second)
 
 	util.Log.Info("Verify echo hello port")
-	sleepPod, err := util.GetPodName("foo", "app=sleep")
+	sleepPod, err := util.GetPodName(foo, "app=sleep")
 	util.Inspect(err, "Failed to get sleep pod name", "", t)
 	ports := []string{"9000", "9001", "9002"}
 	for _, port := range ports {
 		if port == "9000" || port == "9001" {
 			cmd := fmt.Sprintf(`sh -c 'echo "port %s" | nc tcp-echo %s' | grep "hello" && echo 'connection succeeded' || echo 'connection rejected'`, port, port)
-			msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+			msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 			util.Inspect(err, "Failed to get response", "", t)
 			if !strings.Contains(msg, "connection succeeded") {
 				util.Log.Errorf("Verify setup Unexpected response: %s", msg)
@@ -63,10 +61,10 @@ func TestAuthorTCP(t *testing.T) {
 				util.Log.Infof("Success. Get expected response: %s", msg)
 			}
 		} else {
-			tcpEchoPod, err := util.GetPodName("foo", "app=tcp-echo")
-			podIP, err := util.Shell(`kubectl get pod %s -n foo -o jsonpath="{.status.podIP}"`, tcpEchoPod)
+			tcpEchoPod, err := util.GetPodName(foo, "app=tcp-echo")
+			podIP, err := util.Shell(`kubectl get pod %s -n %s -o jsonpath="{.status.podIP}"`, tcpEchoPod, foo)
 			cmd := fmt.Sprintf(`sh -c 'echo "port %s" | nc %s %s' | grep "hello" && echo 'connection succeeded' || echo 'connection rejected'`, port, podIP, port)
-			msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+			msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 			util.Inspect(err, "Failed to get response", "", t)
 			if !strings.Contains(msg, "connection succeeded") {
 				util.Log.Errorf("Verify setup Unexpected response: %s", msg)
@@ -81,14 +79,15 @@ func TestAuthorTCP(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Apply a policy to allow requests to port 9000 and 9001")
-		util.KubeApplyContents("foo", TCPAllowPolicy)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: foo, Selector: "app=tcp-echo"})
+		util.KubeApplyTemplate(foo, TCPAllowPolicyTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, sync.Propagated(wait.Resource{Kind: "AuthorizationPolicy", Namespace: foo, Name: "tcp-policy"}))
 
 		ports := []string{"9000", "9001", "9002"}
 		for _, port := range ports {
 			if port == "9000" || port == "9001" {
 				cmd := fmt.Sprintf(`sh -c 'echo "port %s" | nc tcp-echo %s' | grep "hello" && echo 'connection succeeded' || echo 'connection rejected'`, port, port)
-				msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+				msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 				util.Inspect(err, "Failed to get response", "", t)
 				if !strings.Contains(msg, "connection succeeded") {
 					util.Log.Errorf("Verify allow GET Unexpected response: %s", msg)
@@ -97,10 +96,10 @@ func TestAuthorTCP(t *testing.T) {
 					util.Log.Infof("Success. Get expected response: %s", msg)
 				}
 			} else {
-				tcpEchoPod, err := util.GetPodName("foo", "app=tcp-echo")
-				podIP, err := util.Shell(`kubectl get pod %s -n foo -o jsonpath="{.status.podIP}"`, tcpEchoPod)
+				tcpEchoPod, err := util.GetPodName(foo, "app=tcp-echo")
+				podIP, err := util.Shell(`kubectl get pod %s -n %s -o jsonpath="{.status.podIP}"`, tcpEchoPod, foo)
 				cmd := fmt.Sprintf(`sh -c 'echo "port %s" | nc %s %s' | grep "hello" && echo 'connection succeeded' || echo 'connection rejected'`, port, podIP, port)
-				msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+				msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 				util.Inspect(err, "Failed to get response", "", t)
 				if !strings.Contains(msg, "connection rejected") {
 					util.Log.Errorf("Verify allow GET Unexpected response: %s", msg)
@@ -116,13 +115,14 @@ func TestAuthorTCP(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Apply a policy to allow requests to port 9000 and add an HTTP GET field")
-		util.KubeApplyContents("foo", TCPAllowGETPolicy)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: foo, Selector: "app=tcp-echo"})
+		util.KubeApplyTemplate(foo, TCPAllowGETPolicyTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, sync.Propagated(wait.Resource{Kind: "AuthorizationPolicy", Namespace: foo, Name: "tcp-policy"}))
 
 		ports := []string{"9000", "9001"}
 		for _, port := range ports {
 			cmd := fmt.Sprintf(`sh -c 'echo "port %s" | nc tcp-echo %s' | grep "hello" && echo 'connection succeeded' || echo 'connection rejected'`, port, port)
-			msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+			msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 			util.Inspect(err, "Failed to get response", "", t)
 			if !strings.Contains(msg, "connection rejected") {
 				util.Log.Errorf("Verify invalid rule Unexpected response: %s", msg)
@@ -137,13 +137,14 @@ func TestAuthorTCP(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Apply a DENY policy")
-		util.KubeApplyContents("foo", TCPDenyGETPolicy)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: foo, Selector: "app=tcp-echo"})
+		util.KubeApplyTemplate(foo, TCPDenyGETPolicyTemplate, AppNamespace{Namespace: foo})
+		waitForPolicy(t, sync.Propagated(wait.Resource{Kind: "AuthorizationPolicy", Namespace: foo, Name: "tcp-policy"}))
 
 		ports := []string{"9000", "9001"}
 		for _, port := range ports {
 			cmd := fmt.Sprintf(`sh -c 'echo "port %s" | nc tcp-echo %s' | grep "hello" && echo 'connection succeeded' || echo 'connection rejected'`, port, port)
-			msg, err := util.PodExec("foo", sleepPod, "sleep", cmd, true)
+			msg, err := util.PodExec(foo, sleepPod, "sleep", cmd, true)
 			util.Inspect(err, "Failed to get response", "", t)
 
 			if port == "9000" {
@@ -16,107 +16,124 @@ package authorizaton
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupTrustDomainMigration() {
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T24",
+		Title:      "Authorization policy trust domain migration",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"foo", "bar"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestTrustDomainMigration,
+	})
+}
+
+func cleanupTrustDomainMigration(t *testing.T, snapshot *util.SMCPSnapshot) {
 	util.Log.Info("Cleanup")
-	util.KubeDeleteContents("foo", TrustDomainPolicy)
-	sleep := examples.Sleep{Namespace: "foo"}
-	httpbin := examples.Httpbin{Namespace: "foo"}
-	sleep.Uninstall()
-	httpbin.Uninstall()
-	sleep = examples.Sleep{Namespace: "bar"}
-	sleep.Uninstall()
-	applyTrustDomain("cluster.local", "", false)
+	if err := snapshot.Restore(); err != nil {
+		t.Errorf("Failed to restore smcp/%s: %v", util.GetConfig().SMCPName, err)
+	}
+	restartTrustDomainPods()
 }
 
 func TestTrustDomainMigration(t *testing.T) {
-	defer cleanupTrustDomainMigration()
+	smcpName := util.GetConfig().SMCPName
+	foo := util.Namespace(t, "foo")
+	bar := util.Namespace(t, "bar")
+	snapshot, err := util.SnapshotSMCP(util.GetConfig().MeshNamespace, smcpName)
+	if err != nil {
+		t.Fatalf("Failed to take a snapshot of smcp/%s: %v", smcpName, err)
+	}
+	defer cleanupTrustDomainMigration(t, snapshot)
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Trust Domain Migration")
-	applyTrustDomain("old-td", "", true)
+	applyTrustDomain(t, "old-td", "", true)
 
 	// Deploy workloads
-	httpbin := examples.Httpbin{Namespace: "foo"}
+	httpbin := examples.Httpbin{Namespace: foo}
 	util.Inspect(httpbin.Install(), "Failed to deploy httpbin", "", t)
-	sleep := examples.Sleep{Namespace: "foo"}
+	sleep := examples.Sleep{Namespace: foo}
 	util.Inspect(sleep.Install(), "Failed to deploy sleep", "", t)
-	sleep = examples.Sleep{Namespace: "bar"}
+	sleep = examples.Sleep{Namespace: bar}
 	util.Inspect(sleep.Install(), "Failed to deploy sleep", "", t)
 
 	util.Log.Info("Apply deny all policy except sleep in bar namespace")
-	util.KubeApplyContents("foo", TrustDomainPolicy)
+	util.KubeApplyTemplate(foo, TrustDomainPolicyTemplate, AppNamespace{Namespace: foo, SourceNamespace: bar})
 
 	t.Run("Case 1: Verifying policy works", func(t *testing.T) {
-		sleepPod, err := util.GetPodName("foo", "app=sleep")
+		sleepPod, err := util.GetPodName(foo, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
-		cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		if err := checkOutput("foo", sleepPod, "sleep", cmd, "403"); err != nil {
+		cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		if err := checkOutput(foo, sleepPod, "sleep", cmd, "403"); err != nil {
 			t.Fatal(err)
 		}
 
-		sleepPod, err = util.GetPodName("bar", "app=sleep")
+		sleepPod, err = util.GetPodName(bar, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
-		cmd = fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		if err := checkOutput("bar", sleepPod, "sleep", cmd, "200"); err != nil {
+		cmd = fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		if err := checkOutput(bar, sleepPod, "sleep", cmd, "200"); err != nil {
 			t.Fatal(err)
 		}
 	})
 
 	t.Run("Case 2: Migrate trust domain without trust domain aliases", func(t *testing.T) {
-		applyTrustDomain("new-td", "", true)
+		applyTrustDomain(t, "new-td", "", true)
 
 		// Restart workload pods
-		util.Shell("oc -n foo delete pod --all")
-		util.Shell("oc -n bar delete pod --all")
-		util.Shell("oc -n foo wait --for condition=Ready --all pods --timeout 30s")
-		util.Shell("oc -n bar wait --for condition=Ready --all pods --timeout 30s")
+		util.Shell("oc -n %s delete pod --all", foo)
+		util.Shell("oc -n %s delete pod --all", bar)
+		util.Shell("oc -n %s wait --for condition=Ready --all pods --timeout 30s", foo)
+		util.Shell("oc -n %s wait --for condition=Ready --all pods --timeout 30s", bar)
 
 		// Both must return 403
-		sleepPod, err := util.GetPodName("foo", "app=sleep")
+		sleepPod, err := util.GetPodName(foo, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
-		cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		if err := checkOutput("foo", sleepPod, "sleep", cmd, "403"); err != nil {
+		cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		if err := checkOutput(foo, sleepPod, "sleep", cmd, "403"); err != nil {
 			t.Fatal(err)
 		}
 
-		sleepPod, err = util.GetPodName("bar", "app=sleep")
+		sleepPod, err = util.GetPodName(bar, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
-		cmd = fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		if err := checkOutput("bar", sleepPod, "sleep", cmd, "403"); err != nil {
+		cmd = fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		if err := checkOutput(bar, sleepPod, "sleep", cmd, "403"); err != nil {
 			t.Fatal(err)
 		}
 	})
 
 	t.Run("Case 3: Migrate trust domain with trust domain aliases", func(t *testing.T) {
-		applyTrustDomain("new-td", "old-td", true)
+		applyTrustDomain(t, "new-td", "old-td", true)
 
 		// Restart workload pods
-		util.Shell("oc -n foo delete pod --all")
-		util.Shell("oc -n bar delete pod --all")
-		util.Shell("oc -n foo wait --for condition=Ready --all pods --timeout 30s")
-		util.Shell("oc -n bar wait --for condition=Ready --all pods --timeout 30s")
+		util.Shell("oc -n %s delete pod --all", foo)
+		util.Shell("oc -n %s delete pod --all", bar)
+		util.Shell("oc -n %s wait --for condition=Ready --all pods --timeout 30s", foo)
+		util.Shell("oc -n %s wait --for condition=Ready --all pods --timeout 30s", bar)
 
-		sleepPod, err := util.GetPodName("foo", "app=sleep")
+		sleepPod, err := util.GetPodName(foo, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
-		cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		if err := checkOutput("foo", sleepPod, "sleep", cmd, "403"); err != nil {
+		cmd := fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		if err := checkOutput(foo, sleepPod, "sleep", cmd, "403"); err != nil {
 			t.Fatal(err)
 		}
 
 		// This must return 200, as in the first case
-		sleepPod, err = util.GetPodName("bar", "app=sleep")
+		sleepPod, err = util.GetPodName(bar, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
-		cmd = fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, "foo")
-		if err := checkOutput("bar", sleepPod, "sleep", cmd, "200"); err != nil {
+		cmd = fmt.Sprintf(`curl http://httpbin.%s:8000/ip -sS -o /dev/null -w "%%{http_code}\n"`, foo)
+		if err := checkOutput(bar, sleepPod, "sleep", cmd, "200"); err != nil {
 			t.Fatal(err)
 		}
 	})
@@ -125,52 +142,73 @@ func TestTrustDomainMigration(t *testing.T) {
 
 func checkOutput(namespace, pod, container, cmd, expected string) error {
 	retry := util.Retrier{
-		BaseDelay: 5 * time.Second,
-		MaxDelay:  10 * time.Second,
-		Retries:   5,
+		BaseDelay:      5 * time.Second,
+		MaxDelay:       10 * time.Second,
+		Retries:        5,
+		Jitter:         0.2,
+		AttemptTimeout: time.Minute,
+		Description:    fmt.Sprintf("get %s from %s", expected, pod),
 	}
 
 	util.Log.Infof("Verifying curl output, expecting %s", expected)
-
-	retryFn := func(_ context.Context, i int) error {
+	msg, err := util.RetryValue(context.Background(), retry, func(_ context.Context, _ int) (string, error) {
 		msg, err := util.PodExec(namespace, pod, container, cmd, true)
 		if err != nil {
-			return err
+			return "", err
 		}
 		if !strings.Contains(msg, expected) {
-			util.Log.Errorf("Attempt %d/%d - expected: %v; Got: %v", i, retry.Retries, expected, msg)
-			return fmt.Errorf("expected: %v; Got: %v", expected, msg)
+			return "", fmt.Errorf("expected: %v; Got: %v", expected, msg)
 		}
-
-		util.Log.Infof("Attempt %d/%d - Success, got %s", i, retry.Retries, msg)
-		return nil
+		return msg, nil
+	})
+	if err == nil {
+		util.Log.Infof("Success, got %s", msg)
 	}
-
-	ctx := context.Background()
-	_, err := retry.Retry(ctx, retryFn)
 	return err
 }
 
-func applyTrustDomain(domain, alias string, mtls bool) {
+func applyTrustDomain(t *testing.T, domain, alias string, mtls bool) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
 	util.Log.Infof("Configuring  spec.security.trust.domain to %q and alias %q", domain, alias)
 
+	additionalDomains := []string{}
 	if alias != "" {
-		alias = fmt.Sprintf("%q", alias)
+		additionalDomains = append(additionalDomains, alias)
+	}
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"security": map[string]interface{}{
+				"dataPlane": map[string]interface{}{"mtls": mtls},
+				"trust":     map[string]interface{}{"domain": domain, "additionalDomains": additionalDomains},
+			},
+		},
+	})
+	if _, err := util.Oc("patch", "smcp", smcpName).Namespace(meshNamespace).Patch(util.MergePatch, string(patch)).Run(); err != nil {
+		t.Fatalf("Failed to configure the trust domain: %v", err)
 	}
-
-	util.Shell(`oc -n %s patch smcp/%s --type merge -p '{"spec":{"security":{"dataPlane":{"mtls":%v}, "trust":{"domain":"%s", "additionalDomains": [%s]}}}}'`, meshNamespace, smcpName, mtls, domain, alias)
 
 	// Wait for the operator to reconcile the changes
-	util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName)
+	if err := wait.UpTo(util.SMCPTimeout, wait.SMCPReady(meshNamespace, smcpName)); err != nil {
+		util.Log.Error(err)
+	}
+	restartTrustDomainPods()
+}
 
-	// Restart istiod so it picks up the new trust domain
-	util.Shell(`oc -n %s rollout restart deployment istiod-%s`, meshNamespace, smcpName)
-	// wait 20 seconds and avoid a race condition checking wrong ingressgateway and istiod-basic pods
-	time.Sleep(time.Duration(20) * time.Second)
-	util.Shell(`oc -n %s wait --for condition=Ready --all pods --timeout 180s`, meshNamespace)
+// restartTrustDomainPods restarts the pods that need to pick up a changed trust domain and mtls setting.
+func restartTrustDomainPods() {
+	meshNamespace := util.GetConfig().MeshNamespace
+	// Restart istiod so it picks up the new trust domain. Waiting for the new generation to roll out
+	// avoids checking the pods that are about to be replaced.
+	istiod := "istiod-" + util.GetConfig().SMCPName
+	util.Shell(`oc -n %s rollout restart deployment %s`, meshNamespace, istiod)
+	if err := wait.UpTo(3*time.Minute, wait.DeploymentRolledOut(meshNamespace, istiod)); err != nil {
+		util.Log.Error(err)
+	}
 
 	// Restart ingress gateway since we changed the mtls setting
 	util.Shell(`oc -n %s rollout restart deployment istio-ingressgateway`, meshNamespace)
-	time.Sleep(time.Duration(20) * time.Second)
-	util.Shell(`oc -n %s wait --for condition=Ready --all pods --timeout 180s`, meshNamespace)
+	if err := wait.UpTo(3*time.Minute, wait.DeploymentRolledOut(meshNamespace, "istio-ingressgateway")); err != nil {
+		util.Log.Error(err)
+	}
 }
@@ -15,22 +15,22 @@
 package authorizaton
 
 const (
-	DenyAllPolicy = `
+	DenyAllPolicyTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: allow-nothing
-  namespace: bookinfo
+  namespace: {{ .Namespace }}
 spec:
   {}
 `
 
-	ProductpageGETPolicy = `
+	ProductpageGETPolicyTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "AuthorizationPolicy"
 metadata:
   name: "productpage-viewer"
-  namespace: bookinfo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -42,12 +42,12 @@ spec:
         methods: ["GET"]
 `
 
-	DetailsGETPolicy = `
+	DetailsGETPolicyTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "AuthorizationPolicy"
 metadata:
   name: "details-viewer"
-  namespace: bookinfo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -56,18 +56,18 @@ spec:
   rules:
   - from:
     - source:
-        principals: ["cluster.local/ns/bookinfo/sa/bookinfo-productpage"]
+        principals: ["cluster.local/ns/{{ .Namespace }}/sa/bookinfo-productpage"]
     to:
     - operation:
         methods: ["GET"]
 `
 
-	ReviewsGETPolicy = `
+	ReviewsGETPolicyTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "AuthorizationPolicy"
 metadata:
   name: "reviews-viewer"
-  namespace: bookinfo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -76,18 +76,18 @@ spec:
   rules:
   - from:
     - source:
-        principals: ["cluster.local/ns/bookinfo/sa/bookinfo-productpage"]
+        principals: ["cluster.local/ns/{{ .Namespace }}/sa/bookinfo-productpage"]
     to:
     - operation:
         methods: ["GET"]
 `
 
-	RatingsGETPolicy = `
+	RatingsGETPolicyTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "AuthorizationPolicy"
 metadata:
   name: "ratings-viewer"
-  namespace: bookinfo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -96,24 +96,24 @@ spec:
   rules:
   - from:
     - source:
-        principals: ["cluster.local/ns/bookinfo/sa/bookinfo-reviews"]
+        principals: ["cluster.local/ns/{{ .Namespace }}/sa/bookinfo-reviews"]
     to:
     - operation:
         methods: ["GET"]
 `
 
-	TrustDomainPolicy = `
+	TrustDomainPolicyTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
-  name: service-httpbin.foo.svc.cluster.local
-  namespace: foo
+  name: service-httpbin.{{ .Namespace }}.svc.cluster.local
+  namespace: {{ .Namespace }}
 spec:
   rules:
   - from:
     - source:
         principals:
-        - old-td/ns/bar/sa/sleep
+        - old-td/ns/{{ .SourceNamespace }}/sa/sleep
     to:
     - operation:
         methods:
@@ -123,12 +123,12 @@ spec:
       app: httpbin
 `
 
-	TCPAllowPolicy = `
+	TCPAllowPolicyTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: tcp-policy
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -140,12 +140,12 @@ spec:
        ports: ["9000", "9001"]
 `
 
-	TCPAllowGETPolicy = `
+	TCPAllowGETPolicyTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: tcp-policy
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -158,12 +158,12 @@ spec:
         ports: ["9000"]
 `
 
-	TCPDenyGETPolicy = `
+	TCPDenyGETPolicyTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: tcp-policy
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -176,12 +176,12 @@ spec:
         ports: ["9000"]
 `
 
-	JWTExampleRule = `
+	JWTExampleRuleTemplate = `
 apiVersion: "security.istio.io/v1beta1"
 kind: "RequestAuthentication"
 metadata:
   name: "jwt-example"
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -191,12 +191,12 @@ spec:
     jwksUri: "https://raw.githubusercontent.com/istio/istio/release-1.9/security/tools/jwt/samples/jwks.json"
 `
 
-	JWTRequireRule = `
+	JWTRequireRuleTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: require-jwt
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -208,12 +208,12 @@ spec:
        requestPrincipals: ["testing@secure.istio.io/testing@secure.istio.io"]
 `
 
-	JWTGroupClaimRule = `
+	JWTGroupClaimRuleTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: require-jwt
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -228,12 +228,12 @@ spec:
       values: ["group1"]
 `
 
-	DenyGETPolicy = `
+	DenyGETPolicyTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: deny-method-get
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -245,12 +245,12 @@ spec:
         methods: ["GET"]
 `
 
-	DenyHeaderNotAdminPolicy = `
+	DenyHeaderNotAdminPolicyTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: deny-method-get
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
@@ -265,12 +265,12 @@ spec:
       notValues: ["admin"]
 `
 
-	AllowPathIPPolicy = `
+	AllowPathIPPolicyTemplate = `
 apiVersion: security.istio.io/v1beta1
 kind: AuthorizationPolicy
 metadata:
   name: allow-path-ip
-  namespace: foo
+  namespace: {{ .Namespace }}
 spec:
   selector:
     matchLabels:
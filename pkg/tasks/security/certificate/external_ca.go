@@ -19,25 +19,34 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T17",
+		Title:      "Plugging in external CA certificates",
+		Tags:       []string{suite.ARM, suite.Interop, suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestExternalCert,
+	})
+}
+
 func cleanupExternalCert() {
 	util.Log.Info("Cleanup")
-
-	bookinfo := examples.Bookinfo{Namespace: "bookinfo"}
-	bookinfo.Uninstall()
-
-	util.Shell(`kubectl -n %s delete secret cacerts`, meshNamespace)
-	util.Shell(`kubectl -n %s patch smcp/%s --type=json -p='[{"op": "remove", "path": "/spec/security/certificateAuthority"}, {"op": "remove", "path": "/spec/security/dataPlane"}]'`, meshNamespace, smcpName)
-	util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName)
-	time.Sleep(time.Duration(60) * time.Second)
+	util.Shell(`kubectl -n %s delete secret cacerts`, util.GetConfig().MeshNamespace)
 }
 
 func TestExternalCert(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	smcpName := util.GetConfig().SMCPName
+	ns := util.Namespace(t, "bookinfo")
+	util.PreserveSMCP(t, meshNamespace, smcpName)
 	defer cleanupExternalCert()
 
 	util.Log.Info("Test External Certificates")
@@ -50,22 +59,26 @@ func TestExternalCert(t *testing.T) {
 		util.Shell(`kubectl create -n %s secret generic cacerts --from-file=%s --from-file=%s --from-file=%s --from-file=%s`,
 			meshNamespace, sampleCACert, sampleCAKey, sampleCARoot, sampleCAChain)
 
-		util.Shell(`kubectl -n %s patch smcp/%s --type=merge --patch="%s"`, meshNamespace, smcpName, CertSMCPPath)
-		util.Shell(`oc -n %s wait --for condition=Ready smcp/%s --timeout 180s`, meshNamespace, smcpName)
-		time.Sleep(time.Duration(60) * time.Second)
+		if _, err := util.Kubectl("patch", "smcp", smcpName).Namespace(meshNamespace).Patch(util.MergePatch, CertSMCPPath).Run(); err != nil {
+			t.Fatalf("Failed to plug in the external CA: %v", err)
+		}
+		// istiod is restarted to load the plugged-in CA
+		if err := wait.UpTo(util.SMCPTimeout, wait.All(wait.SMCPReady(meshNamespace, smcpName), wait.DeploymentRolledOut(meshNamespace, "istiod-"+smcpName))); err != nil {
+			t.Fatal(err)
+		}
 
-		bookinfo := examples.Bookinfo{Namespace: "bookinfo"}
+		bookinfo := examples.Bookinfo{Namespace: ns}
 		bookinfo.Install(true)
 
-		productPod, err := util.GetPodName("bookinfo", "app=productpage")
+		productPod, err := util.GetPodName(ns, "app=productpage")
 		util.Inspect(err, "Failed to get productpage pod name", "", t)
 
 		tmpDir, err := ioutil.TempDir("", "cacerts")
 		util.Inspect(err, "Failed to create temp dir", "", t)
 		defer os.RemoveAll(tmpDir)
 
-		if util.Getenv("SAMPLEARCH", "x86") == "p" || util.Getenv("SAMPLEARCH", "x86") == "z" {
-			gatewayHTTP, _ := util.ShellSilent(`kubectl get routes -n %s istio-ingressgateway -o jsonpath='{.spec.host}'`, meshNamespace)
+		if util.GetConfig().SampleArch == "p" || util.GetConfig().SampleArch == "z" {
+			gatewayHTTP := util.TestEnv(t).GatewayHost
 			productpageURL := fmt.Sprintf("http://%s/productpage", gatewayHTTP)
 			resp, _, err := util.GetHTTPResponse(productpageURL, nil)
 			util.Inspect(err, "Failed to get HTTP Response", "", t)
@@ -74,7 +87,7 @@ func TestExternalCert(t *testing.T) {
 			util.Log.Info("Verify the new certificates")
 
 			// Generate the cert files
-			util.ShellMuteOutput(`oc -n bookinfo exec %s -c istio-proxy -- openssl s_client -showcerts -connect details:9080 > %s/bookinfo-proxy-cert.txt`, productPod, tmpDir)
+			util.ShellMuteOutput(`oc -n %s exec %s -c istio-proxy -- openssl s_client -showcerts -connect details:9080 > %s/bookinfo-proxy-cert.txt`, ns, productPod, tmpDir)
 			_, err = util.ShellMuteOutput(`sed -n '/-----BEGIN CERTIFICATE-----/{:start /-----END CERTIFICATE-----/!{N;b start};/.*/p}' %s/bookinfo-proxy-cert.txt > %s/certs.pem`, tmpDir, tmpDir)
 			util.Inspect(err, "Failed to parse 'openssl s_client' output", "", t)
 			_, err = util.ShellMuteOutput(`awk 'BEGIN {counter=0;} /BEGIN CERT/{counter++} { print > "%s/proxy-cert-" counter ".pem"}' < %s/certs.pem`, tmpDir, tmpDir)
@@ -20,47 +20,49 @@ import (
 	"strconv"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupCircuitBreaking() {
-	util.Log.Info("Cleanup")
-	util.KubeDeleteContents("bookinfo", httpbinCircuitBreaker)
-	fortio := examples.Fortio{"bookinfo"}
-	httpbin := examples.Httpbin{"bookinfo"}
-	fortio.Uninstall()
-	httpbin.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T6",
+		Title:      "Circuit breaking",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Test:       TestCircuitBreaking,
+	})
 }
 
 func TestCircuitBreaking(t *testing.T) {
-	defer cleanupCircuitBreaking()
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestCircuitBreaking")
-	fortio := examples.Fortio{"bookinfo"}
-	httpbin := examples.Httpbin{"bookinfo"}
+	fortio := examples.Fortio{Namespace: ns}
+	httpbin := examples.Httpbin{Namespace: ns}
 	httpbin.Install()
 	fortio.Install()
 
 	t.Run("TrafficManagement_tripping_circuit_breaker", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
-		if err := util.KubeApplyContents("bookinfo", httpbinCircuitBreaker); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=fortio"})
+		if _, err := util.KubeApplyContents(ns, httpbinCircuitBreaker); err != nil {
 			t.Errorf("Failed to configure circuit breaker")
 			util.Log.Errorf("Failed to configure circuit breaker")
 		}
-		time.Sleep(time.Duration(10) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "DestinationRule", Namespace: ns, Name: "httpbin"})
 
 		// verify curl
-		pod, err := util.GetPodName("bookinfo", "app=fortio")
+		pod, err := util.GetPodName(ns, "app=fortio")
 		util.Inspect(err, "failed to get fortio pod", "", t)
 
 		command := `/usr/bin/fortio curl -quiet http://httpbin:8000/get`
-		msg, err := util.PodExec("bookinfo", pod, "fortio", command, false)
+		msg, err := util.PodExec(ns, pod, "fortio", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "200 OK") {
 			util.Log.Infof("Success. Get correct response")
@@ -75,7 +77,7 @@ func TestCircuitBreaking(t *testing.T) {
 		tolerance := 0.5
 
 		command = fmt.Sprintf(`/usr/bin/fortio load -c %d -qps 0 -n %d -loglevel Warning http://httpbin:8000/get`, connection, reqCount)
-		msg, err = util.PodExec("bookinfo", pod, "fortio", command, false)
+		msg, err = util.PodExec(ns, pod, "fortio", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 
 		re := regexp.MustCompile(`Code 200.*`)
@@ -104,7 +106,7 @@ func TestCircuitBreaking(t *testing.T) {
 
 		util.Log.Info("Query the istio-proxy stats")
 		command = fmt.Sprintf(`pilot-agent request GET stats | grep httpbin | grep pending`)
-		msg, err = util.PodExec("bookinfo", pod, "istio-proxy", command, false)
+		msg, err = util.PodExec(ns, pod, "istio-proxy", command, false)
 		util.Log.Infof("%s", msg)
 	})
 }
@@ -17,30 +17,32 @@ package egress
 import (
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupAccessExternalServices() {
-	util.Log.Info("Cleanup")
-	sleep := examples.Sleep{"bookinfo"}
-	util.KubeDeleteContents("bookinfo", httpbinextTimeout)
-	util.KubeDeleteContents("bookinfo", redhatextServiceEntry)
-	util.KubeDeleteContents("bookinfo", httbinextServiceEntry)
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T11",
+		Title:      "Accessing external services",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.EgressGateway},
+		Test:       TestAccessExternalServices,
+	})
 }
 
 func TestAccessExternalServices(t *testing.T) {
-	defer cleanupAccessExternalServices()
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestAccessExternalServices")
-	sleep := examples.Sleep{"bookinfo"}
+	sleep := examples.Sleep{Namespace: ns}
 	sleep.Install()
-	sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+	sleepPod, err := util.GetPodName(ns, "app=sleep")
 	util.Inspect(err, "Failed to get sleep pod name", "", t)
 
 	t.Run("TrafficManagement_egress_envoy_passthrough_to_external_services", func(t *testing.T) {
@@ -49,7 +51,7 @@ func TestAccessExternalServices(t *testing.T) {
 		util.Log.Info("Skip checking the meshConfig outboundTrafficPolicy mode")
 		util.Log.Info("make requests to external https services")
 		command := `curl -sSI https://www.redhat.com/en | grep  "HTTP/"`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "200") {
 			util.Log.Infof("Success. Get https://www.redhat.com/en response: %s", msg)
@@ -64,10 +66,11 @@ func TestAccessExternalServices(t *testing.T) {
 
 		util.Log.Info("Skip update global.outboundTrafficPolicy.mode")
 		util.Log.Info("Create a ServiceEntry to external httpbin")
-		util.KubeApplyContents("bookinfo", httbinextServiceEntry)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+		util.KubeApplyContents(ns, httbinextServiceEntry)
+		waitForConfig(t, sync, wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "httpbin-ext"})
 		command := `curl -sS http://httpbin.org/headers`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		if err != nil {
 			util.Log.Infof("Error response: %s", msg)
 			t.Errorf("Error response: %s", msg)
@@ -80,10 +83,11 @@ func TestAccessExternalServices(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Create a ServiceEntry to external https://www.redhat.com/en")
-		util.KubeApplyContents("bookinfo", redhatextServiceEntry)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+		util.KubeApplyContents(ns, redhatextServiceEntry)
+		waitForConfig(t, sync, wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "redhat"})
 		command := `curl -sSI https://www.redhat.com/en | grep  "HTTP/"`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "200") {
 			util.Log.Infof("Success. Get https://www.redhat.com/en response: %s", msg)
@@ -97,10 +101,11 @@ func TestAccessExternalServices(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Create a httpbin-ext timeout")
-		util.KubeApplyContents("bookinfo", httpbinextTimeout)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+		util.KubeApplyContents(ns, httpbinextTimeout)
+		waitForConfig(t, sync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "httpbin-ext"})
 		command := `time curl -o /dev/null -sS -w "%{http_code}\n" http://httpbin.org/delay/5`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "504") {
 			util.Log.Infof("Get expected response failure: %s", msg)
@@ -21,28 +21,30 @@ import (
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupEgressGateways() {
-	util.Log.Info("Cleanup")
-	sleep := examples.Sleep{"bookinfo"}
-	util.KubeDeleteContents("bookinfo", util.RunTemplate(ExGatewayHTTPSTemplate, smcp))
-	util.KubeDeleteContents("bookinfo", util.RunTemplate(ExGatewayTemplate, smcp))
-	util.KubeDeleteContents("bookinfo", ExServiceEntryTLS)
-	util.KubeDeleteContents("bookinfo", ExServiceEntry)
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T13",
+		Title:      "Egress gateways",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.EgressGateway},
+		Test:       TestEgressGateways,
+	})
 }
 
 func TestEgressGateways(t *testing.T) {
-	defer cleanupEgressGateways()
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestEgressGateways")
-	sleep := examples.Sleep{"bookinfo"}
+	sleep := examples.Sleep{Namespace: ns}
 	sleep.Install()
-	sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+	sleepPod, err := util.GetPodName(ns, "app=sleep")
 	util.Inspect(err, "Failed to get sleep pod name", "", t)
 	proxy, _ := util.GetProxy()
 	curlParams := ""
@@ -55,10 +57,11 @@ func TestEgressGateways(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Create a ServiceEntry to external istio.io")
-		util.KubeApplyContents("bookinfo", ExServiceEntry)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := beforeEgressChange(ns)
+		util.KubeApplyContents(ns, ExServiceEntry)
+		waitForConfig(t, sync, wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"})
 		command := fmt.Sprintf(`curl -sSL -o /dev/null %s -D - http://istio.io`, curlParams)
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") {
 			util.Log.Info("Success. Get http://istio.io response")
@@ -68,11 +71,12 @@ func TestEgressGateways(t *testing.T) {
 		}
 
 		util.Log.Info("Create a Gateway to external istio.io")
-		util.KubeApplyContents("bookinfo", util.RunTemplate(ExGatewayTemplate, smcp))
-		time.Sleep(time.Duration(20) * time.Second)
+		sync = beforeEgressChange(ns)
+		util.KubeApplyTemplate(ns, ExGatewayTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync, istioIOGatewayResources(ns)...)
 
 		command = fmt.Sprintf(`curl -sSL -o /dev/null %s -D - http://istio.io`, curlParams)
-		msg, err = util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err = util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") {
 			util.Log.Infof("Success. Get http://istio.io response: %s", msg)
@@ -81,20 +85,22 @@ func TestEgressGateways(t *testing.T) {
 			t.Errorf("Error response: %s", msg)
 		}
 
-		util.KubeDeleteContents("bookinfo", util.RunTemplate(ExGatewayTemplate, smcp))
-		util.KubeDeleteContents("bookinfo", ExServiceEntry)
-		time.Sleep(time.Duration(20) * time.Second)
+		sync = beforeEgressChange(ns)
+		util.KubeDeleteTemplate(ns, ExGatewayTemplate, util.GetConfig().ControlPlane())
+		util.KubeDeleteContents(ns, ExServiceEntry)
+		waitForConfig(t, sync, append(istioIOGatewayResources(ns), wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"})...)
 	})
 
 	t.Run("TrafficManagement_egress_gateway_for_https_traffic", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Create a TLS ServiceEntry to external istio.io")
-		util.KubeApplyContents("bookinfo", ExServiceEntryTLS)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := beforeEgressChange(ns)
+		util.KubeApplyContents(ns, ExServiceEntryTLS)
+		waitForConfig(t, sync, wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"})
 
 		command := `curl -sSL -o /dev/null -D - https://istio.io`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") || !strings.Contains(msg, "200") {
 			util.Log.Infof("Error response: %s", msg)
@@ -104,11 +110,12 @@ func TestEgressGateways(t *testing.T) {
 		}
 
 		util.Log.Info("Create a https Gateway to external istio.io")
-		util.KubeApplyContents("bookinfo", util.RunTemplate(ExGatewayHTTPSTemplate, smcp))
-		time.Sleep(time.Duration(20) * time.Second)
+		sync = beforeEgressChange(ns)
+		util.KubeApplyTemplate(ns, ExGatewayHTTPSTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync, istioIOGatewayResources(ns)...)
 
 		command = `curl -sSL -o /dev/null -D - https://istio.io`
-		msg, err = util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err = util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") || !strings.Contains(msg, "200") {
 			util.Log.Infof("Error response: %s", msg)
@@ -118,3 +125,44 @@ func TestEgressGateways(t *testing.T) {
 		}
 	})
 }
+
+// istioIOGatewayResources returns the resources routing the requests to istio.io through the egress gateway.
+func istioIOGatewayResources(ns string) []wait.Resource {
+	return []wait.Resource{
+		{Kind: "Gateway", Namespace: ns, Name: "istio-egressgateway"},
+		{Kind: "DestinationRule", Namespace: ns, Name: "egressgateway-for-istio-io"},
+		{Kind: "VirtualService", Namespace: ns, Name: "direct-istio-io-through-egress-gateway"},
+	}
+}
+
+// tlsOriginationResources returns the resources routing the requests to istio.io through the egress gateway,
+// which originates TLS.
+func tlsOriginationResources(ns string) []wait.Resource {
+	return append(istioIOGatewayResources(ns), wait.Resource{Kind: "DestinationRule", Namespace: ns, Name: "originate-tls-for-edition-istio-io"})
+}
+
+// nginxGatewayResources returns the resources routing the requests to the nginx server in mesh-external through
+// the egress gateway.
+func nginxGatewayResources(ns string) []wait.Resource {
+	return []wait.Resource{
+		{Kind: "Gateway", Namespace: ns, Name: "istio-egressgateway"},
+		{Kind: "DestinationRule", Namespace: ns, Name: "egressgateway-for-nginx"},
+		{Kind: "VirtualService", Namespace: ns, Name: "direct-nginx-through-egress-gateway"},
+	}
+}
+
+// beforeEgressChange records the state of the proxies of the sleep pod and of the egress gateway, which the
+// egress configuration of the tests applies to, before a change of the configuration.
+func beforeEgressChange(ns string) *wait.ConfigSync {
+	return wait.BeforeConfigChange(
+		wait.Proxies{Namespace: ns, Selector: "app=sleep"},
+		wait.Proxies{Namespace: util.GetConfig().MeshNamespace, Selector: "istio=egressgateway"})
+}
+
+// waitForConfig waits until the proxies tracked by sync received the applied resources, or dropped the
+// deleted ones. The test fails if they do not, as its requests would see the previous configuration.
+func waitForConfig(t *testing.T, sync *wait.ConfigSync, resources ...wait.Resource) {
+	if err := wait.UpTo(2*time.Minute, sync.Propagated(resources...)); err != nil {
+		t.Fatal(err)
+	}
+}
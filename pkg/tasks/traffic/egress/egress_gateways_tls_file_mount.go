@@ -18,10 +18,11 @@ import (
 	"fmt"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
 var (
@@ -69,47 +70,64 @@ var (
 `
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T14",
+		Title:      "Egress gateway TLS origination with file mount",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z, suite.Disruptive},
+		Namespaces: []string{"bookinfo", "mesh-external"},
+		Resources:  []suite.Resource{suite.SMCP},
+		Test:       TestTLSOriginationFileMount,
+	})
+}
+
 func cleanupTLSOriginationFileMount() {
+	meshNamespace := util.GetConfig().MeshNamespace
 	util.Log.Info("Cleanup")
-	sleep := examples.Sleep{"bookinfo"}
-	nginx := examples.Nginx{Namespace: "mesh-external"}
 	util.KubeDeleteContents(meshNamespace, nginxMeshRule)
 	util.KubeDeleteContents(meshNamespace, meshExternalServiceEntry)
-	util.KubeDeleteContents("bookinfo", util.RunTemplate(nginxGatewayTLSTemplate, smcp))
 
 	util.Shell(`kubectl -n %s rollout undo deploy istio-egressgateway`, meshNamespace)
-	time.Sleep(time.Duration(20) * time.Second)
-	util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 180s`, meshNamespace, smcpName)
+	if err := waitForEgressGateway(); err != nil {
+		util.Log.Error(err)
+	}
 	util.Shell(`kubectl -n %s rollout history deploy istio-egressgateway`, meshNamespace)
 
 	util.Shell(`kubectl delete -n %s secret nginx-client-certs`, meshNamespace)
 	util.Shell(`kubectl delete -n %s secret nginx-ca-certs`, meshNamespace)
-	util.KubeDeleteContents("bookinfo", util.RunTemplate(ExGatewayTLSFileTemplate, smcp))
-	util.KubeDeleteContents("bookinfo", ExServiceEntry)
-	nginx.Uninstall()
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+}
+
+// waitForEgressGateway waits until the patched egress gateway deployment is rolled out and the SMCP is ready again.
+func waitForEgressGateway() error {
+	meshNamespace := util.GetConfig().MeshNamespace
+	return wait.UpTo(util.SMCPTimeout, wait.All(
+		wait.DeploymentRolledOut(meshNamespace, "istio-egressgateway"),
+		wait.SMCPReady(meshNamespace, util.GetConfig().SMCPName)))
 }
 
 func TestTLSOriginationFileMount(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	ns := util.Namespace(t, "bookinfo")
+	meshExternal := util.Namespace(t, "mesh-external")
 	defer cleanupTLSOriginationFileMount()
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestEgressGatewaysTLSOrigination File Mount")
-	sleep := examples.Sleep{"bookinfo"}
+	sleep := examples.Sleep{Namespace: ns}
 	sleep.Install()
-	sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+	sleepPod, err := util.GetPodName(ns, "app=sleep")
 	util.Inspect(err, "Failed to get sleep pod name", "", t)
 
 	t.Run("TrafficManagement_egress_gateway_perform_TLS_origination", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Perform TLS origination with an egress gateway")
-		util.KubeApplyContents("bookinfo", ExServiceEntry)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := beforeEgressChange(ns)
+		util.KubeApplyContents(ns, ExServiceEntry)
+		waitForConfig(t, sync, wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"})
 
 		command := `curl -sSL -o /dev/null -D - http://istio.io`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") {
 			util.Log.Info("Success. Get http://istio.io response")
@@ -119,11 +137,12 @@ func TestTLSOriginationFileMount(t *testing.T) {
 		}
 
 		util.Log.Info("Create a Gateway to external istio.io")
-		util.KubeApplyContents("bookinfo", util.RunTemplate(ExGatewayTLSFileTemplate, smcp))
-		time.Sleep(time.Duration(20) * time.Second)
+		sync = beforeEgressChange(ns)
+		util.KubeApplyTemplate(ns, ExGatewayTLSFileTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync, tlsOriginationResources(ns)...)
 
 		command = `curl -sSL -o /dev/null -D - http://istio.io`
-		msg, err = util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err = util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") || !strings.Contains(msg, "200") {
 			util.Log.Infof("Error response: %s", msg)
@@ -133,17 +152,18 @@ func TestTLSOriginationFileMount(t *testing.T) {
 		}
 
 		util.Log.Info("Cleanup the TLS origination example")
-		util.KubeDeleteContents("bookinfo", util.RunTemplate(ExGatewayTLSFileTemplate, smcp))
-		util.KubeDeleteContents("bookinfo", ExServiceEntry)
-		time.Sleep(time.Duration(20) * time.Second)
+		sync = beforeEgressChange(ns)
+		util.KubeDeleteTemplate(ns, ExGatewayTLSFileTemplate, util.GetConfig().ControlPlane())
+		util.KubeDeleteContents(ns, ExServiceEntry)
+		waitForConfig(t, sync, append(tlsOriginationResources(ns), wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"})...)
 	})
 
 	t.Run("TrafficManagement_egress_gateway_perform_MTLS_origination", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Deploy nginx mtls server")
-		nginx := examples.Nginx{Namespace: "mesh-external"}
-		nginx.Install_mTLS("../testdata/examples/x86/nginx/nginx_mesh_external_ssl.conf")
+		nginx := examples.Nginx{Namespace: meshExternal}
+		nginx.Install_mTLS(util.SampleFile("nginx/nginx_mesh_external_ssl.conf"))
 
 		util.Log.Info("Redeploy the egress gateway with the client certs")
 		util.Shell(`kubectl create -n %s secret tls nginx-client-certs --key %s --cert %s`, meshNamespace, nginxClientCertKey, nginxClientCert)
@@ -151,9 +171,12 @@ func TestTLSOriginationFileMount(t *testing.T) {
 
 		util.Log.Info("Patch egress gateway")
 		util.Shell(`kubectl -n %s rollout history deploy istio-egressgateway`, meshNamespace)
-		util.Shell(`kubectl -n %s patch --type=json deploy istio-egressgateway -p='%s'`, meshNamespace, strings.ReplaceAll(gatewayPatchAdd, "\n", ""))
-		time.Sleep(time.Duration(20) * time.Second)
-		util.Shell(`oc wait --for condition=Ready -n %s smcp/%s --timeout 180s`, meshNamespace, smcpName)
+		if _, err := util.Kubectl("patch", "deploy", "istio-egressgateway").Namespace(meshNamespace).Patch(util.JSONPatch, gatewayPatchAdd).Run(); err != nil {
+			t.Fatalf("Failed to patch the egress gateway: %v", err)
+		}
+		if err := waitForEgressGateway(); err != nil {
+			t.Fatal(err)
+		}
 		util.Log.Info("Verify the istio-egressgateway pod")
 		util.Shell(`kubectl exec -n %s "$(kubectl -n %s get pods -l %s -o jsonpath='{.items[0].metadata.name}')" -- ls -al %s %s`,
 			meshNamespace, meshNamespace,
@@ -163,14 +186,17 @@ func TestTLSOriginationFileMount(t *testing.T) {
 		util.Shell(`kubectl -n %s rollout history deploy istio-egressgateway`, meshNamespace)
 
 		util.Log.Info("Configure MTLS origination for egress traffic")
-		util.KubeApplyContents("bookinfo", util.RunTemplate(nginxGatewayTLSTemplate, smcp))
+		sync := beforeEgressChange(ns)
+		util.KubeApplyTemplate(ns, nginxGatewayTLSTemplate, util.GetConfig().ControlPlane())
 		util.KubeApplyContents(meshNamespace, meshExternalServiceEntry)
 		util.KubeApplyContents(meshNamespace, nginxMeshRule)
-		time.Sleep(time.Duration(10) * time.Second)
+		waitForConfig(t, sync, append(nginxGatewayResources(ns),
+			wait.Resource{Kind: "ServiceEntry", Namespace: meshNamespace, Name: "mynginx-mesh-external"},
+			wait.Resource{Kind: "DestinationRule", Namespace: meshNamespace, Name: "originate-mtls-for-nginx"})...)
 
 		util.Log.Info("Verify NGINX server")
 		cmd := fmt.Sprintf(`curl -sS http://my-nginx.mesh-external.svc.cluster.local`)
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", cmd, true)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "failed to get response", "", t)
 		if !strings.Contains(msg, "Welcome to nginx") {
 			t.Errorf("Expected Welcome to nginx; Got unexpected response: %s", msg)
@@ -18,45 +18,54 @@ import (
 	"fmt"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T15",
+		Title:      "Egress gateway TLS origination with SDS",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo", "mesh-external"},
+		Resources:  []suite.Resource{suite.EgressGateway},
+		Test:       TestTLSOriginationSDS,
+	})
+}
+
 func cleanupTLSOriginationSDS() {
+	meshNamespace := util.GetConfig().MeshNamespace
 	util.Log.Info("Cleanup")
 	util.KubeDeleteContents(meshNamespace, OriginateSDS)
 	util.KubeDeleteContents(meshNamespace, meshExternalServiceEntry)
-	util.KubeDeleteContents("bookinfo", util.RunTemplate(EgressGatewaySDSTemplate, smcp))
 	util.Shell(`kubectl delete -n %s secret client-credential`, meshNamespace)
-	util.KubeDeleteContents("bookinfo", util.RunTemplate(ExGatewayTLSFileTemplate, smcp))
-	util.KubeDeleteContents("bookinfo", ExServiceEntry)
-	sleep := examples.Sleep{"bookinfo"}
-	nginx := examples.Nginx{"mesh-external"}
-	sleep.Uninstall()
-	nginx.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
 }
 
 func TestTLSOriginationSDS(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	ns := util.Namespace(t, "bookinfo")
+	meshExternal := util.Namespace(t, "mesh-external")
 	defer cleanupTLSOriginationSDS()
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestEgressGatewaysTLSOrigination SDS")
-	sleep := examples.Sleep{"bookinfo"}
+	sleep := examples.Sleep{Namespace: ns}
 	sleep.Install()
-	sleepPod, _ := util.GetPodName("bookinfo", "app=sleep")
+	sleepPod, _ := util.GetPodName(ns, "app=sleep")
 
 	t.Run("TrafficManagement_egress_gateway_perform_TLS_origination", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Perform TLS origination with an egress gateway")
-		util.KubeApplyContents("bookinfo", ExServiceEntry)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := beforeEgressChange(ns)
+		util.KubeApplyContents(ns, ExServiceEntry)
+		waitForConfig(t, sync, wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"})
 
 		command := `curl -sSL -o /dev/null -D - http://istio.io`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") {
 			util.Log.Info("Success. Get http://istio.io response")
@@ -66,11 +75,12 @@ func TestTLSOriginationSDS(t *testing.T) {
 		}
 
 		util.Log.Info("Create a Gateway to external istio.io")
-		util.KubeApplyContents("bookinfo", util.RunTemplate(ExGatewayTLSFileTemplate, smcp))
-		time.Sleep(time.Duration(20) * time.Second)
+		sync = beforeEgressChange(ns)
+		util.KubeApplyTemplate(ns, ExGatewayTLSFileTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync, tlsOriginationResources(ns)...)
 
 		command = `curl -sSL -o /dev/null -D - http://istio.io`
-		msg, err = util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err = util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") || !strings.Contains(msg, "200") {
 			util.Log.Infof("Error response: %s", msg)
@@ -80,17 +90,18 @@ func TestTLSOriginationSDS(t *testing.T) {
 		}
 
 		util.Log.Info("Cleanup the TLS origination example")
-		util.KubeDeleteContents("bookinfo", util.RunTemplate(ExGatewayTLSFileTemplate, smcp))
-		util.KubeDeleteContents("bookinfo", ExServiceEntry)
-		time.Sleep(time.Duration(20) * time.Second)
+		sync = beforeEgressChange(ns)
+		util.KubeDeleteTemplate(ns, ExGatewayTLSFileTemplate, util.GetConfig().ControlPlane())
+		util.KubeDeleteContents(ns, ExServiceEntry)
+		waitForConfig(t, sync, append(tlsOriginationResources(ns), wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"})...)
 	})
 
 	t.Run("TrafficManagement_egress_gateway_perform_mtls_origination", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Deploy nginx mtls server")
-		nginx := examples.Nginx{Namespace: "mesh-external"}
-		nginx.Install_mTLS("../testdata/examples/x86/nginx/nginx_mesh_external_ssl.conf")
+		nginx := examples.Nginx{Namespace: meshExternal}
+		nginx.Install_mTLS(util.SampleFile("nginx/nginx_mesh_external_ssl.conf"))
 
 		util.Log.Info("Create client cert secret")
 		util.Shell(`kubectl create secret -n %s generic client-credential --from-file=tls.key=%s --from-file=tls.crt=%s --from-file=ca.crt=%s`,
@@ -100,14 +111,17 @@ func TestTLSOriginationSDS(t *testing.T) {
 			nginxServerCACert)
 
 		util.Log.Info("Configure MTLS origination for egress traffic")
-		util.KubeApplyContents("bookinfo", util.RunTemplate(EgressGatewaySDSTemplate, smcp))
+		sync := beforeEgressChange(ns)
+		util.KubeApplyTemplate(ns, EgressGatewaySDSTemplate, util.GetConfig().ControlPlane())
 		util.KubeApplyContents(meshNamespace, meshExternalServiceEntry)
 		util.KubeApplyContents(meshNamespace, OriginateSDS)
-		time.Sleep(time.Duration(10) * time.Second)
+		waitForConfig(t, sync, append(nginxGatewayResources(ns),
+			wait.Resource{Kind: "ServiceEntry", Namespace: meshNamespace, Name: "mynginx-mesh-external"},
+			wait.Resource{Kind: "DestinationRule", Namespace: meshNamespace, Name: "originate-tls-for-nginx"})...)
 
 		util.Log.Info("Verify NGINX server")
 		cmd := fmt.Sprintf(`curl -sS http://my-nginx.mesh-external.svc.cluster.local`)
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", cmd, true)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", cmd, true)
 		util.Inspect(err, "failed to get response", "", t)
 		if !strings.Contains(msg, "Welcome to nginx") {
 			t.Errorf("Expected Welcome to nginx; Got unexpected response: %s", msg)
@@ -18,37 +18,41 @@ import (
 	"fmt"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupEgressTLSOrigination() {
-	util.Log.Info("Cleanup")
-	sleep := examples.Sleep{"bookinfo"}
-	util.KubeDeleteContents("bookinfo", ExServiceEntryOriginate)
-	util.KubeDeleteContents("bookinfo", ExServiceEntry)
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T12",
+		Title:      "Egress TLS origination",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.EgressGateway},
+		Test:       TestEgressTLSOrigination,
+	})
 }
 
 func TestEgressTLSOrigination(t *testing.T) {
-	defer cleanupEgressTLSOrigination()
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestEgressTLSOrigination")
-	sleep := examples.Sleep{"bookinfo"}
+	sleep := examples.Sleep{Namespace: ns}
 	sleep.Install()
-	sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+	sleepPod, err := util.GetPodName(ns, "app=sleep")
 	util.Inspect(err, "Failed to get sleep pod name", "", t)
 
 	t.Run("TrafficManagement_egress_configure_access_to_external_service", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Create a ServiceEntry to external istio.io")
-		util.KubeApplyContents("bookinfo", ExServiceEntry)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+		util.KubeApplyContents(ns, ExServiceEntry)
+		waitForConfig(t, sync, wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"})
 		proxy, _ := util.GetProxy()
 		curlParams := ""
 		if proxy.HTTPProxy == "" {
@@ -57,7 +61,7 @@ func TestEgressTLSOrigination(t *testing.T) {
 			curlParams = curlParams + " -x " + proxy.HTTPProxy
 		}
 		command := fmt.Sprintf(`curl -sSL -o /dev/null %s -D - http://istio.io`, curlParams)
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") {
 			util.Log.Info("Success. Get http://istio.io response")
@@ -71,11 +75,14 @@ func TestEgressTLSOrigination(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("TLS origination for egress traffic")
-		util.KubeApplyContents("bookinfo", ExServiceEntryOriginate)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+		util.KubeApplyContents(ns, ExServiceEntryOriginate)
+		waitForConfig(t, sync,
+			wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "istio-io"},
+			wait.Resource{Kind: "DestinationRule", Namespace: ns, Name: "edition-istio-io"})
 
 		command := `curl -sSL -o /dev/null -D - http://istio.io`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") || strings.Contains(msg, "503 Service Unavailable") {
 			util.Log.Infof("Error response: %s", msg)
@@ -85,7 +92,7 @@ func TestEgressTLSOrigination(t *testing.T) {
 		}
 
 		command = `curl -sSL -o /dev/null -D - https://istio.io`
-		msg, err = util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err = util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "301 Moved Permanently") || strings.Contains(msg, "503 Service Unavailable") {
 			util.Log.Infof("Error response: %s", msg)
@@ -17,40 +17,44 @@ package egress
 import (
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupEgressWildcard() {
-	util.Log.Info("Cleanup")
-	util.KubeDeleteContents("bookinfo", util.RunTemplate(EgressWildcardGatewayTemplate, smcp))
-	util.KubeDeleteContents("bookinfo", EgressWildcardEntry)
-	sleep := examples.Sleep{"bookinfo"}
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T16",
+		Title:      "Egress wildcard hosts",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.EgressGateway},
+		Test:       TestEgressWildcard,
+	})
 }
 
 func TestEgressWildcard(t *testing.T) {
-	defer cleanupEgressWildcard()
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Test Egress Wildcard Hosts")
-	sleep := examples.Sleep{"bookinfo"}
+	sleep := examples.Sleep{Namespace: ns}
 	sleep.Install()
-	sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+	sleepPod, err := util.GetPodName(ns, "app=sleep")
 	util.Inspect(err, "Failed to get sleep pod name", "", t)
 
 	t.Run("TrafficManagement_egress_direct_traffic_wildcard_host", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Configure direct traffic to a wildcard host")
-		util.KubeApplyContents("bookinfo", EgressWildcardEntry)
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+		util.KubeApplyContents(ns, EgressWildcardEntry)
+		waitForConfig(t, sync, wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "wikipedia"})
 
 		command := `curl -s https://en.wikipedia.org/wiki/Main_Page | grep -o "<title>.*</title>"; curl -s https://de.wikipedia.org/wiki/Wikipedia:Hauptseite | grep -o "<title>.*</title>"`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "<title>Wikipedia, the free encyclopedia</title>\n<title>Wikipedia – Die freie Enzyklopädie</title>") {
 			util.Log.Infof("Success. Got Wikipedia response: %s", msg)
@@ -59,18 +63,23 @@ func TestEgressWildcard(t *testing.T) {
 			t.Errorf("Error response: %s", msg)
 		}
 
-		util.KubeDeleteContents("bookinfo", EgressWildcardEntry)
+		util.KubeDeleteContents(ns, EgressWildcardEntry)
 	})
 
 	t.Run("TrafficManagement_egress_gateway_wildcard_host", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Configure egress gateway to a wildcard host")
-		util.KubeApplyContents("bookinfo", util.RunTemplate(EgressWildcardGatewayTemplate, smcp))
-		time.Sleep(time.Duration(10) * time.Second)
+		sync := beforeEgressChange(ns)
+		util.KubeApplyTemplate(ns, EgressWildcardGatewayTemplate, util.GetConfig().ControlPlane())
+		waitForConfig(t, sync,
+			wait.Resource{Kind: "Gateway", Namespace: ns, Name: "istio-egressgateway"},
+			wait.Resource{Kind: "DestinationRule", Namespace: ns, Name: "egressgateway-for-wikipedia"},
+			wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "direct-wikipedia-through-egress-gateway"},
+			wait.Resource{Kind: "ServiceEntry", Namespace: ns, Name: "www-wikipedia"})
 
 		command := `curl -s https://en.wikipedia.org/wiki/Main_Page | grep -o "<title>.*</title>"; curl -s https://de.wikipedia.org/wiki/Wikipedia:Hauptseite | grep -o "<title>.*</title>"`
-		msg, err := util.PodExec("bookinfo", sleepPod, "sleep", command, false)
+		msg, err := util.PodExec(ns, sleepPod, "sleep", command, false)
 		util.Inspect(err, "Failed to get response", "", t)
 		if strings.Contains(msg, "<title>Wikipedia, the free encyclopedia</title>\n<title>Wikipedia – Die freie Enzyklopädie</title>") {
 			util.Log.Infof("Success. Got Wikipedia response: %s", msg)
@@ -79,7 +88,7 @@ func TestEgressWildcard(t *testing.T) {
 			t.Errorf("Error response: %s", msg)
 		}
 
-		util.KubeDeleteContents("bookinfo", util.RunTemplate(EgressWildcardGatewayTemplate, smcp))
+		util.KubeDeleteTemplate(ns, EgressWildcardGatewayTemplate, util.GetConfig().ControlPlane())
 	})
 
 	// setup SNI proxy for wildcard arbitrary domains
@@ -18,86 +18,89 @@ import (
 	"fmt"
 	"io/ioutil"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupFaultInjection() {
-	util.Log.Info("Cleanup")
-	app := examples.Bookinfo{"bookinfo"}
-	util.KubeDelete("bookinfo", bookinfoAllv1Yaml)
-	app.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T2",
+		Title:      "Fault injection",
+		Tags:       []string{suite.ARM, suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway},
+		Test:       TestFaultInjection,
+	})
 }
 
 func TestFaultInjection(t *testing.T) {
-	defer cleanupFaultInjection()
+	gatewayHTTP := util.TestEnv(t).GatewayHost
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestFaultInjection")
-	app := examples.Bookinfo{"bookinfo"}
+	app := examples.Bookinfo{Namespace: ns}
 	app.Install(false)
 	productpageURL := fmt.Sprintf("http://%s/productpage", gatewayHTTP)
 	testUserJar := util.GetCookieJar(testUsername, "", "http://"+gatewayHTTP)
 
-	if err := util.KubeApply("bookinfo", bookinfoAllv1Yaml); err != nil {
+	sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=productpage"})
+	if _, err := util.KubeApply(ns, util.SampleFile(bookinfoAllv1Yaml)); err != nil {
 		t.Errorf("Failed to route traffic to all v1: %s", err)
 		util.Log.Errorf("Failed to route traffic to all v1: %s", err)
 	}
-	if err := util.KubeApply("bookinfo", bookinfoReviewV2Yaml); err != nil {
+	if _, err := util.KubeApply(ns, util.SampleFile(bookinfoReviewV2Yaml)); err != nil {
 		t.Errorf("Failed to route traffic based on user: %s", err)
 		util.Log.Errorf("Failed to route traffic based on user: %s", err)
 	}
-	time.Sleep(time.Duration(5) * time.Second)
+	waitForConfig(t, sync, bookinfoVirtualServices(ns)...)
 
 	t.Run("TrafficManagement_injecting_an_HTTP_delay_fault", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
-		if err := util.KubeApply("bookinfo", bookinfoRatingDelayYaml); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=reviews"})
+		if _, err := util.KubeApply(ns, util.SampleFile(bookinfoRatingDelayYaml)); err != nil {
 			t.Errorf("Failed to inject http delay fault: %s", err)
 			util.Log.Errorf("Failed to inject http delay fault: %s", err)
 		}
-		time.Sleep(time.Duration(5) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "ratings"})
 
 		minDuration := 4000
 		maxDuration := 14000
-		standby := 10
 
-		for i := 0; i < 5; i++ {
-			resp, duration, err := util.GetHTTPResponse(productpageURL, testUserJar)
-			defer util.CloseResponseBody(resp)
-			util.Log.Infof("bookinfo productpage returned in %d ms", duration)
-			body, err := ioutil.ReadAll(resp.Body)
-			util.Inspect(err, "Failed to read response body", "", t)
-			util.Inspect(
-				util.CompareHTTPResponse(body, "productpage-test-user-v2-review-timeout.html"),
-				"Didn't get expected response.",
-				"Success. HTTP_delay_fault.",
-				t)
+		resp, duration, err := util.GetHTTPResponse(productpageURL, testUserJar)
+		defer util.CloseResponseBody(resp)
+		util.Log.Infof("bookinfo productpage returned in %d ms", duration)
+		body, err := ioutil.ReadAll(resp.Body)
+		util.Inspect(err, "Failed to read response body", "", t)
+		util.Inspect(
+			util.CompareHTTPResponse(body, "productpage-test-user-v2-review-timeout.html"),
+			"Didn't get expected response.",
+			"Success. HTTP_delay_fault.",
+			t)
 
-			if err == nil && duration >= minDuration && duration <= maxDuration {
-				util.Log.Info("Success. Fault delay as expected")
-				break
-			} else if i >= 4 {
-				t.Errorf("Fault delay failed. Delay in %d ms while expected between %d ms and %d ms, %s",
-					duration, minDuration, maxDuration, err)
-				util.Log.Errorf("Fault delay failed. Delay in %d ms while expected between %d ms and %d ms, %s",
-					duration, minDuration, maxDuration, err)
-			}
-			time.Sleep(time.Duration(standby) * time.Second)
+		if duration >= minDuration && duration <= maxDuration {
+			util.Log.Info("Success. Fault delay as expected")
+		} else {
+			t.Errorf("Fault delay failed. Delay in %d ms while expected between %d ms and %d ms",
+				duration, minDuration, maxDuration)
+			util.Log.Errorf("Fault delay failed. Delay in %d ms while expected between %d ms and %d ms",
+				duration, minDuration, maxDuration)
 		}
 	})
 
 	t.Run("TrafficManagement_injecting_an_HTTP_abort_fault", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
-		if err := util.KubeApply("bookinfo", bookinfoRatingAbortYaml); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=reviews"})
+		if _, err := util.KubeApply(ns, util.SampleFile(bookinfoRatingAbortYaml)); err != nil {
 			t.Errorf("Failed to inject http abort fault: %s", err)
 			util.Log.Errorf("Failed to inject http abort fault: %s", err)
 		}
-		time.Sleep(time.Duration(5) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "ratings"})
 
 		resp, duration, err := util.GetHTTPResponse(productpageURL, testUserJar)
 		defer util.CloseResponseBody(resp)
@@ -20,33 +20,39 @@ import (
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupIngressGateways() {
-	util.Log.Info("Cleanup")
-	httpbin := examples.Httpbin{"bookinfo"}
-	util.KubeDeleteContents("bookinfo", httpbinGateway1)
-	httpbin.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T8",
+		Title:      "Ingress gateways",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway},
+		Test:       TestIngressGateways,
+	})
 }
 
 func TestIngressGateways(t *testing.T) {
-	defer cleanupIngressGateways()
+	gatewayHTTP := util.TestEnv(t).GatewayHost
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestIngressGateways")
-	httpbin := examples.Httpbin{"bookinfo"}
+	httpbin := examples.Httpbin{Namespace: ns}
 	httpbin.Install()
 
 	t.Run("TrafficManagement_ingress_status_200_test", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
-		if err := util.KubeApplyContents("bookinfo", httpbinGateway1); err != nil {
+		if _, err := util.KubeApplyContents(ns, httpbinGateway1); err != nil {
 			t.Errorf("Failed to configure Gateway")
 			util.Log.Errorf("Failed to configure Gateway")
 		}
-		time.Sleep(time.Duration(20) * time.Second)
+		waitForGateway(t, wait.HTTPStatus(fmt.Sprintf("http://%s/status/200", gatewayHTTP), "httpbin.example.com", 200))
 
 		resp, err := util.GetWithHost(fmt.Sprintf("http://%s/status/200", gatewayHTTP), "httpbin.example.com")
 		defer util.CloseResponseBody(resp)
@@ -57,11 +63,11 @@ func TestIngressGateways(t *testing.T) {
 	t.Run("TrafficManagement_ingress_headers_test", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
-		if err := util.KubeApplyContents("bookinfo", httpbinGateway2); err != nil {
+		if _, err := util.KubeApplyContents(ns, httpbinGateway2); err != nil {
 			t.Errorf("Failed to configure Gateway")
 			util.Log.Errorf("Failed to configure Gateway")
 		}
-		time.Sleep(time.Duration(10) * time.Second)
+		waitForGateway(t, wait.HTTPStatus(fmt.Sprintf("http://%s/headers", gatewayHTTP), "", 200))
 
 		resp, duration, err := util.GetHTTPResponse(fmt.Sprintf("http://%s/headers", gatewayHTTP), nil)
 		defer util.CloseResponseBody(resp)
@@ -70,3 +76,24 @@ func TestIngressGateways(t *testing.T) {
 		util.Inspect(util.CheckHTTPResponse200(resp), "Failed to get HTTP 200", resp.Status, t)
 	})
 }
+
+// waitForGateway waits until the applied gateway configuration takes effect, as observed by a request through
+// the gateway it changes the result of.
+func waitForGateway(t *testing.T, cond wait.Condition) {
+	if err := wait.UpTo(2*time.Minute, cond); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// beforeGatewayChange records the state of the ingress gateway proxies before a change of their configuration.
+func beforeGatewayChange() *wait.ConfigSync {
+	return wait.BeforeConfigChange(wait.Proxies{Namespace: util.GetConfig().MeshNamespace, Selector: "istio=ingressgateway"})
+}
+
+// waitForConfig waits until the proxies tracked by sync received the applied resources, for the changes a request
+// with a Host header cannot observe, e.g. of the TLS settings of a gateway. The test fails if they do not arrive.
+func waitForConfig(t *testing.T, sync *wait.ConfigSync, resources ...wait.Resource) {
+	if err := wait.UpTo(2*time.Minute, sync.Propagated(resources...)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -19,32 +19,39 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupIngressWithoutTLS() {
-	util.Log.Info("Cleanup")
-	util.KubeDeleteContents("bookinfo", nginxIngressGateway)
-	nginx := examples.Nginx{"bookinfo"}
-	nginx.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T10",
+		Title:      "Ingress gateway without TLS termination",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway},
+		Test:       TestIngressWithoutTLS,
+	})
 }
 
 func TestIngressWithoutTLS(t *testing.T) {
-	defer cleanupIngressWithoutTLS()
+	env := util.TestEnv(t)
+	gatewayHTTP := env.GatewayHost
+	secureIngressPort := env.SecureIngressPort
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestIngressWithOutTLS Termination")
-	nginx := examples.Nginx{"bookinfo"}
-	nginx.Install("../testdata/examples/x86/nginx/nginx.conf")
+	nginx := examples.Nginx{Namespace: ns}
+	nginx.Install(util.SampleFile("nginx/nginx.conf"))
 
 	util.Log.Info("Verify NGINX server")
-	pod, err := util.GetPodName("bookinfo", "run=my-nginx")
+	pod, err := util.GetPodName(ns, "run=my-nginx")
 	cmd := fmt.Sprintf(`curl -sS -v -k --resolve nginx.example.com:8443:127.0.0.1 https://nginx.example.com:8443`)
-	msg, err := util.PodExec("bookinfo", pod, "istio-proxy", cmd, true)
+	msg, err := util.PodExec(ns, pod, "istio-proxy", cmd, true)
 	util.Inspect(err, "failed to get response", "", t)
 	if !strings.Contains(msg, "Welcome to nginx") {
 		t.Errorf("Expected Welcome to nginx; Got unexpected response: %s", msg)
@@ -57,11 +64,14 @@ func TestIngressWithoutTLS(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Configure an ingress gateway")
-		if err := util.KubeApplyContents("bookinfo", nginxIngressGateway); err != nil {
+		sync := beforeGatewayChange()
+		if _, err := util.KubeApplyContents(ns, nginxIngressGateway); err != nil {
 			t.Errorf("Failed to configure NGINX ingress gateway")
 			util.Log.Errorf("Failed to configure NGINX ingress gateway")
 		}
-		time.Sleep(time.Duration(30) * time.Second)
+		waitForConfig(t, sync,
+			wait.Resource{Kind: "Gateway", Namespace: ns, Name: "mygateway"},
+			wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "nginx"})
 
 		url := "https://nginx.example.com:" + secureIngressPort
 		resp, err := util.CurlWithCA(url, gatewayHTTP, secureIngressPort, "nginx.example.com", nginxServerCACert)
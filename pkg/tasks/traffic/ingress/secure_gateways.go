@@ -21,39 +21,46 @@ import (
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T9",
+		Title:      "Secure gateways",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway},
+		Test:       TestSecureGateways,
+	})
+}
+
 func cleanupSecureGateways() {
+	meshNamespace := util.GetConfig().MeshNamespace
 	util.Log.Info("Cleanup")
-	httpbin := examples.Httpbin{"bookinfo"}
-	util.KubeDeleteContents("bookinfo", httpbinTLSGatewayMTLS)
-	util.KubeDeleteContents("bookinfo", multiHostsGateway)
-	util.KubeDeleteContents("bookinfo", httpbinTLSGatewayHTTPS)
 	util.ShellMuteOutput(`kubectl delete secret %s -n %s`, "httpbin-credential", meshNamespace)
 	util.ShellMuteOutput(`kubectl delete secret %s -n %s`, "helloworld-credential", meshNamespace)
-	util.KubeDeleteContents("bookinfo", helloworldv1)
-	httpbin.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
 }
 
 func TestSecureGateways(t *testing.T) {
+	meshNamespace := util.GetConfig().MeshNamespace
+	env := util.TestEnv(t)
+	gatewayHTTP := env.GatewayHost
+	secureIngressPort := env.SecureIngressPort
+	ns := util.Namespace(t, "bookinfo")
 	defer cleanupSecureGateways()
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("Test Secure Gateways")
-	httpbin := examples.Httpbin{"bookinfo"}
+	httpbin := examples.Httpbin{Namespace: ns}
 	httpbin.Install()
 
-	if util.Getenv("SAMPLEARCH", "x86") == "p" {
-		util.KubeApplyContents("bookinfo", helloworldv1P)
-	} else if util.Getenv("SAMPLEARCH", "x86") == "z" {
-		util.KubeApplyContents("bookinfo", helloworldv1Z)
-	} else {
-		util.KubeApplyContents("bookinfo", helloworldv1)
+	util.KubeApplyTemplate(ns, helloworldv1, nil)
+	if err := wait.UpTo(2*time.Minute, wait.DeploymentRolledOut(ns, "helloworld-v1")); err != nil {
+		t.Fatal(err)
 	}
-	util.CheckPodRunning("bookinfo", "app=helloworld-v1")
-	time.Sleep(time.Duration(10) * time.Second)
 
 	util.Log.Info("Create TLS secrets")
 	if _, err := util.CreateTLSSecret("httpbin-credential", meshNamespace, httpbinSampleServerCertKey, httpbinSampleServerCert); err != nil {
@@ -64,18 +71,20 @@ func TestSecureGateways(t *testing.T) {
 		t.Errorf("Failed to create secret %s\n", "helloworld-credential ")
 		util.Log.Infof("Failed to create secret %s\n", "helloworld-credential ")
 	}
-	time.Sleep(time.Duration(10) * time.Second)
 
 	t.Run("TrafficManagement_ingress_single_host_tls_test", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Configure a TLS ingress gateway for a single host")
 		// config https gateway
-		if err := util.KubeApplyContents("bookinfo", httpbinTLSGatewayHTTPS); err != nil {
+		sync := beforeGatewayChange()
+		if _, err := util.KubeApplyContents(ns, httpbinTLSGatewayHTTPS); err != nil {
 			t.Errorf("Failed to configure Gateway")
 			util.Log.Errorf("Failed to configure Gateway")
 		}
-		time.Sleep(time.Duration(30) * time.Second)
+		waitForConfig(t, sync,
+			wait.Resource{Kind: "Gateway", Namespace: ns, Name: "mygateway"},
+			wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "httpbin"})
 
 		// check teapot
 		url := "https://httpbin.example.com:" + secureIngressPort + "/status/418"
@@ -97,11 +106,14 @@ func TestSecureGateways(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Configure multiple hosts Gateway")
-		if err := util.KubeApplyContents("bookinfo", multiHostsGateway); err != nil {
+		sync := beforeGatewayChange()
+		if _, err := util.KubeApplyContents(ns, multiHostsGateway); err != nil {
 			t.Errorf("Failed to configure multihosts Gateway")
 			util.Log.Errorf("Failed to configure multihosts Gateway")
 		}
-		time.Sleep(time.Duration(30) * time.Second)
+		waitForConfig(t, sync,
+			wait.Resource{Kind: "Gateway", Namespace: ns, Name: "mygateway"},
+			wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "helloworld-v1"})
 
 		util.Log.Info("Check helloworld")
 		url := "https://helloworld-v1.example.com:" + secureIngressPort + "/hello"
@@ -130,6 +142,7 @@ func TestSecureGateways(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Configure Mutual TLS Gateway")
+		sync := beforeGatewayChange()
 		util.ShellMuteOutput(`kubectl delete secret %s -n %s`, "httpbin-credential", meshNamespace)
 		// create ca secret
 		_, err := util.ShellMuteOutput(`kubectl create secret generic %s --from-file=tls.key=%s --from-file=tls.crt=%s --from-file=ca.crt=%s -n %s`,
@@ -138,14 +151,13 @@ func TestSecureGateways(t *testing.T) {
 			util.Log.Infof("Failed to create generic secret %s\n", "httpbin-credential")
 			t.Errorf("Failed to generic create secret %s\n", "httpbin-credential")
 		}
-		time.Sleep(time.Duration(10) * time.Second)
 
 		// config mutual tls
-		if err := util.KubeApplyContents("bookinfo", httpbinTLSGatewayMTLS); err != nil {
+		if _, err := util.KubeApplyContents(ns, httpbinTLSGatewayMTLS); err != nil {
 			t.Errorf("Failed to configure Gateway")
 			util.Log.Errorf("Failed to configure Gateway")
 		}
-		time.Sleep(time.Duration(30) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "Gateway", Namespace: ns, Name: "mygateway"})
 
 		util.Log.Info("Check SSL handshake failure as expected")
 		url := "https://httpbin.example.com:" + secureIngressPort + "/status/418"
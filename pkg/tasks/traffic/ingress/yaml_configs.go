@@ -166,93 +166,7 @@ spec:
     spec:
       containers:
       - name: helloworld
-        image: istio/examples-helloworld-v1
-        resources:
-          requests:
-            cpu: "100m"
-        imagePullPolicy: IfNotPresent #Always
-        ports:
-        - containerPort: 5000
-`
-
-	helloworldv1P = `
-apiVersion: v1
-kind: Service
-metadata:
-  name: helloworld-v1
-  labels:
-    app: helloworld-v1
-spec:
-  ports:
-  - name: http
-    port: 5000
-  selector:
-    app: helloworld-v1
----
-apiVersion: apps/v1
-kind: Deployment
-metadata:
-  name: helloworld-v1
-spec:
-  replicas: 1
-  selector:
-    matchLabels:
-      app: helloworld-v1
-      version: v1
-  template:
-    metadata:
-      annotations:
-        sidecar.istio.io/inject: "true"
-      labels:
-        app: helloworld-v1
-        version: v1
-    spec:
-      containers:
-      - name: helloworld
-        image: quay.io/maistra/helloworld-v1:0.0-ibm-p
-        resources:
-          requests:
-            cpu: "100m"
-        imagePullPolicy: IfNotPresent #Always
-        ports:
-        - containerPort: 5000
-`
-
-	helloworldv1Z = `
-apiVersion: v1
-kind: Service
-metadata:
-  name: helloworld-v1
-  labels:
-    app: helloworld-v1
-spec:
-  ports:
-  - name: http
-    port: 5000
-  selector:
-    app: helloworld-v1
----
-apiVersion: apps/v1
-kind: Deployment
-metadata:
-  name: helloworld-v1
-spec:
-  replicas: 1
-  selector:
-    matchLabels:
-      app: helloworld-v1
-      version: v1
-  template:
-    metadata:
-      annotations:
-        sidecar.istio.io/inject: "true"
-      labels:
-        app: helloworld-v1
-        version: v1
-    spec:
-      containers:
-      - name: helloworld
-        image: quay.io/maistra/helloworld-v1:0.0-ibm-z
+        image: {{ sampleImage "helloworld-v1" }}
         resources:
           requests:
             cpu: "100m"
@@ -14,10 +14,6 @@
 
 package ingress
 
-import (
-	"github.com/maistra/maistra-test-tool/pkg/util"
-)
-
 const (
 	httpbinSampleServerCertKey = "../sampleCerts/httpbin.example.com/httpbin.example.com.key"
 	httpbinSampleServerCert    = "../sampleCerts/httpbin.example.com/httpbin.example.com.crt"
@@ -34,12 +30,3 @@ const (
 
 	testUsername = "jason"
 )
-
-var (
-	// OCP4.x
-	smcpName             string = util.Getenv("SMCPNAME", "basic")
-	meshNamespace        string = util.Getenv("MESHNAMESPACE", "istio-system")
-	gatewayHTTP, _              = util.ShellSilent(`kubectl get routes -n %s istio-ingressgateway -o jsonpath='{.spec.host}'`, meshNamespace)
-	ingressHTTPPort, _          = util.ShellSilent(`kubectl -n %s get service %s -o jsonpath='{.spec.ports[?(@.name=="http2")].port}'`, meshNamespace, "istio-ingressgateway")
-	secureIngressPort, _        = util.ShellSilent(`kubectl -n %s get service %s -o jsonpath='{.spec.ports[?(@.name=="https")].port}'`, meshNamespace, "istio-ingressgateway")
-)
@@ -21,23 +21,29 @@ import (
 	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupRequestRouting() {
-	util.Log.Info("Cleanup")
-	app := examples.Bookinfo{"bookinfo"}
-	util.KubeDelete("bookinfo", bookinfoAllv1Yaml)
-	app.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T1",
+		Title:      "Request routing",
+		Tags:       []string{suite.Smoke, suite.ARM, suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway},
+		Test:       TestRequestRouting,
+	})
 }
 
 func TestRequestRouting(t *testing.T) {
-	defer cleanupRequestRouting()
+	gatewayHTTP := util.TestEnv(t).GatewayHost
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestRequestRouting")
-	app := examples.Bookinfo{"bookinfo"}
+	app := examples.Bookinfo{Namespace: ns}
 	app.Install(false)
 	productpageURL := fmt.Sprintf("http://%s/productpage", gatewayHTTP)
 	testUserJar := util.GetCookieJar(testUsername, "", "http://"+gatewayHTTP)
@@ -46,11 +52,12 @@ func TestRequestRouting(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Routing traffic to all v1")
-		if err := util.KubeApply("bookinfo", bookinfoAllv1Yaml); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=productpage"})
+		if _, err := util.KubeApply(ns, util.SampleFile(bookinfoAllv1Yaml)); err != nil {
 			t.Errorf("Failed to route traffic to all v1: %s", err)
 			util.Log.Errorf("Failed to route traffic to all v1: %s", err)
 		}
-		time.Sleep(time.Duration(20) * time.Second)
+		waitForConfig(t, sync, bookinfoVirtualServices(ns)...)
 
 		for i := 0; i <= 5; i++ {
 			resp, duration, err := util.GetHTTPResponse(productpageURL, nil)
@@ -71,11 +78,12 @@ func TestRequestRouting(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Traffic routing based on user identity")
-		if err := util.KubeApply("bookinfo", bookinfoReviewV2Yaml); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=productpage"})
+		if _, err := util.KubeApply(ns, util.SampleFile(bookinfoReviewV2Yaml)); err != nil {
 			t.Errorf("Failed to route traffic based on user: %s", err)
 			util.Log.Errorf("Failed to route traffic based on user: %s", err)
 		}
-		time.Sleep(time.Duration(20) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "reviews"})
 
 		for i := 0; i <= 5; i++ {
 			resp, duration, err := util.GetHTTPResponse(productpageURL, testUserJar)
@@ -92,3 +100,20 @@ func TestRequestRouting(t *testing.T) {
 		}
 	})
 }
+
+// bookinfoVirtualServices returns the virtual services of the bookinfo routing samples.
+func bookinfoVirtualServices(ns string) []wait.Resource {
+	var resources []wait.Resource
+	for _, name := range []string{"productpage", "reviews", "ratings", "details"} {
+		resources = append(resources, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: name})
+	}
+	return resources
+}
+
+// waitForConfig waits until the proxies tracked by sync received the applied resources. The test fails
+// if they do not arrive, as the requests of the test would see the previous configuration.
+func waitForConfig(t *testing.T, sync *wait.ConfigSync, resources ...wait.Resource) {
+	if err := wait.UpTo(2*time.Minute, sync.Propagated(resources...)); err != nil {
+		t.Fatal(err)
+	}
+}
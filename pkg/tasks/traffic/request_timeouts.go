@@ -18,50 +18,57 @@ import (
 	"fmt"
 	"io/ioutil"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupRequestTimeouts() {
-	util.Log.Info("Cleanup")
-	app := examples.Bookinfo{"bookinfo"}
-	util.KubeDelete("bookinfo", bookinfoAllv1Yaml)
-	app.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T5",
+		Title:      "Request timeouts",
+		Tags:       []string{suite.ARM, suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway},
+		Test:       TestRequestTimeouts,
+	})
 }
 
 func TestRequestTimeouts(t *testing.T) {
-	defer cleanupRequestTimeouts()
+	gatewayHTTP := util.TestEnv(t).GatewayHost
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Infof("TestRequestTimeouts")
-	app := examples.Bookinfo{"bookinfo"}
+	app := examples.Bookinfo{Namespace: ns}
 	app.Install(false)
 	productpageURL := fmt.Sprintf("http://%s/productpage", gatewayHTTP)
 
-	if err := util.KubeApply("bookinfo", bookinfoAllv1Yaml); err != nil {
+	if _, err := util.KubeApply(ns, util.SampleFile(bookinfoAllv1Yaml)); err != nil {
 		t.Errorf("Failed to route traffic to all v1")
 		util.Log.Errorf("Failed to route traffic to all v1")
 	}
 
 	t.Run("TrafficManagement_request_delay", func(t *testing.T) {
 		defer util.RecoverPanic(t)
-		if err := util.KubeApplyContents("bookinfo", ratingsDelay2); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=productpage"}, wait.Proxies{Namespace: ns, Selector: "app=reviews"})
+		if _, err := util.KubeApplyContents(ns, ratingsDelay2); err != nil {
 			t.Errorf("Failed to inject delay")
 			util.Log.Errorf("Failed to inject delay")
 		}
-		time.Sleep(time.Duration(5) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "reviews"}, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "ratings"})
 	})
 
 	t.Run("TrafficManagement_request_timeouts", func(t *testing.T) {
 		defer util.RecoverPanic(t)
-		if err := util.KubeApplyContents("bookinfo", reviewTimeout); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=productpage"})
+		if _, err := util.KubeApplyContents(ns, reviewTimeout); err != nil {
 			t.Errorf("Failed to set timeouts")
 			util.Log.Errorf("Failed to set timeouts")
 		}
-		time.Sleep(time.Duration(5) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "reviews"})
 
 		resp, duration, err := util.GetHTTPResponse(productpageURL, nil)
 		defer util.CloseResponseBody(resp)
@@ -17,30 +17,30 @@ package traffic
 import (
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupMirroring() {
-	util.Log.Info("Cleanup")
-	httpbin := examples.Httpbin{"bookinfo"}
-	sleep := examples.Sleep{"bookinfo"}
-	util.KubeDeleteContents("bookinfo", httpbinAllv1)
-	httpbin.UninstallV1()
-	httpbin.UninstallV2()
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T7",
+		Title:      "Traffic mirroring",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Test:       TestMirroring,
+	})
 }
 
 func TestMirroring(t *testing.T) {
-	defer cleanupMirroring()
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestMirroring")
-	httpbin := examples.Httpbin{"bookinfo"}
-	sleep := examples.Sleep{"bookinfo"}
+	httpbin := examples.Httpbin{Namespace: ns}
+	sleep := examples.Sleep{Namespace: ns}
 	httpbin.InstallV1()
 	httpbin.InstallV2()
 	sleep.Install()
@@ -48,26 +48,27 @@ func TestMirroring(t *testing.T) {
 	t.Run("TrafficManagement_creating_a_default_routing_policy", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
-		if err := util.KubeApplyContents("bookinfo", httpbinAllv1); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+		if _, err := util.KubeApplyContents(ns, httpbinAllv1); err != nil {
 			t.Errorf("Failed to apply httpbin all v1")
 			util.Log.Errorf("Failed to apply httpbin all v1")
 		}
-		time.Sleep(time.Duration(10) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "httpbin"})
 
-		sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+		sleepPod, err := util.GetPodName(ns, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
-		_, err = util.PodExec("bookinfo", sleepPod, "sleep", `curl -sS http://httpbin:8000/headers`, false)
+		_, err = util.PodExec(ns, sleepPod, "sleep", `curl -sS http://httpbin:8000/headers`, false)
 		util.Inspect(err, "Failed to get sleep curl response", "", t)
 
 		// check httpbin v1 logs
-		v1Pod, err := util.GetPodName("bookinfo", "app=httpbin,version=v1")
+		v1Pod, err := util.GetPodName(ns, "app=httpbin,version=v1")
 		util.Inspect(err, "Failed to get httpbin v1 pod name", "", t)
-		v1msg, err := util.Shell("kubectl logs -n %s --follow=false %s -c %s", "bookinfo", v1Pod, "httpbin")
+		v1msg, err := util.Shell("kubectl logs -n %s --follow=false %s -c %s", ns, v1Pod, "httpbin")
 		util.Inspect(err, "Failed to get httpbin v1 log", "", t)
 		// check httpbin v2 logs
-		v2Pod, err := util.GetPodName("bookinfo", "app=httpbin,version=v2")
+		v2Pod, err := util.GetPodName(ns, "app=httpbin,version=v2")
 		util.Inspect(err, "Failed to get httpbin v2 pod name", "", t)
-		v2msg, err := util.Shell("kubectl logs -n %s --follow=false %s -c %s", "bookinfo", v2Pod, "httpbin")
+		v2msg, err := util.Shell("kubectl logs -n %s --follow=false %s -c %s", ns, v2Pod, "httpbin")
 		util.Inspect(err, "Failed to get httpbin v2 log", "", t)
 		if strings.Contains(v1msg, `"GET /headers HTTP/1.1" 200`) && !strings.Contains(v2msg, `"GET /headers HTTP/1.1" 200`) {
 			util.Log.Info("Success. v1 an v2 logs are expected")
@@ -80,26 +81,27 @@ func TestMirroring(t *testing.T) {
 	t.Run("TrafficManagement_mirroring_traffic_to_v2", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
-		if err := util.KubeApplyContents("bookinfo", httpbinMirrorv2); err != nil {
+		sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+		if _, err := util.KubeApplyContents(ns, httpbinMirrorv2); err != nil {
 			t.Errorf("Failed to apply httpbin mirror v2")
 			util.Log.Errorf("Failed to apply httpbin mirror v2")
 		}
-		time.Sleep(time.Duration(10) * time.Second)
+		waitForConfig(t, sync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "httpbin"})
 
-		sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+		sleepPod, err := util.GetPodName(ns, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
-		_, err = util.PodExec("bookinfo", sleepPod, "sleep", `curl -sS http://httpbin:8000/headers`, false)
+		_, err = util.PodExec(ns, sleepPod, "sleep", `curl -sS http://httpbin:8000/headers`, false)
 		util.Inspect(err, "Failed to get sleep curl response", "", t)
 
 		// check httpbin v1 logs
-		v1Pod, err := util.GetPodName("bookinfo", "app=httpbin,version=v1")
+		v1Pod, err := util.GetPodName(ns, "app=httpbin,version=v1")
 		util.Inspect(err, "Failed to get httpbin v1 pod name", "", t)
-		v1msg, err := util.Shell("kubectl logs -n %s --follow=false %s -c %s", "bookinfo", v1Pod, "httpbin")
+		v1msg, err := util.Shell("kubectl logs -n %s --follow=false %s -c %s", ns, v1Pod, "httpbin")
 		util.Inspect(err, "Failed to get httpbin v1 log", "", t)
 		// check httpbin v2 logs
-		v2Pod, err := util.GetPodName("bookinfo", "app=httpbin,version=v2")
+		v2Pod, err := util.GetPodName(ns, "app=httpbin,version=v2")
 		util.Inspect(err, "Failed to get httpbin v2 pod name", "", t)
-		v2msg, err := util.Shell("kubectl logs -n %s --follow=false %s -c %s", "bookinfo", v2Pod, "httpbin")
+		v2msg, err := util.Shell("kubectl logs -n %s --follow=false %s -c %s", ns, v2Pod, "httpbin")
 		util.Inspect(err, "Failed to get httpbin v2 log", "", t)
 		if strings.Contains(v1msg, `"GET /headers HTTP/1.1" 200`) && strings.Contains(v2msg, `"GET /headers HTTP/1.1" 200`) {
 			util.Log.Info("Success. v1 an v2 logs are expected")
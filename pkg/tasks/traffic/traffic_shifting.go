@@ -19,44 +19,51 @@ import (
 	"io/ioutil"
 	"sync"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
+	"github.com/maistra/maistra-test-tool/pkg/util/wait"
 )
 
-func cleanupTrafficShifting() {
-	util.Log.Info("Cleanup")
-	app := examples.Bookinfo{"bookinfo"}
-	util.KubeDelete("bookinfo", bookinfoAllv1Yaml)
-	app.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T3",
+		Title:      "Traffic shifting",
+		Tags:       []string{suite.ARM, suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Resources:  []suite.Resource{suite.IngressGateway},
+		Test:       TestTrafficShifting,
+	})
 }
 
 func TestTrafficShifting(t *testing.T) {
-	defer cleanupTrafficShifting()
+	gatewayHTTP := util.TestEnv(t).GatewayHost
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestTrafficShifting")
-	app := examples.Bookinfo{"bookinfo"}
+	app := examples.Bookinfo{Namespace: ns}
 	app.Install(false)
 	productpageURL := fmt.Sprintf("http://%s/productpage", gatewayHTTP)
 
-	if err := util.KubeApply("bookinfo", bookinfoAllv1Yaml); err != nil {
+	configSync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=productpage"})
+	if _, err := util.KubeApply(ns, util.SampleFile(bookinfoAllv1Yaml)); err != nil {
 		t.Errorf("Failed to route traffic to all v1: %s", err)
 		util.Log.Errorf("Failed to route traffic to all v1: %s", err)
 	}
-	time.Sleep(time.Duration(5) * time.Second)
+	waitForConfig(t, configSync, bookinfoVirtualServices(ns)...)
 
 	t.Run("TrafficManagement_shift_50_percent_v3_traffic", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("# Traffic shifting 50 percent v1 and 50 percent v3, tolerance 10 percent")
-		if err := util.KubeApply("bookinfo", bookinfoReview50V3Yaml); err != nil {
+		configSync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=productpage"})
+		if _, err := util.KubeApply(ns, util.SampleFile(bookinfoReview50V3Yaml)); err != nil {
 			t.Errorf("Failed to route 50%% traffic to v3: %s", err)
 			util.Log.Errorf("Failed to route 50%% traffic to v3: %s", err)
 		}
-		time.Sleep(time.Duration(5) * time.Second)
+		waitForConfig(t, configSync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "reviews"})
 
 		tolerance := 0.40
 		totalShot := 100
@@ -108,11 +115,12 @@ func TestTrafficShifting(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("# Traffic shifting 100 percent v3, tolerance 0 percent")
-		if err := util.KubeApply("bookinfo", bookinfoReviewV3Yaml); err != nil {
+		configSync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=productpage"})
+		if _, err := util.KubeApply(ns, util.SampleFile(bookinfoReviewV3Yaml)); err != nil {
 			t.Errorf("Failed to route traffic to v3: %s", err)
 			util.Log.Errorf("Failed to route traffic to v3: %s", err)
 		}
-		time.Sleep(time.Duration(5) * time.Second)
+		waitForConfig(t, configSync, wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "reviews"})
 
 		tolerance := 0.0
 		totalShot := 10
@@ -120,7 +128,6 @@ func TestTrafficShifting(t *testing.T) {
 		cVersionToMigrate := 0
 
 		for i := 0; i < totalShot; i++ {
-			time.Sleep(time.Duration(1) * time.Second)
 			resp, _, err := util.GetHTTPResponse(productpageURL, nil)
 			util.Inspect(err, "Failed to get response", "", t)
 			if err := util.CheckHTTPResponse200(resp); err != nil {
@@ -18,42 +18,42 @@ import (
 	"fmt"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/maistra/maistra-test-tool/pkg/examples"
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
-func cleanupTCPShifting() {
-	util.Log.Info("Cleanup")
-	echo := examples.Echo{"bookinfo"}
-	sleep := examples.Sleep{"bookinfo"}
-	util.KubeDelete("bookinfo", echoAllv1Yaml)
-	echo.Uninstall()
-	sleep.Uninstall()
-	time.Sleep(time.Duration(20) * time.Second)
+func init() {
+	suite.Register(suite.TestCase{
+		ID:         "T4",
+		Title:      "TCP traffic shifting",
+		Tags:       []string{suite.Interop, suite.Power, suite.Z},
+		Namespaces: []string{"bookinfo"},
+		Test:       TestTCPShifting,
+	})
 }
 
 func TestTCPShifting(t *testing.T) {
-	defer cleanupTCPShifting()
+	ns := util.Namespace(t, "bookinfo")
 	defer util.RecoverPanic(t)
 
 	util.Log.Info("TestTCPShifting")
-	echo := examples.Echo{"bookinfo"}
-	sleep := examples.Sleep{"bookinfo"}
+	echo := examples.Echo{Namespace: ns}
+	sleep := examples.Sleep{Namespace: ns}
 	echo.Install()
 	sleep.Install()
 
 	t.Run("TrafficManagement_100_percent_v1_tcp_shift_test", func(t *testing.T) {
 		defer util.RecoverPanic(t)
 		util.Log.Info("Shifting all TCP traffic to v1")
-		util.KubeApply("bookinfo", echoAllv1Yaml)
+		util.KubeApply(ns, util.SampleFile(echoAllv1Yaml))
 
-		sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+		sleepPod, err := util.GetPodName(ns, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
 		cmd := fmt.Sprintf(`sh -c "(date; sleep 1) | nc %s %s"`, "tcp-echo", "9000")
 		for i := 0; i < 20; i++ {
-			msg, err := util.PodExec("bookinfo", sleepPod, "sleep", cmd, true)
+			msg, err := util.PodExec(ns, sleepPod, "sleep", cmd, true)
 			util.Inspect(err, "Failed to get response", "", t)
 			if !strings.Contains(msg, "one") {
 				t.Errorf("echo one; Got response: %s", msg)
@@ -68,18 +68,18 @@ func TestTCPShifting(t *testing.T) {
 		defer util.RecoverPanic(t)
 
 		util.Log.Info("Shifting 20%% TCP traffic to v2 tolerance 15%%")
-		util.KubeApply("bookinfo", echo20v2Yaml)
+		util.KubeApply(ns, util.SampleFile(echo20v2Yaml))
 
 		tolerance := 0.15
 		totalShot := 60
 		c1, c2 := 0, 0
 
-		sleepPod, err := util.GetPodName("bookinfo", "app=sleep")
+		sleepPod, err := util.GetPodName(ns, "app=sleep")
 		util.Inspect(err, "Failed to get sleep pod name", "", t)
 		cmd := fmt.Sprintf(`sh -c "(date; sleep 1) | nc %s %s"`, "tcp-echo", "9000")
 
 		for i := 0; i < totalShot; i++ {
-			msg, err := util.PodExec("bookinfo", sleepPod, "sleep", cmd, true)
+			msg, err := util.PodExec(ns, sleepPod, "sleep", cmd, true)
 			util.Inspect(err, "Failed to get response", "", t)
 			if strings.Contains(msg, "one") {
 				c1++
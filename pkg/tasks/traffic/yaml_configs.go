@@ -28,7 +28,7 @@ spec:
     - destination:
         host: reviews
         subset: v2
-
+---
 apiVersion: networking.istio.io/v1alpha3
 kind: VirtualService
 metadata:
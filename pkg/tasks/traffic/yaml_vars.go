@@ -14,30 +14,18 @@
 
 package traffic
 
-import (
-	"github.com/maistra/maistra-test-tool/pkg/util"
-)
-
 const (
-	bookinfoAllv1Yaml       = "../testdata/examples/x86/bookinfo/virtual-service-all-v1.yaml"
-	bookinfoReviewV2Yaml    = "../testdata/examples/x86/bookinfo/virtual-service-reviews-test-v2.yaml"
-	bookinfoRatingDelayYaml = "../testdata/examples/x86/bookinfo/virtual-service-ratings-test-delay.yaml"
-	bookinfoRatingAbortYaml = "../testdata/examples/x86/bookinfo/virtual-service-ratings-test-abort.yaml"
-	bookinfoReview50V3Yaml  = "../testdata/examples/x86/bookinfo/virtual-service-reviews-50-v3.yaml"
-	bookinfoReviewV3Yaml    = "../testdata/examples/x86/bookinfo/virtual-service-reviews-v3.yaml"
+	// sample files, see util.SampleFile
+	bookinfoAllv1Yaml       = "bookinfo/virtual-service-all-v1.yaml"
+	bookinfoReviewV2Yaml    = "bookinfo/virtual-service-reviews-test-v2.yaml"
+	bookinfoRatingDelayYaml = "bookinfo/virtual-service-ratings-test-delay.yaml"
+	bookinfoRatingAbortYaml = "bookinfo/virtual-service-ratings-test-abort.yaml"
+	bookinfoReview50V3Yaml  = "bookinfo/virtual-service-reviews-50-v3.yaml"
+	bookinfoReviewV3Yaml    = "bookinfo/virtual-service-reviews-v3.yaml"
 
 	// OSSM need custom changes in VirtualService tcp-echo
-	echoAllv1Yaml = "../testdata/examples/x86/tcp-echo/tcp-echo-all-v1.yaml"
-	echo20v2Yaml  = "../testdata/examples/x86/tcp-echo/tcp-echo-20-v2.yaml"
+	echoAllv1Yaml = "tcp-echo/tcp-echo-all-v1.yaml"
+	echo20v2Yaml  = "tcp-echo/tcp-echo-20-v2.yaml"
 
 	testUsername = "jason"
 )
-
-var (
-	// OCP4.x
-	smcpName             string = util.Getenv("SMCPNAME", "basic")
-	meshNamespace        string = util.Getenv("MESHNAMESPACE", "istio-system")
-	gatewayHTTP, _              = util.ShellSilent(`kubectl get routes -n %s istio-ingressgateway -o jsonpath='{.spec.host}'`, meshNamespace)
-	ingressHTTPPort, _          = util.ShellSilent(`kubectl -n %s get service %s -o jsonpath='{.spec.ports[?(@.name=="http2")].port}'`, meshNamespace, "istio-ingressgateway")
-	secureIngressPort, _        = util.ShellSilent(`kubectl -n %s get service %s -o jsonpath='{.spec.ports[?(@.name=="https")].port}'`, meshNamespace, "istio-ingressgateway")
-)
@@ -0,0 +1,224 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AuditOutputLimit is the number of bytes of the output of a command kept in the audit trail.
+var AuditOutputLimit = 1024
+
+// AuditEntry is a command in the audit trail, see EnableAudit.
+type AuditEntry struct {
+	// Test is the ID of the test case that ran the command, if it is known, see BindTestLog.
+	Test    string    `json:"test,omitempty"`
+	Command string    `json:"command"`
+	Start   time.Time `json:"start"`
+	// Seconds is the time the command ran, or the time it took to start a background process.
+	Seconds  float64 `json:"seconds"`
+	ExitCode int     `json:"exitCode"`
+	// Error is set if the command could not be started.
+	Error      string `json:"error,omitempty"`
+	Background bool   `json:"background,omitempty"`
+	// Output is the combined output of the command, truncated to AuditOutputLimit bytes.
+	Output string `json:"output,omitempty"`
+}
+
+var (
+	auditMu   sync.Mutex
+	auditFile *os.File
+)
+
+// EnableAudit creates a JSON Lines file and appends every command run by Shell and its variants, PodExec,
+// RunBackground, the command builder and the KubeClient to it from now on, one AuditEntry per line.
+func EnableAudit(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditFile != nil {
+		auditFile.Close()
+	}
+	auditFile = f
+	return nil
+}
+
+// Execute runs a command with the executor set by SetExecutor and adds it to the audit trail. Unlike
+// Shell, it does not log the command.
+func Execute(ctx context.Context, cmd Command) (CommandResult, error) {
+	start := time.Now()
+	res, err := GetExecutor().Execute(ctx, cmd)
+	audit(AuditEntry{
+		Command:  cmd.String(),
+		Start:    start,
+		Seconds:  time.Since(start).Seconds(),
+		ExitCode: res.ExitCode,
+		Output:   res.Output,
+	}, err)
+	return res, err
+}
+
+// audit adds an entry to the audit trail, if it is enabled.
+func audit(e AuditEntry, err error) {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if auditFile == nil {
+		return
+	}
+
+	e.Test = currentTestID()
+	if err != nil && e.ExitCode == -1 {
+		e.Error = err.Error()
+	}
+	if len(e.Output) > AuditOutputLimit {
+		// cut at the start of a character
+		n := AuditOutputLimit
+		for n > 0 && !utf8.RuneStart(e.Output[n]) {
+			n--
+		}
+		e.Output = fmt.Sprintf("%s... (%d bytes truncated)", e.Output[:n], len(e.Output)-n)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if _, err := auditFile.Write(append(data, '\n')); err != nil {
+		Log.Warnf("Failed to write the audit trail: %v", err)
+	}
+}
+
+// LoadAudit reads the entries of audit trail files. Files that do not exist are ignored, e.g. the trail
+// of a retry that did not run.
+func LoadAudit(filenames ...string) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	for _, filename := range filenames {
+		f, err := os.Open(filename)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			var e AuditEntry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s:%d: %v", filename, line, err)
+			}
+			entries = append(entries, e)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// CommandStats is the number of runs and the total time of a command in an AuditSummary.
+type CommandStats struct {
+	Command string
+	Count   int
+	Seconds float64
+}
+
+// AuditSummary shows where the time of a run was spent.
+type AuditSummary struct {
+	Commands int
+	Seconds  float64
+	// Slowest are the slowest runs of commands, slowest first.
+	Slowest []AuditEntry
+	// MostFrequent are the commands run most often, e.g. by polling loops, most frequent first.
+	MostFrequent []CommandStats
+}
+
+// SummarizeAudit returns the n slowest and the n most frequent commands of the entries. Background
+// processes are not included in the slowest commands, their time is only the time to start them.
+func SummarizeAudit(entries []AuditEntry, n int) AuditSummary {
+	s := AuditSummary{Commands: len(entries)}
+	byCommand := map[string]*CommandStats{}
+	var foreground []AuditEntry
+	for _, e := range entries {
+		s.Seconds += e.Seconds
+		stats, ok := byCommand[e.Command]
+		if !ok {
+			stats = &CommandStats{Command: e.Command}
+			byCommand[e.Command] = stats
+		}
+		stats.Count++
+		stats.Seconds += e.Seconds
+		if !e.Background {
+			foreground = append(foreground, e)
+		}
+	}
+
+	sort.SliceStable(foreground, func(i, j int) bool {
+		return foreground[i].Seconds > foreground[j].Seconds
+	})
+	if len(foreground) > n {
+		foreground = foreground[:n]
+	}
+	s.Slowest = foreground
+
+	for _, stats := range byCommand {
+		s.MostFrequent = append(s.MostFrequent, *stats)
+	}
+	sort.Slice(s.MostFrequent, func(i, j int) bool {
+		a, b := s.MostFrequent[i], s.MostFrequent[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Command < b.Command
+	})
+	if len(s.MostFrequent) > n {
+		s.MostFrequent = s.MostFrequent[:n]
+	}
+	return s
+}
+
+// Print logs the summary.
+func (s AuditSummary) Print() {
+	Log.Infof("Commands: %d, total time: %s", s.Commands, seconds(s.Seconds))
+	Log.Info("Slowest commands:")
+	for _, e := range s.Slowest {
+		test := e.Test
+		if test == "" {
+			test = "-"
+		}
+		Log.Infof("  %9s  %-5s %s", seconds(e.Seconds), test, e.Command)
+	}
+	Log.Info("Most frequent commands:")
+	for _, c := range s.MostFrequent {
+		Log.Infof("  %5dx %9s  %s", c.Count, seconds(c.Seconds), c.Command)
+	}
+}
+
+func seconds(s float64) string {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond).String()
+}
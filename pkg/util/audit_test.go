@@ -0,0 +1,143 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSummarizeAudit(t *testing.T) {
+	entries := []AuditEntry{
+		{Command: "oc get smcp", Seconds: 1},
+		{Command: "kubectl apply -f -", Seconds: 5},
+		{Command: "oc get smcp", Seconds: 2},
+		// starting a background process is not slow, the seconds are the time to start it
+		{Command: "kubectl port-forward svc/grafana 3000", Seconds: 9, Background: true},
+		{Command: "oc wait smcp/basic", Seconds: 5},
+		{Command: "kubectl get pods", Seconds: 0.5},
+		{Command: "kubectl get pods", Seconds: 0.5},
+		{Command: "oc get smcp", Seconds: 1},
+		{Command: "oc wait smcp/basic", Seconds: 3},
+	}
+
+	tests := []struct {
+		name         string
+		n            int
+		slowest      []string
+		mostFrequent []CommandStats
+	}{
+		{
+			name: "top 3",
+			n:    3,
+			// runs taking the same time keep their order
+			slowest: []string{"kubectl apply -f - 5s", "oc wait smcp/basic 5s", "oc wait smcp/basic 3s"},
+			// commands run as often are sorted by name
+			mostFrequent: []CommandStats{
+				{Command: "oc get smcp", Count: 3, Seconds: 4},
+				{Command: "kubectl get pods", Count: 2, Seconds: 1},
+				{Command: "oc wait smcp/basic", Count: 2, Seconds: 8},
+			},
+		},
+		{
+			name:    "fewer entries than n",
+			n:       20,
+			slowest: []string{"kubectl apply -f - 5s", "oc wait smcp/basic 5s", "oc wait smcp/basic 3s", "oc get smcp 2s", "oc get smcp 1s", "oc get smcp 1s", "kubectl get pods 500ms", "kubectl get pods 500ms"},
+			mostFrequent: []CommandStats{
+				{Command: "oc get smcp", Count: 3, Seconds: 4},
+				{Command: "kubectl get pods", Count: 2, Seconds: 1},
+				{Command: "oc wait smcp/basic", Count: 2, Seconds: 8},
+				{Command: "kubectl apply -f -", Count: 1, Seconds: 5},
+				{Command: "kubectl port-forward svc/grafana 3000", Count: 1, Seconds: 9},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := SummarizeAudit(entries, tt.n)
+			if s.Commands != len(entries) || s.Seconds != 27 {
+				t.Errorf("got %d commands in %.1fs, want %d in 27s", s.Commands, s.Seconds, len(entries))
+			}
+			var slowest []string
+			for _, e := range s.Slowest {
+				slowest = append(slowest, e.Command+" "+seconds(e.Seconds))
+			}
+			if !reflect.DeepEqual(slowest, tt.slowest) {
+				t.Errorf("got slowest\n%q\nwant\n%q", slowest, tt.slowest)
+			}
+			if !reflect.DeepEqual(s.MostFrequent, tt.mostFrequent) {
+				t.Errorf("got most frequent\n%v\nwant\n%v", s.MostFrequent, tt.mostFrequent)
+			}
+		})
+	}
+
+	// the entries are not reordered
+	if entries[0].Command != "oc get smcp" || entries[1].Command != "kubectl apply -f -" {
+		t.Errorf("the entries were sorted in place")
+	}
+}
+
+func TestAudit(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := EnableAudit(filename); err != nil {
+		t.Fatal(err)
+	}
+	limit := AuditOutputLimit
+	AuditOutputLimit = 8
+	t.Cleanup(func() {
+		auditMu.Lock()
+		auditFile.Close()
+		auditFile = nil
+		auditMu.Unlock()
+		AuditOutputLimit = limit
+	})
+	fake := NewFakeExecutor().
+		On(`^kubectl get pods$`, CommandResult{Output: "NAME      READY"}).
+		On(`^oc whoami$`, CommandResult{Output: "Überprüfung", ExitCode: 1})
+	fakeCommands(t, fake)
+
+	for _, c := range []string{"kubectl get pods", "oc whoami"} {
+		Execute(context.Background(), ShellCommand(c))
+	}
+	audit(AuditEntry{Command: "istioctl version", ExitCode: -1}, errors.New(`exec: "istioctl": executable file not found in $PATH`))
+
+	// missing files are skipped
+	entries, err := LoadAudit(filename, filepath.Join(t.TempDir(), "retry-audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []AuditEntry{
+		{Command: "kubectl get pods", Output: "NAME    ... (7 bytes truncated)"},
+		// "ü" takes the 8th and 9th byte and the cut is moved to the start of the character
+		{Command: "oc whoami", ExitCode: 1, Output: "Überpr... (6 bytes truncated)"},
+		{Command: "istioctl version", ExitCode: -1, Error: `exec: "istioctl": executable file not found in $PATH`},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Start.IsZero() && !strings.HasPrefix(e.Command, "istioctl") {
+			t.Errorf("%s: no start time", e.Command)
+		}
+		if e.Command != w.Command || e.ExitCode != w.ExitCode || e.Output != w.Output || e.Error != w.Error {
+			t.Errorf("got %+v, want %+v", e, w)
+		}
+	}
+}
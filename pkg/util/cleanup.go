@@ -0,0 +1,184 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// LeakedResourcePrefix starts the test log message reporting a resource that could not be removed.
+const LeakedResourcePrefix = "Leaked resource "
+
+// appliedLine matches the objects reported by kubectl apply and create, e.g. "deployment.apps/productpage-v1 created".
+// Objects that existed before are reported as configured or unchanged instead.
+var appliedLine = regexp.MustCompile(`^(\S+/\S+) (created|configured|unchanged|serverside-applied)$`)
+
+// TrackedResource is an object created by a test.
+type TrackedResource struct {
+	Namespace string
+	// Name is the type and name as printed by kubectl, e.g. "deployment.apps/productpage-v1".
+	Name string
+}
+
+func (r TrackedResource) String() string {
+	return r.Namespace + "/" + r.Name
+}
+
+// CleanupStack records the objects created in the namespaces of a test and deletes them in reverse order
+// when the test completes.
+type CleanupStack struct {
+	mu        sync.Mutex
+	resources []TrackedResource
+}
+
+var (
+	stacksMu sync.Mutex
+	// stacks maps a namespace to the cleanup stack of the test owning it.
+	stacks = map[string]*CleanupStack{}
+	// testStacks maps the name of a top level test to its cleanup stack.
+	testStacks = map[string]*CleanupStack{}
+)
+
+// TrackResources starts tracking the objects created by KubeApply and friends in the given namespaces
+// on behalf of t. They are deleted when t completes, in reverse order of their creation, and any object
+// that could not be deleted is reported in the test log. Objects created in shared namespaces, e.g.
+// gateways and secrets in the control plane namespace, are tracked as well while t is the only running
+// test case, see TrackCreated. Changes of the SMCP are reverted by PreserveSMCP instead.
+func TrackResources(t *testing.T, namespaces ...string) *CleanupStack {
+	s := &CleanupStack{}
+	name := topLevelTest(t.Name())
+
+	stacksMu.Lock()
+	for _, ns := range namespaces {
+		stacks[ns] = s
+	}
+	testStacks[name] = s
+	stacksMu.Unlock()
+
+	t.Cleanup(func() {
+		stacksMu.Lock()
+		for _, ns := range namespaces {
+			if stacks[ns] == s {
+				delete(stacks, ns)
+			}
+		}
+		if testStacks[name] == s {
+			delete(testStacks, name)
+		}
+		stacksMu.Unlock()
+
+		for _, leaked := range s.Teardown() {
+			t.Logf("%s%s", LeakedResourcePrefix, leaked)
+		}
+	})
+	return s
+}
+
+// Push adds a resource to the stack, unless it is already on it.
+func (s *CleanupStack) Push(r TrackedResource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.resources {
+		if existing == r {
+			return
+		}
+	}
+	s.resources = append(s.resources, r)
+}
+
+// Teardown deletes the resources in reverse order and waits until they are gone. It returns the
+// resources that could not be deleted, each with the reason.
+func (s *CleanupStack) Teardown() []string {
+	s.mu.Lock()
+	resources := s.resources
+	s.resources = nil
+	s.mu.Unlock()
+
+	var leaked []string
+	for i := len(resources) - 1; i >= 0; i-- {
+		r := resources[i]
+		Log.Infof("Deleting %s", r)
+		if err := deleteResource(r); err != nil {
+			Log.Errorf("Failed to delete %s: %v", r, err)
+			leaked = append(leaked, fmt.Sprintf("%s: %v", r, err))
+		}
+	}
+	return leaked
+}
+
+// deleteResource deletes the object and confirms that it no longer exists.
+func deleteResource(r TrackedResource) error {
+	if _, err := ShellSilent(`kubectl delete -n %s %s --ignore-not-found --wait=true --timeout=120s`, r.Namespace, r.Name); err != nil {
+		return err
+	}
+	msg, err := ShellSilent(`kubectl get -n %s %s --ignore-not-found -o name`, r.Namespace, r.Name)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(msg) != "" {
+		return fmt.Errorf("still exists")
+	}
+	return nil
+}
+
+// TrackCreated pushes the objects that a kubectl apply or create command reported as created in its output
+// to the cleanup stack of the test owning the namespace. In namespaces allocated for the test, existing
+// objects that were configured or left unchanged are pushed as well, e.g. objects of an earlier attempt.
+// Objects in a namespace not owned by a test, e.g. the control plane namespace, are pushed to the stack of
+// the running test case if it created them, except for control planes and member rolls, which PreserveSMCP
+// restores. They are ignored when several test cases run at the same time, because the test that created
+// them is not known, and outside of test cases, e.g. in setup functions.
+func TrackCreated(namespace, output string) {
+	if namespace == "" {
+		return
+	}
+	current := currentTestID()
+	stacksMu.Lock()
+	s := stacks[namespace]
+	shared := s == nil
+	if shared {
+		s = testStacks[current]
+	}
+	stacksMu.Unlock()
+	if s == nil {
+		return
+	}
+	owned := !shared && isAllocated(namespace)
+
+	for _, line := range strings.Split(output, "\n") {
+		m := appliedLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		if shared && isControlPlane(m[1]) {
+			continue
+		}
+		// an object existing before the test is only deleted in a namespace created for the test
+		if (m[2] == "configured" || m[2] == "unchanged") && !owned {
+			continue
+		}
+		s.Push(TrackedResource{Namespace: namespace, Name: m[1]})
+	}
+}
+
+// isControlPlane returns true for the type and name of a SMCP or SMMR as printed by kubectl.
+func isControlPlane(name string) bool {
+	return strings.HasPrefix(name, "servicemeshcontrolplane.") || strings.HasPrefix(name, "servicemeshmemberroll.")
+}
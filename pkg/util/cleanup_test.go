@@ -0,0 +1,187 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tracked returns the resources on the stack.
+func tracked(s *CleanupStack) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, r := range s.resources {
+		names = append(names, r.String())
+	}
+	return names
+}
+
+// allocate registers the namespaces as allocated for the top level test of t, like NamespaceAllocator.Allocate.
+func allocate(t *testing.T, names ...string) {
+	allocated := map[string]string{}
+	for _, name := range names {
+		allocated[name] = name
+	}
+	test := topLevelTest(t.Name())
+	namespacesMu.Lock()
+	namespaces[test] = allocated
+	namespacesMu.Unlock()
+	t.Cleanup(func() {
+		namespacesMu.Lock()
+		delete(namespaces, test)
+		namespacesMu.Unlock()
+	})
+}
+
+func TestTrackCreated(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		// allocated is true if bookinfo was created for the test by the namespace allocator
+		allocated bool
+		output    string
+		want      []string
+	}{
+		{
+			name:      "created objects",
+			namespace: "bookinfo",
+			output:    "service/details created\nserviceaccount/bookinfo-details created\ndeployment.apps/details-v1 created\n",
+			want:      []string{"bookinfo/service/details", "bookinfo/serviceaccount/bookinfo-details", "bookinfo/deployment.apps/details-v1"},
+		},
+		{
+			name:      "objects left by an earlier attempt",
+			namespace: "bookinfo",
+			allocated: true,
+			output: "service/details unchanged\n" +
+				"deployment.apps/details-v1 configured\n" +
+				"virtualservice.networking.istio.io/reviews serverside-applied\n",
+			want: []string{"bookinfo/service/details", "bookinfo/deployment.apps/details-v1", "bookinfo/virtualservice.networking.istio.io/reviews"},
+		},
+		{
+			name:      "existing objects in a namespace that is not isolated",
+			namespace: "bookinfo",
+			output: "service/details unchanged\n" +
+				"deployment.apps/details-v1 configured\n" +
+				"virtualservice.networking.istio.io/reviews serverside-applied\n",
+			want: []string{"bookinfo/virtualservice.networking.istio.io/reviews"},
+		},
+		{
+			name:      "other output",
+			namespace: "bookinfo",
+			allocated: true,
+			output: "Warning: resource services/details is missing the kubectl.kubernetes.io/last-applied-configuration annotation\n" +
+				"service/details configured\n" +
+				"deployment.apps/details-v1 patched\n" +
+				"pod \"details-v1-1\" deleted\n" +
+				"error: unable to recognize \"STDIN\": no matches for kind \"Gateway\"\n" +
+				"  destinationrule.networking.istio.io/reviews created  \n",
+			want: []string{"bookinfo/service/details", "bookinfo/destinationrule.networking.istio.io/reviews"},
+		},
+		{
+			name:      "shared namespace",
+			namespace: "istio-system",
+			allocated: true,
+			// objects that existed before the test, e.g. created by the setup, are kept
+			output: "gateway.networking.istio.io/istio-egressgateway created\n" +
+				"servicemeshcontrolplane.maistra.io/basic configured\n" +
+				"servicemeshmemberroll.maistra.io/default unchanged\n" +
+				"secret/nginx-client-certs unchanged\n" +
+				"configmap/istio-ca-root-cert configured\n",
+			want: []string{"istio-system/gateway.networking.istio.io/istio-egressgateway"},
+		},
+		{
+			name:      "no namespace",
+			namespace: "",
+			output:    "clusterrole.rbac.authorization.k8s.io/reader created\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeCommands(t, &FakeExecutor{Unmatched: &CommandResult{}})
+			// the subtest is the running test case, so that objects in shared namespaces are tracked
+			BindTestLog(t, topLevelTest(t.Name()))
+			if tt.allocated {
+				allocate(t, "bookinfo")
+			}
+			s := TrackResources(t, "bookinfo")
+
+			TrackCreated(tt.namespace, tt.output)
+			// objects reported twice are only deleted once
+			TrackCreated(tt.namespace, tt.output)
+			if got := tracked(s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackCreatedOutsideOfTestCases(t *testing.T) {
+	fakeCommands(t, &FakeExecutor{Unmatched: &CommandResult{}})
+	s := TrackResources(t, "bookinfo")
+
+	// without a bound test case, objects in shared namespaces are not attributed to a test
+	TrackCreated("istio-system", "gateway.networking.istio.io/istio-egressgateway created\n")
+	TrackCreated("bookinfo", "service/details created\n")
+	if got, want := tracked(s), []string{"bookinfo/service/details"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTeardown(t *testing.T) {
+	fake := NewFakeExecutor().
+		On(`^kubectl delete -n bookinfo deployment.apps/stuck `, CommandResult{Stderr: "error: timed out waiting for the condition on deployments/stuck", ExitCode: 1}).
+		On(`^kubectl delete `, CommandResult{}).
+		On(`^kubectl get -n bookinfo secret/finalized `, CommandResult{Stdout: "secret/finalized\n"}).
+		On(`^kubectl get `, CommandResult{})
+	fakeCommands(t, fake)
+
+	s := &CleanupStack{}
+	for _, name := range []string{"service/httpbin", "deployment.apps/stuck", "secret/finalized", "deployment.apps/httpbin"} {
+		s.Push(TrackedResource{Namespace: "bookinfo", Name: name})
+	}
+	leaked := s.Teardown()
+
+	want := []string{
+		"bookinfo/secret/finalized: still exists",
+		"bookinfo/deployment.apps/stuck: command failed",
+	}
+	if len(leaked) != len(want) {
+		t.Fatalf("got leaked %q, want %q", leaked, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(leaked[i], want[i]) {
+			t.Errorf("got leaked %q, want %q", leaked[i], want[i])
+		}
+	}
+
+	// the resources are deleted in reverse order
+	var deleted []string
+	for _, c := range fake.Commands() {
+		if strings.HasPrefix(c, "kubectl delete") {
+			deleted = append(deleted, strings.Fields(c)[4])
+		}
+	}
+	wantDeleted := []string{"deployment.apps/httpbin", "secret/finalized", "deployment.apps/stuck", "service/httpbin"}
+	if !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("got deleted %q, want %q", deleted, wantDeleted)
+	}
+
+	if leaked := s.Teardown(); len(leaked) != 0 {
+		t.Errorf("a second teardown deleted %q again", leaked)
+	}
+}
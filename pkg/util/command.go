@@ -0,0 +1,235 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// CommandBuilder builds a command from separate arguments. The command runs without a shell, so
+// arguments like JSON patches need no quoting, whatever quotes, spaces or `$` they contain:
+//
+//	util.Kubectl("patch", "smcp", smcpName).Namespace(meshNamespace).Patch(util.MergePatch, patch).Run()
+//	util.Kubectl("apply").Namespace(ns).Manifest(yaml).Run()
+//
+// A shell is only used for pipelines requested with Pipe.
+type CommandBuilder struct {
+	ctx      context.Context
+	cmd      Command
+	pipeline []Command
+	silent   bool
+}
+
+// NewCommand starts a command running the program with the arguments.
+func NewCommand(program string, args ...string) *CommandBuilder {
+	return &CommandBuilder{ctx: context.Background(), cmd: Command{Args: append([]string{program}, args...)}}
+}
+
+// Kubectl starts a kubectl command.
+func Kubectl(args ...string) *CommandBuilder {
+	return NewCommand("kubectl", args...)
+}
+
+// Oc starts an oc command.
+func Oc(args ...string) *CommandBuilder {
+	return NewCommand("oc", args...)
+}
+
+// Arg appends arguments.
+func (b *CommandBuilder) Arg(args ...string) *CommandBuilder {
+	b.cmd.Args = append(b.cmd.Args, args...)
+	return b
+}
+
+// Argf appends a single formatted argument, e.g. Argf("smcp/%s", name).
+func (b *CommandBuilder) Argf(format string, args ...interface{}) *CommandBuilder {
+	return b.Arg(fmt.Sprintf(format, args...))
+}
+
+// Namespace appends `-n <namespace>`, unless the namespace is empty.
+func (b *CommandBuilder) Namespace(namespace string) *CommandBuilder {
+	if namespace == "" {
+		return b
+	}
+	return b.Arg("-n", namespace)
+}
+
+// Patch appends the type and the patch, e.g. for `kubectl patch`.
+func (b *CommandBuilder) Patch(patchType PatchType, patch string) *CommandBuilder {
+	return b.Arg("--type", string(patchType), "-p", patch)
+}
+
+// Manifest appends `-f -` and passes the manifest on the standard input, e.g. for `kubectl apply`.
+func (b *CommandBuilder) Manifest(manifest string) *CommandBuilder {
+	return b.Arg("-f", "-").Stdin(manifest)
+}
+
+// Stdin sets the standard input of the command.
+func (b *CommandBuilder) Stdin(stdin string) *CommandBuilder {
+	b.cmd.Stdin = stdin
+	return b
+}
+
+// Context sets the context of the command, which kills the command when it is done.
+func (b *CommandBuilder) Context(ctx context.Context) *CommandBuilder {
+	b.ctx = ctx
+	return b
+}
+
+// Silent stops logging the command and its output, like ShellSilent.
+func (b *CommandBuilder) Silent() *CommandBuilder {
+	b.silent = true
+	return b
+}
+
+// Pipe pipes the standard output of the command into the next command. The pipeline runs with `sh -c`.
+// The standard input of the next command is ignored.
+func (b *CommandBuilder) Pipe(next *CommandBuilder) *CommandBuilder {
+	b.pipeline = append(b.pipeline, Command{Args: next.cmd.Args})
+	b.pipeline = append(b.pipeline, next.pipeline...)
+	return b
+}
+
+// Command returns the built command.
+func (b *CommandBuilder) Command() Command {
+	if len(b.pipeline) == 0 {
+		return b.cmd
+	}
+	parts := []string{Command{Args: b.cmd.Args}.String()}
+	for _, c := range b.pipeline {
+		parts = append(parts, c.String())
+	}
+	return Command{Script: strings.Join(parts, " | "), Stdin: b.cmd.Stdin}
+}
+
+func (b *CommandBuilder) String() string {
+	return b.Command().String()
+}
+
+// Run runs the command like Shell and returns its output. The error is a *CommandError.
+func (b *CommandBuilder) Run() (string, error) {
+	res, err := b.Result()
+	return res.Output, err
+}
+
+// Result runs the command like ShellResult and returns its result. The error is a *CommandError.
+func (b *CommandBuilder) Result() (CommandResult, error) {
+	return run(b.ctx, b.Command(), !b.silent, !b.silent, !b.silent)
+}
+
+// Start starts the command in the background and returns its process. In dry-run mode the command is
+// only printed and the process is nil. Background processes are not run by the Executor, so they are not
+// recorded and Start fails while a session is replayed.
+func (b *CommandBuilder) Start() (*os.Process, error) {
+	cmd := b.Command()
+	Log.Info("RunBackground: ", cmd)
+	if IsDryRun() {
+		_, err := Execute(b.ctx, cmd)
+		return nil, err
+	}
+	if IsReplaying() {
+		return nil, fmt.Errorf("replay: background commands cannot be replayed: %s", cmd)
+	}
+
+	var c *exec.Cmd
+	if cmd.Script != "" {
+		c = exec.Command("sh", "-c", cmd.Script) // #nosec
+	} else {
+		c = exec.Command(cmd.Args[0], cmd.Args[1:]...) // #nosec
+	}
+	if cmd.Stdin != "" {
+		c.Stdin = strings.NewReader(cmd.Stdin)
+	}
+	start := time.Now()
+	err := c.Start()
+	entry := AuditEntry{Command: cmd.String(), Start: start, Seconds: time.Since(start).Seconds(), Background: true}
+	if err != nil {
+		entry.ExitCode = -1
+		audit(entry, err)
+		Log.Errorf("%s, command failed!", cmd)
+		return nil, err
+	}
+	audit(entry, nil)
+	return c.Process, nil
+}
+
+// SplitArgs splits a command line into arguments like sh does, without expanding variables or globs:
+// single quotes preserve everything, double quotes preserve everything except backslash escapes of
+// `"`, `\`, `$` and backquotes, and a backslash outside quotes escapes the next character.
+func SplitArgs(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case r == '\'':
+			inArg = true
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '\'' {
+					closed = true
+					break
+				}
+				arg.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, errors.New("unterminated single quote")
+			}
+		case r == '"':
+			inArg = true
+			closed := false
+			for i++; i < len(runes); i++ {
+				if runes[i] == '"' {
+					closed = true
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+				}
+				arg.WriteRune(runes[i])
+			}
+			if !closed {
+				return nil, errors.New("unterminated double quote")
+			}
+		case r == '\\':
+			inArg = true
+			if i+1 < len(runes) {
+				i++
+				arg.WriteRune(runes[i])
+			}
+		default:
+			inArg = true
+			arg.WriteRune(r)
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "plain arguments",
+			command: "kubectl get pods  -n\tbookinfo\n",
+			want:    []string{"kubectl", "get", "pods", "-n", "bookinfo"},
+		},
+		{
+			name:    "single quotes preserve everything",
+			command: `kubectl patch smcp basic -p '{"spec": {"a": "$HOME \"x\""}}'`,
+			want:    []string{"kubectl", "patch", "smcp", "basic", "-p", `{"spec": {"a": "$HOME \"x\""}}`},
+		},
+		{
+			name:    "double quotes with escapes",
+			command: `echo "a \"b\" \\ \$c \` + "`" + ` \n"`,
+			want:    []string{"echo", `a "b" \ $c ` + "`" + ` \n`},
+		},
+		{
+			name:    "backslash outside quotes",
+			command: `echo a\ b \'c\'`,
+			want:    []string{"echo", "a b", "'c'"},
+		},
+		{
+			name:    "adjacent quoted parts form one argument",
+			command: `curl -H 'Host: '"bookinfo.com"x`,
+			want:    []string{"curl", "-H", "Host: bookinfo.comx"},
+		},
+		{
+			name:    "empty arguments",
+			command: `kubectl get '' "" pods`,
+			want:    []string{"kubectl", "get", "", "", "pods"},
+		},
+		{
+			name:    "empty command",
+			command: "   ",
+		},
+		{
+			name:    "trailing backslash",
+			command: `echo a\`,
+			want:    []string{"echo", "a"},
+		},
+		{
+			name:    "unterminated single quote",
+			command: `echo 'a b`,
+			wantErr: "unterminated single quote",
+		},
+		{
+			name:    "unterminated double quote",
+			command: `echo "a \"`,
+			wantErr: "unterminated double quote",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitArgs(tt.command)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandString(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Command
+		want string
+	}{
+		{
+			name: "safe arguments are not quoted",
+			cmd:  Command{Args: []string{"kubectl", "get", "pods", "-n", "bookinfo", "-l", "app=productpage,version=v1"}},
+			want: "kubectl get pods -n bookinfo -l app=productpage,version=v1",
+		},
+		{
+			name: "arguments with spaces and quotes",
+			cmd:  Command{Args: []string{"kubectl", "patch", "smcp", "basic", "-p", `{"spec": {"version": "v2.3"}}`}},
+			want: `kubectl patch smcp basic -p '{"spec": {"version": "v2.3"}}'`,
+		},
+		{
+			name: "single quotes",
+			cmd:  Command{Args: []string{"echo", "it's"}},
+			want: `echo 'it'"'"'s'`,
+		},
+		{
+			name: "empty argument",
+			cmd:  Command{Args: []string{"echo", ""}},
+			want: "echo ''",
+		},
+		{
+			name: "stdin",
+			cmd:  Command{Args: []string{"kubectl", "apply", "-f", "-"}, Stdin: "kind: List\n"},
+			want: "kubectl apply -f - <<< 'kind: List\n'",
+		},
+		{
+			name: "script",
+			cmd:  Command{Script: "kubectl get pods | grep Running"},
+			want: "kubectl get pods | grep Running",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandStringSplitArgs(t *testing.T) {
+	args := [][]string{
+		{"kubectl", "get", "pods"},
+		{"kubectl", "patch", "smcp", "basic", "--type", "merge", "-p", `{"spec":{"a":"$b","c":"'d'"}}`},
+		{"sh", "-c", `echo "$HOME" \ ` + "`date`"},
+		{"echo", "", " ", "a\nb", `\`, `'`, `"`, "it's"},
+	}
+	for _, want := range args {
+		s := Command{Args: want}.String()
+		got, err := SplitArgs(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestCommandBuilder(t *testing.T) {
+	b := Kubectl("patch", "smcp", "basic").Namespace("istio-system").Patch(MergePatch, `{"spec":{}}`)
+	want := []string{"kubectl", "patch", "smcp", "basic", "-n", "istio-system", "--type", "merge", "-p", `{"spec":{}}`}
+	if got := b.Command().Args; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := Kubectl("get", "pods").Namespace("").String(); got != "kubectl get pods" {
+		t.Errorf("an empty namespace must not be added, got %q", got)
+	}
+
+	pipe := Kubectl("get", "pods").Pipe(NewCommand("grep", "a b"))
+	if got, want := pipe.Command().Script, `kubectl get pods | grep 'a b'`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -36,7 +36,7 @@ func Inspect(err error, fMsg, sMsg string, t *testing.T) {
 	if err != nil {
 		t.Fatalf("%s. Error %s", fMsg, err)
 	} else if sMsg != "" {
-		Log.Info(sMsg)
+		TestLog(t).Info(sMsg)
 	}
 }
 
@@ -56,7 +56,8 @@ func SetupCookieJar(username, pass, gateway string) (*cookiejar.Jar, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed building cookiejar: %v", err)
 	}
-	client := http.Client{Jar: jar}
+	client := HTTPClient(nil)
+	client.Jar = jar
 	resp, err := client.PostForm(fmt.Sprintf("%s/login", gateway), url.Values{
 		"password": {pass},
 		"username": {username},
@@ -71,7 +72,8 @@ func SetupCookieJar(username, pass, gateway string) (*cookiejar.Jar, error) {
 // GetWithCookieJar constructs reqeusts with login user cookie and returns a http response
 func GetWithCookieJar(url string, jar *cookiejar.Jar) (*http.Response, error) {
 	// Declare http client
-	client := &http.Client{Jar: jar}
+	client := HTTPClient(nil)
+	client.Jar = jar
 
 	// Declare HTTP Method and Url
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -85,7 +87,7 @@ func GetWithCookieJar(url string, jar *cookiejar.Jar) (*http.Response, error) {
 // it is equal to curl -HHost:
 func GetWithHost(url string, host string) (*http.Response, error) {
 	// Declare http client
-	client := &http.Client{}
+	client := HTTPClient(nil)
 
 	// Declare HTTP Method and Url
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -100,7 +102,7 @@ func GetWithHost(url string, host string) (*http.Response, error) {
 // GetWithJWT constructs a http request with Host and JWT auth token in header
 func GetWithJWT(url, token, host string) (*http.Response, error) {
 	// Declare http client
-	client := &http.Client{}
+	client := HTTPClient(nil)
 
 	// Declare HTTP Method and Url
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -136,7 +138,7 @@ func GetHTTPResponse(url string, jar *cookiejar.Jar) (*http.Response, int, error
 		duration = int(time.Since(startT) / (time.Second / time.Microsecond))
 	} else {
 		startT := time.Now()
-		resp, err = http.Get(url)
+		resp, err = HTTPClient(nil).Get(url)
 		duration = int(time.Since(startT) / (time.Second / time.Microsecond))
 	}
 	return resp, duration, err
@@ -0,0 +1,375 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+// EnvFile is the optional file with environment variables read by LoadConfig.
+const EnvFile = "test.env"
+
+// SampleArchs are the valid values of Config.SampleArch.
+var SampleArchs = []string{"x86", "arm", "p", "z"}
+
+// Config is the configuration of a test run. Every setting has a default and can be set in the env file,
+// by an environment variable and by a command-line flag, in increasing order of precedence.
+type Config struct {
+	// MeshNamespace is the namespace of the control plane.
+	MeshNamespace string
+	// SMCPName is the name of the ServiceMeshControlPlane.
+	SMCPName string
+	// SampleArch selects the images of the sample applications: x86, arm, p (Power) or z.
+	SampleArch string
+	// ROSA is true on Red Hat OpenShift Service on AWS.
+	ROSA bool
+	// IPv6 is true on IPv6 clusters.
+	IPv6 bool
+	// Nightly installs the nightly builds of the operators before the tests.
+	Nightly bool
+	// MustGatherTag is the tag of the must-gather image.
+	MustGatherTag string
+	// TestGroup is the tag expression selecting the test cases, see suite.Select.
+	TestGroup string
+	// Include and Exclude are comma separated test patterns narrowing the selected test cases.
+	Include string
+	Exclude string
+	// IsolateNamespaces runs every test case in its own namespaces.
+	IsolateNamespaces bool
+	// Parallelism is the maximum number of test cases running at the same time.
+	Parallelism int
+	// Retries is the number of times failed test cases are rerun. Test cases passing on a retry are reported as flaky.
+	Retries int
+	// DryRun prints the commands instead of running them, see EnableDryRun.
+	DryRun bool
+	// PlanDir is the directory the dry-run plan is saved to. It is empty if the plan is only printed.
+	PlanDir string
+	// DryRunStubs is a JSON file with the results of read-only queries in dry-run mode, see LoadStubs.
+	DryRunStubs string
+	// RecordDir is the directory the commands of every test case are recorded to, see RecordSession.
+	RecordDir string
+	// ReplayDir is the directory of the recordings replayed instead of running commands, see ReplaySession.
+	ReplayDir string
+	// LogFormat is the format of the log entries: text or json.
+	LogFormat string
+	// LogLevel is the minimum level of the log entries, e.g. info or debug.
+	LogLevel string
+	// LogDir is the directory of the combined run log and of the log files of the test cases, see BindTestLog.
+	// It is empty if the logs are only printed.
+	LogDir string
+	// Mesh1Kubeconfig and Mesh2Kubeconfig are the kubeconfig files of the two clusters of the federation tests.
+	// Both are the same file when the meshes are federated in a single cluster.
+	Mesh1Kubeconfig string
+	Mesh2Kubeconfig string
+
+	// sources records where the value of each setting came from, by environment variable.
+	sources map[string]string
+}
+
+// setting describes a configuration value, its environment variable and its command-line flag.
+type setting struct {
+	env   string
+	flag  string
+	def   string
+	usage string
+	// boolean flags can be given without a value, e.g. -rosa
+	boolean bool
+	get     func(c *Config) string
+	set     func(c *Config, value string) error
+}
+
+var settings = []setting{
+	{env: "MESHNAMESPACE", flag: "mesh-namespace", def: "istio-system", usage: "namespace of the control plane",
+		get: func(c *Config) string { return c.MeshNamespace },
+		set: func(c *Config, v string) error { c.MeshNamespace = v; return nil }},
+	{env: "SMCPNAME", flag: "smcp-name", def: "basic", usage: "name of the ServiceMeshControlPlane",
+		get: func(c *Config) string { return c.SMCPName },
+		set: func(c *Config, v string) error { c.SMCPName = v; return nil }},
+	{env: "SAMPLEARCH", flag: "sample-arch", def: "x86", usage: "architecture of the sample images: " + strings.Join(SampleArchs, ", "),
+		get: func(c *Config) string { return c.SampleArch },
+		set: func(c *Config, v string) error { c.SampleArch = v; return nil }},
+	{env: "ROSA", flag: "rosa", def: "false", usage: "the cluster is a ROSA cluster", boolean: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.ROSA) },
+		set: func(c *Config, v string) error { return parseBool(&c.ROSA, v) }},
+	{env: "IPV6", flag: "ipv6", def: "false", usage: "the cluster uses IPv6", boolean: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.IPv6) },
+		set: func(c *Config, v string) error { return parseBool(&c.IPv6, v) }},
+	{env: "NIGHTLY", flag: "nightly", def: "false", usage: "install the nightly builds of the operators", boolean: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.Nightly) },
+		set: func(c *Config, v string) error { return parseBool(&c.Nightly, v) }},
+	{env: "MUSTGATHERTAG", flag: "must-gather-tag", def: "2.3", usage: "tag of the must-gather image",
+		get: func(c *Config) string { return c.MustGatherTag },
+		set: func(c *Config, v string) error { c.MustGatherTag = v; return nil }},
+	{env: "TEST_GROUP", flag: "test-group", def: "full", usage: "tag expression selecting the test cases, e.g. smoke,!disruptive",
+		get: func(c *Config) string { return c.TestGroup },
+		set: func(c *Config, v string) error { c.TestGroup = v; return nil }},
+	{env: "RUN_TESTS", flag: "include", def: "", usage: "comma separated list of test patterns to run",
+		get: func(c *Config) string { return c.Include },
+		set: func(c *Config, v string) error { c.Include = v; return nil }},
+	{env: "SKIP_TESTS", flag: "exclude", def: "", usage: "comma separated list of test patterns to skip",
+		get: func(c *Config) string { return c.Exclude },
+		set: func(c *Config, v string) error { c.Exclude = v; return nil }},
+	{env: "ISOLATE_NAMESPACES", flag: "isolate-namespaces", def: "false", usage: "run every test case in its own uniquely named namespaces", boolean: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.IsolateNamespaces) },
+		set: func(c *Config, v string) error { return parseBool(&c.IsolateNamespaces, v) }},
+	{env: "PARALLELISM", flag: "parallelism", def: "1", usage: "maximum number of test cases running at the same time",
+		get: func(c *Config) string { return strconv.Itoa(c.Parallelism) },
+		set: func(c *Config, v string) error { return parseInt(&c.Parallelism, v) }},
+	{env: "RETRIES", flag: "retries", def: "0", usage: "rerun failed test cases up to this many times in a new process",
+		get: func(c *Config) string { return strconv.Itoa(c.Retries) },
+		set: func(c *Config, v string) error { return parseInt(&c.Retries, v) }},
+	{env: "DRY_RUN", flag: "dry-run", def: "false", usage: "print the commands and manifests instead of running them", boolean: true,
+		get: func(c *Config) string { return strconv.FormatBool(c.DryRun) },
+		set: func(c *Config, v string) error { return parseBool(&c.DryRun, v) }},
+	{env: "PLAN_DIR", flag: "plan-dir", def: "plan", usage: "save the dry-run plan to this directory; empty to only print it",
+		get: func(c *Config) string { return c.PlanDir },
+		set: func(c *Config, v string) error { c.PlanDir = v; return nil }},
+	{env: "DRY_RUN_STUBS", flag: "dry-run-stubs", def: "", usage: "JSON file with the results of read-only queries in dry-run mode",
+		get: func(c *Config) string { return c.DryRunStubs },
+		set: func(c *Config, v string) error { c.DryRunStubs = v; return nil }},
+	{env: "RECORD_DIR", flag: "record", def: "", usage: "record the commands of every test case to a fixture file in this directory",
+		get: func(c *Config) string { return c.RecordDir },
+		set: func(c *Config, v string) error { c.RecordDir = v; return nil }},
+	{env: "REPLAY_DIR", flag: "replay", def: "", usage: "replay the fixture files in this directory instead of running commands",
+		get: func(c *Config) string { return c.ReplayDir },
+		set: func(c *Config, v string) error { c.ReplayDir = v; return nil }},
+	{env: "LOG_FORMAT", flag: "log-format", def: "text", usage: "format of the log entries: " + strings.Join(LogFormats, ", "),
+		get: func(c *Config) string { return c.LogFormat },
+		set: func(c *Config, v string) error { c.LogFormat = v; return nil }},
+	{env: "LOG_LEVEL", flag: "log-level", def: "info", usage: "minimum level of the log entries, e.g. debug, info or warn",
+		get: func(c *Config) string { return c.LogLevel },
+		set: func(c *Config, v string) error { c.LogLevel = v; return nil }},
+	{env: "LOG_DIR", flag: "log-dir", def: "logs", usage: "write the run log and a log file per test case to this directory; empty to disable",
+		get: func(c *Config) string { return c.LogDir },
+		set: func(c *Config, v string) error { c.LogDir = v; return nil }},
+	{env: "MESH1_KUBECONFIG", flag: "mesh1-kubeconfig", def: "~/.kube/config", usage: "kubeconfig file of the first cluster of the federation tests",
+		get: func(c *Config) string { return c.Mesh1Kubeconfig },
+		set: func(c *Config, v string) error { c.Mesh1Kubeconfig = v; return nil }},
+	{env: "MESH2_KUBECONFIG", flag: "mesh2-kubeconfig", def: "~/.kube/config", usage: "kubeconfig file of the second cluster of the federation tests",
+		get: func(c *Config) string { return c.Mesh2Kubeconfig },
+		set: func(c *Config, v string) error { c.Mesh2Kubeconfig = v; return nil }},
+}
+
+func parseBool(b *bool, value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*b = v
+	return nil
+}
+
+func parseInt(i *int, value string) error {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*i = v
+	return nil
+}
+
+// ControlPlane identifies a ServiceMeshControlPlane, e.g. as the input of the SMCP templates.
+type ControlPlane struct {
+	Name      string
+	Namespace string
+}
+
+// ControlPlane returns the configured ServiceMeshControlPlane.
+func (c *Config) ControlPlane() ControlPlane {
+	return ControlPlane{Name: c.SMCPName, Namespace: c.MeshNamespace}
+}
+
+// Validate returns an error describing the first invalid value.
+func (c *Config) Validate() error {
+	valid := false
+	for _, arch := range SampleArchs {
+		if c.SampleArch == arch {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid SAMPLEARCH %q, must be one of %s", c.SampleArch, strings.Join(SampleArchs, ", "))
+	}
+	valid = false
+	for _, format := range LogFormats {
+		if c.LogFormat == format {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("invalid LOG_FORMAT %q, must be one of %s", c.LogFormat, strings.Join(LogFormats, ", "))
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL %q: %v", c.LogLevel, err)
+	}
+	if c.MeshNamespace == "" {
+		return fmt.Errorf("MESHNAMESPACE must not be empty")
+	}
+	if c.SMCPName == "" {
+		return fmt.Errorf("SMCPNAME must not be empty")
+	}
+	if c.Mesh1Kubeconfig == "" || c.Mesh2Kubeconfig == "" {
+		return fmt.Errorf("MESH1_KUBECONFIG and MESH2_KUBECONFIG must not be empty")
+	}
+	if c.Parallelism < 1 {
+		return fmt.Errorf("invalid PARALLELISM %d, must be at least 1", c.Parallelism)
+	}
+	if c.Retries < 0 {
+		return fmt.Errorf("invalid RETRIES %d, must not be negative", c.Retries)
+	}
+	modes := 0
+	for _, enabled := range []bool{c.DryRun, c.RecordDir != "", c.ReplayDir != ""} {
+		if enabled {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf("DRY_RUN, RECORD_DIR and REPLAY_DIR are mutually exclusive")
+	}
+	if (c.RecordDir != "" || c.ReplayDir != "") && c.Parallelism > 1 {
+		return fmt.Errorf("recorded and replayed test cases cannot run in parallel, PARALLELISM must be 1")
+	}
+	return nil
+}
+
+// Export sets the environment variables of all settings, so that a child process inherits the configuration.
+func (c *Config) Export() {
+	for _, s := range settings {
+		os.Setenv(s.env, s.get(c))
+	}
+}
+
+// Print logs the effective configuration and where each value came from.
+func (c *Config) Print() {
+	Log.Info("Configuration:")
+	for _, s := range settings {
+		Log.Infof("  %s=%s (%s)", s.env, s.get(c), c.sources[s.env])
+	}
+}
+
+// ConfigFlags are the command-line flags of the configuration settings.
+type ConfigFlags struct {
+	values map[string]*configFlag
+}
+
+type configFlag struct {
+	boolean bool
+	value   string
+	set     bool
+}
+
+func (f *configFlag) String() string { return f.value }
+
+func (f *configFlag) Set(value string) error {
+	f.value = value
+	f.set = true
+	return nil
+}
+
+func (f *configFlag) IsBoolFlag() bool { return f.boolean }
+
+// RegisterConfigFlags defines a flag for every setting in fs, e.g. -mesh-namespace for MESHNAMESPACE.
+func RegisterConfigFlags(fs *flag.FlagSet) *ConfigFlags {
+	flags := &ConfigFlags{values: map[string]*configFlag{}}
+	for _, s := range settings {
+		f := &configFlag{boolean: s.boolean}
+		flags.values[s.env] = f
+		fs.Var(f, s.flag, fmt.Sprintf("%s; env %s, default %q", s.usage, s.env, s.def))
+	}
+	return flags
+}
+
+var envFileOnce sync.Once
+
+// loadEnvFile sets the variables of the env file that are not set in the environment already.
+// The file is optional and only read once.
+func loadEnvFile() {
+	envFileOnce.Do(func() {
+		if _, err := os.Stat(EnvFile); err != nil {
+			return
+		}
+		if err := godotenv.Load(EnvFile); err != nil {
+			Log.Errorf("Failed to load %s: %v", EnvFile, err)
+		}
+	})
+}
+
+// LoadConfig builds the configuration from the defaults, the env file, the environment variables and
+// the flags, in this order. The flags may be nil. The configuration is validated.
+func LoadConfig(flags *ConfigFlags) (*Config, error) {
+	fileValues, _ := godotenv.Read(EnvFile)
+	loadEnvFile()
+
+	c := &Config{sources: map[string]string{}}
+	for _, s := range settings {
+		value, source := s.def, "default"
+		// the env file does not override the environment, so a value equal to the one in the file came from the file
+		if v := os.Getenv(s.env); v != "" {
+			value, source = v, "environment"
+			if fileValues[s.env] == v {
+				source = EnvFile
+			}
+		}
+		if flags != nil {
+			if f := flags.values[s.env]; f.set {
+				value, source = f.value, "flag -"+s.flag
+			}
+		}
+		c.sources[s.env] = source
+		if err := s.set(c, value); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", s.env, value, err)
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+var (
+	configMu sync.Mutex
+	config   *Config
+)
+
+// GetConfig returns the configuration of the run. Unless SetConfig was called, it is loaded on first use
+// from the defaults, the env file and the environment variables. An invalid configuration is fatal.
+func GetConfig() *Config {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if config == nil {
+		c, err := LoadConfig(nil)
+		if err != nil {
+			Log.Fatalf("Invalid configuration: %v", err)
+		}
+		config = c
+	}
+	return config
+}
+
+// SetConfig replaces the configuration returned by GetConfig, e.g. with one including the command-line flags.
+func SetConfig(c *Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	config = c
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadConfig loads the configuration in dir, with the env file content and the command-line arguments.
+func loadConfig(t *testing.T, envFile string, args ...string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	if envFile != "" {
+		if err := ioutil.WriteFile(filepath.Join(dir, EnvFile), []byte(envFile), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := RegisterConfigFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(flags)
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	t.Setenv("MESHNAMESPACE", "env-mesh")
+	t.Setenv("SMCPNAME", "env-smcp")
+	t.Setenv("SAMPLEARCH", "")
+
+	c, err := loadConfig(t, "", "-mesh-namespace", "flag-mesh", "-smcp-name=flag-smcp", "-rosa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MeshNamespace != "flag-mesh" || c.SMCPName != "flag-smcp" {
+		t.Errorf("flags did not override the environment: %s/%s", c.MeshNamespace, c.SMCPName)
+	}
+	if !c.ROSA {
+		t.Error("a boolean flag without value must be true")
+	}
+	if c.SampleArch != "x86" || c.Parallelism != 1 {
+		t.Errorf("unexpected defaults: SampleArch %q, Parallelism %d", c.SampleArch, c.Parallelism)
+	}
+	if got := c.sources["MESHNAMESPACE"]; got != "flag -mesh-namespace" {
+		t.Errorf("source of MESHNAMESPACE is %q", got)
+	}
+
+	c, err = loadConfig(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.MeshNamespace != "env-mesh" || c.SMCPName != "env-smcp" {
+		t.Errorf("the environment did not override the defaults: %s/%s", c.MeshNamespace, c.SMCPName)
+	}
+	if got := c.sources["SMCPNAME"]; got != "environment" {
+		t.Errorf("source of SMCPNAME is %q", got)
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	t.Setenv("SAMPLEARCH", "foo")
+	if _, err := loadConfig(t, ""); err == nil || !strings.Contains(err.Error(), "SAMPLEARCH") {
+		t.Errorf("expected an invalid SAMPLEARCH error, got %v", err)
+	}
+	// a valid flag fixes an invalid environment variable
+	if _, err := loadConfig(t, "", "-sample-arch", "z"); err != nil {
+		t.Error(err)
+	}
+
+	t.Setenv("SAMPLEARCH", "")
+	for _, args := range [][]string{
+		{"-parallelism", "0"},
+		{"-parallelism", "two"},
+		{"-retries", "-1"},
+		{"-rosa=maybe"},
+		{"-log-format", "xml"},
+		{"-log-level", "loud"},
+		{"-mesh-namespace", ""},
+		{"-dry-run", "-record", "rec"},
+		{"-replay", "rec", "-parallelism", "2"},
+	} {
+		if _, err := loadConfig(t, "", args...); err == nil {
+			t.Errorf("expected an error for %v", args)
+		}
+	}
+}
+
+func TestGetConfigAfterSetConfig(t *testing.T) {
+	previous := GetConfig()
+	defer SetConfig(previous)
+
+	c := *previous
+	c.MeshNamespace = "flag-mesh"
+	SetConfig(&c)
+	if got := GetConfig().MeshNamespace; got != "flag-mesh" {
+		t.Errorf("GetConfig returned %q after SetConfig", got)
+	}
+}
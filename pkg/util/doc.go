@@ -0,0 +1,44 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package util holds the helpers the test cases use to drive the cluster.
+//
+// Commands are run through an Executor, see SetExecutor. The default ShellExecutor runs them, a
+// RecordingExecutor or ReplayExecutor records or replays them with their results, a FakeExecutor returns
+// scripted results for unit tests and a Plan prints them in dry-run mode. Commands with payloads are built
+// with Kubectl, Oc or NewCommand and run without a shell, so patches and manifests need no quoting:
+//
+//	util.Kubectl("patch", "smcp", smcpName).Namespace(meshNamespace).Patch(util.MergePatch, patch).Run()
+//
+// Failed commands return a *CommandError with the CommandResult. Failures of commands and of KubeClient
+// requests are classified by the reason reported by the API server; branch on ReasonOf or IsNotFound,
+// IsValidationRejected and friends instead of matching error messages. Requests of HTTPClient are recorded
+// and replayed with the commands of a session, see RecordSession.
+//
+// Manifests are parsed with ParseManifest or LoadManifest. Manifest.Apply and KubeApply and its variants
+// return the applied objects, and the objects a test case creates are deleted in reverse order when it
+// completes, see TrackResources and TrackCreated. Objects that cannot be deleted are reported as leaked.
+//
+// Tests look up their namespaces with Namespace. With isolated namespaces, every test case gets its own
+// from a NamespaceAllocator, named with a random suffix and added to the member roll; otherwise the fixed
+// shared namespaces are used. Tests patching the control plane call PreserveSMCP before their first patch,
+// so that the spec is restored and verified when they complete.
+//
+// Operations failing transiently are retried with a Retrier, see also RetryValue. Templates rendered with
+// RunTemplate, KubeApplyTemplate and Fill fail on missing keys and can use the functions of TemplateFuncs.
+// Sample files and images are resolved for the configured architecture with SampleFile and SampleImage.
+//
+// Log entries of Log and TestLog are attributed to the running test case, see BindTestLog, and every
+// command is appended to the audit trail enabled with EnableAudit.
+package util
@@ -0,0 +1,242 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// readOnlyCommand matches kubectl and oc commands that only query the cluster, e.g.
+// "kubectl get pods -n bookinfo" or "oc -n istio-system wait --for condition=Ready smcp/basic".
+var readOnlyCommand = regexp.MustCompile(`^\s*(kubectl|oc)\s+((-n|--namespace|-c|--context)\s+\S+\s+)*(get|describe|logs|version|whoami|wait|explain|api-resources|api-versions|cluster-info|top|auth\s+can-i|rollout\s+(status|history)|config\s+view)\b`)
+
+var (
+	// shellSeparator splits a script into the commands of its pipelines and lists.
+	shellSeparator = regexp.MustCompile(`\|\|?|&&|;`)
+	// clusterCommand matches a command running kubectl or oc, e.g. "xargs kubectl delete pod".
+	clusterCommand = regexp.MustCompile(`(?:^|\s)(?:kubectl|oc)\s`)
+)
+
+// manifestFile matches the files passed to kubectl and oc with -f, e.g. the temp files of KubeDeleteContents.
+var manifestFile = regexp.MustCompile(`(?:^|\s)(?:-f|--filename)(?:\s+|=)(\S+)`)
+
+var (
+	manifestKind = regexp.MustCompile(`(?m)^kind:\s*(\S+)`)
+	manifestName = regexp.MustCompile(`(?m)^metadata:\s*\n(?:\s+.*\n)*?\s+name:\s*(\S+)`)
+)
+
+// Stub is a scripted result of the read-only commands matching Pattern in dry-run mode.
+type Stub struct {
+	Pattern  string `json:"pattern"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+}
+
+// LoadStubs reads a JSON list of stubs from a file.
+func LoadStubs(filename string) ([]Stub, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var stubs []Stub
+	if err := json.Unmarshal(data, &stubs); err != nil {
+		return nil, fmt.Errorf("failed to parse stubs in %s: %v", filename, err)
+	}
+	return stubs, nil
+}
+
+// Plan is an Executor that prints the commands instead of running them. Commands changing the cluster
+// succeed with an empty output. Read-only queries return the result of the first matching stub, or
+// an empty output if no stub matches, so that the control flow of the tests can continue. If Dir is
+// set, the commands, the manifests they read and the rendered templates are saved there as well.
+// Plan is also the HTTPInterceptor of the dry run: requests are printed instead of sent.
+type Plan struct {
+	// Dir is the plan directory. It is empty if the plan is only printed.
+	Dir string
+
+	mu    sync.Mutex
+	stubs *FakeExecutor
+	steps int
+}
+
+var (
+	planMu sync.Mutex
+	plan   *Plan
+)
+
+// EnableDryRun makes Shell and its variants print the commands, and HTTPClient the requests, instead
+// of running and sending them, see Plan. The plan directory is created if dir is not empty.
+func EnableDryRun(dir string, stubs []Stub) (*Plan, error) {
+	p := &Plan{Dir: dir, stubs: &FakeExecutor{Unmatched: &CommandResult{}}}
+	for _, s := range stubs {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid stub pattern %q: %v", s.Pattern, err)
+		}
+		p.stubs.On(s.Pattern, CommandResult{Stdout: s.Stdout, Stderr: s.Stderr, ExitCode: s.ExitCode})
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	planMu.Lock()
+	plan = p
+	planMu.Unlock()
+	SetExecutor(p)
+	SetHTTPInterceptor(p)
+	return p, nil
+}
+
+// IsDryRun returns true if commands are not run, see EnableDryRun. Helpers waiting for the cluster
+// to reach a state return immediately in dry-run mode.
+func IsDryRun() bool {
+	planMu.Lock()
+	defer planMu.Unlock()
+	return plan != nil
+}
+
+// Execute prints the command and, for read-only queries, returns the stubbed result.
+func (p *Plan) Execute(ctx context.Context, cmd Command) (CommandResult, error) {
+	command := cmd.String()
+	if isReadOnly(command) {
+		p.add("query", command)
+		return p.stubs.Execute(ctx, cmd)
+	}
+
+	step := p.add("run", command)
+	for _, m := range manifestFile.FindAllStringSubmatch(command, -1) {
+		p.saveFile(step, m[1])
+	}
+	if cmd.Stdin != "" {
+		p.save(fmt.Sprintf("%04d-stdin", step), cmd.Stdin)
+	}
+	return CommandResult{}, nil
+}
+
+// RoundTrip prints the request instead of sending it and returns an empty 200 OK response.
+func (p *Plan) RoundTrip(req *http.Request, _ http.RoundTripper) (*http.Response, error) {
+	p.add("request", newRecordedRequest(req).key())
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(&bytes.Buffer{}),
+		Request:    req,
+	}, nil
+}
+
+// AddTemplate records a rendered template.
+func (p *Plan) AddTemplate(rendered string) {
+	step := p.add("template", describeManifest(rendered))
+	p.save(fmt.Sprintf("%04d-template.yaml", step), rendered)
+}
+
+// add prints a step of the plan, appends it to the commands file and returns its number.
+func (p *Plan) add(kind, text string) int {
+	p.mu.Lock()
+	p.steps++
+	step := p.steps
+	p.mu.Unlock()
+
+	Log.Infof("[dry-run] %04d %s: %s", step, kind, text)
+	if p.Dir != "" {
+		f, err := os.OpenFile(filepath.Join(p.Dir, "commands.txt"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			Log.Errorf("Failed to write plan: %v", err)
+			return step
+		}
+		defer f.Close()
+		fmt.Fprintf(f, "%04d %s: %s\n", step, kind, text)
+	}
+	return step
+}
+
+// saveFile copies a file read by a command into the plan directory, before a temp file is removed.
+func (p *Plan) saveFile(step int, filename string) {
+	if p.Dir == "" || filename == "-" {
+		return
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		// e.g. a directory or a URL
+		return
+	}
+	p.save(fmt.Sprintf("%04d-%s", step, filepath.Base(filename)), string(data))
+}
+
+func (p *Plan) save(name, content string) {
+	if p.Dir == "" {
+		return
+	}
+	if err := ioutil.WriteFile(filepath.Join(p.Dir, name), []byte(content), 0644); err != nil {
+		Log.Errorf("Failed to write plan: %v", err)
+	}
+}
+
+// isReadOnly returns true if the command starts with a read-only query and the later commands of the script,
+// e.g. "kubectl get pods -o name | xargs kubectl delete", do not change the cluster either.
+func isReadOnly(command string) bool {
+	stages := shellSeparator.Split(command, -1)
+	if !readOnlyCommand.MatchString(stages[0]) {
+		return false
+	}
+	for _, stage := range stages[1:] {
+		if clusterCommand.MatchString(stage) && !readOnlyCommand.MatchString(stage) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordTemplate adds a rendered template to the plan in dry-run mode.
+func recordTemplate(rendered string) {
+	planMu.Lock()
+	p := plan
+	planMu.Unlock()
+	if p != nil {
+		p.AddTemplate(rendered)
+	}
+}
+
+// describeManifest returns the kinds and names in a manifest, e.g. "VirtualService reviews".
+func describeManifest(s string) string {
+	var objects []string
+	for _, doc := range strings.Split(s, "\n---") {
+		kind := manifestKind.FindStringSubmatch(doc)
+		name := manifestName.FindStringSubmatch(doc)
+		if kind != nil && name != nil {
+			objects = append(objects, kind[1]+" "+name[1])
+		}
+	}
+	if len(objects) == 0 {
+		return fmt.Sprintf("%d bytes", len(s))
+	}
+	return strings.Join(objects, ", ")
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestReadOnlyCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		readOnly bool
+	}{
+		{"kubectl get pods -n bookinfo", true},
+		{"oc get smcp/basic -n istio-system -o json", true},
+		{"kubectl -n bookinfo logs deploy/ratings-v1 -c istio-proxy", true},
+		{"oc -n istio-system wait --for condition=Ready smcp/basic --timeout 180s", true},
+		{"kubectl --namespace bookinfo --context admin describe pod ratings", true},
+		{"  oc whoami", true},
+		{"oc version", true},
+		{"kubectl auth can-i create pods -n bookinfo", true},
+		{"kubectl rollout status deployment/reviews-v1 -n bookinfo", true},
+		{"kubectl rollout history deployment/reviews-v1", true},
+		{"oc config view --minify", true},
+		{"kubectl api-resources", true},
+		{"kubectl apply -n bookinfo -f -", false},
+		{"oc patch smcp/basic -n istio-system --type merge -p '{}'", false},
+		{"kubectl delete namespace bookinfo", false},
+		{"kubectl rollout restart deployment/reviews-v1", false},
+		{"kubectl auth reconcile -f rbac.yaml", false},
+		{"oc config set-context --current --namespace bookinfo", false},
+		{"kubectl exec -n bookinfo ratings -- curl http://productpage:9080", false},
+		{"kubectl label namespace bookinfo get=true", false},
+		// the namespace flag needs a value
+		{"kubectl -n get pods", false},
+		// a subcommand starting with a read-only one
+		{"kubectl getx pods", false},
+		{"curl http://example.com/kubectl get", false},
+		{"echo kubectl get pods", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := readOnlyCommand.MatchString(tt.command); got != tt.readOnly {
+				t.Errorf("got read-only %t, want %t", got, tt.readOnly)
+			}
+		})
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	tests := []struct {
+		command  string
+		readOnly bool
+	}{
+		{"kubectl get pods -n bookinfo -o name | grep ratings", true},
+		{"kubectl get pods -n bookinfo; oc get smcp -n istio-system", true},
+		{"oc get smcp/basic -o json | jq -r .status.readiness", true},
+		{"kubectl get pods -o name | xargs kubectl delete", false},
+		{"kubectl get ns bookinfo || kubectl create ns bookinfo", false},
+		{"kubectl get ns bookinfo && oc -n bookinfo apply -f gateway.yaml", false},
+		{"sleep 5; kubectl get pods", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := isReadOnly(tt.command); got != tt.readOnly {
+				t.Errorf("got read-only %t, want %t", got, tt.readOnly)
+			}
+		})
+	}
+}
+
+func TestPlanExecute(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(t.TempDir(), "gateway.yaml")
+	if err := ioutil.WriteFile(manifest, []byte("kind: Gateway\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := &Plan{Dir: dir, stubs: &FakeExecutor{Unmatched: &CommandResult{}}}
+	p.stubs.On(`^kubectl get pods `, CommandResult{Stdout: "ratings-v1-1"})
+
+	tests := []struct {
+		command *CommandBuilder
+		want    CommandResult
+	}{
+		{command: Kubectl("get", "pods", "-n", "bookinfo"), want: CommandResult{Stdout: "ratings-v1-1"}},
+		// queries without a stub succeed without output
+		{command: Oc("get", "smcp", "-n", "istio-system"), want: CommandResult{}},
+		// changes are not run, even if a stub matches
+		{command: Kubectl("get", "pods").Pipe(NewCommand("xargs", "kubectl", "delete", "pod")), want: CommandResult{}},
+		{command: Kubectl("apply", "-f", manifest).Namespace("bookinfo"), want: CommandResult{}},
+		{command: Kubectl("apply").Namespace("bookinfo").Manifest("kind: Service\n"), want: CommandResult{}},
+	}
+	for _, tt := range tests {
+		got, err := p.Execute(context.Background(), tt.command.Command())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.command, err)
+		}
+		if got.Stdout != tt.want.Stdout || got.ExitCode != tt.want.ExitCode {
+			t.Errorf("%s: got %+v, want %+v", tt.command, got, tt.want)
+		}
+	}
+
+	commands, err := ioutil.ReadFile(filepath.Join(dir, "commands.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the standard input spans several lines
+	steps := regexp.MustCompile(`(?m)^\d{4} \w+: \S+ \S+`).FindAllString(string(commands), -1)
+	want := []string{"0001 query: kubectl get", "0002 query: oc get", "0003 run: kubectl get", "0004 run: kubectl apply", "0005 run: kubectl apply"}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("got steps %q, want %q\n%s", steps, want, commands)
+	}
+
+	// the manifests read by the changes are saved next to the commands
+	for name, want := range map[string]string{"0004-gateway.yaml": "kind: Gateway\n", "0005-stdin": "kind: Service\n"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s: got %q (%v), want %q", name, data, err, want)
+		}
+	}
+}
+
+func TestPlanRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	p := &Plan{Dir: dir, stubs: &FakeExecutor{Unmatched: &CommandResult{}}}
+	previous := SetHTTPInterceptor(p)
+	t.Cleanup(func() { SetHTTPInterceptor(previous) })
+
+	// the address is not reachable, the request must not be sent
+	req, err := http.NewRequest("GET", "http://127.0.0.1:1/productpage", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "bookinfo.example.com"
+	resp, err := HTTPClient(nil).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || len(body) != 0 {
+		t.Errorf("got %d %q, want an empty 200 OK response", resp.StatusCode, body)
+	}
+
+	commands, err := ioutil.ReadFile(filepath.Join(dir, "commands.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0001 request: GET http://127.0.0.1:1/productpage (host bookinfo.example.com)\n"; string(commands) != want {
+		t.Errorf("got %q, want %q", commands, want)
+	}
+}
+
+func TestDescribeManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+	}{
+		{
+			name: "objects",
+			manifest: "apiVersion: networking.istio.io/v1beta1\nkind: VirtualService\nmetadata:\n  name: reviews\n  namespace: bookinfo\n" +
+				"---\napiVersion: networking.istio.io/v1beta1\nkind: DestinationRule\nmetadata:\n  labels:\n    app: reviews\n  name: reviews\n",
+			want: "VirtualService reviews, DestinationRule reviews",
+		},
+		{
+			name:     "document without a name",
+			manifest: "kind: Gateway\nspec:\n  name: other\n---\nkind: Service\nmetadata:\n  name: details\n",
+			want:     "Service details",
+		},
+		{
+			name:     "no objects",
+			manifest: "members:\n- bookinfo\n",
+			want:     "20 bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeManifest(tt.manifest); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	failureHandlersMu sync.Mutex
+	failureHandlers   []func(t *testing.T)
+)
+
+// OnTestFailure registers a function called by RecoverPanic when the test or subtest it was deferred in failed.
+func OnTestFailure(fn func(t *testing.T)) {
+	failureHandlersMu.Lock()
+	defer failureHandlersMu.Unlock()
+	failureHandlers = append(failureHandlers, fn)
+}
+
+func runFailureHandlers(t *testing.T) {
+	failureHandlersMu.Lock()
+	handlers := append([]func(t *testing.T){}, failureHandlers...)
+	failureHandlersMu.Unlock()
+
+	for _, fn := range handlers {
+		fn(t)
+	}
+}
+
+// DumpResources saves diagnostic information about the control plane in meshNamespace and the given
+// application namespaces into dir: resources, events, Istio configuration, the SMCP and SMMR,
+// the istiod logs and the logs and Envoy configuration of every proxy.
+func DumpResources(dir, meshNamespace string, namespaces []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var errs []string
+	dump := func(file, format string, args ...interface{}) {
+		msg, err := ShellSilent(format, args...)
+		if err != nil {
+			// the error details are kept in the file
+			errs = append(errs, file)
+			msg = fmt.Sprintf("%s\n# %s: %v\n", msg, fmt.Sprintf(format, args...), err)
+		}
+		if err := WriteTextFile(filepath.Join(dir, file), msg); err != nil {
+			errs = append(errs, fmt.Sprintf("%s (%v)", file, err))
+		}
+	}
+
+	dump("smcp.yaml", "oc get smcp -n %s -o yaml", meshNamespace)
+	dump("smmr.yaml", "oc get smmr -n %s -o yaml", meshNamespace)
+	dump("istiod.log", "oc logs -n %s -l app=istiod --all-containers --tail=-1", meshNamespace)
+
+	seen := map[string]bool{}
+	for _, ns := range append([]string{meshNamespace}, namespaces...) {
+		if seen[ns] {
+			continue
+		}
+		seen[ns] = true
+
+		nsDir := filepath.Join(dir, ns)
+		if err := os.MkdirAll(nsDir, 0755); err != nil {
+			return err
+		}
+		prefix := ns + string(filepath.Separator)
+
+		dump(prefix+"resources.txt", "oc get %s -n %s -o wide", strings.Join(logDumpResources, ","), ns)
+		dump(prefix+"resources.yaml", "oc get %s -n %s -o yaml", strings.Join(logDumpResources, ","), ns)
+		dump(prefix+"events.txt", "oc get events -n %s --sort-by=.lastTimestamp", ns)
+		dump(prefix+"istio.yaml", "oc get %s -n %s -o yaml", strings.Join(istioDumpResources, ","), ns)
+
+		pods, err := ShellSilent(`oc get pods -n %s -o jsonpath='{range .items[*]}{.metadata.name}{" "}{.spec.containers[*].name}{"\n"}{end}'`, ns)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s pods (%v)", ns, err))
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(pods), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			pod := fields[0]
+			for _, container := range fields[1:] {
+				if container == "istio-proxy" {
+					dump(prefix+pod+".proxy.log", "oc logs -n %s %s -c istio-proxy", ns, pod)
+					dump(prefix+pod+".config_dump.json", "oc exec -n %s %s -c istio-proxy -- pilot-agent request GET config_dump", ns, pod)
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to dump %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
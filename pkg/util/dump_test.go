@@ -0,0 +1,79 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpResources(t *testing.T) {
+	fake := NewFakeExecutor().
+		On(`^oc get pods -n bookinfo `, CommandResult{Stdout: "productpage-v1-1 productpage istio-proxy\nmongodb-1 mongodb\n"}).
+		On(`^oc get pods -n istio-system `, CommandResult{Stdout: "istiod-basic-1 discovery\n"}).
+		On(`^oc get events -n bookinfo `, CommandResult{Stderr: "error: You must be logged in to the server (Unauthorized)", ExitCode: 1}).
+		On(`^oc logs -n bookinfo productpage-v1-1 -c istio-proxy$`, CommandResult{Stdout: "envoy log"})
+	fake.Unmatched = &CommandResult{Stdout: "ok"}
+	fakeCommands(t, fake)
+
+	dir := t.TempDir()
+	err := DumpResources(dir, "istio-system", []string{"bookinfo", "istio-system"})
+	if err == nil || !strings.Contains(err.Error(), filepath.Join("bookinfo", "events.txt")) {
+		t.Errorf("expected the failed events dump to be reported, got %v", err)
+	}
+
+	for _, file := range []string{
+		"smcp.yaml",
+		"smmr.yaml",
+		"istiod.log",
+		"istio-system/resources.yaml",
+		"bookinfo/resources.txt",
+		"bookinfo/istio.yaml",
+		"bookinfo/productpage-v1-1.proxy.log",
+		"bookinfo/productpage-v1-1.config_dump.json",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Errorf("%s was not saved: %v", file, err)
+		}
+	}
+	// only the containers named istio-proxy are proxies
+	for _, file := range []string{"bookinfo/mongodb-1.proxy.log", "istio-system/istiod-basic-1.proxy.log"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err == nil {
+			t.Errorf("%s was saved", file)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "bookinfo", "events.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Unauthorized") || !strings.Contains(string(data), "# oc get events -n bookinfo") {
+		t.Errorf("the failure is not kept in the file: %q", data)
+	}
+
+	// the mesh namespace is dumped once
+	count := 0
+	for _, c := range fake.Commands() {
+		if strings.HasPrefix(c, "oc get events -n istio-system") {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("the mesh namespace was dumped %d times", count)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Environment describes the cluster and the control plane the tests run against.
+type Environment struct {
+	// Kubeconfig is the kubeconfig file used by kubectl and oc. It is empty when the default is used.
+	Kubeconfig string
+	// Server is the URL of the API server.
+	Server string
+	// MeshNamespace is the namespace of the control plane.
+	MeshNamespace string
+	// SMCPName is the name of the ServiceMeshControlPlane.
+	SMCPName string
+	// GatewayHost is the host of the istio-ingressgateway route.
+	GatewayHost string
+	// IngressHTTPPort is the http2 port of the istio-ingressgateway service.
+	IngressHTTPPort string
+	// SecureIngressPort is the https port of the istio-ingressgateway service.
+	SecureIngressPort string
+}
+
+var (
+	envOnce sync.Once
+	envMu   sync.Mutex
+	env     *Environment
+	// testEnvs maps the name of a top level test to the environment handed to it with BindEnvironment.
+	testEnvs = map[string]*Environment{}
+)
+
+// Env returns the environment of the run. It is resolved from the cluster on first use, so that
+// packages can be imported without a cluster. Tests use TestEnv instead, which returns the environment
+// of their own session when it is recorded or replayed.
+func Env() *Environment {
+	envOnce.Do(func() {
+		if resolvedEnvironment() == nil {
+			e := resolveEnvironment()
+			envMu.Lock()
+			env = e
+			envMu.Unlock()
+		}
+	})
+	return resolvedEnvironment()
+}
+
+// TestEnv returns the environment handed to the test case of t with BindEnvironment, or Env if none was.
+// Tests call it instead of querying the cluster for the gateway host and ports themselves.
+func TestEnv(t *testing.T) *Environment {
+	envMu.Lock()
+	e := testEnvs[topLevelTest(t.Name())]
+	envMu.Unlock()
+	if e != nil {
+		return e
+	}
+	return Env()
+}
+
+// BindEnvironment hands an environment to the test case of t until it completes, e.g. the environment
+// saved in the recording the test case replays.
+func BindEnvironment(t *testing.T, e *Environment) {
+	name := topLevelTest(t.Name())
+	envMu.Lock()
+	testEnvs[name] = e
+	envMu.Unlock()
+
+	t.Cleanup(func() {
+		envMu.Lock()
+		if testEnvs[name] == e {
+			delete(testEnvs, name)
+		}
+		envMu.Unlock()
+	})
+}
+
+// SetEnvironment replaces the environment returned by Env, e.g. to run helpers without a cluster.
+// It must be called before the first call of Env.
+func SetEnvironment(e *Environment) {
+	envMu.Lock()
+	defer envMu.Unlock()
+	env = e
+}
+
+// resolvedEnvironment returns the environment if it has been resolved or set, without resolving it.
+func resolvedEnvironment() *Environment {
+	envMu.Lock()
+	defer envMu.Unlock()
+	return env
+}
+
+func resolveEnvironment() *Environment {
+	e := &Environment{
+		Kubeconfig:    os.Getenv("KUBECONFIG"),
+		MeshNamespace: GetConfig().MeshNamespace,
+		SMCPName:      GetConfig().SMCPName,
+	}
+	e.Server = query(`oc whoami --show-server`)
+	e.GatewayHost = query(`kubectl get routes -n %s istio-ingressgateway -o jsonpath='{.spec.host}'`, e.MeshNamespace)
+	e.IngressHTTPPort = query(`kubectl -n %s get service istio-ingressgateway -o jsonpath='{.spec.ports[?(@.name=="http2")].port}'`, e.MeshNamespace)
+	e.SecureIngressPort = query(`kubectl -n %s get service istio-ingressgateway -o jsonpath='{.spec.ports[?(@.name=="https")].port}'`, e.MeshNamespace)
+
+	Log.Infof("Environment: server %s, control plane %s/%s, gateway %s (http %s, https %s)",
+		e.Server, e.MeshNamespace, e.SMCPName, e.GatewayHost, e.IngressHTTPPort, e.SecureIngressPort)
+	return e
+}
+
+// query runs a read-only command and returns its output, or an empty string if it failed.
+func query(format string, args ...interface{}) string {
+	msg, err := ShellSilent(format, args...)
+	if err != nil {
+		Log.Warnf("Failed to resolve environment: %v", err)
+		return ""
+	}
+	return strings.TrimSpace(msg)
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// clusterCommands answers the queries resolving the environment of a cluster with a gateway.
+func clusterCommands() *FakeExecutor {
+	return NewFakeExecutor().
+		On(`^oc whoami --show-server$`, CommandResult{Stdout: "https://api.cluster.example.com:6443\n"}).
+		On(`^kubectl get routes -n mesh-system istio-ingressgateway `, CommandResult{Stdout: "istio-ingressgateway-mesh-system.apps.example.com"}).
+		On(`^kubectl -n mesh-system get service istio-ingressgateway .*"http2"`, CommandResult{Stdout: "80"}).
+		On(`^kubectl -n mesh-system get service istio-ingressgateway .*"https"`, CommandResult{Stdout: "443"})
+}
+
+// unresolvedEnvironment restores the environment, and resolves it again on the next call of Env,
+// when t completes. The configuration names the control plane basic in mesh-system.
+func unresolvedEnvironment(t *testing.T) {
+	t.Helper()
+	envMu.Lock()
+	previous := env
+	env = nil
+	envOnce = sync.Once{}
+	envMu.Unlock()
+
+	previousConfig := GetConfig()
+	c := *previousConfig
+	c.MeshNamespace = "mesh-system"
+	c.SMCPName = "basic"
+	SetConfig(&c)
+
+	t.Cleanup(func() {
+		SetConfig(previousConfig)
+		envMu.Lock()
+		env = previous
+		envOnce = sync.Once{}
+		envMu.Unlock()
+	})
+}
+
+func TestResolveEnvironment(t *testing.T) {
+	tests := []struct {
+		name string
+		fake *FakeExecutor
+		want Environment
+	}{
+		{
+			name: "cluster with a gateway",
+			fake: clusterCommands(),
+			want: Environment{
+				Kubeconfig:        "/home/user/.kube/config",
+				Server:            "https://api.cluster.example.com:6443",
+				MeshNamespace:     "mesh-system",
+				SMCPName:          "basic",
+				GatewayHost:       "istio-ingressgateway-mesh-system.apps.example.com",
+				IngressHTTPPort:   "80",
+				SecureIngressPort: "443",
+			},
+		},
+		{
+			// e.g. before the control plane is installed
+			name: "cluster without a gateway",
+			fake: NewFakeExecutor().
+				On(`^oc whoami --show-server$`, CommandResult{Stdout: "https://api.cluster.example.com:6443\n"}).
+				On(`istio-ingressgateway`, CommandResult{Stderr: `Error from server (NotFound): routes.route.openshift.io "istio-ingressgateway" not found`, ExitCode: 1}),
+			want: Environment{
+				Kubeconfig:    "/home/user/.kube/config",
+				Server:        "https://api.cluster.example.com:6443",
+				MeshNamespace: "mesh-system",
+				SMCPName:      "basic",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unresolvedEnvironment(t)
+			t.Setenv("KUBECONFIG", "/home/user/.kube/config")
+			fakeCommands(t, tt.fake)
+
+			if got := resolveEnvironment(); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvResolvesOnce(t *testing.T) {
+	unresolvedEnvironment(t)
+	fake := clusterCommands()
+	fakeCommands(t, fake)
+
+	if commands := fake.Commands(); len(commands) != 0 {
+		t.Fatalf("the environment was resolved before its first use: %q", commands)
+	}
+	first := Env()
+	if first.GatewayHost != "istio-ingressgateway-mesh-system.apps.example.com" {
+		t.Errorf("got gateway host %q", first.GatewayHost)
+	}
+	if Env() != first || TestEnv(t) != first {
+		t.Error("expected the environment resolved by the first call")
+	}
+	if commands := fake.Commands(); len(commands) != 4 {
+		t.Errorf("expected the 4 queries of a single resolution, got %q", commands)
+	}
+}
+
+func TestSetEnvironment(t *testing.T) {
+	unresolvedEnvironment(t)
+	fake := NewFakeExecutor()
+	fakeCommands(t, fake)
+
+	e := &Environment{MeshNamespace: "istio-system", SMCPName: "basic", GatewayHost: "gateway.example.com"}
+	SetEnvironment(e)
+	if Env() != e || TestEnv(t) != e {
+		t.Error("expected the environment that was set")
+	}
+	if commands := fake.Commands(); len(commands) != 0 {
+		t.Errorf("expected no queries of the cluster, got %q", commands)
+	}
+}
+
+func TestBindEnvironment(t *testing.T) {
+	unresolvedEnvironment(t)
+	fakeCommands(t, NewFakeExecutor())
+	global := &Environment{GatewayHost: "gateway.example.com"}
+	SetEnvironment(global)
+	recorded := &Environment{GatewayHost: "recorded.example.com"}
+
+	t.Run("replay", func(t *testing.T) {
+		BindEnvironment(t, recorded)
+		if got := TestEnv(t); got != recorded {
+			t.Errorf("got %+v, want the bound environment", got)
+		}
+		// subtests share the environment of their top level test
+		t.Run("step", func(t *testing.T) {
+			if got := TestEnv(t); got != recorded {
+				t.Errorf("got %+v in a subtest, want the bound environment", got)
+			}
+		})
+		// Env is the environment of the run, not of the test case
+		if got := Env(); got != global {
+			t.Errorf("got %+v from Env, want the environment of the run", got)
+		}
+	})
+
+	if got := TestEnv(t); got != global {
+		t.Errorf("got %+v after the test case completed, want the environment of the run", got)
+	}
+}
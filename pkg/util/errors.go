@@ -0,0 +1,131 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// StatusReason classifies the failure of a command or of a KubeClient request, so that callers can
+// branch on it instead of matching error messages:
+//
+//	if err := util.CreateOCPNamespace(ns); util.IsAlreadyExists(err) { ... }
+type StatusReason string
+
+const (
+	ReasonNotFound           StatusReason = "NotFound"
+	ReasonAlreadyExists      StatusReason = "AlreadyExists"
+	ReasonForbidden          StatusReason = "Forbidden"
+	ReasonConflict           StatusReason = "Conflict"
+	ReasonTimeout            StatusReason = "Timeout"
+	ReasonValidationRejected StatusReason = "ValidationRejected"
+	ReasonUnknown            StatusReason = ""
+)
+
+var (
+	// serverErrorReason matches the reason of the errors kubectl and oc print for failed requests, e.g.
+	// `Error from server (NotFound): pods "reviews-v1" not found`.
+	serverErrorReason = regexp.MustCompile(`Error from server \((\w+)\)`)
+	// webhookDenied matches the rejection of a request by a validating admission webhook, e.g.
+	// `admission webhook "smcp.validation.maistra.io" denied the request: ...`.
+	webhookDenied = regexp.MustCompile(`admission webhook "[^"]*" denied the request`)
+)
+
+// classifyFailure returns the reason of a failure from the output of the command and the context it ran with.
+func classifyFailure(ctx context.Context, output string) StatusReason {
+	if ctx != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ReasonTimeout
+	}
+	if webhookDenied.MatchString(output) || strings.Contains(output, " is invalid: ") {
+		return ReasonValidationRejected
+	}
+	if m := serverErrorReason.FindStringSubmatch(output); m != nil {
+		switch reason := StatusReason(m[1]); reason {
+		case ReasonNotFound, ReasonAlreadyExists, ReasonForbidden, ReasonConflict:
+			return reason
+		case "Invalid":
+			return ReasonValidationRejected
+		case "Timeout", "ServerTimeout":
+			return ReasonTimeout
+		}
+	}
+	if strings.Contains(output, "timed out waiting for the condition") {
+		return ReasonTimeout
+	}
+	return ReasonUnknown
+}
+
+// CommandError is the error of a command that failed or could not be started.
+type CommandError struct {
+	Command string
+	Result  CommandResult
+	Reason  StatusReason
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command failed: %q %v", e.Result.Output, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ReasonOf returns the reason of a *CommandError or *KubeError in the chain of err, or ReasonUnknown.
+func ReasonOf(err error) StatusReason {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Reason
+	}
+	var kubeErr *KubeError
+	if errors.As(err, &kubeErr) {
+		return kubeErr.Reason
+	}
+	return ReasonUnknown
+}
+
+// IsNotFound returns true if the requested object does not exist.
+func IsNotFound(err error) bool {
+	return ReasonOf(err) == ReasonNotFound
+}
+
+// IsAlreadyExists returns true if the object could not be created because it exists already.
+func IsAlreadyExists(err error) bool {
+	return ReasonOf(err) == ReasonAlreadyExists
+}
+
+// IsForbidden returns true if the request was not allowed for the user.
+func IsForbidden(err error) bool {
+	return ReasonOf(err) == ReasonForbidden
+}
+
+// IsConflict returns true if the object was changed by someone else in the meantime.
+func IsConflict(err error) bool {
+	return ReasonOf(err) == ReasonConflict
+}
+
+// IsTimeout returns true if the command or the request timed out.
+func IsTimeout(err error) bool {
+	return ReasonOf(err) == ReasonTimeout
+}
+
+// IsValidationRejected returns true if the object was rejected by the API server or a validating webhook.
+func IsValidationRejected(err error) bool {
+	return ReasonOf(err) == ReasonValidationRejected
+}
@@ -0,0 +1,162 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   StatusReason
+	}{
+		{
+			name:   "not found",
+			output: `Error from server (NotFound): pods "reviews-v1" not found`,
+			want:   ReasonNotFound,
+		},
+		{
+			name:   "namespace not found",
+			output: `Error from server (NotFound): namespaces "bookinfo" not found`,
+			want:   ReasonNotFound,
+		},
+		{
+			name:   "already exists",
+			output: `Error from server (AlreadyExists): project.project.openshift.io "bookinfo" already exists`,
+			want:   ReasonAlreadyExists,
+		},
+		{
+			name:   "forbidden",
+			output: `Error from server (Forbidden): pods is forbidden: User "developer" cannot list resource "pods" in API group "" in the namespace "istio-system"`,
+			want:   ReasonForbidden,
+		},
+		{
+			name: "conflict",
+			output: `Error from server (Conflict): Operation cannot be fulfilled on servicemeshmemberrolls.maistra.io "default": ` +
+				`the object has been modified; please apply your changes to the latest version and try again`,
+			want: ReasonConflict,
+		},
+		{
+			name: "3scale webhook rejection",
+			output: `Error from server (Forbidden): admission webhook "smcp.validation.maistra.io" denied the request: ` +
+				`support for 3scale has been removed in v2.1; please remove the spec.addons.3scale field to continue`,
+			want: ReasonValidationRejected,
+		},
+		{
+			name: "webhook rejection without server reason",
+			output: `Error from server: error when creating "STDIN": admission webhook "smmr.validation.maistra.io" ` +
+				`denied the request: user 'developer' does not have permission to use namespace bookinfo`,
+			want: ReasonValidationRejected,
+		},
+		{
+			name: "invalid object",
+			output: `The VirtualService "reviews" is invalid: spec.http[0].route[0].destination.host: ` +
+				`Required value: destination.host`,
+			want: ReasonValidationRejected,
+		},
+		{
+			name:   "invalid reason",
+			output: `Error from server (Invalid): error when creating "STDIN": Gateway.networking.istio.io "bookinfo-gateway" is invalid`,
+			want:   ReasonValidationRejected,
+		},
+		{
+			name:   "server timeout",
+			output: `Error from server (Timeout): the server was unable to return a response in the time allotted, but may still be processing the request (get pods)`,
+			want:   ReasonTimeout,
+		},
+		{
+			name:   "server timeout of a long request",
+			output: `Error from server (ServerTimeout): the server cannot complete the requested operation at this time, try again later (post deployments.apps)`,
+			want:   ReasonTimeout,
+		},
+		{
+			name:   "oc wait timeout",
+			output: `error: timed out waiting for the condition on servicemeshcontrolplanes/basic`,
+			want:   ReasonTimeout,
+		},
+		{
+			name:   "other server error",
+			output: `Error from server (InternalError): an error on the server ("") has prevented the request from succeeding`,
+			want:   ReasonUnknown,
+		},
+		{
+			name:   "client error",
+			output: `error: the server doesn't have a resource type "smcp"`,
+			want:   ReasonUnknown,
+		},
+		{
+			name:   "no output",
+			output: "",
+			want:   ReasonUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyFailure(context.Background(), tt.output); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyFailureContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	// the deadline wins over the output of the killed command
+	if got := classifyFailure(ctx, `Error from server (NotFound): pods "reviews-v1" not found`); got != ReasonTimeout {
+		t.Errorf("deadline exceeded: got %q, want %q", got, ReasonTimeout)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if got := classifyFailure(ctx, "signal: killed"); got != ReasonUnknown {
+		t.Errorf("canceled: got %q, want %q", got, ReasonUnknown)
+	}
+}
+
+func TestReasonOf(t *testing.T) {
+	fake := NewFakeExecutor().
+		On(`get pods missing`, CommandResult{Stderr: `Error from server (NotFound): pods "missing" not found`, ExitCode: 1}).
+		On(`patch smcp`, CommandResult{Stderr: `Error from server (Forbidden): admission webhook "smcp.validation.maistra.io" denied the request: 3scale`, ExitCode: 1})
+	fakeCommands(t, fake)
+
+	_, err := ShellSilent("kubectl get pods missing -n bookinfo")
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found command error, got %v", err)
+	}
+	// the reason survives wrapping
+	if !IsNotFound(fmt.Errorf("failed to get the pod: %w", err)) {
+		t.Errorf("the reason of a wrapped error is lost")
+	}
+
+	err = GetKubeClient().Patch(context.Background(), "smcp", "istio-system", "basic", MergePatch, `{"spec":{"addons":{"3scale":{"enabled":true}}}}`)
+	if !IsValidationRejected(err) || IsForbidden(err) {
+		t.Errorf("expected a validation rejection, got %q (%v)", ReasonOf(err), err)
+	}
+
+	if got := ReasonOf(errors.New("not found")); got != ReasonUnknown {
+		t.Errorf("other errors: got %q, want %q", got, ReasonUnknown)
+	}
+	if ReasonOf(nil) != ReasonUnknown || IsNotFound(nil) {
+		t.Errorf("nil is not classified")
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandResult is the outcome of a command.
+type CommandResult struct {
+	Stdout string
+	Stderr string
+	// Output is stdout and stderr interleaved in the order they were written.
+	Output   string
+	ExitCode int
+	// Duration is the time the command ran.
+	Duration time.Duration
+}
+
+// Command is a command run by an Executor: a program with its arguments, run without a shell, or a
+// shell script. Scripts are only used for the format strings of Shell and its variants and for
+// pipelines, see NewCommand.
+type Command struct {
+	// Args are the program and its arguments.
+	Args []string
+	// Script is a shell script run with `sh -c` instead of Args.
+	Script string
+	// Stdin is the standard input of the command, e.g. a manifest or a patch.
+	Stdin string
+}
+
+// ShellCommand returns a command running the script with `sh -c`.
+func ShellCommand(script string) Command {
+	return Command{Script: script}
+}
+
+// String returns the command as it would be typed in bash, e.g. to log, record or match it. Arguments
+// are quoted where needed and the standard input is appended as a here-string.
+func (c Command) String() string {
+	s := c.Script
+	if s == "" {
+		quoted := make([]string, 0, len(c.Args))
+		for _, arg := range c.Args {
+			quoted = append(quoted, shellQuote(arg))
+		}
+		s = strings.Join(quoted, " ")
+	}
+	if c.Stdin != "" {
+		s += " <<< " + shellQuote(c.Stdin)
+	}
+	return s
+}
+
+// Executor runs the commands issued by Shell, its variants and the command builder. The error is non-nil
+// if the command could not be started or exited with a non-zero code. Executors that do not run commands,
+// e.g. to record or fake them, identify commands by their String.
+type Executor interface {
+	Execute(ctx context.Context, cmd Command) (CommandResult, error)
+}
+
+var (
+	executorMu sync.RWMutex
+	executor   Executor = ShellExecutor{}
+)
+
+// SetExecutor replaces the executor of Shell and its variants and returns the previous one.
+func SetExecutor(e Executor) Executor {
+	executorMu.Lock()
+	defer executorMu.Unlock()
+	previous := executor
+	executor = e
+	return previous
+}
+
+// GetExecutor returns the executor of Shell and its variants.
+func GetExecutor() Executor {
+	executorMu.RLock()
+	defer executorMu.RUnlock()
+	return executor
+}
+
+// ShellExecutor runs programs directly and scripts with `sh -c`.
+type ShellExecutor struct{}
+
+// Execute runs the command and waits for it to complete.
+func (ShellExecutor) Execute(ctx context.Context, cmd Command) (CommandResult, error) {
+	var c *exec.Cmd
+	switch {
+	case cmd.Script != "":
+		c = exec.CommandContext(ctx, "sh", "-c", cmd.Script) // #nosec
+	case len(cmd.Args) > 0:
+		c = exec.CommandContext(ctx, cmd.Args[0], cmd.Args[1:]...) // #nosec
+	default:
+		return CommandResult{ExitCode: -1}, errors.New("empty command")
+	}
+	if cmd.Stdin != "" {
+		c.Stdin = strings.NewReader(cmd.Stdin)
+	}
+
+	var stdout, stderr, output bytes.Buffer
+	c.Stdout = io.MultiWriter(&stdout, &output)
+	c.Stderr = io.MultiWriter(&stderr, &output)
+	start := time.Now()
+	err := c.Run()
+
+	res := CommandResult{Stdout: stdout.String(), Stderr: stderr.String(), Output: output.String(), Duration: time.Since(start)}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		res.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		res.ExitCode = -1
+	}
+	return res, err
+}
+
+// CommandRecord is a command run by a RecordingExecutor.
+type CommandRecord struct {
+	// Command is the String of the command.
+	Command  string
+	Result   CommandResult
+	Err      error
+	Start    time.Time
+	Duration time.Duration
+}
+
+// RecordingExecutor runs commands with another executor and records them with their results.
+type RecordingExecutor struct {
+	Executor Executor
+
+	mu      sync.Mutex
+	records []CommandRecord
+}
+
+// NewRecordingExecutor returns an executor recording the commands run by e.
+func NewRecordingExecutor(e Executor) *RecordingExecutor {
+	return &RecordingExecutor{Executor: e}
+}
+
+// Execute runs the command with the wrapped executor and records it.
+func (r *RecordingExecutor) Execute(ctx context.Context, cmd Command) (CommandResult, error) {
+	start := time.Now()
+	res, err := r.Executor.Execute(ctx, cmd)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, CommandRecord{
+		Command:  cmd.String(),
+		Result:   res,
+		Err:      err,
+		Start:    start,
+		Duration: time.Since(start),
+	})
+	return res, err
+}
+
+// Records returns the commands recorded so far, in the order they completed.
+func (r *RecordingExecutor) Records() []CommandRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]CommandRecord{}, r.records...)
+}
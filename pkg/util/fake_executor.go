@@ -0,0 +1,108 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// FakeExecutor returns scripted results instead of running commands. Every rule matches commands by
+// a regular expression; the first matching rule wins. A rule with several results returns them one
+// after another and keeps returning the last one, e.g. to simulate a pod becoming ready while polled.
+//
+//	fake := util.NewFakeExecutor().
+//		On(`kubectl get pods -n bookinfo`, util.CommandResult{Stdout: "productpage-v1-6b746f74dc-9stvs"}).
+//		OnSequence(`oc get smcp`, util.CommandResult{ExitCode: 1}, util.CommandResult{Stdout: "Ready"})
+//	defer util.SetExecutor(util.SetExecutor(fake))
+type FakeExecutor struct {
+	// Unmatched is the result of the commands not matched by any rule. If it is nil, they fail with exit code 127.
+	Unmatched *CommandResult
+
+	mu       sync.Mutex
+	rules    []*fakeRule
+	commands []string
+}
+
+type fakeRule struct {
+	pattern *regexp.Regexp
+	results []CommandResult
+	calls   int
+}
+
+// NewFakeExecutor returns a fake executor without rules.
+func NewFakeExecutor() *FakeExecutor {
+	return &FakeExecutor{}
+}
+
+// On returns the result for the commands matching the regular expression pattern.
+func (f *FakeExecutor) On(pattern string, result CommandResult) *FakeExecutor {
+	return f.OnSequence(pattern, result)
+}
+
+// OnSequence returns the results one after another for the commands matching the regular expression pattern.
+func (f *FakeExecutor) OnSequence(pattern string, results ...CommandResult) *FakeExecutor {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, &fakeRule{pattern: regexp.MustCompile(pattern), results: results})
+	return f
+}
+
+// Execute returns the scripted result of the first rule matching the String of the command. The output
+// is the stdout followed by the stderr of the result, unless the result sets it.
+func (f *FakeExecutor) Execute(ctx context.Context, cmd Command) (CommandResult, error) {
+	command := cmd.String()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, command)
+
+	for _, rule := range f.rules {
+		if !rule.pattern.MatchString(command) || len(rule.results) == 0 {
+			continue
+		}
+		i := rule.calls
+		if i >= len(rule.results) {
+			i = len(rule.results) - 1
+		}
+		rule.calls++
+
+		return f.result(rule.results[i])
+	}
+
+	if f.Unmatched != nil {
+		return f.result(*f.Unmatched)
+	}
+	return f.result(CommandResult{Stderr: fmt.Sprintf("fake: no result for command %q\n", command), ExitCode: 127})
+}
+
+// result fills in the output and returns the error of a non-zero exit code.
+func (f *FakeExecutor) result(res CommandResult) (CommandResult, error) {
+	if res.Output == "" {
+		res.Output = res.Stdout + res.Stderr
+	}
+	if res.ExitCode != 0 {
+		return res, fmt.Errorf("exit status %d", res.ExitCode)
+	}
+	return res, nil
+}
+
+// Commands returns the commands executed so far.
+func (f *FakeExecutor) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.commands...)
+}
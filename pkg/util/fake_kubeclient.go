@@ -0,0 +1,378 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FakeKubeClient is a KubeClient keeping the objects in memory, for unit tests of the helpers:
+//
+//	fake := util.NewFakeKubeClient()
+//	fake.Add("pods", &util.Pod{Metadata: util.ObjectMeta{Name: "reviews-v1-7bc8", Namespace: "bookinfo", Labels: map[string]string{"app": "reviews"}}})
+//	defer util.SetKubeClient(util.SetKubeClient(fake))
+//
+// Kinds are matched in the usual kubectl forms, so objects added as "pods" are found as "pod", "po",
+// "Pod" and "pod.v1". Patches of all types except JSONPatch are applied as merge patches. Exec and Logs return the results set by OnExec and
+// SetLogs.
+type FakeKubeClient struct {
+	mu       sync.Mutex
+	objects  map[string]map[string]*Object // by fakeKind, then by "<namespace>/<name>"
+	version  int
+	exec     func(namespace, pod, container string, command []string) (CommandResult, error)
+	logs     map[string]string
+	requests []string
+}
+
+// NewFakeKubeClient returns a fake client without objects.
+func NewFakeKubeClient() *FakeKubeClient {
+	return &FakeKubeClient{objects: map[string]map[string]*Object{}, logs: map[string]string{}}
+}
+
+// Add stores objects of a kind, e.g. *Pod or *Object values. It panics if an object cannot be encoded.
+func (f *FakeKubeClient) Add(kind string, objects ...interface{}) *FakeKubeClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, obj := range objects {
+		o := &Object{}
+		if err := convertObject(obj, o); err != nil {
+			panic(fmt.Sprintf("fake: cannot encode %T: %v", obj, err))
+		}
+		f.store(kind, o)
+	}
+	return f
+}
+
+// OnExec sets the function returning the results of Exec. Without it, Exec fails with exit code 127.
+func (f *FakeKubeClient) OnExec(exec func(namespace, pod, container string, command []string) (CommandResult, error)) *FakeKubeClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.exec = exec
+	return f
+}
+
+// SetLogs sets the logs of a container of a pod.
+func (f *FakeKubeClient) SetLogs(namespace, pod, container, logs string) *FakeKubeClient {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logs[namespace+"/"+pod+"/"+container] = logs
+	return f
+}
+
+// Requests returns the requests made so far, e.g. "get pods bookinfo/reviews-v1-7bc8".
+func (f *FakeKubeClient) Requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.requests...)
+}
+
+// shortKinds are the short names kubectl accepts for the kinds used by the tests.
+var shortKinds = map[string]string{
+	"po":     "pod",
+	"svc":    "service",
+	"deploy": "deployment",
+	"ns":     "namespace",
+	"cm":     "configmap",
+	"sa":     "serviceaccount",
+	"smcp":   "servicemeshcontrolplane",
+	"smmr":   "servicemeshmemberroll",
+	"smm":    "servicemeshmember",
+	"vs":     "virtualservice",
+	"dr":     "destinationrule",
+	"gw":     "gateway",
+	"se":     "serviceentry",
+}
+
+// fakeKind returns the singular lower case name of a kind, e.g. "virtualservice" for
+// "virtualservices.networking.istio.io", "VirtualService" and "vs".
+func fakeKind(kind string) string {
+	kind = strings.ToLower(kind)
+	if i := strings.Index(kind, "."); i > 0 {
+		kind = kind[:i]
+	}
+	if long, ok := shortKinds[kind]; ok {
+		return long
+	}
+	switch {
+	case strings.HasSuffix(kind, "ies"):
+		return strings.TrimSuffix(kind, "ies") + "y"
+	case strings.HasSuffix(kind, "sses"):
+		return strings.TrimSuffix(kind, "es")
+	case strings.HasSuffix(kind, "s") && !strings.HasSuffix(kind, "ss"):
+		return strings.TrimSuffix(kind, "s")
+	}
+	return kind
+}
+
+// store saves a copy of the object with a new resource version. The caller holds f.mu.
+func (f *FakeKubeClient) store(kind string, o *Object) {
+	stored := &Object{}
+	_ = convertObject(o, stored)
+	if stored.Content == nil {
+		stored.Content = map[string]interface{}{}
+	}
+	metadata, ok := stored.Content["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = map[string]interface{}{}
+		stored.Content["metadata"] = metadata
+	}
+	f.version++
+	metadata["resourceVersion"] = strconv.Itoa(f.version)
+	generation, _ := metadata["generation"].(float64)
+	metadata["generation"] = generation + 1
+
+	kind = fakeKind(kind)
+	if f.objects[kind] == nil {
+		f.objects[kind] = map[string]*Object{}
+	}
+	f.objects[kind][stored.Namespace()+"/"+stored.Name()] = stored
+}
+
+func (f *FakeKubeClient) request(verb, kind, namespace, name string) {
+	f.requests = append(f.requests, strings.TrimSpace(fmt.Sprintf("%s %s %s/%s", verb, kind, namespace, name)))
+}
+
+func notFound(kind, namespace, name string) *KubeError {
+	return &KubeError{Reason: ReasonNotFound, Kind: kind, Namespace: namespace, Name: name,
+		Message: fmt.Sprintf("Error from server (NotFound): %s not found", kindName(kind, name))}
+}
+
+// Get decodes a stored object.
+func (f *FakeKubeClient) Get(ctx context.Context, kind, namespace, name string, obj interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.request("get", kind, namespace, name)
+	o, ok := f.objects[fakeKind(kind)][namespace+"/"+name]
+	if !ok {
+		return notFound(kind, namespace, name)
+	}
+	return convertObject(o, obj)
+}
+
+// List decodes the stored objects matching the namespace and the selectors, ordered by namespace and name.
+func (f *FakeKubeClient) List(ctx context.Context, kind, namespace string, opts ListOptions, list interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.request("list", kind, namespace, "")
+
+	labels, err := parseSelector(opts.LabelSelector)
+	if err != nil {
+		return err
+	}
+	fields, err := parseSelector(opts.FieldSelector)
+	if err != nil {
+		return err
+	}
+	var items []*Object
+	objects := f.objects[fakeKind(kind)]
+	for _, key := range sortedObjectKeys(objects) {
+		o := objects[key]
+		if namespace != "" && o.Namespace() != namespace {
+			continue
+		}
+		if !labels.matches(func(key string) (string, bool) {
+			value, ok := o.Metadata().Labels[key]
+			return value, ok
+		}) {
+			continue
+		}
+		if !fields.matches(func(key string) (string, bool) {
+			value, ok := o.Field(strings.Split(key, ".")...)
+			if !ok {
+				return "", false
+			}
+			return fmt.Sprint(value), true
+		}) {
+			continue
+		}
+		items = append(items, o)
+	}
+	if items == nil {
+		items = []*Object{}
+	}
+	return convertObject(items, list)
+}
+
+// Apply stores the objects under their kind.
+func (f *FakeKubeClient) Apply(ctx context.Context, objects ...*Object) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, o := range objects {
+		f.request("apply", o.Kind(), o.Namespace(), o.Name())
+		f.store(o.Kind(), o)
+	}
+	return nil
+}
+
+// Delete removes a stored object.
+func (f *FakeKubeClient) Delete(ctx context.Context, kind, namespace, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.request("delete", kind, namespace, name)
+	if _, ok := f.objects[fakeKind(kind)][namespace+"/"+name]; !ok {
+		return notFound(kind, namespace, name)
+	}
+	delete(f.objects[fakeKind(kind)], namespace+"/"+name)
+	return nil
+}
+
+// Patch applies a merge patch to a stored object.
+func (f *FakeKubeClient) Patch(ctx context.Context, kind, namespace, name string, patchType PatchType, patch string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.request("patch", kind, namespace, name)
+	o, ok := f.objects[fakeKind(kind)][namespace+"/"+name]
+	if !ok {
+		return notFound(kind, namespace, name)
+	}
+	if patchType == JSONPatch {
+		return fmt.Errorf("fake: %s patches are not supported", patchType)
+	}
+	var p map[string]interface{}
+	if err := json.Unmarshal([]byte(patch), &p); err != nil {
+		return &KubeError{Reason: ReasonValidationRejected, Kind: kind, Namespace: namespace, Name: name, Message: fmt.Sprintf("invalid patch: %v", err)}
+	}
+	patched := &Object{Content: mergePatch(o.Content, p).(map[string]interface{})}
+	f.store(kind, patched)
+	return nil
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386) to a value.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	merged := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		merged[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = mergePatch(merged[k], v)
+		}
+	}
+	return merged
+}
+
+// Exec returns the result of the function set by OnExec.
+func (f *FakeKubeClient) Exec(ctx context.Context, namespace, pod, container string, command ...string) (CommandResult, error) {
+	f.mu.Lock()
+	exec := f.exec
+	f.request("exec", "pods", namespace, pod)
+	f.mu.Unlock()
+	if exec == nil {
+		res := CommandResult{Stderr: fmt.Sprintf("fake: no result for exec %q\n", command), ExitCode: 127}
+		res.Output = res.Stderr
+		return res, fmt.Errorf("exit status %d", res.ExitCode)
+	}
+	return exec(namespace, pod, container, command)
+}
+
+// Logs returns the logs set by SetLogs, limited to the last opts.Tail lines.
+func (f *FakeKubeClient) Logs(ctx context.Context, namespace, pod string, opts LogOptions) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.request("logs", "pods", namespace, pod)
+	if _, ok := f.objects["pod"][namespace+"/"+pod]; !ok {
+		return "", notFound("pods", namespace, pod)
+	}
+	logs := f.logs[namespace+"/"+pod+"/"+opts.Container]
+	if opts.Tail > 0 {
+		lines := strings.SplitAfter(logs, "\n")
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) > opts.Tail {
+			logs = strings.Join(lines[len(lines)-opts.Tail:], "")
+		}
+	}
+	return logs, nil
+}
+
+// Watch lists the stored objects every WatchInterval and reports the changes.
+func (f *FakeKubeClient) Watch(ctx context.Context, kind, namespace string, opts ListOptions) (<-chan WatchEvent, error) {
+	return pollWatch(ctx, f, kind, namespace, opts, WatchInterval)
+}
+
+// selectorRequirement is a requirement of a label or field selector, e.g. "app=reviews" or "!canary".
+type selectorRequirement struct {
+	key      string
+	operator string // "=", "!=", "exists" or "!exists"
+	value    string
+}
+
+type selector []selectorRequirement
+
+// parseSelector parses the equality based subset of the kubectl selector syntax.
+func parseSelector(s string) (selector, error) {
+	var sel selector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		switch {
+		case term == "":
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			sel = append(sel, selectorRequirement{strings.TrimSpace(parts[0]), "!=", strings.TrimSpace(parts[1])})
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(strings.Replace(term, "==", "=", 1), "=", 2)
+			sel = append(sel, selectorRequirement{strings.TrimSpace(parts[0]), "=", strings.TrimSpace(parts[1])})
+		case strings.ContainsAny(term, " ()"):
+			return nil, fmt.Errorf("fake: unsupported selector %q", s)
+		case strings.HasPrefix(term, "!"):
+			sel = append(sel, selectorRequirement{key: term[1:], operator: "!exists"})
+		default:
+			sel = append(sel, selectorRequirement{key: term, operator: "exists"})
+		}
+	}
+	return sel, nil
+}
+
+// matches returns true if the values returned by get meet all requirements.
+func (sel selector) matches(get func(key string) (string, bool)) bool {
+	for _, r := range sel {
+		value, ok := get(r.key)
+		switch r.operator {
+		case "=":
+			if !ok || value != r.value {
+				return false
+			}
+		case "!=":
+			if ok && value == r.value {
+				return false
+			}
+		case "exists":
+			if !ok {
+				return false
+			}
+		case "!exists":
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,219 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HTTPInterceptor handles the requests sent by the clients of HTTPClient, e.g. to record or replay them
+// like the Executor does for commands. Next sends the request to the network.
+type HTTPInterceptor interface {
+	RoundTrip(req *http.Request, next http.RoundTripper) (*http.Response, error)
+}
+
+var (
+	interceptorMu   sync.RWMutex
+	httpInterceptor HTTPInterceptor
+)
+
+// SetHTTPInterceptor replaces the interceptor of the HTTP clients and returns the previous one. A nil
+// interceptor sends the requests directly.
+func SetHTTPInterceptor(i HTTPInterceptor) HTTPInterceptor {
+	interceptorMu.Lock()
+	defer interceptorMu.Unlock()
+	previous := httpInterceptor
+	httpInterceptor = i
+	return previous
+}
+
+func getHTTPInterceptor() HTTPInterceptor {
+	interceptorMu.RLock()
+	defer interceptorMu.RUnlock()
+	return httpInterceptor
+}
+
+// HTTPClient returns a client sending its requests with the transport, or with http.DefaultTransport if
+// it is nil. The helpers sending HTTP requests use it instead of http.Get and http.Client, so that the
+// requests are recorded and replayed with the commands of a session, see RecordSession.
+func HTTPClient(transport http.RoundTripper) *http.Client {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &http.Client{Transport: interceptedTransport{next: transport}}
+}
+
+type interceptedTransport struct {
+	next http.RoundTripper
+}
+
+func (t interceptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if i := getHTTPInterceptor(); i != nil {
+		return i.RoundTrip(req, t.next)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// RecordedRequest is an HTTP request saved in a recording, with its response or its error.
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// Host is the Host header, if it differs from the host of the URL.
+	Host       string        `json:"host,omitempty"`
+	StatusCode int           `json:"statusCode,omitempty"`
+	Header     http.Header   `json:"header,omitempty"`
+	Body       string        `json:"body,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Start      time.Time     `json:"start"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// key identifies the occurrences of the same request.
+func (r RecordedRequest) key() string {
+	if r.Host == "" {
+		return r.Method + " " + r.URL
+	}
+	return fmt.Sprintf("%s %s (host %s)", r.Method, r.URL, r.Host)
+}
+
+// newRecordedRequest returns the request without its response.
+func newRecordedRequest(req *http.Request) RecordedRequest {
+	rec := RecordedRequest{Method: req.Method, URL: req.URL.String(), Host: req.Host}
+	if rec.Host == req.URL.Host {
+		rec.Host = ""
+	}
+	return rec
+}
+
+// HTTPRecorder is an HTTPInterceptor sending the requests and recording them with their responses.
+type HTTPRecorder struct {
+	mu       sync.Mutex
+	requests []RecordedRequest
+}
+
+// RoundTrip sends the request and records it. The body of the response is read completely.
+func (r *HTTPRecorder) RoundTrip(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+	rec := newRecordedRequest(req)
+	rec.Start = time.Now()
+	resp, err := next.RoundTrip(req)
+	if err == nil {
+		var body []byte
+		body, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		rec.StatusCode = resp.StatusCode
+		rec.Header = resp.Header
+		rec.Body = string(body)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	rec.Duration = time.Since(rec.Start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, rec)
+	return resp, err
+}
+
+// Requests returns the requests recorded so far, in the order they completed.
+func (r *HTTPRecorder) Requests() []RecordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedRequest{}, r.requests...)
+}
+
+// HTTPReplayer is an HTTPInterceptor returning the recorded responses instead of sending the requests.
+// Like ReplayExecutor, every occurrence of a request returns the next recorded occurrence of the same
+// method, URL and host. Requests that were not recorded, or not as often, fail and are reported by Unexpected.
+type HTTPReplayer struct {
+	mu         sync.Mutex
+	responses  map[string][]RecordedRequest
+	unexpected []string
+}
+
+// NewHTTPReplayer returns an interceptor replaying the requests of the recording.
+func NewHTTPReplayer(rec *Recording) *HTTPReplayer {
+	r := &HTTPReplayer{responses: map[string][]RecordedRequest{}}
+	for _, req := range rec.Requests {
+		r.responses[req.key()] = append(r.responses[req.key()], req)
+	}
+	return r
+}
+
+// RoundTrip returns the next recorded response of the request.
+func (r *HTTPReplayer) RoundTrip(req *http.Request, _ http.RoundTripper) (*http.Response, error) {
+	key := newRecordedRequest(req).key()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	queue := r.responses[key]
+	if len(queue) == 0 {
+		r.unexpected = append(r.unexpected, key)
+		notReplayed(key)
+		Log.Errorf("Request not in the recording: %s", key)
+		return nil, fmt.Errorf("replay: request not in the recording: %s", key)
+	}
+	rec := queue[0]
+	r.responses[key] = queue[1:]
+	replayed(rec.Start.Add(rec.Duration))
+
+	if rec.Error != "" {
+		return nil, errors.New(rec.Error)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+		StatusCode:    rec.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header,
+		Body:          ioutil.NopCloser(bytes.NewBufferString(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unexpected returns the requests that were not in the recording, or were sent more often than recorded.
+func (r *HTTPReplayer) Unexpected() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.unexpected...)
+}
+
+// Unused returns the recorded requests that were not replayed.
+func (r *HTTPReplayer) Unused() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var remaining []RecordedRequest
+	for _, queue := range r.responses {
+		remaining = append(remaining, queue...)
+	}
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i].Start.Before(remaining[j].Start)
+	})
+	unused := make([]string, 0, len(remaining))
+	for _, req := range remaining {
+		unused = append(unused, req.key())
+	}
+	return unused
+}
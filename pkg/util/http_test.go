@@ -0,0 +1,112 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// interceptHTTP replaces the HTTP interceptor for the duration of the test.
+func interceptHTTP(t *testing.T, i HTTPInterceptor) {
+	t.Helper()
+	previous := SetHTTPInterceptor(i)
+	t.Cleanup(func() { SetHTTPInterceptor(previous) })
+}
+
+func TestHTTPRecordAndReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("X-Host", r.Host)
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		fmt.Fprintf(w, "call %d", calls)
+	}))
+	url := server.URL + "/productpage"
+
+	recorder := &HTTPRecorder{}
+	interceptHTTP(t, recorder)
+	for i := 0; i < 2; i++ {
+		resp, err := GetWithHost(url, "bookinfo.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the recorder leaves the body readable
+		body, _ := ioutil.ReadAll(resp.Body)
+		CloseResponseBody(resp)
+		if string(body) != fmt.Sprintf("call %d", i+1) {
+			t.Errorf("unexpected body %q", body)
+		}
+	}
+	server.Close()
+
+	// the replay does not need the server
+	replayer := NewHTTPReplayer(&Recording{Requests: recorder.Requests()})
+	SetHTTPInterceptor(replayer)
+	for i, want := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		resp, err := GetWithHost(url, "bookinfo.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		CloseResponseBody(resp)
+		if resp.StatusCode != want || string(body) != fmt.Sprintf("call %d", i+1) {
+			t.Errorf("request %d: got %d %q", i+1, resp.StatusCode, body)
+		}
+		if got := resp.Header.Get("X-Host"); got != "bookinfo.com" {
+			t.Errorf("request %d: unexpected header %q", i+1, got)
+		}
+	}
+
+	// a third occurrence and another host are not in the recording
+	if _, err := GetWithHost(url, "bookinfo.com"); err == nil {
+		t.Error("expected an error for a request sent more often than recorded")
+	}
+	if _, err := GetWithHost(url, "other.com"); err == nil {
+		t.Error("expected an error for a request not in the recording")
+	}
+	want := []string{"GET " + url + " (host bookinfo.com)", "GET " + url + " (host other.com)"}
+	if got := replayer.Unexpected(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected: got %q, want %q", got, want)
+	}
+	if got := replayer.Unused(); len(got) != 0 {
+		t.Errorf("unused: got %q", got)
+	}
+}
+
+func TestHTTPReplayError(t *testing.T) {
+	rec := &Recording{Requests: []RecordedRequest{
+		{Method: http.MethodGet, URL: "http://gateway.example.com/", Error: "dial tcp: connection refused"},
+		{Method: http.MethodGet, URL: "http://gateway.example.com/headers", StatusCode: 200},
+	}}
+	replayer := NewHTTPReplayer(rec)
+	interceptHTTP(t, replayer)
+
+	_, _, err := GetHTTPResponse("http://gateway.example.com/", nil)
+	if err == nil || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("expected the recorded error, got %v", err)
+	}
+	want := []string{"GET http://gateway.example.com/headers"}
+	if got := replayer.Unused(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unused: got %q, want %q", got, want)
+	}
+}
@@ -25,8 +25,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
-	"text/template"
 	"time"
 )
 
@@ -50,10 +50,28 @@ type Proxy struct {
 }
 
 var (
+	// logDumpResources are the resources saved by DumpResources for every namespace.
 	logDumpResources = []string{
 		"pod",
 		"service",
+		"endpoints",
+		"deployment",
 		"ingress",
+		"route",
+		"configmap",
+	}
+
+	// istioDumpResources are the Istio custom resources saved by DumpResources for every namespace.
+	istioDumpResources = []string{
+		"gateways.networking.istio.io",
+		"virtualservices.networking.istio.io",
+		"destinationrules.networking.istio.io",
+		"serviceentries.networking.istio.io",
+		"sidecars.networking.istio.io",
+		"envoyfilters.networking.istio.io",
+		"peerauthentications.security.istio.io",
+		"requestauthentications.security.istio.io",
+		"authorizationpolicies.security.istio.io",
 	}
 )
 
@@ -65,9 +83,10 @@ type PodInfo struct {
 	IPAddr string
 }
 
-// Fill complete a template with given values and generate a new output file
+// Fill complete a template with given values and generate a new output file. Like RunTemplate, missing
+// keys are errors and the template can use the functions of TemplateFuncs.
 func Fill(outFile, inFile string, values interface{}) error {
-	tmpl, err := template.ParseFiles(inFile)
+	tmpl, err := newTemplate(filepath.Base(inFile)).ParseFiles(inFile)
 	if err != nil {
 		return err
 	}
@@ -105,10 +124,8 @@ func CreateAndFill(outDir, templateFile string, values interface{}) (string, err
 
 // DeleteNamespace delete a kubernetes namespace
 func DeleteNamespace(n string) error {
-	if _, err := Shell("kubectl delete project %s", n); err != nil {
-		if !strings.Contains(err.Error(), "not found") {
-			return err
-		}
+	if err := GetKubeClient().Delete(context.Background(), "project", "", n); err != nil && !IsNotFound(err) {
+		return err
 	}
 	Log.Infof("namespace %s deleted\n", n)
 	return nil
@@ -116,20 +133,18 @@ func DeleteNamespace(n string) error {
 
 // DeleteDeployment deletes deployment from the specified namespace
 func DeleteDeployment(d string, n string) error {
-	_, err := Shell("kubectl delete deployment %s -n %s", d, n)
-	return err
+	return GetKubeClient().Delete(context.Background(), "deployment", n, d)
 }
 
 // delete SMCP from specified namespace
 func DeleteSMCP(d string, n string) error {
-	_, err := Shell("kubectl delete smcp/%s -n %s", d, n)
-	return err
+	return GetKubeClient().Delete(context.Background(), "smcp", n, d)
 }
 
 // NamespaceDeleted check if a kubernete namespace is deleted
 func NamespaceDeleted(n string) (bool, error) {
-	output, err := ShellSilent("kubectl get namespace %s -o name", n)
-	if strings.Contains(output, "NotFound") {
+	err := GetKubeClient().Get(context.Background(), "namespace", "", n, &Object{})
+	if IsNotFound(err) {
 		return true, nil
 	}
 	return false, err
@@ -137,18 +152,18 @@ func NamespaceDeleted(n string) (bool, error) {
 
 // ValidatingWebhookConfigurationExists check if a kubernetes ValidatingWebhookConfiguration is deleted
 func ValidatingWebhookConfigurationExists(name string) bool {
-	output, _ := ShellSilent("kubectl get validatingwebhookconfiguration %s -o name", name)
-	return !strings.Contains(output, "NotFound")
+	err := GetKubeClient().Get(context.Background(), "validatingwebhookconfiguration", "", name, &Object{})
+	return !IsNotFound(err)
 }
 
-// KubeApplyContents kubectl apply from contents
-func KubeApplyContents(namespace, yamlContents string) error {
-	tmpfile, err := WriteTempfile(os.TempDir(), "kubeapply", ".yaml", yamlContents)
+// KubeApplyContents kubectl apply from contents and returns the applied objects, see Manifest.Apply.
+func KubeApplyContents(namespace, yamlContents string) ([]ObjectRef, error) {
+	m, err := ParseManifest(yamlContents)
 	if err != nil {
-		return err
+		Log.Errorf("Invalid manifest: %v", err)
+		return nil, err
 	}
-	defer removeFile(tmpfile)
-	return KubeApply(namespace, tmpfile)
+	return m.Apply(namespace)
 }
 
 func kubeCommand(subCommand, namespace, yamlFileName string) string {
@@ -158,10 +173,14 @@ func kubeCommand(subCommand, namespace, yamlFileName string) string {
 	return fmt.Sprintf("kubectl %s -n %s -f %s", subCommand, namespace, yamlFileName)
 }
 
-// KubeApply kubectl apply from file
-func KubeApply(namespace, yamlFileName string) error {
-	_, err := Shell(kubeCommand("apply", namespace, yamlFileName))
-	return err
+// KubeApply kubectl apply from file and returns the applied objects. Objects created in the namespaces of a test are deleted when the test completes, see TrackResources.
+func KubeApply(namespace, yamlFileName string) ([]ObjectRef, error) {
+	m, err := LoadManifest(yamlFileName)
+	if err != nil {
+		Log.Errorf("Invalid manifest: %v", err)
+		return nil, err
+	}
+	return m.Apply(namespace)
 }
 
 // KubeGetYaml kubectl get yaml content for given resource.
@@ -175,19 +194,21 @@ func KubeGetYaml(namespace, resource, name string) (string, error) {
 }
 
 // KubeApplyContentSilent kubectl apply from contents silently
-func KubeApplyContentSilent(namespace, yamlContents string) error {
-	tmpfile, err := WriteTempfile(os.TempDir(), "kubeapply", ".yaml", yamlContents)
+func KubeApplyContentSilent(namespace, yamlContents string) ([]ObjectRef, error) {
+	m, err := ParseManifest(yamlContents)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer removeFile(tmpfile)
-	return KubeApplySilent(namespace, tmpfile)
+	return m.apply(namespace, true)
 }
 
 // KubeApplySilent kubectl apply from file silently
-func KubeApplySilent(namespace, yamlFileName string) error {
-	_, err := ShellSilent(kubeCommand("apply", namespace, yamlFileName))
-	return err
+func KubeApplySilent(namespace, yamlFileName string) ([]ObjectRef, error) {
+	m, err := LoadManifest(yamlFileName)
+	if err != nil {
+		return nil, err
+	}
+	return m.apply(namespace, true)
 }
 
 // KubeScale kubectl scale a pod specified using typeName
@@ -222,16 +243,24 @@ func KubeDelete(namespace, yamlFileName string) error {
 
 // GetKubeMasterIP returns the IP address of the kubernetes master service.
 func GetKubeMasterIP() (string, error) {
-	return ShellSilent("kubectl get svc kubernetes -n default -o jsonpath='{.spec.clusterIP}'")
+	var svc Service
+	if err := GetKubeClient().Get(context.Background(), "svc", "default", "kubernetes", &svc); err != nil {
+		return "", err
+	}
+	return svc.Spec.ClusterIP, nil
 }
 
 // GetClusterSubnet returns the subnet (in CIDR form, e.g. "24") for the nodes in the cluster.
 func GetClusterSubnet() (string, error) {
-	cidr, err := ShellSilent("kubectl get nodes -o jsonpath='{.items[0].spec.podCIDR}'")
-	if err != nil {
-		// This command should never fail. If the field isn't found, it will just return and empty string.
+	var nodes []*Object
+	if err := GetKubeClient().List(context.Background(), "nodes", "", ListOptions{}, &nodes); err != nil {
+		// This request should never fail. If the field isn't found, the CIDR is just empty.
 		return "", err
 	}
+	cidr := ""
+	if len(nodes) > 0 {
+		cidr = nodes[0].StringField("spec", "podCIDR")
+	}
 	parts := strings.Split(cidr, "/")
 	if len(parts) != 2 {
 		// TODO(nmittler): Need a way to get the subnet on minikube. For now, just return a default value.
@@ -259,36 +288,30 @@ func getRetrier(serviceType string) Retrier {
 	}
 }
 
-func getServiceLoadBalancer(name, namespace string) (string, error) {
-	ip, err := ShellSilent(
-		"kubectl get svc %s -n %s -o jsonpath='{.status.loadBalancer.ingress[*].ip}'",
-		name, namespace)
+// ipv4Address matches an IPv4 address, e.g. "10.0.0.1".
+var ipv4Address = regexp.MustCompile(`^[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}$`)
 
-	if err != nil {
+func getServiceLoadBalancer(name, namespace string) (string, error) {
+	var svc Service
+	if err := GetKubeClient().Get(context.Background(), "svc", namespace, name, &svc); err != nil {
 		return "", err
 	}
 
-	ip = strings.Trim(ip, "'")
-	ri := regexp.MustCompile(`^[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}$`)
-	if ri.FindString(ip) == "" {
-		return "", errors.New("ingress ip not available yet")
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ipv4Address.MatchString(ingress.IP) {
+			return ingress.IP, nil
+		}
 	}
-
-	return ip, nil
+	return "", errors.New("ingress ip not available yet")
 }
 
 func getServiceNodePort(serviceName, podLabel, namespace string) (string, error) {
-	ip, err := Shell(
-		"kubectl get po -l istio=%s -n %s -o jsonpath='{.items[0].status.hostIP}'",
-		podLabel, namespace)
-
+	pods, err := listPods(namespace, "istio="+podLabel)
 	if err != nil {
 		return "", err
 	}
 
-	ip = strings.Trim(ip, "'")
-	ri := regexp.MustCompile(`^[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}\.[0-9]{1,3}$`)
-	if ri.FindString(ip) == "" {
+	if len(pods) == 0 || !ipv4Address.MatchString(pods[0].Status.HostIP) {
 		return "", fmt.Errorf("the ip of %s is not available yet", serviceName)
 	}
 
@@ -297,44 +320,46 @@ func getServiceNodePort(serviceName, podLabel, namespace string) (string, error)
 		return "", err
 	}
 
-	return ip + ":" + port, nil
+	return pods[0].Status.HostIP + ":" + port, nil
 }
 
 func getServicePort(serviceName, namespace string) (string, error) {
-	port, err := Shell(
-		"kubectl get svc %s -n %s -o jsonpath='{.spec.ports[0].nodePort}'",
-		serviceName, namespace)
-
-	if err != nil {
+	var svc Service
+	if err := GetKubeClient().Get(context.Background(), "svc", namespace, serviceName, &svc); err != nil {
 		return "", err
 	}
 
-	port = strings.Trim(port, "'")
-	rp := regexp.MustCompile(`^[0-9]{1,5}$`)
-	if rp.FindString(port) == "" {
-		err = fmt.Errorf("unable to find the port of %s", serviceName)
+	if len(svc.Spec.Ports) == 0 || svc.Spec.Ports[0].NodePort == 0 {
+		err := fmt.Errorf("unable to find the port of %s", serviceName)
 		Log.Warn(err)
 		return "", err
 	}
-	return port, nil
+	return strconv.Itoa(svc.Spec.Ports[0].NodePort), nil
+}
+
+// listPods returns the pods matching the label selector in the namespace.
+func listPods(n, selector string) ([]Pod, error) {
+	var pods []Pod
+	err := GetKubeClient().List(context.Background(), "pods", n, ListOptions{LabelSelector: selector}, &pods)
+	return pods, err
 }
 
 // GetIngressPodNames get the pod names for the Istio ingress deployment.
 func GetIngressPodNames(n string) ([]string, error) {
-	res, err := Shell("kubectl get pod -l istio=ingress -n %s -o jsonpath='{.items[*].metadata.name}'", n)
+	pods, err := listPods(n, "istio=ingress")
 	if err != nil {
 		return nil, err
 	}
-	res = strings.Trim(res, "'")
-	return strings.Split(res, " "), nil
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Metadata.Name)
+	}
+	return names, nil
 }
 
-// GetAppPodsInfo returns a map of a list of PodInfo
+// GetAppPodsInfo returns the names of the pods having the label and a map of the label values to the IP addresses of the pods
 func GetAppPodsInfo(n string, label string) ([]string, map[string][]string, error) {
-	// This will return a table where c0=pod_name and c1=label_value and c2=IPAddr.
-	// The columns are separated by a space and each result is on a separate line (separated by '\n').
-	res, err := Shell("kubectl -n %s -l=%s get pods -o=jsonpath='{range .items[*]}{.metadata.name}{\" \"}{"+
-		".metadata.labels.%s}{\" \"}{.status.podIP}{\"\\n\"}{end}'", n, label, label)
+	pods, err := listPods(n, label)
 	if err != nil {
 		Log.Infof("Failed to get pods by label %s in namespace %s: %s", label, n, err)
 		return nil, nil, err
@@ -342,12 +367,14 @@ func GetAppPodsInfo(n string, label string) ([]string, map[string][]string, erro
 
 	var podNames []string
 	eps := make(map[string][]string)
-	for _, line := range strings.Split(res, "\n") {
-		f := strings.Fields(line)
-		if len(f) >= 3 {
-			podNames = append(podNames, f[0])
-			eps[f[1]] = append(eps[f[1]], f[2])
+	for _, pod := range pods {
+		// pods are only reachable once they have an IP address
+		if pod.Status.PodIP == "" {
+			continue
 		}
+		podNames = append(podNames, pod.Metadata.Name)
+		value := pod.Metadata.Labels[label]
+		eps[value] = append(eps[value], pod.Status.PodIP)
 	}
 
 	return podNames, eps, nil
@@ -369,21 +396,16 @@ func GetAppPods(n string) (map[string][]string, error) {
 
 // GetPodLabelValues gets a map of pod name to label value for the given label and namespace
 func GetPodLabelValues(n, label string) (map[string]string, error) {
-	// This will return a table where c0=pod_name and c1=label_value.
-	// The columns are separated by a space and each result is on a separate line (separated by '\n').
-	res, err := Shell("kubectl -n %s -l=%s get pods -o=jsonpath='{range .items[*]}{.metadata.name}{\" \"}{"+
-		".metadata.labels.%s}{\"\\n\"}{end}'", n, label, label)
+	pods, err := listPods(n, label)
 	if err != nil {
 		Log.Infof("Failed to get pods by label %s in namespace %s: %s", label, n, err)
 		return nil, err
 	}
 
-	// Split the lines in the result
 	m := make(map[string]string)
-	for _, line := range strings.Split(res, "\n") {
-		f := strings.Fields(line)
-		if len(f) >= 2 {
-			m[f[0]] = f[1]
+	for _, pod := range pods {
+		if value := pod.Metadata.Labels[label]; value != "" {
+			m[pod.Metadata.Name] = value
 		}
 	}
 
@@ -393,37 +415,36 @@ func GetPodLabelValues(n, label string) (map[string]string, error) {
 // GetPodAnnotations gets a map annotations from a pod name for the given: namespace, pod label and retry times for checking the pod annotations
 func GetPodAnnotations(n, podName string, retries int) (map[string]string, error) {
 	retry := Retrier{
-		BaseDelay: 1 * time.Second,
-		MaxDelay:  1 * time.Second,
-		Retries:   retries,
-	}
-	var annotations map[string]string
-	_, err := retry.Retry(context.Background(), func(_ context.Context, _ int) error {
-		output, err := Shell("kubectl get pod %s -n %s -o jsonpath='{.metadata.annotations}'", podName, n)
-		if err != nil {
-			Log.Infof("Failed to get pods by label %s in namespace %s: %s", podName, n, err)
-			return fmt.Errorf("failed to get pod %s: %s", podName, err)
+		BaseDelay:      1 * time.Second,
+		MaxDelay:       1 * time.Second,
+		Retries:        retries,
+		Jitter:         0.2,
+		AttemptTimeout: 30 * time.Second,
+		Retryable:      Transient,
+		Description:    fmt.Sprintf("get the annotations of pod %s in namespace %s", podName, n),
+	}
+	return RetryValue(context.Background(), retry, func(ctx context.Context, _ int) (map[string]string, error) {
+		var pod Pod
+		if err := GetKubeClient().Get(ctx, "pod", n, podName, &pod); err != nil {
+			return nil, fmt.Errorf("failed to get pod %s: %w", podName, err)
 		}
-		if output != "" {
-			if err := json.Unmarshal([]byte(output), &annotations); err != nil {
-				return fmt.Errorf("failed to unmarshal pod annotations: %s", err)
-			}
-			return nil
+		if len(pod.Metadata.Annotations) == 0 {
+			return nil, fmt.Errorf("pod annotations not found yet")
 		}
-		return fmt.Errorf("pod annotations not found yet")
+		return pod.Metadata.Annotations, nil
 	})
-	return annotations, err
 }
 
 // GetPodNames gets names of all pods in specific namespace and return in a slice
 func GetPodNames(n string) (pods []string) {
-	res, err := Shell("kubectl -n %s get pods -o jsonpath='{.items[*].metadata.name}'", n)
+	list, err := listPods(n, "")
 	if err != nil {
 		Log.Infof("Failed to get pods name in namespace %s: %s", n, err)
 		return
 	}
-	res = strings.Trim(res, "'")
-	pods = strings.Split(res, " ")
+	for _, pod := range list {
+		pods = append(pods, pod.Metadata.Name)
+	}
 	Log.Infof("Existing pods: %v", pods)
 	return
 }
@@ -447,12 +468,15 @@ func GetPodStatus(n, pod string) string {
 
 // GetPodName gets the pod name for the given namespace and label selector
 func GetPodName(n, labelSelector string) (pod string, err error) {
-	pod, err = Shell("kubectl -n %s get pod -l %s -o jsonpath='{.items[0].metadata.name}'", n, labelSelector)
+	pods, err := listPods(n, labelSelector)
+	if err == nil && len(pods) == 0 {
+		err = fmt.Errorf("no pods %s in namespace %s", labelSelector, n)
+	}
 	if err != nil {
 		Log.Warnf("could not get %s pod: %v", labelSelector, err)
 		return
 	}
-	pod = strings.Trim(pod, "'")
+	pod = pods[0].Metadata.Name
 	Log.Infof("%s pod name: %s", labelSelector, pod)
 	return
 }
@@ -527,7 +551,7 @@ func CheckPodDeletion(n, labelSelector string, previousPodName string, timeout i
 			deleted = true
 			return deleted, nil
 		}
-		time.Sleep(time.Duration(1) * time.Second)
+		time.Sleep(Delay(time.Second))
 	}
 	return deleted, nil
 }
@@ -543,19 +567,22 @@ func GetPodLogsForLabel(n, labelSelector string, container string, tail, alsoSho
 
 // GetPodLogs retrieves the logs for the given namespace, pod and container.
 func GetPodLogs(n, pod, container string, tail, alsoShowPreviousPodLogs bool) string {
-	tailOption := ""
+	opts := LogOptions{Container: container}
 	if tail {
-		tailOption = "--tail=40"
+		opts.Tail = 40
 	}
 	o1 := ""
 	if alsoShowPreviousPodLogs {
-		Log.Info("Expect and ignore an error getting crash logs when there are no crash (-p invocation)")
-		// Do not use Shell. It dumps the entire log on the console and makes the test unusable due to very large amount of output
-		o1, _ = ShellMuteOutput("kubectl --namespace %s logs %s -c %s %s -p", n, pod, container, tailOption)
+		// there are no previous logs if the container did not crash
+		previous := opts
+		previous.Previous = true
+		o1, _ = GetKubeClient().Logs(context.Background(), n, pod, previous)
 		o1 += "\n"
 	}
-	// Do not use Shell. It dumps the entire log on the console and makes the test unusable due to very large amount of output
-	o2, _ := ShellMuteOutput("kubectl --namespace %s logs %s -c %s %s", n, pod, container, tailOption)
+	o2, err := GetKubeClient().Logs(context.Background(), n, pod, opts)
+	if err != nil {
+		Log.Infof("Failed to get the logs of %s in namespace %s: %v", pod, n, err)
+	}
 	return o1 + o2
 }
 
@@ -575,9 +602,9 @@ func PodExec(n, pod, container, command string, muteOutput bool) (string, error)
 
 // CreateTLSSecret creates a secret from the provided cert and key files
 func CreateTLSSecret(secretName, n, keyFile, certFile string) (string, error) {
-	//cmd := fmt.Sprintf("kubectl create secret tls %s -n %s --key %s --cert %s", secretName, n, keyFile, certFile)
-	//return Shell(cmd)
-	return Shell("kubectl create secret tls %s -n %s --key %s --cert %s", secretName, n, keyFile, certFile)
+	msg, err := Shell("kubectl create secret tls %s -n %s --key %s --cert %s", secretName, n, keyFile, certFile)
+	TrackCreated(n, msg)
+	return msg, err
 }
 
 // CheckPodsRunningWithMaxDuration returns if all pods in a namespace are in "Running" status
@@ -675,21 +702,15 @@ func WaitForDeploymentsReady(ns string, timeout time.Duration) error {
 // CheckDeploymentsReady checks if deployment resources are ready.
 // get podsReady() sometimes gets pods created by the "Job" resource which never reach the "Running" steady state.
 func CheckDeploymentsReady(ns string) (int, error) {
-	CMD := "kubectl -n %s get deployments -o jsonpath='{range .items[*]}{@.metadata.name}{\" \"}" +
-		"{@.status.availableReplicas}{\"\\n\"}{end}'"
-	out, err := Shell(fmt.Sprintf(CMD, ns))
-
-	if err != nil {
+	var deployments []Deployment
+	if err := GetKubeClient().List(context.Background(), "deployments", ns, ListOptions{}, &deployments); err != nil {
 		return 0, fmt.Errorf("could not list deployments in namespace %q: %v", ns, err)
 	}
 
 	notReady := 0
-	for _, line := range strings.Split(out, "\n") {
-		flds := strings.Fields(line)
-		if len(flds) < 2 {
-			continue
-		}
-		if flds[1] == "0" { // no replicas ready
+	for _, d := range deployments {
+		scaledDown := d.Spec.Replicas != nil && *d.Spec.Replicas == 0
+		if d.Status.AvailableReplicas == 0 && !scaledDown { // no replicas ready
 			notReady++
 		}
 	}
@@ -793,35 +814,23 @@ func GetJsonObject(jsonString string) (map[string]interface{}, error) {
 
 // GetHTTPProxy returns the Proxy struc object from the cluster
 func GetProxy() (*Proxy, error) {
-	Proxy := &Proxy{}
-	proxyString, err := ShellSilent(`oc get Proxy -o json`)
-	if err != nil {
-		Log.Error("Error getting proxy String")
-		return nil, err
+	var proxies []struct {
+		Status Proxy `json:"status"`
 	}
-	proxyObject, err := GetJsonObject(proxyString)
-	if err != nil {
+	if err := GetKubeClient().List(context.Background(), "proxy.config.openshift.io", "", ListOptions{}, &proxies); err != nil {
 		Log.Error("Error getting proxy object")
 		return nil, err
 	}
-	proxyObject = proxyObject["items"].([]interface{})[0].(map[string]interface{})
-	if proxyObject["status"] != nil {
-		proxyStatus := proxyObject["status"].(map[string]interface{})
-		if proxyStatus["httpProxy"] != nil && proxyStatus["httpsProxy"] != nil {
-			Proxy.HTTPProxy = proxyStatus["httpProxy"].(string)
-			Proxy.HTTPSProxy = proxyStatus["httpsProxy"].(string)
-			Log.Info("Current httpProxy: ", Proxy.HTTPProxy)
-			Log.Info("Current httpsProxy: ", Proxy.HTTPSProxy)
-			if proxyStatus["noProxy"] != nil {
-				Proxy.NoProxy = proxyStatus["noProxy"].(string)
-				Log.Info("Current noProxy: ", Proxy.NoProxy)
-			}
+	Proxy := &Proxy{}
+	if len(proxies) > 0 && proxies[0].Status.HTTPProxy != "" && proxies[0].Status.HTTPSProxy != "" {
+		*Proxy = proxies[0].Status
+		Log.Info("Current httpProxy: ", Proxy.HTTPProxy)
+		Log.Info("Current httpsProxy: ", Proxy.HTTPSProxy)
+		if Proxy.NoProxy != "" {
+			Log.Info("Current noProxy: ", Proxy.NoProxy)
 		}
 	} else {
 		Log.Info("No proxy variables need to be configured")
-		Proxy.HTTPProxy = ""
-		Proxy.HTTPSProxy = ""
-		Proxy.NoProxy = ""
 	}
 	return Proxy, nil
 }
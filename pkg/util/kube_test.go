@@ -0,0 +1,315 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const podsJSON = `{"apiVersion": "v1", "kind": "List", "items": [
+	{"metadata": {"name": "httpbin-v1-7d8f9", "labels": {"app": "httpbin", "version": "v1"}},
+	 "status": {"podIP": "10.128.0.10", "hostIP": "10.0.0.1"}},
+	{"metadata": {"name": "httpbin-v2-5c6b7", "labels": {"app": "httpbin", "version": "v2"}},
+	 "status": {"podIP": "10.128.0.11", "hostIP": "10.0.0.2"}},
+	{"metadata": {"name": "httpbin-v2-pending", "labels": {"app": "httpbin", "version": "v2"}},
+	 "status": {}}
+]}`
+
+// fakeCommands replaces the executor with fake for the duration of the test.
+func fakeCommands(t *testing.T, fake *FakeExecutor) {
+	t.Helper()
+	previous := SetExecutor(fake)
+	t.Cleanup(func() { SetExecutor(previous) })
+}
+
+// fakeKube replaces the KubeClient with fake for the duration of the test.
+func fakeKube(t *testing.T, fake *FakeKubeClient) {
+	t.Helper()
+	previous := SetKubeClient(fake)
+	t.Cleanup(func() { SetKubeClient(previous) })
+}
+
+// fromJSON decodes an object for FakeKubeClient.Add.
+func fromJSON(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var o map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestGetAppPodsInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  CommandResult
+		names   []string
+		eps     map[string][]string
+		wantErr string
+	}{
+		{
+			name:   "pods with an IP address",
+			result: CommandResult{Stdout: podsJSON},
+			names:  []string{"httpbin-v1-7d8f9", "httpbin-v2-5c6b7"},
+			eps:    map[string][]string{"v1": {"10.128.0.10"}, "v2": {"10.128.0.11"}},
+		},
+		{
+			name:   "no pods",
+			result: CommandResult{Stdout: `{"apiVersion": "v1", "kind": "List", "items": []}`},
+			eps:    map[string][]string{},
+		},
+		{
+			name:    "namespace not found",
+			result:  CommandResult{Stderr: `Error from server (NotFound): namespaces "bookinfo" not found`, ExitCode: 1},
+			wantErr: "not found",
+		},
+		{
+			name:    "invalid output",
+			result:  CommandResult{Stdout: "{"},
+			wantErr: "failed to parse the list of pods",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeExecutor().On(`^kubectl get pods -n bookinfo -l version -o json$`, tt.result)
+			fakeCommands(t, fake)
+
+			names, eps, err := GetAppPodsInfo("bookinfo", "version")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("names: got %v, want %v", names, tt.names)
+			}
+			if !reflect.DeepEqual(eps, tt.eps) {
+				t.Errorf("endpoints: got %v, want %v", eps, tt.eps)
+			}
+		})
+	}
+}
+
+func TestGetServiceNodePort(t *testing.T) {
+	const (
+		pods    = `kubectl get pods -n istio-system -l istio=ingressgateway -o json`
+		service = `kubectl get svc istio-ingressgateway -n istio-system -o json`
+	)
+	svcJSON := `{"spec": {"ports": [{"name": "http2", "port": 80, "nodePort": 31380}]}}`
+
+	tests := []struct {
+		name    string
+		pods    CommandResult
+		svc     CommandResult
+		want    string
+		wantErr string
+	}{
+		{
+			name: "host IP and node port",
+			pods: CommandResult{Stdout: podsJSON},
+			svc:  CommandResult{Stdout: svcJSON},
+			want: "10.0.0.1:31380",
+		},
+		{
+			name:    "no pods",
+			pods:    CommandResult{Stdout: `{"items": []}`},
+			wantErr: "the ip of istio-ingressgateway is not available yet",
+		},
+		{
+			name:    "pod without host IP",
+			pods:    CommandResult{Stdout: `{"items": [{"metadata": {"name": "istio-ingressgateway-1"}, "status": {}}]}`},
+			wantErr: "the ip of istio-ingressgateway is not available yet",
+		},
+		{
+			name:    "pods cannot be listed",
+			pods:    CommandResult{Stderr: `Error from server (Forbidden): pods is forbidden`, ExitCode: 1},
+			wantErr: "forbidden",
+		},
+		{
+			name:    "service without node port",
+			pods:    CommandResult{Stdout: podsJSON},
+			svc:     CommandResult{Stdout: `{"spec": {"ports": [{"name": "http2", "port": 80}]}}`},
+			wantErr: "unable to find the port of istio-ingressgateway",
+		},
+		{
+			name:    "service not found",
+			pods:    CommandResult{Stdout: podsJSON},
+			svc:     CommandResult{Stderr: `Error from server (NotFound): services "istio-ingressgateway" not found`, ExitCode: 1},
+			wantErr: "not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeExecutor().
+				On("^"+pods+"$", tt.pods).
+				On("^"+service+"$", tt.svc)
+			fakeCommands(t, fake)
+
+			got, err := getServiceNodePort("istio-ingressgateway", "ingressgateway", "istio-system")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAppPodsInfoSelectsLabel(t *testing.T) {
+	fake := NewFakeKubeClient().Add("pods",
+		fromJSON(t, `{"metadata": {"name": "reviews-v1-545db", "namespace": "bookinfo", "labels": {"app": "reviews", "version": "v1"}}, "status": {"podIP": "10.128.0.20"}}`),
+		fromJSON(t, `{"metadata": {"name": "reviews-v2-7bc8f", "namespace": "bookinfo", "labels": {"app": "reviews", "version": "v2"}}, "status": {"podIP": "10.128.0.21"}}`),
+		// pods without the label and pods of other namespaces are not listed
+		fromJSON(t, `{"metadata": {"name": "sleep-6c8f9", "namespace": "bookinfo", "labels": {"app": "sleep"}}, "status": {"podIP": "10.128.0.22"}}`),
+		fromJSON(t, `{"metadata": {"name": "reviews-v3-9d7c6", "namespace": "foo", "labels": {"app": "reviews", "version": "v3"}}, "status": {"podIP": "10.128.0.23"}}`))
+	fakeKube(t, fake)
+
+	names, eps, err := GetAppPodsInfo("bookinfo", "version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"reviews-v1-545db", "reviews-v2-7bc8f"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("names: got %v, want %v", names, want)
+	}
+	if want := map[string][]string{"v1": {"10.128.0.20"}, "v2": {"10.128.0.21"}}; !reflect.DeepEqual(eps, want) {
+		t.Errorf("endpoints: got %v, want %v", eps, want)
+	}
+}
+
+func TestCheckDeploymentsReady(t *testing.T) {
+	tests := []struct {
+		name        string
+		deployments []string
+		want        int
+	}{
+		{
+			name: "all available",
+			deployments: []string{
+				`{"metadata": {"name": "ratings-v1", "namespace": "bookinfo"}, "spec": {"replicas": 1}, "status": {"availableReplicas": 1}}`,
+				`{"metadata": {"name": "reviews-v1", "namespace": "bookinfo"}, "spec": {"replicas": 2}, "status": {"availableReplicas": 1}}`,
+			},
+		},
+		{
+			name: "no replica available",
+			deployments: []string{
+				`{"metadata": {"name": "ratings-v1", "namespace": "bookinfo"}, "spec": {"replicas": 1}, "status": {"availableReplicas": 1}}`,
+				`{"metadata": {"name": "reviews-v1", "namespace": "bookinfo"}, "spec": {"replicas": 1}, "status": {}}`,
+				`{"metadata": {"name": "reviews-v2", "namespace": "bookinfo"}, "status": {}}`,
+			},
+			want: 2,
+		},
+		{
+			name: "scaled down",
+			deployments: []string{
+				`{"metadata": {"name": "reviews-v3", "namespace": "bookinfo"}, "spec": {"replicas": 0}, "status": {}}`,
+			},
+		},
+		{
+			name: "other namespace",
+			deployments: []string{
+				`{"metadata": {"name": "httpbin", "namespace": "foo"}, "spec": {"replicas": 1}, "status": {}}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeKubeClient()
+			for _, d := range tt.deployments {
+				fake.Add("deployments", fromJSON(t, d))
+			}
+			fakeKube(t, fake)
+
+			notReady, err := CheckDeploymentsReady("bookinfo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if notReady != tt.want {
+				t.Errorf("got %d deployments not ready, want %d", notReady, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetServiceLoadBalancer(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "IPv4 address",
+			service: `{"metadata": {"name": "istio-ingressgateway", "namespace": "istio-system"}, "status": {"loadBalancer": {"ingress": [{"ip": "34.120.10.7"}]}}}`,
+			want:    "34.120.10.7",
+		},
+		{
+			name: "IPv6 address before the IPv4 address",
+			service: `{"metadata": {"name": "istio-ingressgateway", "namespace": "istio-system"},
+				"status": {"loadBalancer": {"ingress": [{"ip": "2600:1900:4000::1"}, {"ip": "34.120.10.7"}]}}}`,
+			want: "34.120.10.7",
+		},
+		{
+			name:    "host name only",
+			service: `{"metadata": {"name": "istio-ingressgateway", "namespace": "istio-system"}, "status": {"loadBalancer": {"ingress": [{"hostname": "a1b2.elb.amazonaws.com"}]}}}`,
+			wantErr: "ingress ip not available yet",
+		},
+		{
+			name:    "pending",
+			service: `{"metadata": {"name": "istio-ingressgateway", "namespace": "istio-system"}, "status": {"loadBalancer": {}}}`,
+			wantErr: "ingress ip not available yet",
+		},
+		{
+			name:    "not found",
+			wantErr: "not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeKubeClient()
+			if tt.service != "" {
+				fake.Add("services", fromJSON(t, tt.service))
+			}
+			fakeKube(t, fake)
+
+			ip, err := getServiceLoadBalancer("istio-ingressgateway", "istio-system")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ip != tt.want {
+				t.Errorf("got %q, want %q", ip, tt.want)
+			}
+		})
+	}
+}
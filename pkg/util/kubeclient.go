@@ -0,0 +1,456 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KubeClient reads and changes Kubernetes, Istio and Maistra resources and returns them as structured objects.
+// Resources are identified by the kind as accepted by kubectl, e.g. "pods", "smcp" or
+// "virtualservices.networking.istio.io", and an empty namespace for cluster scoped resources.
+// Objects are decoded from their JSON representation into the given value, e.g. a *Pod or a *[]Pod:
+//
+//	var pods []util.Pod
+//	err := util.GetKubeClient().List(ctx, "pods", "bookinfo", util.ListOptions{LabelSelector: "app=reviews"}, &pods)
+//
+// Failures reported by the API server are returned as *KubeError, classified like the failures of commands, see ReasonOf.
+type KubeClient interface {
+	// Get decodes the object into obj.
+	Get(ctx context.Context, kind, namespace, name string, obj interface{}) error
+	// List decodes the matching objects into list, a pointer to a slice. An empty namespace lists all namespaces.
+	List(ctx context.Context, kind, namespace string, opts ListOptions, list interface{}) error
+	// Apply creates or updates the objects in the namespaces of their metadata.
+	Apply(ctx context.Context, objects ...*Object) error
+	// Delete deletes the object and waits until it is gone.
+	Delete(ctx context.Context, kind, namespace, name string) error
+	// Patch patches the object.
+	Patch(ctx context.Context, kind, namespace, name string, patchType PatchType, patch string) error
+	// Exec runs a command in a container of a pod without a shell. The error is non-nil if the command exited with a non-zero code.
+	Exec(ctx context.Context, namespace, pod, container string, command ...string) (CommandResult, error)
+	// Logs returns the logs of a container of a pod.
+	Logs(ctx context.Context, namespace, pod string, opts LogOptions) (string, error)
+	// Watch reports the changes of the matching objects until ctx is done. The objects existing when the
+	// watch starts are reported as added.
+	Watch(ctx context.Context, kind, namespace string, opts ListOptions) (<-chan WatchEvent, error)
+}
+
+var (
+	kubeClientMu sync.RWMutex
+	kubeClient   KubeClient = KubectlClient{}
+)
+
+// SetKubeClient replaces the client returned by GetKubeClient and returns the previous one.
+func SetKubeClient(c KubeClient) KubeClient {
+	kubeClientMu.Lock()
+	defer kubeClientMu.Unlock()
+	previous := kubeClient
+	kubeClient = c
+	return previous
+}
+
+// GetKubeClient returns the client used by the helpers of this package, by default a KubectlClient.
+func GetKubeClient() KubeClient {
+	kubeClientMu.RLock()
+	defer kubeClientMu.RUnlock()
+	return kubeClient
+}
+
+// ListOptions selects the objects of List and Watch.
+type ListOptions struct {
+	// LabelSelector is a kubectl label selector, e.g. "app=reviews,version!=v1".
+	LabelSelector string
+	// FieldSelector is a kubectl field selector, e.g. "spec.nodeName=worker-0".
+	FieldSelector string
+}
+
+// LogOptions selects the logs returned by Logs.
+type LogOptions struct {
+	Container string
+	// Tail is the number of lines to return from the end of the logs, or 0 for all lines.
+	Tail int
+	// Previous returns the logs of the previous instance of the container, e.g. after a crash.
+	Previous bool
+}
+
+// PatchType is the format of a patch, as accepted by `kubectl patch --type`.
+type PatchType string
+
+const (
+	MergePatch     PatchType = "merge"
+	JSONPatch      PatchType = "json"
+	StrategicPatch PatchType = "strategic"
+)
+
+// WatchEventType is the type of a change reported by Watch.
+type WatchEventType string
+
+const (
+	Added    WatchEventType = "ADDED"
+	Modified WatchEventType = "MODIFIED"
+	Deleted  WatchEventType = "DELETED"
+)
+
+// WatchEvent is a change of an object reported by Watch. Deleted events carry the last state of the object.
+type WatchEvent struct {
+	Type   WatchEventType
+	Object *Object
+}
+
+// KubeError is a failure of a KubeClient request.
+type KubeError struct {
+	Reason    StatusReason
+	Kind      string
+	Namespace string
+	Name      string
+	Message   string
+}
+
+func (e *KubeError) Error() string {
+	return e.Message
+}
+
+// ObjectMeta is the metadata common to all objects.
+type ObjectMeta struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace,omitempty"`
+	UID               string            `json:"uid,omitempty"`
+	ResourceVersion   string            `json:"resourceVersion,omitempty"`
+	Generation        int64             `json:"generation,omitempty"`
+	CreationTimestamp *time.Time        `json:"creationTimestamp,omitempty"`
+	DeletionTimestamp *time.Time        `json:"deletionTimestamp,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+}
+
+// Pod is a core/v1 Pod, limited to the fields used by the tests.
+type Pod struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		NodeName   string      `json:"nodeName,omitempty"`
+		Containers []Container `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase             string            `json:"phase,omitempty"`
+		HostIP            string            `json:"hostIP,omitempty"`
+		PodIP             string            `json:"podIP,omitempty"`
+		ContainerStatuses []ContainerStatus `json:"containerStatuses,omitempty"`
+	} `json:"status"`
+}
+
+// Container is a container of a Pod.
+type Container struct {
+	Name  string `json:"name"`
+	Image string `json:"image,omitempty"`
+}
+
+// ContainerStatus is the status of a container of a Pod.
+type ContainerStatus struct {
+	Name         string `json:"name"`
+	Ready        bool   `json:"ready"`
+	RestartCount int    `json:"restartCount"`
+}
+
+// Ready returns true if the pod is running with all containers ready.
+func (p *Pod) Ready() bool {
+	if p.Status.Phase != "Running" || p.Metadata.DeletionTimestamp != nil {
+		return false
+	}
+	for _, c := range p.Status.ContainerStatuses {
+		if !c.Ready {
+			return false
+		}
+	}
+	return true
+}
+
+// Service is a core/v1 Service, limited to the fields used by the tests.
+type Service struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Type      string        `json:"type,omitempty"`
+		ClusterIP string        `json:"clusterIP,omitempty"`
+		Ports     []ServicePort `json:"ports,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		LoadBalancer struct {
+			Ingress []struct {
+				IP       string `json:"ip,omitempty"`
+				Hostname string `json:"hostname,omitempty"`
+			} `json:"ingress,omitempty"`
+		} `json:"loadBalancer"`
+	} `json:"status"`
+}
+
+// ServicePort is a port of a Service.
+type ServicePort struct {
+	Name       string      `json:"name,omitempty"`
+	Protocol   string      `json:"protocol,omitempty"`
+	Port       int         `json:"port"`
+	TargetPort interface{} `json:"targetPort,omitempty"`
+	NodePort   int         `json:"nodePort,omitempty"`
+}
+
+// Deployment is an apps/v1 Deployment, limited to the fields used by the tests.
+type Deployment struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas *int `json:"replicas,omitempty"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+		Replicas           int   `json:"replicas,omitempty"`
+		UpdatedReplicas    int   `json:"updatedReplicas,omitempty"`
+		ReadyReplicas      int   `json:"readyReplicas,omitempty"`
+		AvailableReplicas  int   `json:"availableReplicas,omitempty"`
+	} `json:"status"`
+}
+
+// Condition is a status condition of an object, e.g. the Ready condition of a ServiceMeshControlPlane.
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// ServiceMeshControlPlane is a maistra.io/v2 ServiceMeshControlPlane, limited to its status. The spec
+// can be read with the Field method of Object.
+type ServiceMeshControlPlane struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Status   struct {
+		ObservedGeneration int64       `json:"observedGeneration,omitempty"`
+		ChartVersion       string      `json:"chartVersion,omitempty"`
+		Conditions         []Condition `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+// Condition returns the status condition of the given type, or nil if there is none.
+func (s *ServiceMeshControlPlane) Condition(conditionType string) *Condition {
+	for i := range s.Status.Conditions {
+		if s.Status.Conditions[i].Type == conditionType {
+			return &s.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// CheckCondition returns nil if the given status condition, e.g. "Ready", is True and the operator has
+// observed the latest generation of the control plane, so that the condition does not refer to the spec
+// before a patch. Otherwise it returns an error describing the current state.
+func (s *ServiceMeshControlPlane) CheckCondition(conditionType string) error {
+	if s.Status.ObservedGeneration != s.Metadata.Generation {
+		return fmt.Errorf("generation %d not observed yet", s.Metadata.Generation)
+	}
+	if c := s.Condition(conditionType); c == nil {
+		return fmt.Errorf("condition %s not reported yet", conditionType)
+	} else if c.Status != "True" {
+		return fmt.Errorf("condition %s is %q: %s", conditionType, c.Status, c.Message)
+	}
+	return nil
+}
+
+// Object is an object of any kind, e.g. an Istio VirtualService, with all of its fields.
+type Object struct {
+	Content map[string]interface{}
+}
+
+// NewObject returns an object with the given identity and no other fields.
+func NewObject(apiVersion, kind, namespace, name string) *Object {
+	metadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	return &Object{Content: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	}}
+}
+
+// ObjectFromJSON decodes an object.
+func ObjectFromJSON(data []byte) (*Object, error) {
+	o := &Object{}
+	if err := json.Unmarshal(data, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// MarshalJSON encodes the content of the object.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Content)
+}
+
+// UnmarshalJSON decodes the content of the object.
+func (o *Object) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &o.Content)
+}
+
+// Field returns the field at the path, e.g. Field("spec", "hosts"), and whether it exists.
+func (o *Object) Field(path ...string) (interface{}, bool) {
+	var value interface{} = o.Content
+	for _, p := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[p]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// StringField returns the string field at the path, or an empty string if there is none.
+func (o *Object) StringField(path ...string) string {
+	value, _ := o.Field(path...)
+	s, _ := value.(string)
+	return s
+}
+
+func (o *Object) APIVersion() string { return o.StringField("apiVersion") }
+func (o *Object) Kind() string       { return o.StringField("kind") }
+func (o *Object) Name() string       { return o.StringField("metadata", "name") }
+func (o *Object) Namespace() string  { return o.StringField("metadata", "namespace") }
+
+// Metadata returns the metadata of the object.
+func (o *Object) Metadata() ObjectMeta {
+	var meta ObjectMeta
+	if value, ok := o.Field("metadata"); ok {
+		_ = convertObject(value, &meta)
+	}
+	return meta
+}
+
+// Decode converts the object to a typed object, e.g. a *Pod.
+func (o *Object) Decode(obj interface{}) error {
+	return convertObject(o.Content, obj)
+}
+
+// String returns the kind, namespace and name of the object, e.g. "VirtualService bookinfo/reviews".
+func (o *Object) String() string {
+	if ns := o.Namespace(); ns != "" {
+		return fmt.Sprintf("%s %s/%s", o.Kind(), ns, o.Name())
+	}
+	return fmt.Sprintf("%s %s", o.Kind(), o.Name())
+}
+
+// convertObject converts between representations of an object by encoding it as JSON.
+func convertObject(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// pollWatch implements Watch by listing the objects every Interval and comparing their resource versions,
+// because the commands of the executor cannot stream.
+func pollWatch(ctx context.Context, c KubeClient, kind, namespace string, opts ListOptions, interval time.Duration) (<-chan WatchEvent, error) {
+	list := func() (map[string]*Object, error) {
+		var objects []*Object
+		if err := c.List(ctx, kind, namespace, opts, &objects); err != nil {
+			return nil, err
+		}
+		byName := make(map[string]*Object, len(objects))
+		for _, o := range objects {
+			byName[o.Namespace()+"/"+o.Name()] = o
+		}
+		return byName, nil
+	}
+	// the first list is done before returning, so that errors like unknown kinds are returned by Watch
+	current, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan WatchEvent)
+	go func() {
+		defer close(events)
+		send := func(t WatchEventType, o *Object) bool {
+			select {
+			case events <- WatchEvent{Type: t, Object: o}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, key := range sortedObjectKeys(current) {
+			if !send(Added, current[key]) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			next, err := list()
+			if err != nil {
+				// a failed list is retried with the next poll, the objects are assumed unchanged
+				continue
+			}
+			for _, key := range sortedObjectKeys(next) {
+				o := next[key]
+				previous, ok := current[key]
+				switch {
+				case !ok:
+					if !send(Added, o) {
+						return
+					}
+				case previous.Metadata().ResourceVersion != o.Metadata().ResourceVersion:
+					if !send(Modified, o) {
+						return
+					}
+				}
+			}
+			for _, key := range sortedObjectKeys(current) {
+				if _, ok := next[key]; !ok {
+					if !send(Deleted, current[key]) {
+						return
+					}
+				}
+			}
+			current = next
+		}
+	}()
+	return events, nil
+}
+
+func sortedObjectKeys(objects map[string]*Object) []string {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WatchInterval is the time between two lists of the watches of KubectlClient and FakeKubeClient.
+var WatchInterval = 2 * time.Second
+
+// kindName returns the kind and name of a resource for messages, e.g. `pods "reviews-v1"`.
+func kindName(kind, name string) string {
+	return fmt.Sprintf("%s %q", strings.ToLower(kind), name)
+}
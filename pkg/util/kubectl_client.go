@@ -0,0 +1,209 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KubectlClient is a KubeClient running kubectl commands with the executor set by SetExecutor, so that
+// its requests are recorded, replayed, printed in dry-run mode and audited like all other commands. The commands
+// run without a shell and pass manifests on the standard input. Queries are not logged, commands
+// changing the cluster are logged like Shell logs them.
+type KubectlClient struct{}
+
+// run runs a kubectl command and converts a failure to a *KubeError.
+func (c KubectlClient) run(ctx context.Context, log bool, kind, namespace, name string, b *CommandBuilder) (CommandResult, error) {
+	cmd := b.Command()
+	if log {
+		Log.Infof("Running command %s", cmd)
+	}
+	res, err := Execute(ctx, cmd)
+	if log {
+		if output := strings.TrimSuffix(res.Output, "\n"); len(output) > 0 {
+			Log.Infof("Command output: \n%s", output)
+		}
+	}
+	if err != nil {
+		return res, kubectlError(ctx, kind, namespace, name, res, err)
+	}
+	return res, nil
+}
+
+// kubectlError returns the error of a failed kubectl command, with the reason reported by the API server.
+func kubectlError(ctx context.Context, kind, namespace, name string, res CommandResult, err error) *KubeError {
+	msg := strings.TrimSpace(res.Stderr)
+	if msg == "" {
+		msg = strings.TrimSpace(res.Output)
+	}
+	if msg == "" {
+		msg = err.Error()
+	}
+	return &KubeError{Reason: classifyFailure(ctx, msg), Kind: kind, Namespace: namespace, Name: name, Message: msg}
+}
+
+// Get runs `kubectl get -o json`.
+func (c KubectlClient) Get(ctx context.Context, kind, namespace, name string, obj interface{}) error {
+	res, err := c.run(ctx, false, kind, namespace, name, Kubectl("get", kind, name).Namespace(namespace).Arg("-o", "json"))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(res.Stdout) == "" {
+		// e.g. in dry-run mode, queries succeed without output
+		return &KubeError{Reason: ReasonNotFound, Kind: kind, Namespace: namespace, Name: name, Message: kindName(kind, name) + " not found"}
+	}
+	if err := json.Unmarshal([]byte(res.Stdout), obj); err != nil {
+		return fmt.Errorf("failed to parse %s: %v", kindName(kind, name), err)
+	}
+	return nil
+}
+
+// List runs `kubectl get -o json` with the selectors of opts.
+func (c KubectlClient) List(ctx context.Context, kind, namespace string, opts ListOptions, list interface{}) error {
+	b := Kubectl("get", kind)
+	if namespace == "" {
+		b.Arg("--all-namespaces")
+	} else {
+		b.Namespace(namespace)
+	}
+	if opts.LabelSelector != "" {
+		b.Arg("-l", opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		b.Arg("--field-selector", opts.FieldSelector)
+	}
+	res, err := c.run(ctx, false, kind, namespace, "", b.Arg("-o", "json"))
+	if err != nil {
+		return err
+	}
+
+	items := json.RawMessage("[]")
+	if strings.TrimSpace(res.Stdout) != "" {
+		var l struct {
+			Items json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(res.Stdout), &l); err != nil {
+			return fmt.Errorf("failed to parse the list of %s: %v", kind, err)
+		}
+		if len(l.Items) > 0 && string(l.Items) != "null" {
+			items = l.Items
+		}
+	}
+	if err := json.Unmarshal(items, list); err != nil {
+		return fmt.Errorf("failed to parse the list of %s: %v", kind, err)
+	}
+	return nil
+}
+
+// Apply runs `kubectl apply` once per namespace of the objects. Objects created in the namespaces of a
+// test are deleted when the test completes, see TrackResources.
+func (c KubectlClient) Apply(ctx context.Context, objects ...*Object) error {
+	byNamespace := map[string][]*Object{}
+	for _, o := range objects {
+		byNamespace[o.Namespace()] = append(byNamespace[o.Namespace()], o)
+	}
+	namespaces := make([]string, 0, len(byNamespace))
+	for ns := range byNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		list, err := objectList(byNamespace[ns])
+		if err != nil {
+			return err
+		}
+		res, err := c.run(ctx, true, "", ns, "", Kubectl("apply").Namespace(ns).Manifest(list))
+		TrackCreated(ns, res.Output)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectList encodes objects as a JSON List object, which kubectl reads like a multi-document manifest.
+func objectList(objects []*Object) (string, error) {
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      objects,
+	}, "", "  ")
+	return string(data), err
+}
+
+// Delete runs `kubectl delete`.
+func (c KubectlClient) Delete(ctx context.Context, kind, namespace, name string) error {
+	_, err := c.run(ctx, true, kind, namespace, name, Kubectl("delete", kind, name).Namespace(namespace))
+	return err
+}
+
+// Patch runs `kubectl patch`.
+func (c KubectlClient) Patch(ctx context.Context, kind, namespace, name string, patchType PatchType, patch string) error {
+	_, err := c.run(ctx, true, kind, namespace, name, Kubectl("patch", kind, name).Namespace(namespace).Patch(patchType, patch))
+	return err
+}
+
+// Exec runs `kubectl exec`.
+func (c KubectlClient) Exec(ctx context.Context, namespace, pod, container string, command ...string) (CommandResult, error) {
+	b := Kubectl("exec", pod).Namespace(namespace)
+	if container != "" {
+		b.Arg("-c", container)
+	}
+	cmd := b.Arg("--").Arg(command...).Command()
+
+	Log.Infof("Running command %s", cmd)
+	res, err := Execute(ctx, cmd)
+	if err != nil && !strings.Contains(res.Stderr, "command terminated with exit code") {
+		// the pod or the container could not be reached, as opposed to a failure of the command
+		return res, kubectlError(ctx, "pods", namespace, pod, res, err)
+	}
+	return res, err
+}
+
+// Logs runs `kubectl logs`.
+func (c KubectlClient) Logs(ctx context.Context, namespace, pod string, opts LogOptions) (string, error) {
+	b := Kubectl("logs", pod).Namespace(namespace)
+	if opts.Container != "" {
+		b.Arg("-c", opts.Container)
+	}
+	if opts.Tail > 0 {
+		b.Argf("--tail=%d", opts.Tail)
+	}
+	if opts.Previous {
+		b.Arg("-p")
+	}
+	res, err := c.run(ctx, false, "pods", namespace, pod, b)
+	return res.Stdout, err
+}
+
+// Watch lists the objects every WatchInterval and reports the changes.
+func (c KubectlClient) Watch(ctx context.Context, kind, namespace string, opts ListOptions) (<-chan WatchEvent, error) {
+	return pollWatch(ctx, c, kind, namespace, opts, WatchInterval)
+}
+
+// shellQuote quotes an argument for sh, unless it only consists of characters without special meaning.
+func shellQuote(arg string) string {
+	if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,@%+", r))
+	}) < 0 {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+}
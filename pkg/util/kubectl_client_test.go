@@ -0,0 +1,235 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKubectlClientGet(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   CommandResult
+		want     string
+		notFound bool
+		wantErr  string
+	}{
+		{
+			name:   "existing pod",
+			result: CommandResult{Stdout: `{"metadata": {"name": "ratings-v1", "namespace": "bookinfo"}, "status": {"podIP": "10.128.0.12"}}`},
+			want:   "10.128.0.12",
+		},
+		{
+			name:     "missing pod",
+			result:   CommandResult{Stderr: `Error from server (NotFound): pods "ratings-v1" not found`, ExitCode: 1},
+			notFound: true,
+			wantErr:  `pods "ratings-v1" not found`,
+		},
+		{
+			// e.g. in dry-run mode
+			name:     "no output",
+			result:   CommandResult{},
+			notFound: true,
+			wantErr:  "not found",
+		},
+		{
+			name:    "forbidden",
+			result:  CommandResult{Stderr: `Error from server (Forbidden): pods "ratings-v1" is forbidden: User "developer" cannot get resource "pods"`, ExitCode: 1},
+			wantErr: "forbidden",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeCommands(t, NewFakeExecutor().On(`^kubectl get pods ratings-v1 -n bookinfo -o json$`, tt.result))
+
+			var pod Pod
+			err := KubectlClient{}.Get(context.Background(), "pods", "bookinfo", "ratings-v1", &pod)
+			if IsNotFound(err) != tt.notFound {
+				t.Errorf("IsNotFound(%v) = %v, want %v", err, IsNotFound(err), tt.notFound)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pod.Status.PodIP != tt.want {
+				t.Errorf("got pod IP %q, want %q", pod.Status.PodIP, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubectlClientList(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "pods",
+			stdout: `{"apiVersion": "v1", "kind": "List", "items": [{"metadata": {"name": "ratings-v1"}}, {"metadata": {"name": "reviews-v1"}}]}`,
+			want:   []string{"ratings-v1", "reviews-v1"},
+		},
+		{
+			name:   "no pods",
+			stdout: `{"apiVersion": "v1", "kind": "List", "items": []}`,
+		},
+		{
+			// kubectl prints a null list for some kinds without objects
+			name:   "null items",
+			stdout: `{"apiVersion": "v1", "kind": "List", "items": null}`,
+		},
+		{
+			name: "no output",
+		},
+		{
+			name:    "invalid output",
+			stdout:  `{"items": [`,
+			wantErr: "failed to parse the list of pods",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeCommands(t, NewFakeExecutor().On(`^kubectl get pods -n bookinfo -l app -o json$`, CommandResult{Stdout: tt.stdout}))
+
+			var pods []Pod
+			err := KubectlClient{}.List(context.Background(), "pods", "bookinfo", ListOptions{LabelSelector: "app"}, &pods)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pods == nil {
+				t.Error("expected an empty list instead of nil")
+			}
+			var names []string
+			for _, p := range pods {
+				names = append(names, p.Metadata.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("got %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestKubectlClientExec(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   CommandResult
+		want     CommandResult
+		notFound bool
+		wantErr  string
+	}{
+		{
+			name:   "command succeeded",
+			result: CommandResult{Stdout: "HTTP/1.1 200 OK\n"},
+			want:   CommandResult{Stdout: "HTTP/1.1 200 OK\n"},
+		},
+		{
+			// the result of the command is returned with the error
+			name:    "command failed",
+			result:  CommandResult{Stdout: "partial\n", Stderr: "curl: (7) Failed to connect\ncommand terminated with exit code 7\n", ExitCode: 7},
+			want:    CommandResult{Stdout: "partial\n", Stderr: "curl: (7) Failed to connect\ncommand terminated with exit code 7\n", ExitCode: 7},
+			wantErr: "exit status 7",
+		},
+		{
+			name:     "pod not found",
+			result:   CommandResult{Stderr: `Error from server (NotFound): pods "sleep-1" not found`, ExitCode: 1},
+			want:     CommandResult{Stderr: `Error from server (NotFound): pods "sleep-1" not found`, ExitCode: 1},
+			notFound: true,
+			wantErr:  `pods "sleep-1" not found`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeExecutor().On(`^kubectl exec sleep-1 -n bookinfo -c sleep -- curl -sS http://httpbin:8000/status/200$`, tt.result)
+			fakeCommands(t, fake)
+
+			res, err := KubectlClient{}.Exec(context.Background(), "bookinfo", "sleep-1", "sleep", "curl", "-sS", "http://httpbin:8000/status/200")
+			if res.Stdout != tt.want.Stdout || res.Stderr != tt.want.Stderr || res.ExitCode != tt.want.ExitCode {
+				t.Errorf("got %+v, want %+v", res, tt.want)
+			}
+			if IsNotFound(err) != tt.notFound {
+				t.Errorf("IsNotFound(%v) = %v, want %v", err, IsNotFound(err), tt.notFound)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestKubectlClientWatch(t *testing.T) {
+	previous := WatchInterval
+	WatchInterval = time.Millisecond
+	t.Cleanup(func() { WatchInterval = previous })
+
+	fake := NewFakeExecutor().OnSequence(`^kubectl get pods -n bookinfo -l app=reviews -o json$`,
+		CommandResult{Stdout: `{"items": [{"metadata": {"name": "reviews-v1", "namespace": "bookinfo", "resourceVersion": "1"}}]}`},
+		// a failed list is retried
+		CommandResult{Stderr: "Unable to connect to the server: net/http: TLS handshake timeout", ExitCode: 1},
+		CommandResult{Stdout: `{"items": [{"metadata": {"name": "reviews-v1", "namespace": "bookinfo", "resourceVersion": "2"}},
+			{"metadata": {"name": "reviews-v2", "namespace": "bookinfo", "resourceVersion": "3"}}]}`},
+		CommandResult{Stdout: `{"items": [{"metadata": {"name": "reviews-v2", "namespace": "bookinfo", "resourceVersion": "3"}}]}`})
+	fakeCommands(t, fake)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, err := KubectlClient{}.Watch(ctx, "pods", "bookinfo", ListOptions{LabelSelector: "app=reviews"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ADDED reviews-v1", "MODIFIED reviews-v1", "ADDED reviews-v2", "DELETED reviews-v1"}
+	var got []string
+	for e := range events {
+		got = append(got, string(e.Type)+" "+e.Object.Name())
+		if len(got) == len(want) {
+			cancel()
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got events %q, want %q", got, want)
+	}
+}
+
+func TestKubectlClientWatchUnknownKind(t *testing.T) {
+	fakeCommands(t, NewFakeExecutor().On(`^kubectl get pod-templates `,
+		CommandResult{Stderr: `error: the server doesn't have a resource type "pod-templates"`, ExitCode: 1}))
+
+	if _, err := (KubectlClient{}).Watch(context.Background(), "pod-templates", "bookinfo", ListOptions{}); err == nil {
+		t.Error("expected the error of the first list")
+	}
+}
@@ -15,6 +15,9 @@
 package util
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -50,3 +53,24 @@ func NewJSONLogger() *StandardLogger {
 
 	return standardLogger
 }
+
+// LogFormats are the valid values of Config.LogFormat.
+var LogFormats = []string{"text", "json"}
+
+// ConfigureLog sets the format of Log, "text" or "json", and its level, e.g. "debug".
+func ConfigureLog(format, level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case "text":
+		Log.Formatter = NewTextLogger().Formatter
+	case "json":
+		Log.Formatter = NewJSONLogger().Formatter
+	default:
+		return fmt.Errorf("unknown log format %q, must be one of %s", format, strings.Join(LogFormats, ", "))
+	}
+	Log.SetLevel(lvl)
+	return nil
+}
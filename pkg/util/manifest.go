@@ -0,0 +1,204 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// documentSeparator matches the lines separating the documents of a multi-document YAML file.
+var documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*(#.*)?$`)
+
+// clusterScopedKinds are the kinds used by the tests whose objects do not belong to a namespace.
+var clusterScopedKinds = map[string]bool{
+	"Namespace":                      true,
+	"Project":                        true,
+	"Node":                           true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"MutatingWebhookConfiguration":   true,
+	"ValidatingWebhookConfiguration": true,
+	"PersistentVolume":               true,
+	"StorageClass":                   true,
+	"PriorityClass":                  true,
+	"SecurityContextConstraints":     true,
+}
+
+// ObjectRef identifies an object of a manifest.
+type ObjectRef struct {
+	APIVersion string
+	Kind       string
+	// Namespace is empty for cluster scoped objects.
+	Namespace string
+	Name      string
+}
+
+// Resource returns the resource type of the object as accepted by kubectl, e.g. "deployment.apps" or
+// "virtualservice.networking.istio.io", so that kinds with the same name in different API groups are
+// not confused.
+func (r ObjectRef) Resource() string {
+	resource := strings.ToLower(r.Kind)
+	if i := strings.Index(r.APIVersion, "/"); i > 0 {
+		resource += "." + r.APIVersion[:i]
+	}
+	return resource
+}
+
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return fmt.Sprintf("%s/%s", r.Resource(), r.Name)
+	}
+	return fmt.Sprintf("%s/%s -n %s", r.Resource(), r.Name, r.Namespace)
+}
+
+// Manifest is the list of objects of a multi-document YAML manifest, in the order of the documents.
+type Manifest []*Object
+
+// ParseManifest parses the documents of a YAML manifest, e.g. one of the yaml_configs.go constants.
+// Empty documents are skipped and the items of List objects are added in their place. Every object must
+// have a kind and a name.
+func ParseManifest(contents string) (Manifest, error) {
+	var m Manifest
+	for i, doc := range documentSeparator.Split(contents, -1) {
+		data, err := yaml.YAMLToJSON([]byte(doc))
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		if string(data) == "null" {
+			continue
+		}
+		o := &Object{}
+		if err := json.Unmarshal(data, o); err != nil {
+			return nil, fmt.Errorf("document %d is not an object: %v", i+1, err)
+		}
+
+		objects := []*Object{o}
+		if strings.HasSuffix(o.Kind(), "List") {
+			var list struct {
+				Items []*Object `json:"items"`
+			}
+			if err := json.Unmarshal(data, &list); err != nil {
+				return nil, fmt.Errorf("document %d: %v", i+1, err)
+			}
+			objects = list.Items
+		}
+		for _, o := range objects {
+			if o.Kind() == "" || o.Name() == "" {
+				return nil, fmt.Errorf("document %d: object without kind or name", i+1)
+			}
+			m = append(m, o)
+		}
+	}
+	return m, nil
+}
+
+// LoadManifest parses a YAML manifest file, e.g. one of the testdata files.
+func LoadManifest(filename string) (Manifest, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseManifest(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return m, nil
+}
+
+// Refs returns the objects of the manifest when applied to the namespace. Namespaced objects without a
+// namespace of their own are in the given namespace.
+func (m Manifest) Refs(namespace string) []ObjectRef {
+	refs := make([]ObjectRef, 0, len(m))
+	for _, o := range m {
+		ref := ObjectRef{APIVersion: o.APIVersion(), Kind: o.Kind(), Namespace: o.Namespace(), Name: o.Name()}
+		if clusterScopedKinds[ref.Kind] {
+			ref.Namespace = ""
+		} else if ref.Namespace == "" {
+			ref.Namespace = namespace
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// Apply runs `kubectl apply` for the objects of the manifest and returns them. Objects created in the
+// namespaces of a test are deleted when the test completes, see TrackResources.
+func (m Manifest) Apply(namespace string) ([]ObjectRef, error) {
+	return m.apply(namespace, false)
+}
+
+func (m Manifest) apply(namespace string, silent bool) ([]ObjectRef, error) {
+	list, err := objectList(m)
+	if err != nil {
+		return nil, err
+	}
+	cmd := Kubectl("apply").Namespace(namespace).Manifest(list)
+	if silent {
+		cmd.Silent()
+	}
+	msg, err := cmd.Run()
+	TrackCreated(namespace, msg)
+	return m.Refs(namespace), err
+}
+
+// Delete deletes exactly the objects of the manifest, in reverse order, and waits up to timeout until
+// they are gone. Objects that do not exist are ignored.
+func (m Manifest) Delete(namespace string, timeout time.Duration) error {
+	ctx := context.Background()
+	refs := m.Refs(namespace)
+	for i := len(refs) - 1; i >= 0; i-- {
+		r := refs[i]
+		if err := GetKubeClient().Delete(ctx, r.Resource(), r.Namespace, r.Name); err != nil && !IsNotFound(err) {
+			return err
+		}
+	}
+	if IsDryRun() {
+		return nil
+	}
+
+	retry := Retrier{
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Second,
+		MaxDuration: timeout,
+		Retries:     int(timeout/time.Second) + 1,
+	}
+	var remaining error
+	_, err := retry.Retry(ctx, func(ctx context.Context, _ int) error {
+		for _, r := range refs {
+			err := GetKubeClient().Get(ctx, r.Resource(), r.Namespace, r.Name, &Object{})
+			if err == nil {
+				remaining = fmt.Errorf("%s still exists", r)
+				return remaining
+			}
+			if !IsNotFound(err) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && remaining != nil {
+		return fmt.Errorf("timed out after %s: %v", timeout, remaining)
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name: "multiple documents",
+			contents: `apiVersion: v1
+kind: Service
+metadata:
+  name: httpbin
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: httpbin
+  namespace: other
+`,
+			want: []string{"Service httpbin", "Deployment httpbin"},
+		},
+		{
+			name: "list items",
+			contents: `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: a
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: b
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: c
+`,
+			want: []string{"ConfigMap a", "ConfigMap b", "Secret c"},
+		},
+		{
+			name: "empty and comment-only documents",
+			contents: `---
+# the gateway
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: bookinfo-gateway
+---   # trailing comment
+---
+
+`,
+			want: []string{"Gateway bookinfo-gateway"},
+		},
+		{
+			name:     "no documents",
+			contents: "",
+		},
+		{
+			name:     "object without name",
+			contents: "apiVersion: v1\nkind: ConfigMap\nmetadata: {}\n",
+			wantErr:  "document 1: object without kind or name",
+		},
+		{
+			name:     "invalid document",
+			contents: "kind: ConfigMap\nmetadata:\n  name: a\n---\nkind: [\n",
+			wantErr:  "document 2",
+		},
+		{
+			name:     "scalar document",
+			contents: "just text",
+			wantErr:  "document 1 is not an object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseManifest(tt.contents)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, o := range m {
+				got = append(got, o.Kind()+" "+o.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestRefs(t *testing.T) {
+	m, err := ParseManifest(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: bookinfo
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+  namespace: ignored
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: mesh
+  namespace: istio-system
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ObjectRef{
+		{APIVersion: "v1", Kind: "Namespace", Name: "bookinfo"},
+		{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "reader"},
+		{APIVersion: "networking.istio.io/v1alpha3", Kind: "VirtualService", Namespace: "bookinfo", Name: "reviews"},
+		{APIVersion: "v1", Kind: "ConfigMap", Namespace: "istio-system", Name: "mesh"},
+	}
+	if got := m.Refs("bookinfo"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	wantStrings := []string{
+		"namespace/bookinfo",
+		"clusterrole.rbac.authorization.k8s.io/reader",
+		"virtualservice.networking.istio.io/reviews -n bookinfo",
+		"configmap/mesh -n istio-system",
+	}
+	for i, ref := range want {
+		if got := ref.String(); got != wantStrings[i] {
+			t.Errorf("got %q, want %q", got, wantStrings[i])
+		}
+	}
+}
+
+func TestObjectList(t *testing.T) {
+	m, err := ParseManifest("kind: ConfigMap\nmetadata:\n  name: a\n---\nkind: Secret\nmetadata:\n  name: b\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := objectList(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the list is a manifest of its own, with the same objects
+	parsed, err := ParseManifest(list)
+	if err != nil {
+		t.Fatalf("failed to parse the list: %v\n%s", err, list)
+	}
+	if !reflect.DeepEqual(parsed.Refs("ns"), m.Refs("ns")) {
+		t.Errorf("got %v, want %v", parsed.Refs("ns"), m.Refs("ns"))
+	}
+}
+
+func TestKubeApplyContents(t *testing.T) {
+	fake := NewFakeExecutor().On(`^kubectl apply -n bookinfo -f - <<< `, CommandResult{Stdout: "service/httpbin created\n"})
+	fakeCommands(t, fake)
+
+	refs, err := KubeApplyContents("bookinfo", "apiVersion: v1\nkind: Service\nmetadata:\n  name: httpbin\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ObjectRef{{APIVersion: "v1", Kind: "Service", Namespace: "bookinfo", Name: "httpbin"}}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("got %v, want %v", refs, want)
+	}
+
+	if _, err := KubeApplyContents("bookinfo", "kind: Service\n"); err == nil {
+		t.Error("expected an error for an object without a name")
+	}
+	if got := len(fake.Commands()); got != 1 {
+		t.Errorf("expected a single command, got %d", got)
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// nonMemberNamespaces are created for a test but never added to the member roll.
+// Workloads deployed there run outside of the mesh.
+var nonMemberNamespaces = map[string]bool{
+	"mesh-external": true,
+	"redis":         true,
+}
+
+// fixedNamespaces are created for a test without a random suffix because the sample certificates
+// deployed there are issued for their service host names, e.g. my-nginx.mesh-external.svc.cluster.local.
+// They are shared by all test cases declaring them and deleted when one of them completes, so these
+// test cases must not run at the same time, see IsFixedNamespace.
+var fixedNamespaces = map[string]bool{
+	"mesh-external": true,
+}
+
+// IsFixedNamespace returns true if the namespace with the given base name is never isolated and
+// therefore shared by all test cases declaring it.
+func IsFixedNamespace(name string) bool {
+	return fixedNamespaces[name]
+}
+
+// memberRollInterval is the time between two checks of the member roll or of a deleted namespace.
+var memberRollInterval = 2 * time.Second
+
+var (
+	namespacesMu sync.Mutex
+	// namespaces maps a top level test name to the namespaces allocated for it, by their base name.
+	namespaces = map[string]map[string]string{}
+)
+
+// Namespace returns the name of the namespace allocated for the test t under the given base name,
+// e.g. "bookinfo-x7k2q" for "bookinfo". Subtests share the namespaces of their top level test.
+// If no namespace was allocated, the base name itself is returned.
+func Namespace(t *testing.T, name string) string {
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+
+	test := t.Name()
+	if i := strings.Index(test, "/"); i >= 0 {
+		test = test[:i]
+	}
+	if ns, ok := namespaces[test][name]; ok {
+		return ns
+	}
+	return name
+}
+
+// isAllocated returns true if ns was created for a running test by a NamespaceAllocator.
+func isAllocated(ns string) bool {
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	for _, allocated := range namespaces {
+		for _, name := range allocated {
+			if name == ns {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NamespaceAllocator creates uniquely named namespaces for a test and adds them to the ServiceMeshMemberRoll.
+type NamespaceAllocator struct {
+	// MeshNamespace is the namespace of the control plane and its member roll.
+	MeshNamespace string
+	// MemberRoll is the name of the ServiceMeshMemberRoll.
+	MemberRoll string
+	// Timeout is the maximum time to wait for the member roll to be reconciled.
+	Timeout time.Duration
+}
+
+// NewNamespaceAllocator returns an allocator using the "default" member roll in meshNamespace.
+func NewNamespaceAllocator(meshNamespace string) *NamespaceAllocator {
+	return &NamespaceAllocator{
+		MeshNamespace: meshNamespace,
+		MemberRoll:    "default",
+		Timeout:       3 * time.Minute,
+	}
+}
+
+// Allocate creates a namespace with a random suffix for every base name, adds the namespaces to the member roll
+// and waits until the control plane configured them. The namespaces are removed from the member roll and
+// deleted when t and its subtests complete. Use Namespace to get the allocated names.
+func (a *NamespaceAllocator) Allocate(t *testing.T, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	allocated := map[string]string{}
+	var members []string
+	for _, name := range names {
+		ns := name
+		if !fixedNamespaces[name] {
+			ns = fmt.Sprintf("%s-%s", name, randomSuffix(5))
+		}
+		allocated[name] = ns
+		if !nonMemberNamespaces[name] {
+			members = append(members, ns)
+		}
+	}
+
+	namespacesMu.Lock()
+	namespaces[t.Name()] = allocated
+	namespacesMu.Unlock()
+
+	t.Cleanup(func() {
+		a.release(t, allocated, members)
+	})
+
+	for _, name := range names {
+		if fixedNamespaces[name] {
+			// a previous test may still be deleting it
+			if err := waitForNamespaceDeleted(name, a.Timeout); err != nil {
+				return err
+			}
+		}
+		TestLog(t).Infof("Creating namespace %s", allocated[name])
+		if _, err := ShellMuteOutputError(`oc new-project %s --skip-config-write`, allocated[name]); err != nil {
+			return fmt.Errorf("failed to create namespace %s: %v", allocated[name], err)
+		}
+	}
+	if err := a.addMembers(members); err != nil {
+		return err
+	}
+	return a.waitForMembers(members)
+}
+
+// release removes the namespaces from the member roll and deletes them. Failures are reported as test errors.
+func (a *NamespaceAllocator) release(t *testing.T, allocated map[string]string, members []string) {
+	for _, ns := range members {
+		if err := a.removeMember(ns); err != nil {
+			t.Errorf("Failed to remove namespace %s from the member roll: %v", ns, err)
+		}
+	}
+	for _, ns := range allocated {
+		if err := DeleteNamespace(ns); err != nil {
+			t.Errorf("Failed to delete namespace %s: %v", ns, err)
+		}
+	}
+
+	namespacesMu.Lock()
+	delete(namespaces, t.Name())
+	namespacesMu.Unlock()
+}
+
+func (a *NamespaceAllocator) getMembers() ([]string, error) {
+	_, members, err := a.getMemberRoll()
+	return members, err
+}
+
+// getMemberRoll returns the resource version and the members of the member roll.
+func (a *NamespaceAllocator) getMemberRoll() (string, []string, error) {
+	msg, err := ShellSilent(`oc get smmr %s -n %s -o jsonpath='{.metadata.resourceVersion} {.spec.members[*]}'`, a.MemberRoll, a.MeshNamespace)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get member roll: %v", err)
+	}
+	fields := strings.Fields(msg)
+	if len(fields) == 0 {
+		return "", nil, nil
+	}
+	return fields[0], fields[1:], nil
+}
+
+// patchRetrier retries reading and patching the member roll when it changed in the meantime.
+var patchRetrier = Retrier{BaseDelay: time.Second, MaxDelay: time.Second, Retries: 5}
+
+// pollRetrier checks a condition every memberRollInterval until the timeout expires.
+func pollRetrier(timeout time.Duration) Retrier {
+	return Retrier{
+		BaseDelay:   memberRollInterval,
+		MaxDelay:    memberRollInterval,
+		MaxDuration: timeout,
+		Retries:     int(timeout/memberRollInterval) + 1,
+	}
+}
+
+// addMembers appends the namespaces to the member roll. A JSON patch is used so that concurrent
+// changes of the member roll are not overwritten: members are appended to an existing list, and a
+// missing or empty list is only set if the member roll did not change since it was read. The patch is
+// retried if the member roll changed in the meantime.
+func (a *NamespaceAllocator) addMembers(members []string) error {
+	if len(members) == 0 {
+		return nil
+	}
+	_, err := patchRetrier.Retry(context.Background(), func(context.Context, int) error {
+		version, current, err := a.getMemberRoll()
+		if err != nil {
+			return err
+		}
+
+		var ops []map[string]interface{}
+		if len(current) == 0 {
+			ops = append(ops,
+				map[string]interface{}{"op": "test", "path": "/metadata/resourceVersion", "value": version},
+				map[string]interface{}{"op": "add", "path": "/spec/members", "value": members})
+		} else {
+			for _, ns := range members {
+				ops = append(ops, map[string]interface{}{"op": "add", "path": "/spec/members/-", "value": ns})
+			}
+		}
+		return a.patch(ops)
+	})
+	return err
+}
+
+// removeMember removes a namespace from the member roll. The patch tests that the member is still
+// at the expected index and is retried if the member roll changed in the meantime.
+func (a *NamespaceAllocator) removeMember(ns string) error {
+	_, err := patchRetrier.Retry(context.Background(), func(context.Context, int) error {
+		members, err := a.getMembers()
+		if err != nil {
+			return err
+		}
+		index := -1
+		for j, member := range members {
+			if member == ns {
+				index = j
+			}
+		}
+		if index < 0 {
+			return nil
+		}
+		path := fmt.Sprintf("/spec/members/%d", index)
+		return a.patch([]map[string]interface{}{
+			{"op": "test", "path": path, "value": ns},
+			{"op": "remove", "path": path},
+		})
+	})
+	return err
+}
+
+func (a *NamespaceAllocator) patch(ops []map[string]interface{}) error {
+	data, err := json.Marshal(ops)
+	if err != nil {
+		return err
+	}
+	if _, err := Oc("patch", "smmr", a.MemberRoll).Namespace(a.MeshNamespace).Patch(JSONPatch, string(data)).Silent().Run(); err != nil {
+		return fmt.Errorf("failed to patch member roll: %w", err)
+	}
+	return nil
+}
+
+// waitForMembers waits until all members are listed in the configured members of the member roll status.
+func (a *NamespaceAllocator) waitForMembers(members []string) error {
+	if len(members) == 0 || IsDryRun() {
+		return nil
+	}
+	missing := members
+	_, err := pollRetrier(a.Timeout).Retry(context.Background(), func(context.Context, int) error {
+		msg, err := ShellSilent(`oc get smmr %s -n %s -o jsonpath='{.status.configuredMembers[*]}'`, a.MemberRoll, a.MeshNamespace)
+		if err != nil {
+			return err
+		}
+		if missing = difference(members, strings.Fields(msg)); len(missing) > 0 {
+			return fmt.Errorf("namespaces %s not configured", strings.Join(missing, ", "))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for the member roll to configure namespaces %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// waitForNamespaceDeleted waits until the namespace does not exist. Failures to get the namespace, e.g. an
+// expired token, are retried until the timeout expires.
+func waitForNamespaceDeleted(ns string, timeout time.Duration) error {
+	if IsDryRun() {
+		return nil
+	}
+	var last error
+	_, err := pollRetrier(timeout).Retry(context.Background(), func(context.Context, int) error {
+		deleted, err := NamespaceDeleted(ns)
+		switch {
+		case err != nil:
+			last = err
+		case !deleted:
+			last = fmt.Errorf("namespace %s still exists", ns)
+		default:
+			return nil
+		}
+		return last
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for namespace %s to be deleted: %v", ns, last)
+	}
+	return nil
+}
+
+// difference returns the elements of a that are not in b.
+func difference(a, b []string) []string {
+	in := map[string]bool{}
+	for _, s := range b {
+		in[s] = true
+	}
+	var diff []string
+	for _, s := range a {
+		if !in[s] {
+			diff = append(diff, s)
+		}
+	}
+	return diff
+}
+
+var (
+	randMu sync.Mutex
+	seed   = time.Now().UnixNano()
+	random = rand.New(rand.NewSource(seed))
+)
+
+// SeedRandom makes the random namespace suffixes repeatable, e.g. when a recorded run is replayed.
+func SeedRandom(s int64) {
+	randMu.Lock()
+	defer randMu.Unlock()
+	seed = s
+	random = rand.New(rand.NewSource(s))
+}
+
+func randomSeed() int64 {
+	randMu.Lock()
+	defer randMu.Unlock()
+	return seed
+}
+
+// randomSuffix returns n random lowercase letters and digits usable in a Kubernetes name.
+func randomSuffix(n int) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	randMu.Lock()
+	defer randMu.Unlock()
+
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = chars[random.Intn(len(chars))]
+	}
+	return string(b)
+}
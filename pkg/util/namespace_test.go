@@ -0,0 +1,254 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	getMemberRoll = `^oc get smmr default -n istio-system -o jsonpath='\{\.metadata\.resourceVersion\} \{\.spec\.members\[\*\]\}'$`
+	patchMembers  = `^oc patch smmr default -n istio-system --type json -p `
+	getConfigured = `^oc get smmr default -n istio-system -o jsonpath='\{\.status\.configuredMembers\[\*\]\}'$`
+	conflict      = `Error from server (Conflict): Operation cannot be fulfilled on servicemeshmemberrolls.maistra.io "default": the object has been modified`
+)
+
+// fastMemberRoll shortens the delays of the member roll retries for the duration of the test.
+func fastMemberRoll(t *testing.T) {
+	retrier, interval := patchRetrier, memberRollInterval
+	patchRetrier.BaseDelay, patchRetrier.MaxDelay = time.Millisecond, time.Millisecond
+	memberRollInterval = time.Millisecond
+	t.Cleanup(func() {
+		patchRetrier, memberRollInterval = retrier, interval
+	})
+}
+
+// patches returns the JSON patches of the member roll sent so far.
+func patches(fake *FakeExecutor) []string {
+	var ops []string
+	for _, c := range fake.Commands() {
+		if i := strings.Index(c, " -p "); i >= 0 && strings.HasPrefix(c, "oc patch smmr") {
+			ops = append(ops, strings.Trim(c[i+4:], "'"))
+		}
+	}
+	return ops
+}
+
+func TestAddMembers(t *testing.T) {
+	tests := []struct {
+		name    string
+		get     []CommandResult
+		patch   []CommandResult
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "empty member roll guarded by its resource version",
+			get:   []CommandResult{{Stdout: "100"}},
+			patch: []CommandResult{{}},
+			want: []string{
+				`[{"op":"test","path":"/metadata/resourceVersion","value":"100"},{"op":"add","path":"/spec/members","value":["a-1","b-2"]}]`,
+			},
+		},
+		{
+			name:  "appended to the existing members",
+			get:   []CommandResult{{Stdout: "100 other"}},
+			patch: []CommandResult{{}},
+			want: []string{
+				`[{"op":"add","path":"/spec/members/-","value":"a-1"},{"op":"add","path":"/spec/members/-","value":"b-2"}]`,
+			},
+		},
+		{
+			name:  "member roll changed since it was read",
+			get:   []CommandResult{{Stdout: "100"}, {Stdout: "101 other"}},
+			patch: []CommandResult{{Stderr: conflict, ExitCode: 1}, {}},
+			want: []string{
+				`[{"op":"test","path":"/metadata/resourceVersion","value":"100"},{"op":"add","path":"/spec/members","value":["a-1","b-2"]}]`,
+				`[{"op":"add","path":"/spec/members/-","value":"a-1"},{"op":"add","path":"/spec/members/-","value":"b-2"}]`,
+			},
+		},
+		{
+			name:  "member roll cannot be read at first",
+			get:   []CommandResult{{Stderr: "Unable to connect to the server: EOF", ExitCode: 1}, {Stdout: "100 other"}},
+			patch: []CommandResult{{}},
+			want: []string{
+				`[{"op":"add","path":"/spec/members/-","value":"a-1"},{"op":"add","path":"/spec/members/-","value":"b-2"}]`,
+			},
+		},
+		{
+			name:    "conflict on every attempt",
+			get:     []CommandResult{{Stdout: "100"}},
+			patch:   []CommandResult{{Stderr: conflict, ExitCode: 1}},
+			wantErr: "failed to patch member roll",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastMemberRoll(t)
+			fake := NewFakeExecutor().
+				OnSequence(getMemberRoll, tt.get...).
+				OnSequence(patchMembers, tt.patch...)
+			fakeCommands(t, fake)
+
+			err := NewNamespaceAllocator("istio-system").addMembers([]string{"a-1", "b-2"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				if !IsConflict(err) {
+					t.Errorf("expected a conflict, got %v", err)
+				}
+				if got := len(patches(fake)); got != patchRetrier.Retries {
+					t.Errorf("expected %d attempts, got %d", patchRetrier.Retries, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := patches(fake); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got patches\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	tests := []struct {
+		name  string
+		get   []CommandResult
+		patch []CommandResult
+		want  []string
+	}{
+		{
+			name:  "member at its index",
+			get:   []CommandResult{{Stdout: "100 x a-1 y"}},
+			patch: []CommandResult{{}},
+			want:  []string{`[{"op":"test","path":"/spec/members/1","value":"a-1"},{"op":"remove","path":"/spec/members/1"}]`},
+		},
+		{
+			name:  "member moved in the meantime",
+			get:   []CommandResult{{Stdout: "100 x a-1"}, {Stdout: "102 a-1"}},
+			patch: []CommandResult{{Stderr: `The request is invalid: the server rejected our request due to an error in our request`, ExitCode: 1}, {}},
+			want: []string{
+				`[{"op":"test","path":"/spec/members/1","value":"a-1"},{"op":"remove","path":"/spec/members/1"}]`,
+				`[{"op":"test","path":"/spec/members/0","value":"a-1"},{"op":"remove","path":"/spec/members/0"}]`,
+			},
+		},
+		{
+			name: "not a member",
+			get:  []CommandResult{{Stdout: "100 x y"}},
+		},
+		{
+			name: "no members",
+			get:  []CommandResult{{Stdout: "100"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastMemberRoll(t)
+			fake := NewFakeExecutor().
+				OnSequence(getMemberRoll, tt.get...).
+				OnSequence(patchMembers, tt.patch...)
+			fakeCommands(t, fake)
+
+			if err := NewNamespaceAllocator("istio-system").removeMember("a-1"); err != nil {
+				t.Fatal(err)
+			}
+			if got := patches(fake); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got patches\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestWaitForMembers(t *testing.T) {
+	fastMemberRoll(t)
+	fake := NewFakeExecutor().OnSequence(getConfigured,
+		CommandResult{Stderr: "Unable to connect to the server: EOF", ExitCode: 1},
+		CommandResult{Stdout: "other a-1"},
+		CommandResult{Stdout: "other a-1 b-2"})
+	fakeCommands(t, fake)
+
+	a := NewNamespaceAllocator("istio-system")
+	if err := a.waitForMembers([]string{"a-1", "b-2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(fake.Commands()); got != 3 {
+		t.Errorf("expected 3 checks, got %d", got)
+	}
+
+	a.Timeout = 20 * time.Millisecond
+	err := a.waitForMembers([]string{"a-1", "c-3"})
+	if err == nil || !strings.Contains(err.Error(), "configure namespaces c-3") {
+		t.Errorf("expected a timeout for c-3, got %v", err)
+	}
+}
+
+func TestWaitForNamespaceDeleted(t *testing.T) {
+	const getNamespace = `^kubectl get namespace mesh-external -o json$`
+	notFound := CommandResult{Stderr: `Error from server (NotFound): namespaces "mesh-external" not found`, ExitCode: 1}
+	unauthorized := CommandResult{Stderr: `error: You must be logged in to the server (Unauthorized)`, ExitCode: 1}
+	terminating := CommandResult{Stdout: `{"kind": "Namespace", "metadata": {"name": "mesh-external"}, "status": {"phase": "Terminating"}}`}
+
+	tests := []struct {
+		name    string
+		results []CommandResult
+		wantErr string
+	}{
+		{
+			name:    "deleted",
+			results: []CommandResult{notFound},
+		},
+		{
+			name:    "deleted after a while",
+			results: []CommandResult{terminating, terminating, notFound},
+		},
+		{
+			name:    "transient error",
+			results: []CommandResult{unauthorized, notFound},
+		},
+		{
+			name:    "error until the timeout",
+			results: []CommandResult{unauthorized},
+			wantErr: "Unauthorized",
+		},
+		{
+			name:    "never deleted",
+			results: []CommandResult{terminating},
+			wantErr: "namespace mesh-external still exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fastMemberRoll(t)
+			fakeCommands(t, NewFakeExecutor().OnSequence(getNamespace, tt.results...))
+
+			err := waitForNamespaceDeleted("mesh-external", 50*time.Millisecond)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 	"strings"
@@ -25,10 +26,8 @@ var testRetryTimes = 5
 
 // CreateOCPNamespace create a kubernetes namespace
 func CreateOCPNamespace(n string) error {
-	if _, err := ShellMuteOutput("oc new-project %s", n); err != nil {
-		if !strings.Contains(err.Error(), "AlreadyExists") {
-			return err
-		}
+	if _, err := ShellMuteOutput("oc new-project %s", n); err != nil && !IsAlreadyExists(err) {
+		return err
 	}
 	Log.Infof("namespace %s created\n", n)
 	return nil
@@ -36,10 +35,8 @@ func CreateOCPNamespace(n string) error {
 
 // DeleteOCPNamespace create a kubernetes namespace
 func DeleteOCPNamespace(n string) error {
-	if _, err := ShellMuteOutput("oc delete project %s", n); err != nil {
-		if !strings.Contains(err.Error(), "NotFound") {
-			return err
-		}
+	if _, err := ShellMuteOutput("oc delete project %s", n); err != nil && !IsNotFound(err) {
+		return err
 	}
 	Log.Infof("namespace %s deleted\n", n)
 	return nil
@@ -54,21 +51,18 @@ func OcGrantPermission(account, namespace string) {
 
 // GetOCPIngressgateway returns the OCP cluster ingressgateway host URL.
 func GetOCPIngressgateway(podLabel, namespace string) (string, error) {
-	ingress, err := Shell("oc get routes -l %s -n %s -o jsonpath='{.items[0].spec.host}'",
-		podLabel, namespace)
-
-	for i := 0; i < testRetryTimes; i++ {
-		if err == nil {
-			break
-		}
-		time.Sleep(time.Duration(5) * time.Second)
-		ingress, err = Shell("oc get routes -l %s -n %s -o jsonpath='{.items[0].spec.host}'",
-			podLabel, namespace)
-	}
-	if err != nil {
-		return "", err
+	retry := Retrier{
+		BaseDelay:      5 * time.Second,
+		MaxDelay:       5 * time.Second,
+		Retries:        testRetryTimes + 1,
+		Jitter:         0.2,
+		AttemptTimeout: time.Minute,
+		Retryable:      Transient,
+		Description:    fmt.Sprintf("get the route %s in namespace %s", podLabel, namespace),
 	}
-	return ingress, nil
+	return RetryValue(context.Background(), retry, func(ctx context.Context, _ int) (string, error) {
+		return ShellContext(ctx, "oc get routes -l %s -n %s -o jsonpath='{.items[0].spec.host}'", podLabel, namespace)
+	})
 }
 
 // GetOCP4Ingressgateway returns OCP4 ingress-ingresssgateway external IP hostname
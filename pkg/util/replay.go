@@ -0,0 +1,319 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tempFileName matches the names of the files created by CreateTempfile, e.g. "/tmp/kubeapply123456789.yaml".
+// They differ between runs and are replaced with a placeholder before commands are compared.
+var tempFileName = regexp.MustCompile(`\S*/([A-Za-z_-]+)\d{5,}(\.[A-Za-z]+)?\b`)
+
+// normalizeCommand replaces the parts of a command that differ between runs of the same code.
+func normalizeCommand(command string) string {
+	return tempFileName.ReplaceAllString(command, "<tmp>/$1$2")
+}
+
+var (
+	replayMu sync.Mutex
+	// replaying is true while a session is replayed, see ReplaySession.
+	replaying bool
+	// replayNow is the time of the recorded run at the end of the last replayed command or request.
+	replayNow time.Time
+	// replayDiverged is the first command or request of the replayed session that was not in the recording.
+	replayDiverged string
+)
+
+// IsReplaying returns true while a session is replayed, see ReplaySession.
+func IsReplaying() bool {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	return replaying
+}
+
+// Now returns the current time or, while a session is replayed, the time of the recorded run the replay
+// has reached. Checks depending on the time since an earlier check use it, so that they see the recorded
+// times and replay the same number of checks as the recorded run.
+func Now() time.Time {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	if replaying {
+		return replayNow
+	}
+	return time.Now()
+}
+
+// Delay returns the time to wait before checking the cluster again: d, or no time at all in dry-run mode
+// and while a session is replayed, since the results of the next check do not depend on it.
+func Delay(d time.Duration) time.Duration {
+	if IsDryRun() || IsReplaying() {
+		return 0
+	}
+	return d
+}
+
+// ReplayDiverged returns an error if the replayed session ran a command or sent a request that is not in
+// the recording. Helpers polling the cluster give up then instead of polling until they time out.
+func ReplayDiverged() error {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	if replaying && replayDiverged != "" {
+		return fmt.Errorf("replay: not in the recording: %s", replayDiverged)
+	}
+	return nil
+}
+
+// replayed advances the time of the replayed session to the end of a replayed command or request.
+func replayed(end time.Time) {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	if end.After(replayNow) {
+		replayNow = end
+	}
+}
+
+// notReplayed records a command or request that is not in the recording.
+func notReplayed(key string) {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	if replayDiverged == "" {
+		replayDiverged = key
+	}
+}
+
+// startReplay starts the replay of a session at the time of its first recorded command or request.
+func startReplay(rec *Recording) {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	replaying = true
+	replayNow = time.Time{}
+	replayDiverged = ""
+	for _, c := range rec.Commands {
+		if replayNow.IsZero() || c.Start.Before(replayNow) {
+			replayNow = c.Start
+		}
+	}
+	for _, r := range rec.Requests {
+		if replayNow.IsZero() || r.Start.Before(replayNow) {
+			replayNow = r.Start
+		}
+	}
+}
+
+func stopReplay() {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+	replaying = false
+}
+
+// RecordedCommand is a command saved in a recording.
+type RecordedCommand struct {
+	Command  string        `json:"command"`
+	Stdout   string        `json:"stdout,omitempty"`
+	Stderr   string        `json:"stderr,omitempty"`
+	Output   string        `json:"output,omitempty"`
+	ExitCode int           `json:"exitCode"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Recording is a fixture file with the commands and the HTTP requests of a run and their results.
+type Recording struct {
+	// Seed is the seed of the random namespace suffixes, so that a replay allocates the same namespaces.
+	Seed int64 `json:"seed"`
+	// Environment is the environment resolved during the run, if any, so that a replay does not resolve it again.
+	Environment *Environment      `json:"environment,omitempty"`
+	Commands    []RecordedCommand `json:"commands"`
+	// Requests are the requests sent with HTTPClient.
+	Requests []RecordedRequest `json:"requests,omitempty"`
+}
+
+// saveRecording writes the recorded commands and requests to a fixture file.
+func saveRecording(filename string, commands *RecordingExecutor, requests *HTTPRecorder) error {
+	rec := Recording{Seed: randomSeed(), Environment: resolvedEnvironment(), Requests: requests.Requests()}
+	for _, c := range commands.Records() {
+		rec.Commands = append(rec.Commands, RecordedCommand{
+			Command:  c.Command,
+			Stdout:   c.Result.Stdout,
+			Stderr:   c.Result.Stderr,
+			Output:   c.Result.Output,
+			ExitCode: c.Result.ExitCode,
+			Start:    c.Start,
+			Duration: c.Duration,
+		})
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// LoadRecording reads a fixture file written by RecordSession.
+func LoadRecording(filename string) (*Recording, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %v", filename, err)
+	}
+	return &rec, nil
+}
+
+// ReplayExecutor returns the recorded results instead of running commands. Every occurrence of a command
+// returns the result of the next recorded occurrence of the same command, so helpers polling the cluster
+// see the same sequence of results as in the recorded run. Commands that were not recorded, or not as
+// often, fail with exit code 127 and are reported by Unexpected.
+type ReplayExecutor struct {
+	mu         sync.Mutex
+	results    map[string][]RecordedCommand
+	unexpected []string
+}
+
+// NewReplayExecutor returns an executor replaying the recording. It seeds the random namespace suffixes
+// with the seed of the recording and, unless Env has been resolved already, uses the recorded environment.
+func NewReplayExecutor(rec *Recording) *ReplayExecutor {
+	e := &ReplayExecutor{results: map[string][]RecordedCommand{}}
+	for _, c := range rec.Commands {
+		key := normalizeCommand(c.Command)
+		e.results[key] = append(e.results[key], c)
+	}
+	SeedRandom(rec.Seed)
+	if rec.Environment != nil && resolvedEnvironment() == nil {
+		SetEnvironment(rec.Environment)
+	}
+	return e
+}
+
+// Execute returns the next recorded result of the command.
+func (e *ReplayExecutor) Execute(ctx context.Context, cmd Command) (CommandResult, error) {
+	command := cmd.String()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := normalizeCommand(command)
+	queue := e.results[key]
+	if len(queue) == 0 {
+		e.unexpected = append(e.unexpected, command)
+		notReplayed(command)
+		Log.Errorf("Command not in the recording: %s", command)
+		res := CommandResult{Stderr: fmt.Sprintf("replay: command not in the recording: %s\n", command), ExitCode: 127}
+		res.Output = res.Stderr
+		return res, fmt.Errorf("exit status %d", res.ExitCode)
+	}
+	c := queue[0]
+	e.results[key] = queue[1:]
+	replayed(c.Start.Add(c.Duration))
+
+	res := CommandResult{Stdout: c.Stdout, Stderr: c.Stderr, Output: c.Output, ExitCode: c.ExitCode, Duration: c.Duration}
+	if c.ExitCode != 0 {
+		return res, fmt.Errorf("exit status %d", c.ExitCode)
+	}
+	return res, nil
+}
+
+// Unexpected returns the commands that were not in the recording, or were run more often than recorded.
+func (e *ReplayExecutor) Unexpected() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string{}, e.unexpected...)
+}
+
+// Unused returns the recorded commands that were not replayed.
+func (e *ReplayExecutor) Unused() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var remaining []RecordedCommand
+	for _, queue := range e.results {
+		remaining = append(remaining, queue...)
+	}
+	sort.Slice(remaining, func(i, j int) bool {
+		return remaining[i].Start.Before(remaining[j].Start)
+	})
+	unused := make([]string, 0, len(remaining))
+	for _, c := range remaining {
+		unused = append(unused, c.Command)
+	}
+	return unused
+}
+
+// RecordSession records the commands and the HTTP requests of t and saves them to the fixture file when t
+// completes. The executor and the HTTP interceptor are replaced for the whole process, so sessions must not
+// run in parallel.
+func RecordSession(t *testing.T, filename string) {
+	// a fresh seed per session, so that the replay of a single session allocates the same namespaces
+	SeedRandom(time.Now().UnixNano())
+	// the environment is resolved before the recording starts and saved with it, so that the replay of a
+	// single session does not depend on the sessions replayed before it
+	BindEnvironment(t, Env())
+	rec := NewRecordingExecutor(GetExecutor())
+	requests := &HTTPRecorder{}
+	previous := SetExecutor(rec)
+	previousInterceptor := SetHTTPInterceptor(requests)
+	t.Cleanup(func() {
+		SetExecutor(previous)
+		SetHTTPInterceptor(previousInterceptor)
+		if err := saveRecording(filename, rec, requests); err != nil {
+			t.Errorf("Failed to save recording %s: %v", filename, err)
+		}
+	})
+}
+
+// ReplaySession replays the fixture file for the commands and the HTTP requests of t. When t completes, commands
+// and requests that were not in the recording and recorded ones that were not replayed fail the test, so that
+// changes of the cluster interactions are noticed. Like RecordSession, sessions must not run in parallel.
+// Helpers polling the cluster do not wait between their checks while replaying, see Delay and Now.
+func ReplaySession(t *testing.T, filename string) {
+	rec, err := LoadRecording(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Environment != nil {
+		BindEnvironment(t, rec.Environment)
+	}
+	replay := NewReplayExecutor(rec)
+	requests := NewHTTPReplayer(rec)
+	previous := SetExecutor(replay)
+	previousInterceptor := SetHTTPInterceptor(requests)
+	startReplay(rec)
+	t.Cleanup(func() {
+		stopReplay()
+		SetExecutor(previous)
+		SetHTTPInterceptor(previousInterceptor)
+		for _, c := range replay.Unexpected() {
+			t.Errorf("Command not in the recording %s: %s", filename, c)
+		}
+		for _, c := range replay.Unused() {
+			t.Errorf("Recorded command not replayed: %s", c)
+		}
+		for _, r := range requests.Unexpected() {
+			t.Errorf("Request not in the recording %s: %s", filename, r)
+		}
+		for _, r := range requests.Unused() {
+			t.Errorf("Recorded request not replayed: %s", r)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordedRun is a recording of a test polling a pod, which was ready on the second check 5s later.
+func recordedRun() *Recording {
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	return &Recording{Commands: []RecordedCommand{
+		{Command: "kubectl get pods -n bookinfo", Stdout: "ratings-v1 0/2", Start: start, Duration: time.Second},
+		{Command: "kubectl get pods -n bookinfo", Stdout: "ratings-v1 2/2", Start: start.Add(5 * time.Second), Duration: time.Second},
+	}}
+}
+
+// replay replays the recording until t completes, without checking that it was replayed completely.
+func replay(t *testing.T, rec *Recording) {
+	t.Helper()
+	previous := SetExecutor(NewReplayExecutor(rec))
+	t.Cleanup(func() { SetExecutor(previous) })
+	startReplay(rec)
+	t.Cleanup(stopReplay)
+}
+
+func TestReplayClock(t *testing.T) {
+	rec := recordedRun()
+	start := rec.Commands[0].Start
+	replay(t, rec)
+
+	if !IsReplaying() {
+		t.Fatal("expected a replay")
+	}
+	if d := Delay(time.Minute); d != 0 {
+		t.Errorf("got a delay of %s while replaying", d)
+	}
+	if now := Now(); !now.Equal(start) {
+		t.Errorf("got %s before the first command, want the start of the recording %s", now, start)
+	}
+	for _, want := range []time.Time{start.Add(time.Second), start.Add(6 * time.Second)} {
+		if _, err := ShellSilent("kubectl get pods -n bookinfo"); err != nil {
+			t.Fatal(err)
+		}
+		if now := Now(); !now.Equal(want) {
+			t.Errorf("got %s, want the end of the replayed command %s", now, want)
+		}
+	}
+	if err := ReplayDiverged(); err != nil {
+		t.Errorf("unexpected divergence: %v", err)
+	}
+}
+
+func TestReplayDiverged(t *testing.T) {
+	replay(t, recordedRun())
+
+	if _, err := ShellSilent("kubectl delete pods -n bookinfo --all"); err == nil {
+		t.Fatal("expected a command not in the recording to fail")
+	}
+	err := ReplayDiverged()
+	if err == nil || !strings.Contains(err.Error(), "kubectl delete pods -n bookinfo --all") {
+		t.Errorf("expected an error naming the command, got %v", err)
+	}
+}
+
+func TestStartWhileReplaying(t *testing.T) {
+	replay(t, recordedRun())
+
+	_, err := NewCommand("oc", "port-forward", "svc/prometheus", "9090").Start()
+	if err == nil || !strings.Contains(err.Error(), "background commands cannot be replayed") {
+		t.Errorf("expected an error, got %v", err)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	if d := Delay(time.Minute); d != time.Minute {
+		t.Errorf("got %s, want the delay outside of a replay", d)
+	}
+}
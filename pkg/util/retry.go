@@ -16,6 +16,9 @@ package util
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -23,9 +26,9 @@ const (
 	backoffFactor = 1.3 // backoff increases by this factor on each retry
 )
 
-// Backoff returns a random value in [0, maxDelay] that increases exponentially with
-// retries, starting from baseDelay.  It is the Go equivalent to C++'s
-// //util/time/backoff.cc.
+// Backoff returns a delay that increases exponentially with retries, starting from baseDelay, up to
+// maxDelay. It is the Go equivalent to C++'s //util/time/backoff.cc. The delay is not randomized, see
+// Retrier.Jitter.
 func Backoff(baseDelay, maxDelay time.Duration, retries int) time.Duration {
 	backoff, max := float64(baseDelay), float64(maxDelay)
 	for backoff < max && retries > 0 {
@@ -52,6 +55,19 @@ type Retrier struct {
 	MaxDuration time.Duration
 	// Retries defines number of retry attempts
 	Retries int
+	// Jitter is the fraction of the delay between attempts that is randomized, from 0 to 1, so that tests
+	// polling the same resource do not retry in lockstep. With a jitter of 0.2 the delay is between 80%
+	// and 100% of the backoff.
+	Jitter float64
+	// AttemptTimeout is the maximum duration of a single attempt. The context of the attempt is cancelled
+	// when it expires, and an attempt ignoring its context is abandoned and counts as failed.
+	AttemptTimeout time.Duration
+	// Retryable returns false for errors that a retry cannot fix, which end the retries like a Break.
+	// All errors are retried if it is nil, see also Transient.
+	Retryable func(err error) bool
+	// Description names the operation in the log of failed attempts, e.g. "get the ingress route".
+	// Failed attempts are logged with the info level if it is set, with the debug level otherwise.
+	Description string
 }
 
 // Break the retry loop if the error returned is of this type.
@@ -63,7 +79,19 @@ func (e Break) Error() string {
 	return e.Err.Error()
 }
 
-// Retry calls the given function a number of times, unless it returns a nil or a Break
+// Transient is a Retryable predicate rejecting the failures a retry cannot fix: requests that are
+// forbidden or rejected by the API server or a validating webhook.
+func Transient(err error) bool {
+	switch ReasonOf(err) {
+	case ReasonForbidden, ReasonValidationRejected:
+		return false
+	}
+	return true
+}
+
+// Retry calls the given function a number of times, unless it returns a nil or a Break, or an error
+// that is not Retryable. The returned error is the error of the last attempt, or the error of the
+// context if it was done before.
 func (r Retrier) Retry(ctx context.Context, fn func(ctx context.Context, retryIndex int) error) (int, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -81,19 +109,123 @@ func (r Retrier) Retry(ctx context.Context, fn func(ctx context.Context, retryIn
 		r.Retries = 1
 	}
 	for i = 1; i <= r.Retries; i++ {
-		err = fn(ctx, i)
+		err = r.attempt(ctx, fn, i)
 		if err == nil {
 			return i, nil
 		}
-		if be, ok := err.(Break); ok {
+		if be, ok := breakError(err); ok {
 			return i, be.Err
 		}
+		if r.Retryable != nil && !r.Retryable(err) {
+			return i, err
+		}
+		if i == r.Retries {
+			break
+		}
 
+		delay := r.delay(i)
+		r.logAttempt(i, err, delay)
 		select {
 		case <-ctx.Done():
 			return i - 1, ctx.Err()
-		case <-time.After(Backoff(r.BaseDelay, r.MaxDelay, i)):
+		case <-time.After(Delay(delay)):
+		}
+	}
+	return r.Retries, err
+}
+
+// attempt calls fn with the context of an attempt. A panic of an abandoned attempt is raised in the caller.
+func (r Retrier) attempt(ctx context.Context, fn func(ctx context.Context, retryIndex int) error, i int) error {
+	if r.AttemptTimeout <= 0 {
+		return fn(ctx, i)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, r.AttemptTimeout)
+	defer cancel()
+	type result struct {
+		err   error
+		panic interface{}
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{panic: p}
+			}
+		}()
+		done <- result{err: fn(attemptCtx, i)}
+	}()
+
+	select {
+	case res := <-done:
+		if res.panic != nil {
+			panic(res.panic)
+		}
+		return res.err
+	case <-attemptCtx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
+		return fmt.Errorf("attempt %d timed out after %s: %w", i, r.AttemptTimeout, context.DeadlineExceeded)
+	}
+}
+
+// delay returns the backoff after the given attempt, with jitter.
+func (r Retrier) delay(attempt int) time.Duration {
+	d := Backoff(r.BaseDelay, r.MaxDelay, attempt)
+	if r.Jitter > 0 {
+		jitter := r.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		d -= time.Duration(rand.Float64() * jitter * float64(d))
+	}
+	return d
+}
+
+func (r Retrier) logAttempt(attempt int, err error, delay time.Duration) {
+	if r.Description != "" {
+		Log.Infof("Attempt %d/%d to %s failed: %v; retrying in %s", attempt, r.Retries, r.Description, err, delay.Round(time.Millisecond))
+	} else {
+		Log.Debugf("Attempt %d/%d failed: %v; retrying in %s", attempt, r.Retries, err, delay.Round(time.Millisecond))
+	}
+}
+
+// breakError returns the Break of err, which may also be returned as a *Break.
+func breakError(err error) (Break, bool) {
+	switch be := err.(type) {
+	case Break:
+		return be, true
+	case *Break:
+		return *be, true
+	}
+	return Break{}, false
+}
+
+// RetryValue calls fn like r.Retry and returns the value returned by the successful attempt:
+//
+//	host, err := util.RetryValue(ctx, retrier, func(ctx context.Context, _ int) (string, error) {
+//		return getHost(ctx)
+//	})
+func RetryValue[T any](ctx context.Context, r Retrier, fn func(ctx context.Context, retryIndex int) (T, error)) (T, error) {
+	var mu sync.Mutex
+	// the values by attempt, so that an abandoned attempt cannot overwrite the value of a later one
+	values := map[int]T{}
+	i, err := r.Retry(ctx, func(ctx context.Context, retryIndex int) error {
+		value, err := fn(ctx, retryIndex)
+		if err == nil {
+			mu.Lock()
+			values[retryIndex] = value
+			mu.Unlock()
+		}
+		return err
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil {
+		var zero T
+		return zero, err
 	}
-	return i - 1, err
+	return values[i], nil
 }
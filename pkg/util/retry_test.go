@@ -0,0 +1,203 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		retries int
+		want    time.Duration
+	}{
+		{retries: 0, want: 100 * time.Millisecond},
+		{retries: 1, want: 130 * time.Millisecond},
+		{retries: 2, want: 169 * time.Millisecond},
+		{retries: 20, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.retries), func(t *testing.T) {
+			got := Backoff(100*time.Millisecond, time.Second, tt.retries)
+			if got.Round(time.Millisecond) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetrierDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		jitter   float64
+		min, max time.Duration
+	}{
+		{name: "no jitter", jitter: 0, min: 130 * time.Millisecond, max: 130 * time.Millisecond},
+		{name: "20%", jitter: 0.2, min: 104 * time.Millisecond, max: 130 * time.Millisecond},
+		{name: "capped at 100%", jitter: 3, min: 0, max: 130 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Retrier{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: tt.jitter}
+			lowest, highest := time.Duration(1<<62), time.Duration(0)
+			for i := 0; i < 1000; i++ {
+				d := r.delay(1)
+				if d < lowest {
+					lowest = d
+				}
+				if d > highest {
+					highest = d
+				}
+			}
+			if lowest < tt.min || highest > tt.max {
+				t.Errorf("got delays from %s to %s, want them between %s and %s", lowest, highest, tt.min, tt.max)
+			}
+			// the delays are spread over the range
+			if tt.jitter > 0 && highest-lowest < (tt.max-tt.min)/2 {
+				t.Errorf("got delays from %s to %s, want them spread between %s and %s", lowest, highest, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errFailed := errors.New("503")
+	forbidden := &CommandError{Command: "oc patch smcp/basic", Err: errors.New("exit status 1"), Reason: ReasonForbidden}
+
+	tests := []struct {
+		name      string
+		retryable func(error) bool
+		errs      []error
+		attempts  int
+		wantErr   error
+	}{
+		{
+			name:     "success",
+			errs:     []error{nil},
+			attempts: 1,
+		},
+		{
+			name:     "success after failures",
+			errs:     []error{errFailed, errFailed, nil},
+			attempts: 3,
+		},
+		{
+			name:     "retries exhausted",
+			errs:     []error{errors.New("first"), errors.New("second"), errFailed},
+			attempts: 3,
+			wantErr:  errFailed,
+		},
+		{
+			name:     "break",
+			errs:     []error{errFailed, Break{errFailed}},
+			attempts: 2,
+			wantErr:  errFailed,
+		},
+		{
+			name:     "break pointer",
+			errs:     []error{&Break{errFailed}},
+			attempts: 1,
+			wantErr:  errFailed,
+		},
+		{
+			name:      "not retryable",
+			retryable: Transient,
+			errs:      []error{errFailed, forbidden},
+			attempts:  2,
+			wantErr:   forbidden,
+		},
+		{
+			name:      "retryable",
+			retryable: Transient,
+			errs:      []error{&CommandError{Command: "oc get smcp", Err: errors.New("exit status 1"), Reason: ReasonTimeout}, nil},
+			attempts:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Retrier{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Retries: 3, Retryable: tt.retryable}
+			calls := 0
+			attempts, err := r.Retry(context.Background(), func(_ context.Context, i int) error {
+				calls++
+				if i != calls {
+					t.Errorf("got retry index %d in call %d", i, calls)
+				}
+				return tt.errs[i-1]
+			})
+			if attempts != tt.attempts || calls != tt.attempts {
+				t.Errorf("got %d attempts in %d calls, want %d", attempts, calls, tt.attempts)
+			}
+			if err != tt.wantErr {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryTimeouts(t *testing.T) {
+	// an attempt ignoring its context is abandoned
+	r := Retrier{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Retries: 2, AttemptTimeout: 10 * time.Millisecond}
+	block := make(chan struct{})
+	defer close(block)
+	attempts, err := r.Retry(context.Background(), func(ctx context.Context, _ int) error {
+		<-block
+		return nil
+	})
+	if attempts != 2 || !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "attempt 2 timed out after 10ms") {
+		t.Errorf("got %d attempts and %v, want 2 attempts timing out", attempts, err)
+	}
+
+	// the retries end with the maximum duration
+	r = Retrier{BaseDelay: time.Second, MaxDelay: time.Second, Retries: 10, MaxDuration: 20 * time.Millisecond}
+	start := time.Now()
+	attempts, err = r.Retry(context.Background(), func(ctx context.Context, _ int) error {
+		return errors.New("503")
+	})
+	if attempts != 0 || err != context.DeadlineExceeded || time.Since(start) > time.Second {
+		t.Errorf("got %d completed attempts and %v after %s, want 0 and the deadline", attempts, err, time.Since(start))
+	}
+}
+
+func TestRetryValue(t *testing.T) {
+	r := Retrier{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Retries: 3, AttemptTimeout: 20 * time.Millisecond}
+	host, err := RetryValue(context.Background(), r, func(ctx context.Context, i int) (string, error) {
+		switch i {
+		case 1:
+			// abandoned, its value arrives after the value of the next attempt
+			time.Sleep(50 * time.Millisecond)
+			return "stale.example.com", nil
+		case 2:
+			return "", errors.New("no route")
+		}
+		return "istio-ingressgateway.example.com", nil
+	})
+	if err != nil || host != "istio-ingressgateway.example.com" {
+		t.Errorf("got %q and %v", host, err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	r = Retrier{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Retries: 2}
+	port, err := RetryValue(context.Background(), r, func(ctx context.Context, i int) (int, error) {
+		return 80, Break{errors.New("no gateway")}
+	})
+	if port != 0 || err == nil || err.Error() != "no gateway" {
+		t.Errorf("failed attempts: got %d and %v, want the zero value and the error", port, err)
+	}
+}
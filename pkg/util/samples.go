@@ -0,0 +1,82 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+// SamplesDir is the directory of the sample applications, relative to the tests directory. It has a
+// subdirectory for every architecture of SampleArchs.
+var SamplesDir = "../testdata/examples"
+
+// BaseSampleArch is the architecture whose directory has every sample file. The directories of the other
+// architectures are overlays: they only contain the files that differ, e.g. manifests with other images.
+const BaseSampleArch = "x86"
+
+// sampleImagesFile is the file of an architecture directory mapping logical image names to image references.
+const sampleImagesFile = "images.yaml"
+
+// SampleFile returns the path of a sample file for the configured architecture, e.g.
+// SampleFile("bookinfo/bookinfo.yaml"). Files the architecture does not override are taken from the
+// BaseSampleArch directory. The architecture is read when the file is needed, so it must not be resolved
+// before the configuration is loaded, e.g. in package variables.
+func SampleFile(name string) string {
+	if arch := GetConfig().SampleArch; arch != BaseSampleArch {
+		path := filepath.Join(SamplesDir, arch, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(SamplesDir, BaseSampleArch, name)
+}
+
+// SampleImage returns the image reference of a logical image name for the configured architecture, e.g.
+// SampleImage("testssl"). The images are listed in the images.yaml file of each architecture directory;
+// images missing there are taken from the BaseSampleArch directory. Templates use it as
+// {{ sampleImage "testssl" }}.
+func SampleImage(name string) (string, error) {
+	for _, arch := range []string{GetConfig().SampleArch, BaseSampleArch} {
+		images, err := loadSampleImages(arch)
+		if err != nil {
+			return "", err
+		}
+		if image, ok := images[name]; ok {
+			return image, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sample image %q", name)
+}
+
+// loadSampleImages returns the images of an architecture, or none if it has no images file.
+func loadSampleImages(arch string) (map[string]string, error) {
+	filename := filepath.Join(SamplesDir, arch, sampleImagesFile)
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	images := map[string]string{}
+	if err := yaml.Unmarshal(data, &images); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return images, nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sampleArch sets the architecture of the configuration for the duration of the test.
+func sampleArch(t *testing.T, arch string) {
+	t.Helper()
+	previous := GetConfig()
+	c := *previous
+	c.SampleArch = arch
+	SetConfig(&c)
+	t.Cleanup(func() { SetConfig(previous) })
+}
+
+// samplesDir creates the files in a temp samples directory and uses it for the duration of the test.
+func samplesDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := SamplesDir
+	SamplesDir = dir
+	t.Cleanup(func() { SamplesDir = previous })
+	return dir
+}
+
+func TestSampleFile(t *testing.T) {
+	dir := samplesDir(t, map[string]string{
+		"x86/bookinfo/bookinfo.yaml": "x86",
+		"x86/httpbin/httpbin.yaml":   "x86",
+		"z/bookinfo/bookinfo.yaml":   "z",
+		"p/images.yaml":              "testssl: p",
+	})
+
+	tests := []struct {
+		arch string
+		name string
+		want string
+	}{
+		{arch: "x86", name: "bookinfo/bookinfo.yaml", want: "x86/bookinfo/bookinfo.yaml"},
+		{arch: "z", name: "bookinfo/bookinfo.yaml", want: "z/bookinfo/bookinfo.yaml"},
+		// files the architecture does not override
+		{arch: "z", name: "httpbin/httpbin.yaml", want: "x86/httpbin/httpbin.yaml"},
+		{arch: "p", name: "bookinfo/bookinfo.yaml", want: "x86/bookinfo/bookinfo.yaml"},
+		// an architecture without a directory
+		{arch: "arm", name: "bookinfo/bookinfo.yaml", want: "x86/bookinfo/bookinfo.yaml"},
+		// a file missing everywhere resolves to the base path, so that the error names it
+		{arch: "z", name: "sleep/sleep.yaml", want: "x86/sleep/sleep.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arch+"/"+tt.name, func(t *testing.T) {
+			sampleArch(t, tt.arch)
+			if got, want := SampleFile(tt.name), filepath.Join(dir, tt.want); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSampleImage(t *testing.T) {
+	samplesDir(t, map[string]string{
+		"x86/images.yaml": "testssl: quay.io/maistra/testssl:x86\nsleep: curlimages/curl:7.84.0\n",
+		"z/images.yaml":   "testssl: quay.io/maistra/testssl:z\n",
+		"p/images.yaml":   "testssl: [\n",
+	})
+
+	tests := []struct {
+		arch    string
+		name    string
+		want    string
+		wantErr string
+	}{
+		{arch: "x86", name: "testssl", want: "quay.io/maistra/testssl:x86"},
+		{arch: "z", name: "testssl", want: "quay.io/maistra/testssl:z"},
+		// images the architecture does not override
+		{arch: "z", name: "sleep", want: "curlimages/curl:7.84.0"},
+		// an architecture without an images file
+		{arch: "arm", name: "testssl", want: "quay.io/maistra/testssl:x86"},
+		{arch: "z", name: "nginx", wantErr: `unknown sample image "nginx"`},
+		{arch: "p", name: "testssl", wantErr: "p/images.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arch+"/"+tt.name, func(t *testing.T) {
+			sampleArch(t, tt.arch)
+			got, err := SampleImage(tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSampleOverlays checks that the architecture directories of the repository only override sample files
+// and images of the base architecture, so that a test never uses a sample that is missing on x86.
+func TestSampleOverlays(t *testing.T) {
+	root := filepath.Join("..", "..", "testdata", "examples")
+	previous := SamplesDir
+	SamplesDir = root
+	t.Cleanup(func() { SamplesDir = previous })
+	base, err := loadSampleImages(BaseSampleArch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, arch := range SampleArchs {
+		if arch == BaseSampleArch {
+			continue
+		}
+		images, err := loadSampleImages(arch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name := range images {
+			if _, ok := base[name]; !ok {
+				t.Errorf("%s: image %s is not in %s/%s", arch, name, BaseSampleArch, sampleImagesFile)
+			}
+		}
+
+		dir := filepath.Join(root, arch)
+		err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, _ := filepath.Rel(dir, path)
+			if _, err := os.Stat(filepath.Join(root, BaseSampleArch, rel)); err != nil {
+				t.Errorf("%s: %s does not override a file of %s", arch, rel, BaseSampleArch)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -23,7 +23,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -62,7 +61,7 @@ func Poll(interval time.Duration, numTrials int, do func() (bool, error)) error
 		} else if success {
 			return nil
 		} else {
-			time.Sleep(interval)
+			time.Sleep(Delay(interval))
 		}
 	}
 	return fmt.Errorf("max polling iteration reached")
@@ -129,15 +128,28 @@ func ShellSilent(format string, args ...interface{}) (string, error) {
 	return sh(context.Background(), format, false, false, false, args...)
 }
 
+// ShellResult runs command on shell like Shell, but returns its stdout, stderr, exit code and duration
+// separately. The error is a *CommandError.
+func ShellResult(format string, args ...interface{}) (CommandResult, error) {
+	return run(context.Background(), ShellCommand(fmt.Sprintf(format, args...)), true, true, true)
+}
+
+// sh formats and runs a command with the executor set by SetExecutor and returns its output.
 func sh(ctx context.Context, format string, logCommand, logOutput, logError bool, args ...interface{}) (string, error) {
-	command := fmt.Sprintf(format, args...)
+	res, err := run(ctx, ShellCommand(fmt.Sprintf(format, args...)), logCommand, logOutput, logError)
+	return res.Output, err
+}
+
+// run runs a command with the executor set by SetExecutor. A failure is returned as a *CommandError
+// classified by the output of the command.
+func run(ctx context.Context, cmd Command, logCommand, logOutput, logError bool) (CommandResult, error) {
+	command := cmd.String()
 	if logCommand {
 		Log.Infof("Running command %s", command)
 	}
-	c := exec.CommandContext(ctx, "sh", "-c", command) // #nosec
-	bytes, err := c.CombinedOutput()
+	res, err := Execute(ctx, cmd)
 	if logOutput {
-		if output := strings.TrimSuffix(string(bytes), "\n"); len(output) > 0 {
+		if output := strings.TrimSuffix(res.Output, "\n"); len(output) > 0 {
 			Log.Infof("Command output: \n%s", output)
 		}
 	}
@@ -146,23 +158,24 @@ func sh(ctx context.Context, format string, logCommand, logOutput, logError bool
 		if logError {
 			Log.Infof("Command error: %v", err)
 		}
-		return string(bytes), fmt.Errorf("command failed: %q %v", string(bytes), err)
+		return res, &CommandError{Command: command, Result: res, Reason: classifyFailure(ctx, res.Output), Err: err}
 	}
-	return string(bytes), nil
+	return res, nil
 }
 
-// RunBackground starts a background process and return the Process if succeed
+// RunBackground starts a background process and return the Process if succeed. The command is split
+// into arguments like sh would split it, see SplitArgs, and runs without a shell.
 func RunBackground(format string, args ...interface{}) (*os.Process, error) {
 	command := fmt.Sprintf(format, args...)
-	Log.Info("RunBackground: ", command)
-	parts := strings.Split(command, " ")
-	c := exec.Command(parts[0], parts[1:]...) // #nosec
-	err := c.Start()
+	parts, err := SplitArgs(command)
+	if err == nil && len(parts) == 0 {
+		err = errors.New("empty command")
+	}
 	if err != nil {
 		Log.Errorf("%s, command failed!", command)
-		return nil, err
+		return nil, fmt.Errorf("invalid command %q: %v", command, err)
 	}
-	return c.Process, nil
+	return NewCommand(parts[0], parts[1:]...).Start()
 }
 
 // Record run command and record output into a file
@@ -190,7 +203,7 @@ func HTTPDownload(dst string, src string) error {
 			Log.Errorf("Error: close file %s, %s", dst, err)
 		}
 	}()
-	resp, err = http.Get(src)
+	resp, err = HTTPClient(nil).Get(src)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,192 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// snapshotMetadata are the metadata fields kept in a snapshot. Fields populated by the API server,
+// e.g. resourceVersion and uid, are dropped so that the snapshot can be used to recreate the object.
+var snapshotMetadata = []string{"name", "namespace", "labels", "annotations", "finalizers"}
+
+// SMCPTimeout is the time a control plane gets to become ready or to be deleted.
+const SMCPTimeout = 10 * time.Minute
+
+// smcpInterval is the time between two checks of a restored control plane.
+var smcpInterval = 2 * time.Second
+
+// SMCPSnapshot is a copy of a ServiceMeshControlPlane taken before a test modifies it.
+type SMCPSnapshot struct {
+	Namespace string
+	Name      string
+	// Timeout is the maximum time to wait for the restored control plane to become ready.
+	Timeout time.Duration
+
+	object map[string]interface{}
+}
+
+// SnapshotSMCP reads the ServiceMeshControlPlane with the given name.
+func SnapshotSMCP(namespace, name string) (*SMCPSnapshot, error) {
+	object, err := getSMCP(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if object == nil {
+		return nil, fmt.Errorf("smcp/%s not found in namespace %s", name, namespace)
+	}
+
+	metadata, _ := object["metadata"].(map[string]interface{})
+	kept := map[string]interface{}{}
+	for _, field := range snapshotMetadata {
+		if value, ok := metadata[field]; ok {
+			kept[field] = value
+		}
+	}
+	return &SMCPSnapshot{
+		Namespace: namespace,
+		Name:      name,
+		Timeout:   SMCPTimeout,
+		object: map[string]interface{}{
+			"apiVersion": object["apiVersion"],
+			"kind":       object["kind"],
+			"metadata":   kept,
+			"spec":       object["spec"],
+		},
+	}, nil
+}
+
+// Restore replaces the spec of the control plane with the spec of the snapshot, or recreates the control
+// plane if it was deleted, and waits until it is ready. Fields added after the snapshot are removed.
+// It returns an error with a diff if the spec of the restored control plane differs from the snapshot.
+func (s *SMCPSnapshot) Restore() error {
+	current, err := getSMCP(s.Namespace, s.Name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(s.object)
+	if err != nil {
+		return err
+	}
+	tmpfile, err := WriteTempfile(os.TempDir(), "smcp", ".json", string(data))
+	if err != nil {
+		return err
+	}
+	defer removeFile(tmpfile)
+
+	if current == nil {
+		Log.Infof("Recreating smcp/%s in namespace %s", s.Name, s.Namespace)
+		_, err = Shell(`oc create -n %s -f %s`, s.Namespace, tmpfile)
+	} else {
+		Log.Infof("Restoring smcp/%s in namespace %s", s.Name, s.Namespace)
+		// replace sets the whole spec, which removes the fields added by merge patches
+		_, err = Shell(`oc replace -n %s -f %s`, s.Namespace, tmpfile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore smcp/%s: %v", s.Name, err)
+	}
+
+	if err := s.waitReady(); err != nil {
+		return fmt.Errorf("smcp/%s did not become ready after restoring it: %v", s.Name, err)
+	}
+	return s.Verify()
+}
+
+// waitReady waits until the operator has reconciled the restored spec. A Ready condition alone may
+// still refer to the spec before the restore, see ServiceMeshControlPlane.CheckCondition.
+func (s *SMCPSnapshot) waitReady() error {
+	if IsDryRun() {
+		return nil
+	}
+
+	retry := Retrier{
+		BaseDelay:   smcpInterval,
+		MaxDelay:    smcpInterval,
+		MaxDuration: s.Timeout,
+		Retries:     int(s.Timeout/smcpInterval) + 1,
+	}
+	var last error
+	_, err := retry.Retry(context.Background(), func(ctx context.Context, _ int) error {
+		var smcp ServiceMeshControlPlane
+		if last = GetKubeClient().Get(ctx, "smcp", s.Namespace, s.Name, &smcp); last != nil {
+			return last
+		}
+		last = smcp.CheckCondition("Ready")
+		return last
+	})
+	if err != nil && last != nil && err != last {
+		return fmt.Errorf("timed out after %s: %v", s.Timeout, last)
+	}
+	return err
+}
+
+// Verify compares the spec of the control plane with the spec of the snapshot. It returns an error
+// with a diff if they differ.
+func (s *SMCPSnapshot) Verify() error {
+	current, err := getSMCP(s.Namespace, s.Name)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return fmt.Errorf("smcp/%s not found in namespace %s", s.Name, s.Namespace)
+	}
+
+	expected, err := json.MarshalIndent(s.object["spec"], "", "  ")
+	if err != nil {
+		return err
+	}
+	actual, err := json.MarshalIndent(current["spec"], "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := Compare(actual, expected); err != nil {
+		return fmt.Errorf("spec of smcp/%s differs from the snapshot:\n%v", s.Name, err)
+	}
+	return nil
+}
+
+// PreserveSMCP takes a snapshot of the ServiceMeshControlPlane and restores it when t completes. Tests
+// patching the control plane use it instead of undoing their patches. A control plane that could not be
+// restored exactly fails the test with a diff of the spec.
+func PreserveSMCP(t *testing.T, namespace, name string) *SMCPSnapshot {
+	snapshot, err := SnapshotSMCP(namespace, name)
+	if err != nil {
+		t.Fatalf("Failed to take a snapshot of smcp/%s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		if err := snapshot.Restore(); err != nil {
+			t.Errorf("Failed to restore smcp/%s: %v", name, err)
+		}
+	})
+	return snapshot
+}
+
+// getSMCP returns the control plane as a JSON object, or nil if it does not exist.
+func getSMCP(namespace, name string) (map[string]interface{}, error) {
+	msg, err := ShellSilent(`oc get smcp/%s -n %s -o json`, name, namespace)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get smcp/%s: %v", name, err)
+	}
+	return GetJsonObject(msg)
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	getSMCPCommand = `^oc get smcp/basic -n istio-system -o json$`
+
+	smcpJSON = `{
+	"apiVersion": "maistra.io/v2",
+	"kind": "ServiceMeshControlPlane",
+	"metadata": {
+		"name": "basic",
+		"namespace": "istio-system",
+		"labels": {"app": "mesh"},
+		"annotations": {"note": "test"},
+		"finalizers": ["maistra.io/istio-operator"],
+		"resourceVersion": "12345",
+		"uid": "0b5c7a53-52a4-4b3b-9d0e-3c8b3b6c0e51",
+		"generation": 3,
+		"creationTimestamp": "2026-10-16T10:00:00Z",
+		"managedFields": [{"manager": "kubectl"}]
+	},
+	"spec": {"version": "v2.3", "security": {"dataPlane": {"mtls": false}}},
+	"status": {"conditions": [{"type": "Ready", "status": "True"}]}
+}`
+	// the spec after a test enabled mtls and added a tracing section
+	patchedSMCPJSON = `{
+	"apiVersion": "maistra.io/v2",
+	"kind": "ServiceMeshControlPlane",
+	"metadata": {"name": "basic", "namespace": "istio-system", "resourceVersion": "12399"},
+	"spec": {"version": "v2.3", "security": {"dataPlane": {"mtls": true}}, "tracing": {"type": "None"}}
+}`
+	notFoundSMCP = `Error from server (NotFound): servicemeshcontrolplanes.maistra.io "basic" not found`
+)
+
+func TestSnapshotSMCP(t *testing.T) {
+	fakeCommands(t, NewFakeExecutor().On(getSMCPCommand, CommandResult{Stdout: smcpJSON}))
+
+	s, err := SnapshotSMCP("istio-system", "basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"apiVersion": "maistra.io/v2",
+		"kind":       "ServiceMeshControlPlane",
+		// the fields set by the API server are dropped, so that the snapshot can recreate the object
+		"metadata": map[string]interface{}{
+			"name":        "basic",
+			"namespace":   "istio-system",
+			"labels":      map[string]interface{}{"app": "mesh"},
+			"annotations": map[string]interface{}{"note": "test"},
+			"finalizers":  []interface{}{"maistra.io/istio-operator"},
+		},
+		"spec": map[string]interface{}{
+			"version":  "v2.3",
+			"security": map[string]interface{}{"dataPlane": map[string]interface{}{"mtls": false}},
+		},
+	}
+	if !reflect.DeepEqual(s.object, want) {
+		t.Errorf("got %v\nwant %v", s.object, want)
+	}
+}
+
+func TestSnapshotSMCPNotFound(t *testing.T) {
+	fakeCommands(t, NewFakeExecutor().On(getSMCPCommand, CommandResult{Stderr: notFoundSMCP, ExitCode: 1}))
+
+	if _, err := SnapshotSMCP("istio-system", "basic"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestSMCPSnapshotVerify(t *testing.T) {
+	fake := NewFakeExecutor().OnSequence(getSMCPCommand,
+		CommandResult{Stdout: smcpJSON},
+		// the status and the metadata may change, only the spec is compared
+		CommandResult{Stdout: strings.Replace(smcpJSON, `"12345"`, `"12346"`, 1)},
+		CommandResult{Stdout: patchedSMCPJSON},
+		CommandResult{Stderr: notFoundSMCP, ExitCode: 1})
+	fakeCommands(t, fake)
+
+	s, err := SnapshotSMCP("istio-system", "basic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(); err != nil {
+		t.Errorf("unchanged spec: %v", err)
+	}
+
+	err = s.Verify()
+	if err == nil {
+		t.Fatal("expected a diff of the patched spec")
+	}
+	for _, want := range []string{"spec of smcp/basic differs from the snapshot", `"mtls": true`, `"tracing"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("the diff does not contain %q:\n%v", want, err)
+		}
+	}
+
+	if err := s.Verify(); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error for a deleted control plane, got %v", err)
+	}
+}
+
+func TestSMCPSnapshotRestore(t *testing.T) {
+	interval := smcpInterval
+	smcpInterval = time.Millisecond
+	t.Cleanup(func() { smcpInterval = interval })
+
+	ready := CommandResult{Stdout: `{"metadata": {"generation": 4}, "status": {"observedGeneration": 4, "conditions": [{"type": "Ready", "status": "True"}]}}`}
+	tests := []struct {
+		name    string
+		current CommandResult
+		status  []CommandResult
+		command string
+		wantErr string
+	}{
+		{
+			name:    "patched control plane is replaced",
+			current: CommandResult{Stdout: patchedSMCPJSON},
+			status:  []CommandResult{ready},
+			command: "oc replace -n istio-system -f ",
+		},
+		{
+			name:    "deleted control plane is recreated",
+			current: CommandResult{Stderr: notFoundSMCP, ExitCode: 1},
+			status:  []CommandResult{{Stderr: notFoundSMCP, ExitCode: 1}, ready},
+			command: "oc create -n istio-system -f ",
+		},
+		{
+			name:    "stale Ready condition is not trusted",
+			current: CommandResult{Stdout: patchedSMCPJSON},
+			status: []CommandResult{
+				{Stdout: `{"metadata": {"generation": 4}, "status": {"observedGeneration": 3, "conditions": [{"type": "Ready", "status": "True"}]}}`},
+				{Stdout: `{"metadata": {"generation": 4}, "status": {"observedGeneration": 4, "conditions": [{"type": "Ready", "status": "False", "message": "reconciling"}]}}`},
+				ready,
+			},
+			command: "oc replace -n istio-system -f ",
+		},
+		{
+			name:    "control plane not ready",
+			current: CommandResult{Stdout: patchedSMCPJSON},
+			status: []CommandResult{
+				{Stdout: `{"metadata": {"generation": 4}, "status": {"observedGeneration": 3, "conditions": [{"type": "Ready", "status": "True"}]}}`},
+			},
+			command: "oc replace -n istio-system -f ",
+			wantErr: "smcp/basic did not become ready after restoring it: timed out after 50ms: generation 4 not observed yet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeExecutor().
+				OnSequence(getSMCPCommand, CommandResult{Stdout: smcpJSON}, tt.current, CommandResult{Stdout: smcpJSON}).
+				On(`^oc (replace|create) -n istio-system -f \S+smcp\S*\.json$`, CommandResult{}).
+				OnSequence(`^kubectl get smcp basic -n istio-system -o json$`, tt.status...)
+			fakeCommands(t, fake)
+
+			s, err := SnapshotSMCP("istio-system", "basic")
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Timeout = 50 * time.Millisecond
+			err = s.Restore()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			commands := fake.Commands()
+			if len(commands) != 4+len(tt.status) || !strings.HasPrefix(commands[2], tt.command) {
+				t.Errorf("expected %q after reading the control plane, got %q", tt.command, commands)
+			}
+		})
+	}
+}
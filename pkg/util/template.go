@@ -0,0 +1,156 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"sigs.k8s.io/yaml"
+)
+
+// TemplateFuncs are the functions available to the templates of RunTemplate and Fill, in addition to the
+// builtin functions of text/template. Like the builtins, the value of a pipeline is their last argument,
+// e.g. {{ .Cert | indent 4 }}.
+var TemplateFuncs = template.FuncMap{
+	// indent N s indents every line of s by N spaces, e.g. to embed a file in a YAML block scalar.
+	"indent": indent,
+	// nindent N s is indent starting with a newline.
+	"nindent": func(n int, s string) string { return "\n" + indent(n, s) },
+	// quote s returns s as a double quoted string, with escapes valid in YAML and JSON.
+	"quote": quote,
+	// squote s returns s as a single quoted YAML string.
+	"squote": func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" },
+	// b64enc s and b64dec s encode and decode base64, e.g. for the data of a Secret.
+	"b64enc": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec": func(s string) (string, error) {
+		data, err := base64.StdEncoding.DecodeString(s)
+		return string(data), err
+	},
+	// default d v returns v, or d if v is empty, e.g. {{ .SourceNamespace | default .Namespace }}.
+	"default": func(d, v interface{}) interface{} {
+		if isEmpty(v) {
+			return d
+		}
+		return v
+	},
+	// required msg v returns v and fails the rendering with msg if v is empty.
+	"required": func(msg string, v interface{}) (interface{}, error) {
+		if isEmpty(v) {
+			return nil, errors.New(msg)
+		}
+		return v, nil
+	},
+	// sampleImage name returns the image of the sample applications for the configured architecture, see SampleImage.
+	"sampleImage": SampleImage,
+	// include file returns the contents of a file, e.g. a certificate from the testdata directory.
+	"include": func(filename string) (string, error) {
+		data, err := ioutil.ReadFile(filename)
+		return string(data), err
+	},
+}
+
+// newTemplate returns a strict template: referencing a missing map key is an error instead of rendering
+// "<no value>". Missing fields of structs are always errors.
+func newTemplate(name string) *template.Template {
+	return template.New(name).Option("missingkey=error").Funcs(TemplateFuncs)
+}
+
+// RunTemplate renders a yaml template string in the yaml_configs.go file. The rendered template must be
+// valid YAML, see ValidateYAML.
+func RunTemplate(tmpl string, input interface{}) (string, error) {
+	tt, err := newTemplate("").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tt.Execute(&buf, input); err != nil {
+		return "", fmt.Errorf("failed to render template: %v", err)
+	}
+	rendered := buf.String()
+	recordTemplate(rendered)
+	if err := ValidateYAML(rendered); err != nil {
+		return "", fmt.Errorf("rendered template is not valid YAML: %v\n%s", err, rendered)
+	}
+	return rendered, nil
+}
+
+// KubeApplyTemplate renders a template with RunTemplate and applies it like KubeApplyContents.
+func KubeApplyTemplate(namespace, tmpl string, input interface{}) ([]ObjectRef, error) {
+	contents, err := RunTemplate(tmpl, input)
+	if err != nil {
+		Log.Error(err)
+		return nil, err
+	}
+	return KubeApplyContents(namespace, contents)
+}
+
+// KubeDeleteTemplate renders a template with RunTemplate and deletes its objects like KubeDeleteContents.
+func KubeDeleteTemplate(namespace, tmpl string, input interface{}) error {
+	contents, err := RunTemplate(tmpl, input)
+	if err != nil {
+		Log.Error(err)
+		return err
+	}
+	return KubeDeleteContents(namespace, contents)
+}
+
+// ValidateYAML returns an error if a document of a multi-document YAML string cannot be parsed.
+func ValidateYAML(contents string) error {
+	for i, doc := range documentSeparator.Split(contents, -1) {
+		if _, err := yaml.YAMLToJSON([]byte(doc)); err != nil {
+			return fmt.Errorf("document %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func quote(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// isEmpty returns true for nil and for zero values, e.g. "", 0 and empty slices and maps.
+func isEmpty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
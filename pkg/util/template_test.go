@@ -0,0 +1,225 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestRunTemplate(t *testing.T) {
+	type gateway struct {
+		Name      string
+		Namespace string
+		Hosts     []string
+	}
+	certFile := filepath.Join(t.TempDir(), "tls.crt")
+	if err := ioutil.WriteFile(certFile, []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tmpl    string
+		input   interface{}
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "struct",
+			tmpl:  "name: {{ .Name }}\nnamespace: {{ .Namespace | default \"bookinfo\" }}\nhosts:{{ range .Hosts }}\n- {{ quote . }}{{ end }}\n",
+			input: gateway{Name: "bookinfo-gateway", Hosts: []string{"*", "bookinfo.com"}},
+			want:  "name: bookinfo-gateway\nnamespace: bookinfo\nhosts:\n- \"*\"\n- \"bookinfo.com\"\n",
+		},
+		{
+			name:  "map",
+			tmpl:  "mtls: {{ .mtls }}\nversion: {{ .version | squote }}\n",
+			input: map[string]interface{}{"mtls": true, "version": "v2.3"},
+			want:  "mtls: true\nversion: 'v2.3'\n",
+		},
+		{
+			name:  "secret",
+			tmpl:  "data:\n  tls.crt: {{ include .Cert | b64enc }}\nstringData:\n  ca.crt: |{{ include .Cert | nindent 4 }}",
+			input: map[string]string{"Cert": certFile},
+			want: "data:\n  tls.crt: LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0tCk1JSUIKLS0tLS1FTkQgQ0VSVElGSUNBVEUtLS0tLQo=\n" +
+				"stringData:\n  ca.crt: |\n    -----BEGIN CERTIFICATE-----\n    MIIB\n    -----END CERTIFICATE-----\n",
+		},
+		{
+			name:    "missing map key",
+			tmpl:    "mtls: {{ .mtls }}\n",
+			input:   map[string]interface{}{"mTLS": true},
+			wantErr: `map has no entry for key "mtls"`,
+		},
+		{
+			name:    "missing field",
+			tmpl:    "name: {{ .Nmae }}\n",
+			input:   gateway{Name: "bookinfo-gateway"},
+			wantErr: "can't evaluate field Nmae",
+		},
+		{
+			name:    "required value",
+			tmpl:    "name: {{ required \"the gateway needs a name\" .Name }}\n",
+			input:   gateway{},
+			wantErr: "the gateway needs a name",
+		},
+		{
+			name:    "missing file",
+			tmpl:    "ca: {{ include \"missing.crt\" }}\n",
+			wantErr: "missing.crt",
+		},
+		{
+			name:    "syntax error",
+			tmpl:    "name: {{ .Name }\n",
+			input:   gateway{},
+			wantErr: "invalid template",
+		},
+		{
+			name:    "invalid YAML",
+			tmpl:    "hosts:\n- {{ .Name }}\n  port: 80\n",
+			input:   gateway{Name: "productpage"},
+			wantErr: "rendered template is not valid YAML",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RunTemplate(tt.tmpl, tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		wantErr  string
+	}{
+		{
+			name:     "documents",
+			contents: "kind: Gateway\n---\nkind: VirtualService\n--- # reviews\nkind: DestinationRule\n",
+		},
+		{
+			name:     "empty documents",
+			contents: "---\nkind: Gateway\n---\n",
+		},
+		{
+			name:     "tab indentation",
+			contents: "kind: Gateway\n---\nkind: VirtualService\nspec:\n\thosts: []\n",
+			wantErr:  "document 2",
+		},
+		{
+			name:     "unclosed quote",
+			contents: "kind: Gateway\nmetadata:\n  name: \"bookinfo-gateway\n",
+			wantErr:  "document 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateYAML(tt.contents)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		n    int
+		s    string
+		want string
+	}{
+		{n: 2, s: "a", want: "  a"},
+		// empty lines are not padded, so that no trailing spaces end up in block scalars
+		{n: 4, s: "a\n\nb\n", want: "    a\n\n    b\n"},
+		{n: 0, s: "a\nb", want: "a\nb"},
+		{n: 2, s: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := indent(tt.n, tt.s); got != tt.want {
+			t.Errorf("indent(%d, %q): got %q, want %q", tt.n, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, s := range []string{"", "*.bookinfo.com", "yes", "a: b", `say "hi"`, "line\nbreak\ttab", "<html> & 'quotes'", "Überprüfung"} {
+		quoted := quote(s)
+		// the quoted string is a YAML and a JSON string with the original value
+		var got string
+		if err := yaml.Unmarshal([]byte("value: "+quoted), &struct{ Value *string }{&got}); err != nil {
+			t.Errorf("quote(%q) = %s is not valid YAML: %v", s, quoted, err)
+			continue
+		}
+		if got != s {
+			t.Errorf("quote(%q) = %s: got %q back", s, quoted, got)
+		}
+	}
+}
+
+func TestIsEmpty(t *testing.T) {
+	var nilPointer *string
+	empty := ""
+	tests := []struct {
+		name  string
+		value interface{}
+		want  bool
+	}{
+		{"nil", nil, true},
+		{"empty string", "", true},
+		{"string", "bookinfo", false},
+		{"zero", 0, true},
+		{"number", 8080, false},
+		{"false", false, true},
+		{"true", true, false},
+		{"empty slice", []string{}, true},
+		{"nil slice", []string(nil), true},
+		{"slice", []string{"a"}, false},
+		{"empty map", map[string]string{}, true},
+		{"nil pointer", nilPointer, true},
+		// a pointer to an empty value is set
+		{"pointer", &empty, false},
+		{"zero struct", struct{ Name string }{}, true},
+		{"struct", struct{ Name string }{"a"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isEmpty(tt.value); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
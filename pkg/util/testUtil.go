@@ -15,7 +15,6 @@
 package util
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -24,18 +23,14 @@ import (
 	"net/http"
 	"os"
 	"testing"
-	"text/template"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
-// getenv loads test.env file and returns an environment variable value.
+// Getenv returns an environment variable value, also looking at the test.env file if it exists.
 // If the environment variable is empty, it returns the fallback as a default value.
+// The settings of Config are read with GetConfig instead.
 func Getenv(key, fallback string) string {
-	if err := godotenv.Load("test.env"); err != nil {
-		Log.Fatal("Error loading .env file")
-	}
+	loadEnvFile()
 	value := os.Getenv(key)
 	if len(value) == 0 {
 		return fallback
@@ -43,24 +38,15 @@ func Getenv(key, fallback string) string {
 	return value
 }
 
-// RunTemplate renders a yaml template string in the yaml_configs.go file
-func RunTemplate(tmpl string, input interface{}) string {
-	tt, err := template.New("").Parse(tmpl)
-	if err != nil {
-		Log.Fatal(err)
-	}
-	var buf bytes.Buffer
-	if err := tt.Execute(&buf, input); err != nil {
-		Log.Fatal(err)
-	}
-	return buf.String()
-}
-
 // recover from panic if one occurred. This allows cleanup to be executed after panic.
+// If the test failed, the handlers registered with OnTestFailure are called.
 func RecoverPanic(t *testing.T) {
 	if err := recover(); err != nil {
 		t.Errorf("Test panic: %v", err)
 	}
+	if t.Failed() {
+		runFailureHandlers(t)
+	}
 }
 
 func IsWithinPercentage(count int, total int, rate float64, tolerance float64) bool {
@@ -101,7 +87,7 @@ func CurlWithCA(url, ingressHost, secureIngressPort, host, cacertFile string) (*
 	}
 
 	// Setup HTTPS client
-	client := &http.Client{Transport: transport}
+	client := HTTPClient(transport)
 
 	// GET something
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -154,7 +140,7 @@ func CurlWithCAClient(url, ingressHost, secureIngressPort, host, cacertFile, cer
 	}
 
 	// Setup HTTPS client
-	client := &http.Client{Transport: transport}
+	client := HTTPClient(transport)
 
 	// GET something
 	req, err := http.NewRequest(http.MethodGet, url, nil)
@@ -171,7 +157,7 @@ func CurlWithCAClient(url, ingressHost, secureIngressPort, host, cacertFile, cer
 // check user key from header
 func CheckUserGroup(url, ingress, ingressPort, user string) (*http.Response, error) {
 	// Declare http client
-	client := &http.Client{}
+	client := HTTPClient(nil)
 
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
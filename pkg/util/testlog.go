@@ -0,0 +1,213 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// testLog is the logging context of a running test case, see BindTestLog.
+type testLog struct {
+	mu     sync.Mutex
+	fields logrus.Fields
+	// file is the log file of the test case, or nil if EnableTestLogs was not called.
+	file *os.File
+}
+
+var (
+	testLogsMu sync.Mutex
+	// testLogs maps a top level test name to its logging context.
+	testLogs   = map[string]*testLog{}
+	testLogDir string
+)
+
+func init() {
+	Log.AddHook(testLogHook{})
+}
+
+// EnableTestLogs makes BindTestLog write the entries of every test case to a log file in dir, see TestLogFile.
+func EnableTestLogs(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	testLogsMu.Lock()
+	defer testLogsMu.Unlock()
+	testLogDir = dir
+	return nil
+}
+
+// TestLogFile returns the log file of a test case in a log directory.
+func TestLogFile(dir, id string) string {
+	return filepath.Join(dir, id+".log")
+}
+
+// BindTestLog binds the log entries of the test case with the given ID to t until t completes. Entries of
+// TestLog(t) carry the ID as "test" field. While it is the only bound test case, e.g. unless test cases run
+// in parallel, the entries of Log are attributed to it as well. If EnableTestLogs was called, the entries
+// of the test case are also written to its log file, in the format of Log.
+func BindTestLog(t *testing.T, id string) {
+	l := &testLog{fields: logrus.Fields{"test": id}}
+	name := topLevelTest(t.Name())
+
+	testLogsMu.Lock()
+	dir := testLogDir
+	testLogsMu.Unlock()
+	if dir != "" {
+		f, err := os.Create(TestLogFile(dir, id))
+		if err != nil {
+			Log.Warnf("Failed to create the log file of %s: %v", id, err)
+		} else {
+			l.file = f
+		}
+	}
+
+	testLogsMu.Lock()
+	testLogs[name] = l
+	testLogsMu.Unlock()
+
+	t.Cleanup(func() {
+		testLogsMu.Lock()
+		if testLogs[name] == l {
+			delete(testLogs, name)
+		}
+		testLogsMu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.file != nil {
+			l.file.Close()
+			l.file = nil
+		}
+	})
+}
+
+// SetTestLogField adds a field to the entries of the test case bound to t, e.g. the namespaces allocated for it.
+func SetTestLogField(t *testing.T, key string, value interface{}) {
+	testLogsMu.Lock()
+	defer testLogsMu.Unlock()
+	if l, ok := testLogs[topLevelTest(t.Name())]; ok {
+		l.mu.Lock()
+		l.fields[key] = value
+		l.mu.Unlock()
+	}
+}
+
+// TestLog returns an entry of Log carrying the fields of the test case bound to t, see BindTestLog, and the
+// name of the subtest as "step", so that the output of a test case can be told apart even when test cases
+// run in parallel:
+//
+//	util.TestLog(t).Infof("Checking %s", url)
+func TestLog(t *testing.T) *logrus.Entry {
+	name := t.Name()
+	top := topLevelTest(name)
+	fields := logrus.Fields{"test": top}
+
+	testLogsMu.Lock()
+	if l, ok := testLogs[top]; ok {
+		l.mu.Lock()
+		for k, v := range l.fields {
+			fields[k] = v
+		}
+		l.mu.Unlock()
+	}
+	testLogsMu.Unlock()
+
+	if len(name) > len(top) {
+		fields["step"] = name[len(top)+1:]
+	}
+	return Log.WithFields(fields)
+}
+
+// testLogHook adds the fields of the test case an entry belongs to and writes it to the log file of the test case.
+type testLogHook struct{}
+
+func (testLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (testLogHook) Fire(e *logrus.Entry) error {
+	l := boundTestLog(e)
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for k, v := range l.fields {
+		if _, ok := e.Data[k]; !ok {
+			e.Data[k] = v
+		}
+	}
+	if l.file == nil {
+		return nil
+	}
+	data, err := e.Logger.Formatter.Format(e)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.Write(data)
+	return err
+}
+
+// boundTestLog returns the test case of an entry: the one named by its "test" field or the only bound one.
+func boundTestLog(e *logrus.Entry) *testLog {
+	testLogsMu.Lock()
+	defer testLogsMu.Unlock()
+
+	if id, ok := e.Data["test"]; ok {
+		for _, l := range testLogs {
+			if l.fields["test"] == id {
+				return l
+			}
+		}
+		return nil
+	}
+	if len(testLogs) == 1 {
+		for _, l := range testLogs {
+			return l
+		}
+	}
+	return nil
+}
+
+// currentTestID returns the ID of the only bound test case, or an empty string.
+func currentTestID() string {
+	testLogsMu.Lock()
+	defer testLogsMu.Unlock()
+	if len(testLogs) != 1 {
+		return ""
+	}
+	for _, l := range testLogs {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		id, _ := l.fields["test"].(string)
+		return id
+	}
+	return ""
+}
+
+// topLevelTest returns the name of the top level test of a (sub)test name.
+func topLevelTest(name string) string {
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
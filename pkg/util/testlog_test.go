@@ -0,0 +1,172 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// captureLog writes the entries of Log as JSON to the returned buffer for the duration of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	out, formatter, level := Log.Out, Log.Formatter, Log.Level
+	t.Cleanup(func() {
+		Log.Out, Log.Formatter = out, formatter
+		Log.SetLevel(level)
+	})
+	if err := ConfigureLog("json", "info"); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	Log.Out = buf
+	return buf
+}
+
+// entries parses the JSON log entries in data.
+func entries(t *testing.T, data []byte) []map[string]interface{} {
+	t.Helper()
+	var parsed []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid log entry %q: %v", line, err)
+		}
+		parsed = append(parsed, e)
+	}
+	return parsed
+}
+
+func TestConfigureLog(t *testing.T) {
+	captureLog(t)
+	tests := []struct {
+		format  string
+		level   string
+		wantErr string
+	}{
+		{format: "text", level: "debug"},
+		{format: "json", level: "warning"},
+		{format: "xml", level: "info", wantErr: `unknown log format "xml", must be one of text, json`},
+		{format: "text", level: "verbose", wantErr: `not a valid logrus Level: "verbose"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.level, func(t *testing.T) {
+			err := ConfigureLog(tt.format, tt.level)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if Log.Level.String() != tt.level {
+				t.Errorf("got level %s, want %s", Log.Level, tt.level)
+			}
+			if _, json := Log.Formatter.(*logrus.JSONFormatter); json != (tt.format == "json") {
+				t.Errorf("got formatter %T for format %s", Log.Formatter, tt.format)
+			}
+		})
+	}
+}
+
+func TestBindTestLog(t *testing.T) {
+	buf := captureLog(t)
+	dir := t.TempDir()
+	if err := EnableTestLogs(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { testLogDir = "" })
+
+	t.Run("T1", func(t *testing.T) {
+		BindTestLog(t, "T1")
+		SetTestLogField(t, "namespace", "bookinfo-1")
+		Log.Info("unattributed")
+		t.Run("step a", func(t *testing.T) {
+			TestLog(t).Info("in step")
+		})
+	})
+	// the test case is unbound when it completes
+	Log.Info("after the test")
+
+	want := []map[string]interface{}{
+		{"msg": "unattributed", "test": "T1", "namespace": "bookinfo-1"},
+		// the step is relative to the top level test, which is the test function here rather than the test case
+		{"msg": "in step", "test": "T1", "namespace": "bookinfo-1", "step": "T1/step_a"},
+		{"msg": "after the test"},
+	}
+	logged := entries(t, buf.Bytes())
+	if len(logged) != len(want) {
+		t.Fatalf("got entries\n%s", buf)
+	}
+	for i, w := range want {
+		for _, key := range []string{"msg", "test", "namespace", "step"} {
+			if logged[i][key] != w[key] {
+				t.Errorf("entry %d: got %s %v, want %v", i, key, logged[i][key], w[key])
+			}
+		}
+	}
+
+	// the log file of the test case holds its entries only, in the format of Log
+	data, err := ioutil.ReadFile(TestLogFile(dir, "T1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inFile := entries(t, data); len(inFile) != 2 || inFile[0]["msg"] != "unattributed" || inFile[1]["msg"] != "in step" {
+		t.Errorf("got log file\n%s", data)
+	}
+}
+
+func TestBindTestLogInParallel(t *testing.T) {
+	buf := captureLog(t)
+	// another test case running at the same time
+	testLogsMu.Lock()
+	testLogs["T9"] = &testLog{fields: logrus.Fields{"test": "T9"}}
+	testLogsMu.Unlock()
+	t.Cleanup(func() {
+		testLogsMu.Lock()
+		delete(testLogs, "T9")
+		testLogsMu.Unlock()
+	})
+
+	t.Run("T1", func(t *testing.T) {
+		BindTestLog(t, "T1")
+		if id := currentTestID(); id != "" {
+			t.Errorf("got current test %q while two test cases are bound", id)
+		}
+		Log.Info("ambiguous")
+		TestLog(t).Info("attributed")
+	})
+
+	logged := entries(t, buf.Bytes())
+	if len(logged) != 2 {
+		t.Fatalf("got entries\n%s", buf)
+	}
+	if _, ok := logged[0]["test"]; ok {
+		t.Errorf("an entry of Log is attributed to %v while two test cases are bound", logged[0]["test"])
+	}
+	if logged[1]["test"] != "T1" {
+		t.Errorf("got test %v, want T1", logged[1]["test"])
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// query runs a read-only command without logging it and returns its stdout.
+func query(ctx context.Context, format string, args ...interface{}) (string, error) {
+	command := fmt.Sprintf(format, args...)
+	res, err := util.Execute(ctx, util.ShellCommand(command))
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %s", command, strings.TrimSpace(res.Output))
+	}
+	return strings.TrimSpace(res.Stdout), nil
+}
+
+// SMCPReady is met when the Ready condition of the ServiceMeshControlPlane is True.
+func SMCPReady(ns, name string) Condition {
+	return SMCPCondition(ns, name, "Ready")
+}
+
+// SMCPCondition is met when the given status condition of the ServiceMeshControlPlane is True,
+// e.g. "Installed" or "Reconciled", and the operator has observed the latest generation of the
+// SMCP, so that the condition does not refer to the spec before a patch.
+func SMCPCondition(ns, name, condition string) Condition {
+	return Condition{
+		Description: fmt.Sprintf("SMCP %s/%s %s", ns, name, condition),
+		Check: func(ctx context.Context) error {
+			var smcp util.ServiceMeshControlPlane
+			if err := util.GetKubeClient().Get(ctx, "smcp", ns, name, &smcp); err != nil {
+				return err
+			}
+			return smcp.CheckCondition(condition)
+		},
+	}
+}
+
+// DeploymentRolledOut is met when the latest generation of the deployment has been rolled out: all
+// replicas are updated and available and no replicas of an earlier generation are left.
+func DeploymentRolledOut(ns, name string) Condition {
+	return Condition{
+		Description: fmt.Sprintf("deployment %s/%s rolled out", ns, name),
+		Check: func(ctx context.Context) error {
+			var d util.Deployment
+			if err := util.GetKubeClient().Get(ctx, "deployment", ns, name, &d); err != nil {
+				return err
+			}
+			desired := 1
+			if d.Spec.Replicas != nil {
+				desired = *d.Spec.Replicas
+			}
+			generation := d.Metadata.Generation
+			replicas, updated, available := d.Status.Replicas, d.Status.UpdatedReplicas, d.Status.AvailableReplicas
+			switch {
+			case d.Status.ObservedGeneration < generation:
+				return fmt.Errorf("generation %d not observed yet", generation)
+			case updated < desired:
+				return fmt.Errorf("%d of %d replicas updated", updated, desired)
+			case replicas > updated:
+				return fmt.Errorf("%d old replicas pending termination", replicas-updated)
+			case available < desired:
+				return fmt.Errorf("%d of %d replicas available", available, desired)
+			}
+			return nil
+		},
+	}
+}
+
+// PodsReady is met when there is at least one pod matching the label selector in the namespace and
+// all of them are running with all containers ready. Completed pods, e.g. of jobs, are ignored.
+// An empty selector selects all pods of the namespace.
+func PodsReady(ns, selector string) Condition {
+	return podsReady(ns, selector, false)
+}
+
+// PodsReadyWithSidecar is like PodsReady, but additionally requires every pod to have an istio-proxy
+// container, i.e. to have been created after sidecar injection was enabled.
+func PodsReadyWithSidecar(ns, selector string) Condition {
+	return podsReady(ns, selector, true)
+}
+
+func podsReady(ns, selector string, sidecar bool) Condition {
+	desc := fmt.Sprintf("pods in %s ready", ns)
+	if selector != "" {
+		desc = fmt.Sprintf("pods %s in %s ready", selector, ns)
+	}
+	if sidecar {
+		desc += " with sidecars"
+	}
+	return Condition{
+		Description: desc,
+		Check: func(ctx context.Context) error {
+			var pods []util.Pod
+			if err := util.GetKubeClient().List(ctx, "pods", ns, util.ListOptions{LabelSelector: selector}, &pods); err != nil {
+				return err
+			}
+
+			found := 0
+			for _, p := range pods {
+				if p.Status.Phase == "Succeeded" {
+					continue
+				}
+				found++
+				name := p.Metadata.Name
+				if p.Metadata.DeletionTimestamp != nil {
+					return fmt.Errorf("pod %s is terminating", name)
+				}
+				if p.Status.Phase != "Running" {
+					return fmt.Errorf("pod %s is %s", name, p.Status.Phase)
+				}
+				if sidecar && !hasContainer(p, "istio-proxy") {
+					return fmt.Errorf("pod %s has no sidecar", name)
+				}
+				for _, c := range p.Status.ContainerStatuses {
+					if !c.Ready {
+						return fmt.Errorf("container %s of pod %s is not ready", c.Name, name)
+					}
+				}
+			}
+			if found == 0 {
+				return fmt.Errorf("no pods found")
+			}
+			return nil
+		},
+	}
+}
+
+func hasContainer(p util.Pod, name string) bool {
+	for _, c := range p.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Deleted is met when the resource does not exist anymore, e.g. Deleted("bookinfo", "pod", "productpage-v1-6b746f74dc-9stvs").
+// The namespace is empty for cluster scoped resources.
+func Deleted(ns, kind, name string) Condition {
+	desc := fmt.Sprintf("%s %s", kind, name)
+	if ns != "" {
+		desc = ns + " " + desc
+	}
+	return Condition{
+		Description: desc + " deleted",
+		Check: func(ctx context.Context) error {
+			err := util.GetKubeClient().Get(ctx, kind, ns, name, &util.Object{})
+			if util.IsNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("%s %s still exists", kind, name)
+		},
+	}
+}
+
+// AllDeleted is met when no resource of the kind matching the label selector exists in the namespace.
+func AllDeleted(ns, kind, selector string) Condition {
+	return Condition{
+		Description: fmt.Sprintf("%s %s %s deleted", ns, kind, selector),
+		Check: func(ctx context.Context) error {
+			var objects []*util.Object
+			if err := util.GetKubeClient().List(ctx, kind, ns, util.ListOptions{LabelSelector: selector}, &objects); err != nil {
+				return err
+			}
+			if len(objects) > 0 {
+				names := make([]string, 0, len(objects))
+				for _, o := range objects {
+					names = append(names, o.Name())
+				}
+				return fmt.Errorf("%s %s still exists", kind, strings.Join(names, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// HTTPStatus is met when a GET request of the URL returns the expected status code. If host is not
+// empty, it is sent as the Host header, e.g. to reach a service through the ingress gateway.
+// Certificates are not verified.
+func HTTPStatus(url, host string, expected int) Condition {
+	client := util.HTTPClient(&http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}) // #nosec
+	client.Timeout = 10 * time.Second
+	desc := fmt.Sprintf("%s to return %d", url, expected)
+	if host != "" {
+		desc = fmt.Sprintf("%s (host %s) to return %d", url, host, expected)
+	}
+	return Condition{
+		Description: desc,
+		Check: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			if host != "" {
+				req.Host = host
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			util.CloseResponseBody(resp)
+			if resp.StatusCode != expected {
+				return fmt.Errorf("status %d", resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
+
+// PodHTTPStatus is met when curl in a container of a pod returns the expected status code, e.g. for a
+// request from the sleep pod to a service in the mesh. The extra curl arguments are added to the
+// command, e.g. `-H "Authorization: Bearer <token>"`.
+func PodHTTPStatus(ns, pod, container, url string, expected int, curlArgs ...string) Condition {
+	return Condition{
+		Description: fmt.Sprintf("%s from %s/%s to return %d", url, ns, pod, expected),
+		Check: func(ctx context.Context) error {
+			out, err := query(ctx, `kubectl exec %s -n %s -c %s -- curl "%s" -sS -o /dev/null -w "%%{http_code}" %s`,
+				pod, ns, container, url, strings.Join(curlArgs, " "))
+			if err != nil {
+				return err
+			}
+			if out != strconv.Itoa(expected) {
+				return fmt.Errorf("status %s", out)
+			}
+			return nil
+		},
+	}
+}
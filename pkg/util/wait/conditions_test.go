@@ -0,0 +1,379 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// objects decodes the JSON objects for FakeKubeClient.Add. The fake stores an object with the next generation,
+// like the API server does on a change, so an object without a generation is stored with generation 1.
+func objects(t *testing.T, objs ...string) []interface{} {
+	t.Helper()
+	var decoded []interface{}
+	for _, s := range objs {
+		var o map[string]interface{}
+		if err := json.Unmarshal([]byte(s), &o); err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, o)
+	}
+	return decoded
+}
+
+// checkCondition checks the condition once and compares the error with wantErr, which is empty if the
+// condition must be met.
+func checkCondition(t *testing.T, cond Condition, wantErr string) {
+	t.Helper()
+	err := cond.Check(context.Background())
+	if wantErr == "" {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), wantErr) {
+		t.Fatalf("expected an error containing %q, got %v", wantErr, err)
+	}
+}
+
+func TestSMCPReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		smcp    string
+		wantErr string
+	}{
+		{
+			name: "ready",
+			smcp: `{"metadata": {"name": "basic", "namespace": "istio-system"},
+				"status": {"observedGeneration": 1, "conditions": [{"type": "Ready", "status": "True"}]}}`,
+		},
+		{
+			// Ready refers to the spec before a patch
+			name: "generation not observed",
+			smcp: `{"metadata": {"name": "basic", "namespace": "istio-system", "generation": 1},
+				"status": {"observedGeneration": 1, "conditions": [{"type": "Ready", "status": "True"}]}}`,
+			wantErr: "generation 2 not observed yet",
+		},
+		{
+			name: "not ready",
+			smcp: `{"metadata": {"name": "basic", "namespace": "istio-system"},
+				"status": {"observedGeneration": 1, "conditions": [{"type": "Ready", "status": "False", "message": "Some components are not fully available"}]}}`,
+			wantErr: `condition Ready is "False": Some components are not fully available`,
+		},
+		{
+			name:    "no conditions",
+			smcp:    `{"metadata": {"name": "basic", "namespace": "istio-system"}, "status": {"observedGeneration": 1}}`,
+			wantErr: "condition Ready not reported yet",
+		},
+		{
+			name:    "missing",
+			smcp:    `{"metadata": {"name": "other", "namespace": "istio-system"}}`,
+			wantErr: "not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := util.NewFakeKubeClient().Add("smcp", objects(t, tt.smcp)...)
+			defer util.SetKubeClient(util.SetKubeClient(fake))
+
+			checkCondition(t, SMCPReady("istio-system", "basic"), tt.wantErr)
+		})
+	}
+}
+
+func TestDeploymentRolledOut(t *testing.T) {
+	tests := []struct {
+		name       string
+		deployment string
+		wantErr    string
+	}{
+		{
+			name: "rolled out",
+			deployment: `{"metadata": {"name": "reviews-v1", "namespace": "bookinfo"}, "spec": {"replicas": 2},
+				"status": {"observedGeneration": 1, "replicas": 2, "updatedReplicas": 2, "availableReplicas": 2}}`,
+		},
+		{
+			name: "one replica by default",
+			deployment: `{"metadata": {"name": "reviews-v1", "namespace": "bookinfo"},
+				"status": {"observedGeneration": 1, "replicas": 1, "updatedReplicas": 1, "availableReplicas": 1}}`,
+		},
+		{
+			name: "generation not observed",
+			deployment: `{"metadata": {"name": "reviews-v1", "namespace": "bookinfo", "generation": 1}, "spec": {"replicas": 2},
+				"status": {"observedGeneration": 1, "replicas": 2, "updatedReplicas": 2, "availableReplicas": 2}}`,
+			wantErr: "generation 2 not observed yet",
+		},
+		{
+			name: "replicas not updated",
+			deployment: `{"metadata": {"name": "reviews-v1", "namespace": "bookinfo"}, "spec": {"replicas": 2},
+				"status": {"observedGeneration": 1, "replicas": 3, "updatedReplicas": 1, "availableReplicas": 2}}`,
+			wantErr: "1 of 2 replicas updated",
+		},
+		{
+			name: "old replicas terminating",
+			deployment: `{"metadata": {"name": "reviews-v1", "namespace": "bookinfo"}, "spec": {"replicas": 2},
+				"status": {"observedGeneration": 1, "replicas": 3, "updatedReplicas": 2, "availableReplicas": 2}}`,
+			wantErr: "1 old replicas pending termination",
+		},
+		{
+			name: "replicas not available",
+			deployment: `{"metadata": {"name": "reviews-v1", "namespace": "bookinfo"}, "spec": {"replicas": 2},
+				"status": {"observedGeneration": 1, "replicas": 2, "updatedReplicas": 2, "availableReplicas": 1}}`,
+			wantErr: "1 of 2 replicas available",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := util.NewFakeKubeClient().Add("deployment", objects(t, tt.deployment)...)
+			defer util.SetKubeClient(util.SetKubeClient(fake))
+
+			checkCondition(t, DeploymentRolledOut("bookinfo", "reviews-v1"), tt.wantErr)
+		})
+	}
+}
+
+func TestPodsReady(t *testing.T) {
+	const (
+		withSidecar = `{"metadata": {"name": "reviews-v1-545db", "namespace": "bookinfo", "labels": {"app": "reviews"}},
+			"spec": {"containers": [{"name": "reviews"}, {"name": "istio-proxy"}]},
+			"status": {"phase": "Running", "containerStatuses": [{"name": "reviews", "ready": true}, {"name": "istio-proxy", "ready": true}]}}`
+		withoutSidecar = `{"metadata": {"name": "reviews-v2-7bc8f", "namespace": "bookinfo", "labels": {"app": "reviews"}},
+			"spec": {"containers": [{"name": "reviews"}]},
+			"status": {"phase": "Running", "containerStatuses": [{"name": "reviews", "ready": true}]}}`
+		notReady = `{"metadata": {"name": "reviews-v3-84779", "namespace": "bookinfo", "labels": {"app": "reviews"}},
+			"spec": {"containers": [{"name": "reviews"}, {"name": "istio-proxy"}]},
+			"status": {"phase": "Running", "containerStatuses": [{"name": "reviews", "ready": true}, {"name": "istio-proxy", "ready": false}]}}`
+		pending = `{"metadata": {"name": "reviews-v3-84779", "namespace": "bookinfo", "labels": {"app": "reviews"}},
+			"status": {"phase": "Pending"}}`
+		terminating = `{"metadata": {"name": "reviews-v3-84779", "namespace": "bookinfo", "labels": {"app": "reviews"}, "deletionTimestamp": "2021-06-01T10:00:00Z"},
+			"status": {"phase": "Running"}}`
+		completed = `{"metadata": {"name": "reviews-job-x7k2q", "namespace": "bookinfo", "labels": {"app": "reviews"}},
+			"status": {"phase": "Succeeded"}}`
+		otherApp = `{"metadata": {"name": "ratings-v1-b6994", "namespace": "bookinfo", "labels": {"app": "ratings"}},
+			"status": {"phase": "Pending"}}`
+	)
+	tests := []struct {
+		name    string
+		pods    []string
+		sidecar bool
+		wantErr string
+	}{
+		{
+			name: "ready",
+			pods: []string{withSidecar, withoutSidecar, otherApp},
+		},
+		{
+			name:    "ready with sidecars",
+			pods:    []string{withSidecar},
+			sidecar: true,
+		},
+		{
+			// e.g. created before the namespace was added to the mesh
+			name:    "without sidecar",
+			pods:    []string{withSidecar, withoutSidecar},
+			sidecar: true,
+			wantErr: "pod reviews-v2-7bc8f has no sidecar",
+		},
+		{
+			name:    "container not ready",
+			pods:    []string{withSidecar, notReady},
+			wantErr: "container istio-proxy of pod reviews-v3-84779 is not ready",
+		},
+		{
+			name:    "pending",
+			pods:    []string{pending},
+			wantErr: "pod reviews-v3-84779 is Pending",
+		},
+		{
+			name:    "terminating",
+			pods:    []string{withSidecar, terminating},
+			wantErr: "pod reviews-v3-84779 is terminating",
+		},
+		{
+			name: "completed pods ignored",
+			pods: []string{withSidecar, completed},
+		},
+		{
+			name:    "only completed pods",
+			pods:    []string{completed},
+			wantErr: "no pods found",
+		},
+		{
+			name:    "no pods",
+			pods:    []string{otherApp},
+			wantErr: "no pods found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := util.NewFakeKubeClient().Add("pods", objects(t, tt.pods...)...)
+			defer util.SetKubeClient(util.SetKubeClient(fake))
+
+			cond := PodsReady("bookinfo", "app=reviews")
+			if tt.sidecar {
+				cond = PodsReadyWithSidecar("bookinfo", "app=reviews")
+			}
+			checkCondition(t, cond, tt.wantErr)
+		})
+	}
+}
+
+func TestDeleted(t *testing.T) {
+	const (
+		productpage = `{"metadata": {"name": "productpage-v1-6b746", "namespace": "bookinfo", "labels": {"app": "productpage"}}}`
+		reviews     = `{"metadata": {"name": "reviews-v1-545db", "namespace": "bookinfo", "labels": {"app": "reviews"}}}`
+		namespace   = `{"metadata": {"name": "mesh-external"}}`
+	)
+	tests := []struct {
+		name       string
+		pods       []string
+		namespaces []string
+		cond       Condition
+		wantErr    string
+	}{
+		{
+			name: "deleted",
+			pods: []string{reviews},
+			cond: Deleted("bookinfo", "pod", "productpage-v1-6b746"),
+		},
+		{
+			name:    "not deleted",
+			pods:    []string{productpage, reviews},
+			cond:    Deleted("bookinfo", "pod", "productpage-v1-6b746"),
+			wantErr: "pod productpage-v1-6b746 still exists",
+		},
+		{
+			name: "cluster scoped",
+			cond: Deleted("", "namespace", "mesh-external"),
+		},
+		{
+			name:       "cluster scoped not deleted",
+			namespaces: []string{namespace},
+			cond:       Deleted("", "namespace", "mesh-external"),
+			wantErr:    "namespace mesh-external still exists",
+		},
+		{
+			name: "all deleted",
+			pods: []string{reviews},
+			cond: AllDeleted("bookinfo", "pods", "app=productpage"),
+		},
+		{
+			name:    "not all deleted",
+			pods:    []string{productpage, reviews},
+			cond:    AllDeleted("bookinfo", "pods", "app"),
+			wantErr: "pods productpage-v1-6b746, reviews-v1-545db still exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := util.NewFakeKubeClient().
+				Add("pods", objects(t, tt.pods...)...).
+				Add("namespaces", objects(t, tt.namespaces...)...)
+			defer util.SetKubeClient(util.SetKubeClient(fake))
+
+			checkCondition(t, tt.cond, tt.wantErr)
+		})
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	// the server answers like the ingress gateway with a route for httpbin.example.com only
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "httpbin.example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/status/503" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		host     string
+		expected int
+		wantErr  string
+	}{
+		{
+			name:     "expected status",
+			path:     "/headers",
+			host:     "httpbin.example.com",
+			expected: 200,
+		},
+		{
+			name:     "other status",
+			path:     "/status/503",
+			host:     "httpbin.example.com",
+			expected: 200,
+			wantErr:  "status 503",
+		},
+		{
+			// e.g. before the gateway has the route
+			name:     "without host",
+			path:     "/headers",
+			expected: 404,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkCondition(t, HTTPStatus(server.URL+tt.path, tt.host, tt.expected), tt.wantErr)
+		})
+	}
+
+	t.Run("no server", func(t *testing.T) {
+		checkCondition(t, HTTPStatus("http://127.0.0.1:1/headers", "", 200), "connection refused")
+	})
+}
+
+func TestPodHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  util.CommandResult
+		wantErr string
+	}{
+		{
+			name:   "expected status",
+			result: util.CommandResult{Stdout: "403"},
+		},
+		{
+			name:    "other status",
+			result:  util.CommandResult{Stdout: "200"},
+			wantErr: "status 200",
+		},
+		{
+			name:    "curl failed",
+			result:  util.CommandResult{Stdout: "000", Stderr: "curl: (56) Recv failure: Connection reset by peer", ExitCode: 56},
+			wantErr: "Connection reset by peer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := util.NewFakeExecutor().On(`^kubectl exec sleep-1 -n foo -c sleep -- curl "http://httpbin.foo:8000/headers" -sS -o /dev/null -w "%\{http_code\}" -H "Authorization: Bearer token"$`, tt.result)
+			defer util.SetExecutor(util.SetExecutor(fake))
+
+			checkCondition(t, PodHTTPStatus("foo", "sleep-1", "sleep", "http://httpbin.foo:8000/headers", 403, `-H "Authorization: Bearer token"`), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// Resource identifies an Istio configuration resource, e.g. Resource{"VirtualService", "bookinfo", "reviews"}.
+type Resource struct {
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (r Resource) String() string {
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+}
+
+// marker returns a string the Envoy config dump contains once the proxy received the resource, or an
+// empty string if the resource cannot be recognized in the config dump.
+func (r Resource) marker() string {
+	switch strings.ToLower(r.Kind) {
+	case "virtualservice":
+		return fmt.Sprintf("/namespaces/%s/virtual-service/%s", r.Namespace, r.Name)
+	case "destinationrule":
+		return fmt.Sprintf("/namespaces/%s/destination-rule/%s", r.Namespace, r.Name)
+	case "authorizationpolicy":
+		return fmt.Sprintf("ns[%s]-policy[%s]", r.Namespace, r.Name)
+	}
+	return ""
+}
+
+// PushDebounce is how long a proxy must go without a push after istiod has a change, for the change to count
+// as propagated to it. istiod pushes a change after a debounce of 100ms by default; changes that do not
+// affect the configuration of a proxy, e.g. a route for another host, are not pushed to it at all.
+var PushDebounce = 2 * time.Second
+
+// Proxies selects the sidecars of the pods matching a label selector, e.g. Proxies{"bookinfo", "app=reviews"}.
+type Proxies struct {
+	Namespace string
+	Selector  string
+}
+
+// syncStatus is the xDS state of a proxy as reported by istiod's /debug/syncz. The fields are the
+// nonces of the last push of each resource type and of the last push the proxy acknowledged.
+type syncStatus struct {
+	Proxy         string `json:"proxy"`
+	ClusterSent   string `json:"cluster_sent"`
+	ClusterAcked  string `json:"cluster_acked"`
+	ListenerSent  string `json:"listener_sent"`
+	ListenerAcked string `json:"listener_acked"`
+	RouteSent     string `json:"route_sent"`
+	RouteAcked    string `json:"route_acked"`
+	EndpointSent  string `json:"endpoint_sent"`
+	EndpointAcked string `json:"endpoint_acked"`
+}
+
+// pending returns the resource types pushed to the proxy but not acknowledged yet.
+func (s syncStatus) pending() []string {
+	var pending []string
+	for _, t := range []struct{ name, sent, acked string }{
+		{"clusters", s.ClusterSent, s.ClusterAcked},
+		{"listeners", s.ListenerSent, s.ListenerAcked},
+		{"routes", s.RouteSent, s.RouteAcked},
+		{"endpoints", s.EndpointSent, s.EndpointAcked},
+	} {
+		if t.sent != t.acked {
+			pending = append(pending, t.name)
+		}
+	}
+	return pending
+}
+
+// pushedSince returns true if any resource type was pushed to the proxy after the earlier state.
+func (s syncStatus) pushedSince(earlier syncStatus) bool {
+	return s.ClusterSent != earlier.ClusterSent || s.ListenerSent != earlier.ListenerSent ||
+		s.RouteSent != earlier.RouteSent || s.EndpointSent != earlier.EndpointSent
+}
+
+// ConfigSync tracks the propagation of a configuration change from istiod to the chosen proxies.
+// It is created before the change, because it needs the xDS state of the proxies before the change
+// to tell a push of the new configuration from an earlier push:
+//
+//	sync := wait.BeforeConfigChange(wait.Proxies{ns, "app=reviews"})
+//	util.KubeApply(ns, ratingsDelayYaml)
+//	err := wait.UpTo(time.Minute, sync.Propagated(wait.Resource{"VirtualService", ns, "ratings"}))
+type ConfigSync struct {
+	proxies []Proxies
+	before  map[string]syncStatus
+	// quiet records when a proxy was first seen without a push since the change while istiod had it
+	quiet map[string]time.Time
+}
+
+// BeforeConfigChange records the xDS state of the proxies before a configuration change.
+func BeforeConfigChange(proxies ...Proxies) *ConfigSync {
+	s := &ConfigSync{proxies: proxies, before: map[string]syncStatus{}, quiet: map[string]time.Time{}}
+	if util.IsDryRun() {
+		return s
+	}
+	statuses, err := istiodSyncStatus(context.Background())
+	if err != nil {
+		// without the earlier state, any acknowledged push after istiod has the change counts
+		util.Log.Warnf("Failed to get the sync status of the proxies: %v", err)
+		return s
+	}
+	s.before = statuses
+	return s
+}
+
+// Propagated is met when istiod has the current generation of all resources and every selected proxy
+// acknowledged all pushes, with the resources in its config dump where they can be recognized there.
+// Resources that no longer exist in the API server must be gone from istiod and the config dumps, so
+// that the condition also waits for a deletion.
+// A proxy that got no push since BeforeConfigChange must stay without one for PushDebounce, because not
+// every change results in a push. An error names the resource or the proxy the change has not reached.
+func (s *ConfigSync) Propagated(resources ...Resource) Condition {
+	names := make([]string, 0, len(resources))
+	for _, r := range resources {
+		names = append(names, r.String())
+	}
+	selectors := make([]string, 0, len(s.proxies))
+	for _, p := range s.proxies {
+		selectors = append(selectors, fmt.Sprintf("%s in %s", p.Selector, p.Namespace))
+	}
+	return Condition{
+		Description: fmt.Sprintf("%s propagated to proxies %s", strings.Join(names, ", "), strings.Join(selectors, ", ")),
+		Check: func(ctx context.Context) error {
+			deleted := map[Resource]bool{}
+			for _, r := range resources {
+				gone, err := istiodHasGeneration(ctx, r)
+				if err != nil {
+					return err
+				}
+				deleted[r] = gone
+			}
+
+			statuses, err := istiodSyncStatus(ctx)
+			if err != nil {
+				return err
+			}
+			for _, p := range s.proxies {
+				var pods []util.Pod
+				if err := util.GetKubeClient().List(ctx, "pods", p.Namespace, util.ListOptions{LabelSelector: p.Selector}, &pods); err != nil {
+					return err
+				}
+				if len(pods) == 0 {
+					return fmt.Errorf("no pods %s in %s", p.Selector, p.Namespace)
+				}
+				for _, pod := range pods {
+					if err := s.proxyReceived(ctx, statuses, pod.Metadata.Name, p.Namespace, resources, deleted); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// proxyReceived checks that the proxy of a pod acknowledged the pushes after the change and has the resources,
+// except the deleted ones, which it must not have anymore. It is called once istiod has the change.
+func (s *ConfigSync) proxyReceived(ctx context.Context, statuses map[string]syncStatus, pod, ns string, resources []Resource, deleted map[Resource]bool) error {
+	id := pod + "." + ns
+	status, ok := statuses[id]
+	if !ok {
+		return fmt.Errorf("proxy %s is not connected to istiod", id)
+	}
+	if pending := status.pending(); len(pending) > 0 {
+		return fmt.Errorf("proxy %s has not acknowledged %s", id, strings.Join(pending, ", "))
+	}
+	if before, ok := s.before[id]; ok && !status.pushedSince(before) {
+		// either the push is still debounced by istiod or the change does not affect the proxy
+		since, ok := s.quiet[id]
+		if !ok {
+			since = util.Now()
+			s.quiet[id] = since
+		}
+		if util.Now().Sub(since) < PushDebounce {
+			return fmt.Errorf("no push to proxy %s since the change yet", id)
+		}
+	}
+
+	var markers []Resource
+	for _, r := range resources {
+		if r.marker() != "" {
+			markers = append(markers, r)
+		}
+	}
+	if len(markers) == 0 {
+		return nil
+	}
+	dump, err := query(ctx, `kubectl exec %s -n %s -c istio-proxy -- pilot-agent request GET config_dump`, pod, ns)
+	if err != nil {
+		return err
+	}
+	for _, r := range markers {
+		received := strings.Contains(dump, r.marker())
+		if deleted[r] && received {
+			return fmt.Errorf("proxy %s still has deleted %s", id, r)
+		}
+		if !deleted[r] && !received {
+			return fmt.Errorf("proxy %s has not received %s", id, r)
+		}
+	}
+	return nil
+}
+
+// istiodRequest returns the response of a debug endpoint of istiod.
+func istiodRequest(ctx context.Context, path string) (string, error) {
+	config := util.GetConfig()
+	return query(ctx, `kubectl exec -n %s deploy/istiod-%s -c discovery -- pilot-discovery request GET %s`, config.MeshNamespace, config.SMCPName, path)
+}
+
+// istiodSyncStatus returns the xDS state of all proxies connected to istiod, by proxy ID ("<pod>.<namespace>").
+func istiodSyncStatus(ctx context.Context) (map[string]syncStatus, error) {
+	out, err := istiodRequest(ctx, "/debug/syncz")
+	if err != nil {
+		return nil, err
+	}
+	var list []syncStatus
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, fmt.Errorf("failed to parse the sync status of istiod: %v", err)
+	}
+	statuses := make(map[string]syncStatus, len(list))
+	for _, s := range list {
+		statuses[s.Proxy] = s
+	}
+	return statuses, nil
+}
+
+// istiodHasGeneration checks that istiod has the generation of the resource currently stored in the API server,
+// or does not have the resource anymore if it was deleted from the API server. It reports whether it was deleted.
+func istiodHasGeneration(ctx context.Context, r Resource) (bool, error) {
+	var obj util.Object
+	err := util.GetKubeClient().Get(ctx, strings.ToLower(r.Kind), r.Namespace, r.Name, &obj)
+	deleted := util.IsNotFound(err)
+	if err != nil && !deleted {
+		return false, err
+	}
+
+	out, err := istiodRequest(ctx, "/debug/configz")
+	if err != nil {
+		return deleted, err
+	}
+	var configs []struct {
+		Type struct {
+			Kind string `json:"kind"`
+		} `json:"type"`
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Generation int64  `json:"generation"`
+	}
+	if err := json.Unmarshal([]byte(out), &configs); err != nil {
+		return deleted, fmt.Errorf("failed to parse the configuration of istiod: %v", err)
+	}
+	for _, c := range configs {
+		if strings.EqualFold(c.Type.Kind, r.Kind) && c.Namespace == r.Namespace && c.Name == r.Name {
+			if deleted {
+				return true, fmt.Errorf("istiod still has deleted %s", r)
+			}
+			if generation := obj.Metadata().Generation; c.Generation < generation {
+				return false, fmt.Errorf("istiod has generation %d of %s, expected %d", c.Generation, r, generation)
+			}
+			return false, nil
+		}
+	}
+	if deleted {
+		return true, nil
+	}
+	return false, fmt.Errorf("istiod has not received %s", r)
+}
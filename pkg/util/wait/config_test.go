@@ -0,0 +1,194 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+const (
+	syncz  = `pilot-discovery request GET /debug/syncz`
+	config = `pilot-discovery request GET /debug/configz`
+	dump   = `pilot-agent request GET config_dump`
+
+	// the state of the proxy before the change
+	synczBefore = `[{"proxy": "reviews-v1-545db77b95-2xh4p.bookinfo",
+		"cluster_sent": "n1", "cluster_acked": "n1", "listener_sent": "n2", "listener_acked": "n2",
+		"route_sent": "n3", "route_acked": "n3", "endpoint_sent": "n4", "endpoint_acked": "n4"}]`
+	// the routes were pushed and acknowledged
+	synczPushed = `[{"proxy": "reviews-v1-545db77b95-2xh4p.bookinfo",
+		"cluster_sent": "n1", "cluster_acked": "n1", "listener_sent": "n2", "listener_acked": "n2",
+		"route_sent": "n5", "route_acked": "n5", "endpoint_sent": "n4", "endpoint_acked": "n4"}]`
+	// the routes were pushed and not acknowledged yet
+	synczPending = `[{"proxy": "reviews-v1-545db77b95-2xh4p.bookinfo",
+		"cluster_sent": "n1", "cluster_acked": "n1", "listener_sent": "n2", "listener_acked": "n2",
+		"route_sent": "n5", "route_acked": "n3", "endpoint_sent": "n4", "endpoint_acked": "n4"}]`
+	synczOtherProxy = `[{"proxy": "ratings-v1-b6994bb9-7lq8s.bookinfo"}]`
+
+	pods           = `{"items": [{"metadata": {"name": "reviews-v1-545db77b95-2xh4p", "namespace": "bookinfo"}}]}`
+	virtualService = `{"apiVersion": "networking.istio.io/v1beta1", "kind": "VirtualService",
+		"metadata": {"name": "reviews", "namespace": "bookinfo", "generation": 2}}`
+	configzCurrent = `[{"type": {"kind": "VirtualService"}, "name": "reviews", "namespace": "bookinfo", "generation": 2}]`
+	configzOld     = `[{"type": {"kind": "VirtualService"}, "name": "reviews", "namespace": "bookinfo", "generation": 1}]`
+	dumpWithRoute  = `{"configs": [{"name": "reviews.bookinfo.svc.cluster.local:9080",
+		"metadata": {"filter_metadata": {"istio": {"config": "/apis/networking.istio.io/v1alpha3/namespaces/bookinfo/virtual-service/reviews"}}}}]}`
+)
+
+func TestPropagated(t *testing.T) {
+	tests := []struct {
+		name     string
+		after    string
+		configz  string
+		dump     string
+		debounce time.Duration
+		deleted  bool
+		wantErr  string
+	}{
+		{
+			name:    "pushed and acknowledged",
+			after:   synczPushed,
+			configz: configzCurrent,
+			dump:    dumpWithRoute,
+		},
+		{
+			name:    "push not acknowledged",
+			after:   synczPending,
+			configz: configzCurrent,
+			wantErr: "has not acknowledged routes",
+		},
+		{
+			name:    "istiod has an older generation",
+			after:   synczPushed,
+			configz: configzOld,
+			wantErr: "istiod has generation 1 of VirtualService bookinfo/reviews, expected 2",
+		},
+		{
+			name:    "istiod has not received the resource",
+			after:   synczPushed,
+			configz: `[]`,
+			wantErr: "istiod has not received VirtualService bookinfo/reviews",
+		},
+		{
+			name:    "proxy not connected",
+			after:   synczOtherProxy,
+			configz: configzCurrent,
+			wantErr: "is not connected to istiod",
+		},
+		{
+			name:    "pushed without the resource",
+			after:   synczPushed,
+			configz: configzCurrent,
+			dump:    `{"configs": []}`,
+			wantErr: "has not received VirtualService bookinfo/reviews",
+		},
+		{
+			// the change is not pushed to the proxy, e.g. because it does not affect its routes
+			name:    "no push after the debounce",
+			after:   synczBefore,
+			configz: configzCurrent,
+			dump:    dumpWithRoute,
+		},
+		{
+			name:     "no push within the debounce",
+			after:    synczBefore,
+			configz:  configzCurrent,
+			debounce: time.Hour,
+			wantErr:  "no push to proxy reviews-v1-545db77b95-2xh4p.bookinfo since the change yet",
+		},
+		{
+			name:    "deleted",
+			after:   synczPushed,
+			configz: `[]`,
+			dump:    `{"configs": []}`,
+			deleted: true,
+		},
+		{
+			name:    "istiod has the deleted resource",
+			after:   synczPushed,
+			configz: configzCurrent,
+			deleted: true,
+			wantErr: "istiod still has deleted VirtualService bookinfo/reviews",
+		},
+		{
+			name:    "proxy has the deleted resource",
+			after:   synczPushed,
+			configz: `[]`,
+			dump:    dumpWithRoute,
+			deleted: true,
+			wantErr: "still has deleted VirtualService bookinfo/reviews",
+		},
+	}
+
+	previous := PushDebounce
+	defer func() { PushDebounce = previous }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			PushDebounce = tt.debounce
+			get := util.CommandResult{Stdout: virtualService}
+			if tt.deleted {
+				get = util.CommandResult{Stderr: `Error from server (NotFound): virtualservices.networking.istio.io "reviews" not found`, ExitCode: 1}
+			}
+			fake := util.NewFakeExecutor().
+				OnSequence(syncz, util.CommandResult{Stdout: synczBefore}, util.CommandResult{Stdout: tt.after}).
+				On(config, util.CommandResult{Stdout: tt.configz}).
+				On(dump, util.CommandResult{Stdout: tt.dump}).
+				On(`^kubectl get pods -n bookinfo -l app=reviews -o json$`, util.CommandResult{Stdout: pods}).
+				On(`^kubectl get virtualservice reviews -n bookinfo -o json$`, get)
+			defer util.SetExecutor(util.SetExecutor(fake))
+
+			sync := BeforeConfigChange(Proxies{"bookinfo", "app=reviews"})
+			err := sync.Propagated(Resource{"VirtualService", "bookinfo", "reviews"}).Check(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPropagatedWithoutPush(t *testing.T) {
+	previous := PushDebounce
+	defer func() { PushDebounce = previous }()
+	PushDebounce = 50 * time.Millisecond
+
+	fake := util.NewFakeExecutor().
+		On(syncz, util.CommandResult{Stdout: synczBefore}).
+		On(config, util.CommandResult{Stdout: configzCurrent}).
+		On(dump, util.CommandResult{Stdout: dumpWithRoute}).
+		On(`^kubectl get pods `, util.CommandResult{Stdout: pods}).
+		On(`^kubectl get virtualservice `, util.CommandResult{Stdout: virtualService})
+	defer util.SetExecutor(util.SetExecutor(fake))
+
+	sync := BeforeConfigChange(Proxies{"bookinfo", "app=reviews"})
+	cond := sync.Propagated(Resource{"VirtualService", "bookinfo", "reviews"})
+	if err := cond.Check(context.Background()); err == nil {
+		t.Fatal("a change without a push counted as propagated before the debounce")
+	}
+	time.Sleep(PushDebounce)
+	if err := cond.Check(context.Background()); err != nil {
+		t.Fatalf("a change without a push did not count as propagated after the debounce: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wait waits for the cluster to reach a state instead of sleeping for a fixed time.
+//
+//	util.KubeApplyContents(ns, deployment)
+//	if err := wait.UpTo(3*time.Minute, wait.DeploymentRolledOut(ns, "httpbin")); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// A configuration change takes effect only after istiod pushed it to the proxies. BeforeConfigChange
+// records the xDS state of the proxies handling the requests of a test, and Propagated is met once they
+// acknowledged the pushes carrying the changed resources:
+//
+//	sync := wait.BeforeConfigChange(wait.Proxies{Namespace: ns, Selector: "app=sleep"})
+//	util.KubeApplyContents(ns, virtualService)
+//	if err := wait.UpTo(time.Minute, sync.Propagated(wait.Resource{Kind: "VirtualService", Namespace: ns, Name: "reviews"})); err != nil {
+//		t.Fatal(err)
+//	}
+package wait
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+// Interval is the time between two checks of a condition.
+var Interval = 2 * time.Second
+
+// Condition is a state of the cluster to wait for.
+type Condition struct {
+	// Description describes the state, e.g. "SMCP istio-system/basic Ready".
+	Description string
+	// Check returns nil if the state has been reached, or an error describing the current state otherwise.
+	Check func(ctx context.Context) error
+}
+
+// For checks the condition until it is met or ctx is done. If ctx is done first, the returned error
+// includes the last state reported by the condition.
+func For(ctx context.Context, cond Condition) error {
+	if util.IsDryRun() {
+		return nil
+	}
+
+	start := time.Now()
+	var last error
+	for {
+		if last = cond.Check(ctx); last == nil {
+			util.Log.Infof("%s after %s", cond.Description, time.Since(start).Round(time.Second))
+			return nil
+		}
+
+		if err := util.ReplayDiverged(); err != nil {
+			return fmt.Errorf("waiting for %s: %v: %v", cond.Description, err, last)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out after %s waiting for %s: %v", time.Since(start).Round(time.Second), cond.Description, last)
+		case <-time.After(util.Delay(Interval)):
+		}
+	}
+}
+
+// UpTo checks the condition until it is met or the timeout expires.
+func UpTo(timeout time.Duration, cond Condition) error {
+	util.Log.Infof("Waiting up to %s for %s", timeout, cond.Description)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return For(ctx, cond)
+}
+
+// All combines conditions into one that is met when all of them are met.
+func All(conds ...Condition) Condition {
+	desc := ""
+	for i, c := range conds {
+		if i > 0 {
+			desc += " and "
+		}
+		desc += c.Description
+	}
+	return Condition{
+		Description: desc,
+		Check: func(ctx context.Context) error {
+			for _, c := range conds {
+				if err := c.Check(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 Red Hat, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maistra/maistra-test-tool/pkg/util"
+)
+
+const (
+	ratingsPending = `{"items": [{"metadata": {"name": "ratings-v1-b6994bb9-7lq8s"}, "status": {"phase": "Pending"}}]}`
+	ratingsRunning = `{"items": [{"metadata": {"name": "ratings-v1-b6994bb9-7lq8s"}, "status": {"phase": "Running",
+		"containerStatuses": [{"name": "ratings", "ready": true}]}}]}`
+)
+
+func TestUpToWhileReplaying(t *testing.T) {
+	previous := Interval
+	defer func() { Interval = previous }()
+	Interval = time.Hour
+
+	// the pods became ready on the third check
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	rec := util.Recording{}
+	for i, stdout := range []string{ratingsPending, ratingsPending, ratingsRunning} {
+		rec.Commands = append(rec.Commands, util.RecordedCommand{
+			Command:  "kubectl get pods -n bookinfo -l app=ratings -o json",
+			Stdout:   stdout,
+			Start:    start.Add(time.Duration(i) * previous),
+			Duration: 100 * time.Millisecond,
+		})
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "T1.json")
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("T1", func(t *testing.T) {
+		util.ReplaySession(t, filename)
+		begin := time.Now()
+		if err := UpTo(time.Minute, PodsReady("bookinfo", "app=ratings")); err != nil {
+			t.Fatal(err)
+		}
+		if elapsed := time.Since(begin); elapsed > 10*time.Second {
+			t.Errorf("the replay waited %s between the checks", elapsed)
+		}
+	})
+}
@@ -25,4 +25,6 @@ done
 # collect logs
 oc cp maistra-pipelines/${podName}:test.log ${WORKSPACE}/tests/test.log -c step-run-all-test-cases
 oc cp maistra-pipelines/${podName}:results.xml ${WORKSPACE}/tests/results.xml -c step-run-all-test-cases
+oc cp maistra-pipelines/${podName}:results.json ${WORKSPACE}/tests/results.json -c step-run-all-test-cases
+oc cp maistra-pipelines/${podName}:artifacts ${WORKSPACE}/tests/artifacts -c step-run-all-test-cases || true
 
@@ -15,18 +15,29 @@
 /*
  * This package includes an entrypoint of running tests.
  * main_test.go is calling Golang testing.Main framework and it reloads all packages from pkg directory.
- * All test cases are mapped in the test_cases.go file.
+ * Test cases register themselves in the suite package. The packages are imported in the test_cases.go file.
  */
 
 package tests
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/maistra/maistra-test-tool/pkg/suite"
 	"github.com/maistra/maistra-test-tool/pkg/util"
 )
 
 // Create namespaces. All test samples and configurations will be in those namespaces.
+// Only used when namespaces are not isolated, otherwise every test case gets its own namespaces.
 func setupNamespaces() {
 	util.ShellSilent(`oc new-project bookinfo`)
 	util.ShellSilent(`oc new-project foo`)
@@ -35,27 +46,323 @@ func setupNamespaces() {
 	util.ShellSilent(`oc new-project mesh-external`)
 }
 
+var (
+	junitReport  = flag.String("junit", "results.xml", "write a JUnit XML report to this file; empty to disable")
+	jsonReport   = flag.String("json-report", "results.json", "write a JSON report to this file; empty to disable")
+	artifactsDir = flag.String("artifacts", "artifacts", "collect diagnostic artifacts of failed tests into this directory; empty to disable")
+	// the settings of util.Config, e.g. -mesh-namespace or -sample-arch
+	configFlags = util.RegisterConfigFlags(flag.CommandLine)
+	// output receives the output of the child processes running the test cases
+	output io.Writer = os.Stdout
+)
+
+// auditFile is the command audit trail a child process writes to its log directory.
+const auditFile = "commands.jsonl"
+
 // this function is used for matching command line argument <test case name>,
-// e.g. `go test -run <test case name>` with the names in the test_cases.go file.
+// e.g. `go test -run <test case name>` with the IDs of the registered test cases.
+// Besides an exact ID, the pattern can be a regular expression such as `T1|T2` or `T1[0-9]`.
 func matchString(a, b string) (bool, error) {
-	return a == b, nil
+	return suite.MatchString(a, b)
+}
+
+// selectTests returns the test cases of the group further narrowed by the include and exclude patterns.
+// Patterns are globs or /regular expressions/ matching the ID, the title or the function name of a test case,
+// e.g. SKIP_TESTS=T6,T28 or -include '*egress*'.
+func selectTests(group string, config *util.Config) ([]suite.TestCase, error) {
+	tests, err := suite.Select(group)
+	if err != nil {
+		return nil, err
+	}
+	filter := suite.Filter{
+		Include: suite.ParsePatterns(config.Include),
+		Exclude: suite.ParsePatterns(config.Exclude),
+	}
+	return filter.Apply(tests)
+}
+
+// listPattern returns the regular expression given to `go test -list <regexp>`, if any.
+func listPattern() string {
+	if f := flag.Lookup("test.list"); f != nil {
+		return f.Value.String()
+	}
+	return ""
 }
 
 func TestMain(m *testing.M) {
-	setupNamespaces()
-
-	// run test group defined by env variable 'TEST_GROUP'
-	// groups are defined in test_cases.go
-	// TODO check https://go.dev/blog/subtests if we want to use that instead of this
-	if util.Getenv("TEST_GROUP", "full") == "full" {
-		testing.Main(matchString, full, nil, nil)
-	} else if util.Getenv("SAMPLEARCH", "x86") == "arm" ||
-		util.Getenv("TEST_GROUP", "full") == "arm" {
-		testing.Main(matchString, arm, nil, nil)
-	} else if util.Getenv("TEST_GROUP", "full") == "smoke" {
-		testing.Main(matchString, smoke, nil, nil)
-	} else if util.Getenv("TEST_GROUP", "full") == "interop" {
-		testing.Main(matchString, interop, nil, nil)
+	flag.Parse()
+
+	config, err := util.LoadConfig(configFlags)
+	if err != nil {
+		util.Log.Fatalf("Invalid configuration: %v", err)
+	}
+	util.SetConfig(config)
+	if err := util.ConfigureLog(config.LogFormat, config.LogLevel); err != nil {
+		util.Log.Fatal(err)
+	}
+	if config.DryRun {
+		enableDryRun(config)
+	}
+
+	// run the test cases selected by the tag expression in env variable 'TEST_GROUP',
+	// e.g. "full", "smoke", "interop" or "smoke,!disruptive". Tags are declared where tests are registered.
+	// Unless the full group is requested, arm clusters run the test cases tagged arm instead of the group.
+	group := config.TestGroup
+	if config.SampleArch == "arm" && group != suite.Full {
+		group = suite.ARM
+	}
+	tests, err := selectTests(group, config)
+	if err != nil {
+		util.Log.Fatal(err)
+	}
+
+	// `go test -list .` prints the selectable test cases with their groups instead of running them
+	if pattern := listPattern(); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			util.Log.Fatal(err)
+		}
+		var listed []suite.TestCase
+		for _, tc := range tests {
+			if re.MatchString(tc.ID) {
+				listed = append(listed, tc)
+			}
+		}
+		if err := suite.PrintList(os.Stdout, listed); err != nil {
+			util.Log.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if !suite.IsChild() {
+		if config.LogDir != "" {
+			// the run log is not buffered, it does not need to be closed before exiting
+			runLog, err := createRunLog(config.LogDir)
+			if err != nil {
+				util.Log.Fatalf("Failed to create the run log: %v", err)
+			}
+			util.Log.Out = io.MultiWriter(os.Stderr, runLog)
+			output = io.MultiWriter(os.Stdout, runLog)
+		}
+		config.Print()
+		// the child process reads the effective configuration from the environment
+		config.Export()
+		os.Exit(runAndReport(group, config))
+	}
+
+	// the test cases run in a child process so that their output can be captured per test case.
+	// Logs go to stdout to keep them in order with the output of the testing package.
+	util.Log.Out = os.Stdout
+	if config.LogDir != "" {
+		if err := util.EnableTestLogs(config.LogDir); err != nil {
+			util.Log.Fatalf("Failed to create the log directory: %v", err)
+		}
+		if err := util.EnableAudit(filepath.Join(config.LogDir, auditFile)); err != nil {
+			util.Log.Fatalf("Failed to create the command audit trail: %v", err)
+		}
+	}
+	if config.ReplayDir != "" {
+		// the recordings start from the cluster state left by the setup of the recorded run. Commands
+		// outside of a replayed test case fail instead of reaching a cluster.
+		util.SetExecutor(util.NewFakeExecutor())
+		suite.ReplaySessions(config.ReplayDir)
+	} else {
+		suite.RunSetup()
+	}
+	if config.RecordDir != "" {
+		if err := suite.RecordSessions(config.RecordDir); err != nil {
+			util.Log.Fatalf("Failed to create the record directory: %v", err)
+		}
+	}
+	if *artifactsDir != "" && config.ReplayDir == "" {
+		suite.NewArtifactCollector(*artifactsDir, config.MeshNamespace).Enable()
+	}
+	if config.IsolateNamespaces {
+		suite.SetNamespaceAllocator(util.NewNamespaceAllocator(config.MeshNamespace))
+	} else if config.ReplayDir == "" {
+		setupNamespaces()
+	}
+
+	// test cases that do not declare the same resources run in parallel. The scheduler limits the
+	// parallelism, so the limit of the testing package is raised to the number of test cases.
+	if config.Parallelism > 1 {
+		if !config.IsolateNamespaces {
+			util.Log.Fatal("Running test cases in parallel requires isolated namespaces, see -isolate-namespaces")
+		}
+		suite.SetScheduler(suite.NewScheduler(config.Parallelism))
+		flag.Set("test.parallel", strconv.Itoa(len(tests)+1))
+	}
+	util.Log.Infof("Test group %q selected %d test cases", group, len(tests))
+
+	testing.Main(matchString, suite.InternalTests(tests), nil, nil)
+}
+
+// createRunLog creates the log file of the whole run in dir. It receives the output of the parent and
+// of the child processes, while the child processes write the entries of every test case to a file of its own.
+func createRunLog(dir string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(filepath.Join(dir, "run.log"))
+}
+
+// enableDryRun prints the commands instead of running them. The child process saves them to the plan directory.
+func enableDryRun(config *util.Config) {
+	var stubs []util.Stub
+	if config.DryRunStubs != "" {
+		var err error
+		if stubs, err = util.LoadStubs(config.DryRunStubs); err != nil {
+			util.Log.Fatal(err)
+		}
 	}
+	dir := ""
+	if suite.IsChild() {
+		dir = config.PlanDir
+	}
+	if _, err := util.EnableDryRun(dir, stubs); err != nil {
+		util.Log.Fatalf("Failed to enable dry-run mode: %v", err)
+	}
+}
+
+// runAndReport runs the selected test cases in a child process, writes the reports and returns the exit code.
+func runAndReport(group string, config *util.Config) int {
+	report, code, err := suite.RunChild("maistra-test-tool", output)
+	if err != nil {
+		util.Log.Error(err)
+		if report == nil {
+			return 1
+		}
+	}
+	readTestLogs(report, config, config.LogDir)
+	if *artifactsDir != "" {
+		suite.AddArtifacts(report, *artifactsDir)
+	}
+	code = retryFailed(report, code, config)
+
+	addProperties(report, group, config)
+	if *junitReport != "" {
+		if err := report.WriteJUnit(*junitReport); err != nil {
+			util.Log.Errorf("Failed to write JUnit report: %v", err)
+		}
+	}
+	if *jsonReport != "" {
+		if err := report.WriteJSON(*jsonReport); err != nil {
+			util.Log.Errorf("Failed to write JSON report: %v", err)
+		}
+	}
+
+	total, failed, skipped := report.Counts()
+	flaky := report.Flaky()
+	util.Log.Infof("Tests: %d, failed: %d, skipped: %d, flaky: %d", total, failed, skipped, len(flaky))
+	for _, res := range flaky {
+		util.Log.Warnf("Flaky test case %s passed on retry %d", res.Name, len(res.Attempts))
+	}
+	for _, leaked := range report.Leaked() {
+		util.Log.Warnf("Leaked resource %s", leaked)
+	}
+	if config.LogDir != "" {
+		printAuditSummary(config.LogDir, config.Retries)
+	}
+	return code
+}
+
+// printAuditSummary logs the slowest and the most frequent commands of the run and of its retries.
+func printAuditSummary(logDir string, retries int) {
+	files := []string{filepath.Join(logDir, auditFile)}
+	for retry := 1; retry <= retries; retry++ {
+		files = append(files, filepath.Join(logDir, fmt.Sprintf("retry-%d", retry), auditFile))
+	}
+	entries, err := util.LoadAudit(files...)
+	if err != nil {
+		util.Log.Errorf("Failed to read the command audit trail: %v", err)
+		return
+	}
+	util.SummarizeAudit(entries, 10).Print()
+}
 
+// readTestLogs takes the output of the test cases running in parallel from their log files in dir, since
+// their lines are interleaved on stdout.
+func readTestLogs(report *suite.Report, config *util.Config, dir string) {
+	if config.Parallelism <= 1 || config.LogDir == "" {
+		return
+	}
+	if err := report.ReadTestLogs(dir); err != nil {
+		util.Log.Errorf("Failed to read the test case logs: %v", err)
+	}
+}
+
+// retryFailed reruns the failed test cases of the report up to config.Retries times, each time in a new child
+// process with new namespaces, and returns the exit code of the last run. The artifacts and the test case
+// logs of a retry are saved to a subdirectory of the artifacts and log directories.
+func retryFailed(report *suite.Report, code int, config *util.Config) int {
+	retries, logDir := config.Retries, config.LogDir
+	for retry := 1; retry <= retries; retry++ {
+		failed := report.Failed()
+		if len(failed) == 0 {
+			break
+		}
+		util.Log.Infof("Retrying failed test cases %s (retry %d of %d)", strings.Join(failed, ", "), retry, retries)
+
+		args := []string{"-test.run", suite.RunPattern(failed)}
+		dir := ""
+		if *artifactsDir != "" {
+			dir = filepath.Join(*artifactsDir, fmt.Sprintf("retry-%d", retry))
+			args = append(args, "-artifacts", dir)
+		}
+		if logDir != "" {
+			args = append(args, "-log-dir", filepath.Join(logDir, fmt.Sprintf("retry-%d", retry)))
+		}
+		rerun, rerunCode, err := suite.RunChild("maistra-test-tool", output, args...)
+		if err != nil {
+			util.Log.Error(err)
+			if rerun == nil {
+				return code
+			}
+		}
+		readTestLogs(rerun, config, filepath.Join(logDir, fmt.Sprintf("retry-%d", retry)))
+		if dir != "" {
+			suite.AddArtifacts(rerun, dir)
+		}
+		report.AddRetry(rerun)
+		code = rerunCode
+	}
+	return code
+}
+
+// addProperties records the environment the tests ran against. Replayed test cases did not run against
+// a cluster, so the cluster is not queried for its versions then.
+func addProperties(report *suite.Report, group string, config *util.Config) {
+	report.AddProperty("TEST_GROUP", group)
+	report.AddProperty("SAMPLEARCH", config.SampleArch)
+	report.AddProperty("MESHNAMESPACE", config.MeshNamespace)
+	report.AddProperty("SMCPNAME", config.SMCPName)
+	if config.ReplayDir != "" {
+		report.AddProperty("REPLAY_DIR", config.ReplayDir)
+		return
+	}
+	report.AddProperty("smcp.version", shellProperty(`oc get smcp/%s -n %s -o jsonpath='{.spec.version}'`, config.SMCPName, config.MeshNamespace))
+	report.AddProperty("operator.version", shellProperty(`oc get csv -n openshift-operators -l operators.coreos.com/servicemeshoperator.openshift-operators -o jsonpath='{.items[0].spec.version}'`))
+	report.AddProperty("ocp.version", ocpVersion())
+}
+
+func shellProperty(format string, args ...interface{}) string {
+	msg, err := util.ShellSilent(format, args...)
+	if err != nil || msg == "" {
+		return "unknown"
+	}
+	return msg
+}
+
+func ocpVersion() string {
+	msg, err := util.ShellSilent(`oc version -o json`)
+	if err != nil {
+		return "unknown"
+	}
+	var version struct {
+		OpenshiftVersion string `json:"openshiftVersion"`
+	}
+	if err := json.Unmarshal([]byte(msg), &version); err != nil || version.OpenshiftVersion == "" {
+		return "unknown"
+	}
+	return version.OpenshiftVersion
 }